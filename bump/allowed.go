@@ -0,0 +1,56 @@
+package bump
+
+import (
+	"fmt"
+	"io/ioutil"
+	"strings"
+)
+
+// allowedValues returns the values given with --allowed-value and in --allowed-values-file,
+// or nil if neither is set.  The file has one value per line; blank lines and lines starting
+// with # are ignored.
+func allowedValues(cfg *config) ([]string, error) {
+	allowed := append([]string(nil), cfg.Allowed...)
+	if cfg.AllowedFile != "" {
+		content, err := ioutil.ReadFile(cfg.AllowedFile)
+		if err != nil {
+			return nil, fmt.Errorf("read --allowed-values-file: %w", err)
+		}
+		var n int
+		for _, line := range strings.Split(string(content), "\n") {
+			line = strings.TrimSpace(line)
+			if line == "" || strings.HasPrefix(line, "#") {
+				continue
+			}
+			allowed = append(allowed, line)
+			n++
+		}
+		if n == 0 {
+			return nil, fmt.Errorf("--allowed-values-file %s lists no values", cfg.AllowedFile)
+		}
+	}
+	return allowed, nil
+}
+
+// checkAllowed returns an error if the allowed values are restricted and value is not one of
+// them, or if --semver is set and value is not a semantic version.
+func checkAllowed(cfg *config, value string) error {
+	if cfg.Semver {
+		if _, err := parseVersion(value); err != nil {
+			return fmt.Errorf("%w: %v", errInvalidReplacement, err)
+		}
+	}
+	allowed, err := allowedValues(cfg)
+	if err != nil {
+		return err
+	}
+	if allowed == nil {
+		return nil
+	}
+	for _, a := range allowed {
+		if value == a {
+			return nil
+		}
+	}
+	return fmt.Errorf("replacement %q is not an allowed value; want one of %s", value, strings.Join(allowed, ", "))
+}
@@ -0,0 +1,63 @@
+package bump
+
+import (
+	"context"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestAllowedValues(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "channels")
+	if err := ioutil.WriteFile(file, []byte("# release channels\nstable\n\nbeta\n"), 0644); err != nil {
+		t.Fatalf("write allowed values: %v", err)
+	}
+	testData := []struct {
+		name        string
+		allowed     []string
+		file        string
+		replacement string
+		wantErr     bool
+	}{
+		{name: "unrestricted", replacement: "anything"},
+		{name: "allowed", allowed: []string{"stable", "beta", "edge"}, replacement: "beta"},
+		{name: "typo", allowed: []string{"stable", "beta", "edge"}, replacement: "stabel", wantErr: true},
+		{name: "allowed by file", file: file, replacement: "stable"},
+		{name: "allowed by flag and file", allowed: []string{"edge"}, file: file, replacement: "edge"},
+		{name: "comment in file", file: file, replacement: "# release channels", wantErr: true},
+		{name: "missing file", file: filepath.Join(t.TempDir(), "missing"), replacement: "stable", wantErr: true},
+	}
+	for _, test := range testData {
+		t.Run(test.name, func(t *testing.T) {
+			gh, client := newFakeGitHub(t)
+			before := gh.push("o/r", "master", map[string]string{"config.yaml": lines("channel: edge")})
+			cfg := &config{
+				GithubOwner:  "o",
+				GithubRepo:   "r",
+				GithubBranch: "master",
+				File:         "config.yaml",
+				Locations:    []string{"channel"},
+				Replacement:  test.replacement,
+				Allowed:      test.allowed,
+				AllowedFile:  test.file,
+			}
+			err := run(context.Background(), client, cfg, ioutil.Discard)
+			if !test.wantErr {
+				if err != nil {
+					t.Fatalf("run: %v", err)
+				}
+				if got, _ := gh.file("o/r", "master", "config.yaml"); got != lines("channel: "+test.replacement) {
+					t.Errorf("content: got %q", got)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), "allowed") {
+				t.Errorf("want an error about allowed values, got %v", err)
+			}
+			if after := gh.head("o/r", "master").SHA; after != before {
+				t.Errorf("branch moved from %s to %s", before, after)
+			}
+		})
+	}
+}
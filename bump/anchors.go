@@ -0,0 +1,86 @@
+package bump
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+
+	"sigs.k8s.io/kustomize/kyaml/yaml"
+)
+
+// identifyingFields are the fields, in order of preference, that identify an element of a
+// sequence independently of its position.
+var identifyingFields = []string{"name", "id", "key"}
+
+// anchors remember which element each sequence index in a location pointed at, so that the
+// element can still be found after the sequence is reordered.  They are keyed by document
+// number and the location up to the index, like "1:spec.containers[0]", and hold a match for
+// the element, like "[name=app]".
+type anchors map[string]string
+
+// resolve returns path with each index replaced by the recorded match for its element, if the
+// index no longer points at that element but another element matches.  The element each index
+// ends up pointing at is recorded for next time.
+func (a anchors) resolve(doc *yaml.RNode, docNum int, path []string, trace io.Writer) ([]string, error) {
+	resolved := append([]string(nil), path...)
+	for k, segment := range path {
+		if !isIndex(segment) {
+			continue
+		}
+		key := fmt.Sprintf("%d:%s", docNum, formatLocation(path[:k+1]))
+		seq, err := doc.Pipe(lookup(resolved[:k])...)
+		if err != nil {
+			return nil, err
+		}
+		if seq == nil || seq.YNode().Kind != yaml.SequenceNode {
+			// Missing; the edit will skip it.
+			return resolved, nil
+		}
+		i, _ := strconv.Atoi(segment[1 : len(segment)-1])
+		elem, err := elementAt(i).Filter(seq)
+		if err != nil {
+			return nil, err
+		}
+		if match, ok := a[key]; ok && !matchesElement(elem, match) {
+			field, value, err := yaml.SplitIndexNameValue(match)
+			if err != nil {
+				return nil, fmt.Errorf("recorded match for %s: %w", key, err)
+			}
+			moved, err := seq.Pipe(yaml.MatchElement(field, value))
+			if err != nil {
+				return nil, err
+			}
+			if moved != nil {
+				if trace != nil {
+					fmt.Fprintf(trace, "document %d: %s no longer points at the element %s; following it\n", docNum, formatLocation(path[:k+1]), match)
+				}
+				resolved[k] = match
+				elem = moved
+			}
+		}
+		if match := identify(elem); match != "" {
+			a[key] = match
+		}
+	}
+	return resolved, nil
+}
+
+// identify returns a match like "[name=app]" for elem, using the first of identifyingFields
+// that it has, or "" if it has none.
+func identify(elem *yaml.RNode) string {
+	if elem == nil || elem.YNode().Kind != yaml.MappingNode {
+		return ""
+	}
+	for _, field := range identifyingFields {
+		if f := elem.Field(field); f != nil && f.Value.YNode().Kind == yaml.ScalarNode {
+			return fmt.Sprintf("[%s=%s]", field, f.Value.YNode().Value)
+		}
+	}
+	return ""
+}
+
+// matchesElement returns true if elem is the element that match, as returned by identify,
+// refers to.
+func matchesElement(elem *yaml.RNode, match string) bool {
+	return elem != nil && identify(elem) == match
+}
@@ -0,0 +1,123 @@
+package bump
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/go-github/v32/github"
+	"golang.org/x/crypto/openpgp"
+)
+
+// File is a file read from a branch by Fetch.
+type File struct {
+	Path    string
+	Content string
+	// CommitSHA is the commit the file was read from, the head of the branch at the time, and
+	// TreeSHA is the commit's tree.  Commit builds on them.
+	CommitSHA string
+	TreeSHA   string
+}
+
+// FetchOptions say which file Fetch reads.
+type FetchOptions struct {
+	Owner, Repo, Branch string
+	Path                string
+	// PathPrefix, if set, is a directory containing Path.  Only the trees along the way to it
+	// are read, rather than the whole tree of the repository.
+	PathPrefix string
+}
+
+// Fetch reads a file from the head of a branch.
+func Fetch(ctx context.Context, client *github.Client, opts FetchOptions) (*File, error) {
+	f, err := fetch(ctx, client, opts.Owner, opts.Repo, opts.Branch, opts.Path, opts.PathPrefix, false)
+	if err != nil {
+		return nil, fmt.Errorf("fetch %s from github.com/%s/%s@%s: %w", opts.Path, opts.Owner, opts.Repo, opts.Branch, err)
+	}
+	return &File{Path: opts.Path, Content: f.Content, CommitSHA: f.CommitSHA, TreeSHA: f.Tree.GetSHA()}, nil
+}
+
+// Edit is an edit for EditYAML to make: the value at Location, written as for --location, is
+// replaced with Value.
+type Edit struct {
+	Location string
+	Value    string
+}
+
+// EditOptions say what EditYAML edits.
+type EditOptions struct {
+	Edits []Edit
+	// MatchLabels, if non-empty, restricts edits to documents whose metadata.labels contain
+	// every one of these labels.
+	MatchLabels map[string]string
+	// FirstMatchOnly, if set, edits each location only in the first document it is found in.
+	FirstMatchOnly bool
+	// Comment, if set, is attached to each edited value as a line comment.
+	Comment string
+}
+
+// Change describes the effect of editing one location in one document.
+type Change struct {
+	Location string
+	Found    bool
+	Document int // The number of the document, from 1, if found.
+	Line     int
+	Old, New string
+}
+
+// EditYAML makes the edits in opts to each document in input, exactly as the version-bump
+// command does, and returns the result with a Change for each location edited in each
+// document, followed by one with Found unset for each location not found in any document.
+func EditYAML(input string, opts EditOptions) (string, []Change, error) {
+	var edits []locationEdit
+	for _, e := range opts.Edits {
+		edits = append(edits, locationEdit{Path: e.Location, Value: e.Value})
+	}
+	out, changes, err := editYAML(input, edits, editOptions{MatchLabels: opts.MatchLabels, FirstMatchOnly: opts.FirstMatchOnly, Comment: opts.Comment})
+	if err != nil {
+		return "", nil, err
+	}
+	result := make([]Change, len(changes))
+	for i, c := range changes {
+		result[i] = Change(c)
+	}
+	return out, result, nil
+}
+
+// CommitOptions say what Commit commits, and where.
+type CommitOptions struct {
+	Owner, Repo, Branch string
+	// Parent is the commit to build on, normally the CommitSHA of a File from Fetch.  Unless
+	// Force is set, Branch must still point at it.
+	Parent string
+	// BaseTree is the tree to write Files on top of.  It defaults to the tree of Parent.
+	BaseTree string
+	// Files maps the paths of the files to write to their new content.
+	Files                   map[string]string
+	Message                 string
+	AuthorName, AuthorEmail string
+	// CommitterName and CommitterEmail default to the author's.
+	CommitterName, CommitterEmail string
+	// SigningKey, if set, signs the commit.  Its private key must already be decrypted.
+	SigningKey *openpgp.Entity
+	Force      bool
+}
+
+// Commit writes files in a new commit on top of a parent commit, moves a branch to it, and
+// returns its SHA.
+func Commit(ctx context.Context, client *github.Client, opts CommitOptions) (string, error) {
+	if opts.Parent == "" {
+		return "", errors.New("no parent commit")
+	}
+	baseTree := opts.BaseTree
+	if baseTree == "" {
+		parent, _, err := client.Git.GetCommit(ctx, opts.Owner, opts.Repo, opts.Parent)
+		if err != nil {
+			return "", fmt.Errorf("get parent commit %s: %w", opts.Parent, err)
+		}
+		baseTree = parent.GetTree().GetSHA()
+	}
+	id := resolveIdentity(&config{AuthorName: opts.AuthorName, AuthorEmail: opts.AuthorEmail, CommitterName: opts.CommitterName, CommitterMail: opts.CommitterEmail})
+	id.SigningKey = opts.SigningKey
+	return commit(ctx, client, baseTree, opts.Parent, opts.Owner, opts.Repo, opts.Branch, opts.Files, opts.Message, id, opts.Force)
+}
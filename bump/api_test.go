@@ -0,0 +1,62 @@
+package bump
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestAPI(t *testing.T) {
+	gh, client := newFakeGitHub(t)
+	gh.push("o/r", "master", map[string]string{"deploy/app.yaml": lines("image:", "  tag: v1")})
+	ctx := context.Background()
+
+	f, err := Fetch(ctx, client, FetchOptions{Owner: "o", Repo: "r", Branch: "master", Path: "deploy/app.yaml"})
+	if err != nil {
+		t.Fatalf("fetch: %v", err)
+	}
+	if want := lines("image:", "  tag: v1"); f.Content != want {
+		t.Errorf("content: got %q, want %q", f.Content, want)
+	}
+
+	edited, changes, err := EditYAML(f.Content, EditOptions{Edits: []Edit{{Location: "image.tag", Value: "v2"}, {Location: "image.pull", Value: "Always"}}})
+	if err != nil {
+		t.Fatalf("edit: %v", err)
+	}
+	wantChanges := []Change{
+		{Location: "image.tag", Found: true, Document: 1, Line: 2, Old: "v1", New: "v2"},
+		{Location: "image.pull"},
+	}
+	if diff := cmp.Diff(wantChanges, changes); diff != "" {
+		t.Errorf("changes (-want +got):\n%s", diff)
+	}
+
+	for _, baseTree := range []string{f.TreeSHA, ""} {
+		sha, err := Commit(ctx, client, CommitOptions{
+			Owner:       "o",
+			Repo:        "r",
+			Branch:      "master",
+			Parent:      gh.head("o/r", "master").SHA,
+			BaseTree:    baseTree,
+			Files:       map[string]string{f.Path: edited},
+			Message:     "bump",
+			AuthorName:  "bumper",
+			AuthorEmail: "bumper@example.com",
+		})
+		if err != nil {
+			t.Fatalf("commit on base tree %q: %v", baseTree, err)
+		}
+		if head := gh.head("o/r", "master").SHA; head != sha {
+			t.Errorf("head: got %s, want the new commit %s", head, sha)
+		}
+		if got, _ := gh.file("o/r", "master", "deploy/app.yaml"); got != lines("image:", "  tag: v2") {
+			t.Errorf("committed content: got %q", got)
+		}
+	}
+
+	// Committing on the old head fails, since the branch has moved on.
+	if _, err := Commit(ctx, client, CommitOptions{Owner: "o", Repo: "r", Branch: "master", Parent: f.CommitSHA, BaseTree: f.TreeSHA, Files: map[string]string{f.Path: edited}}); err == nil {
+		t.Error("expected an error committing on a stale parent")
+	}
+}
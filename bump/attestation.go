@@ -0,0 +1,100 @@
+package bump
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/v32/github"
+)
+
+// provenance is a record of one edit, appended to the --attestation file as a line of JSON.
+type provenance struct {
+	// Subject is the file that was edited, and Parent the commit it was read from.
+	Subject string `json:"subject"`
+	Parent  string `json:"parent"`
+	// Changes are the values at each location before and after the edit.
+	Changes []valueChange `json:"changes"`
+	// Actor is the author of the commit.
+	Actor string `json:"actor"`
+	// Source describes where the replacement came from.
+	Source string    `json:"source"`
+	Time   time.Time `json:"time"`
+}
+
+// valueChange is the change to the value at one location.
+type valueChange struct {
+	Location string `json:"location"`
+	Old      string `json:"old"`
+	New      string `json:"new"`
+}
+
+// attest returns the content of the attestation file with a record of editing orig into new
+// appended.  The file is created if it does not exist yet.  source describes where the
+// replacement came from.
+func attest(ctx context.Context, client *github.Client, cfg *config, source string, orig *fileInTree, new string) (string, error) {
+	if cfg.Attestation == cfg.File {
+		return "", errors.New("--attestation must be different from --file")
+	}
+	existing, err := readFile(ctx, client, cfg.GithubOwner, cfg.GithubRepo, orig.Tree, cfg.Attestation)
+	if err != nil && !errors.Is(err, errNotFound) {
+		return "", fmt.Errorf("read attestation file: %w", err)
+	}
+	if existing != "" && !strings.HasSuffix(existing, "\n") {
+		existing += "\n"
+	}
+	p := provenance{
+		Subject: cfg.File,
+		Parent:  orig.CommitSHA,
+		Actor:   resolveIdentity(cfg).Author.String(),
+		Source:  source,
+		Time:    time.Now().UTC().Truncate(time.Second),
+	}
+	edits, err := locationEdits(cfg)
+	if err != nil {
+		return "", err
+	}
+	for _, e := range edits {
+		location := e.Path
+		old, _, err := valueAt(orig.Content, location)
+		if err != nil {
+			return "", fmt.Errorf("read old value of %s: %w", location, err)
+		}
+		value, _, err := valueAt(new, location)
+		if err != nil {
+			return "", fmt.Errorf("read new value of %s: %w", location, err)
+		}
+		p.Changes = append(p.Changes, valueChange{Location: location, Old: old, New: value})
+	}
+	line, err := json.Marshal(p)
+	if err != nil {
+		return "", fmt.Errorf("marshal provenance: %w", err)
+	}
+	return existing + string(line) + "\n", nil
+}
+
+// replacementSource describes where cfg's replacement comes from, for provenance records.  It
+// must be called before the replacement is resolved.
+func replacementSource(cfg *config) string {
+	switch {
+	case cfg.Upstream != "":
+		file := cfg.UpstreamFile
+		if file == "" {
+			file = cfg.File
+		}
+		return fmt.Sprintf("upstream %s file %s", cfg.Upstream, file)
+	case cfg.Resolver != "":
+		return fmt.Sprintf("resolver %s", cfg.Resolver)
+	case cfg.FromLocation:
+		return fmt.Sprintf("location %s", cfg.Replacement)
+	case cfg.TagSource != "":
+		return fmt.Sprintf("tag %s in %s", cfg.Replacement, cfg.TagSource)
+	case cfg.Object != "":
+		return "replacement object"
+	default:
+		return "command line"
+	}
+}
@@ -0,0 +1,105 @@
+package bump
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+)
+
+func TestAttestation(t *testing.T) {
+	prior := `{"subject":"config.yaml","parent":"0000000000000000000000000000000000000000","changes":[],"actor":"someone","source":"command line","time":"2020-01-01T00:00:00Z"}`
+	testData := []struct {
+		name      string
+		existing  string
+		wantPrior []string
+	}{
+		{name: "new attestation file"},
+		{name: "existing attestation file", existing: prior + "\n", wantPrior: []string{prior}},
+		{name: "existing file without trailing newline", existing: prior, wantPrior: []string{prior}},
+	}
+	for _, test := range testData {
+		t.Run(test.name, func(t *testing.T) {
+			gh, client := newFakeGitHub(t)
+			files := map[string]string{"config.yaml": lines("image:", "  tag: v1")}
+			if test.existing != "" {
+				files["provenance.jsonl"] = test.existing
+			}
+			before := gh.push("o/r", "master", files)
+			cfg := &config{
+				GithubOwner:  "o",
+				GithubRepo:   "r",
+				GithubBranch: "master",
+				File:         "config.yaml",
+				Locations:    []string{"image.tag"},
+				Replacement:  "v2",
+				AuthorName:   "Name",
+				AuthorEmail:  "name@example.com",
+				Attestation:  "provenance.jsonl",
+			}
+			if err := run(context.Background(), client, cfg, ioutil.Discard); err != nil {
+				t.Fatalf("run: %v", err)
+			}
+			head := gh.head("o/r", "master")
+			if diff := cmp.Diff([]string{before}, head.Parents); diff != "" {
+				t.Errorf("parents of the new head (-want +got):\n%s", diff)
+			}
+			if got, _ := gh.file("o/r", "master", "config.yaml"); got != lines("image:", "  tag: v2") {
+				t.Errorf("config.yaml: got %q", got)
+			}
+			got, ok := gh.file("o/r", "master", "provenance.jsonl")
+			if !ok {
+				t.Fatal("attestation file not committed")
+			}
+			if !strings.HasSuffix(got, "\n") {
+				t.Errorf("attestation file does not end with a newline: %q", got)
+			}
+			records := strings.Split(strings.TrimSuffix(got, "\n"), "\n")
+			if diff := cmp.Diff(test.wantPrior, records[:len(records)-1], cmpopts.EquateEmpty()); diff != "" {
+				t.Errorf("prior records (-want +got):\n%s", diff)
+			}
+			var p provenance
+			if err := json.Unmarshal([]byte(records[len(records)-1]), &p); err != nil {
+				t.Fatalf("unmarshal new record: %v", err)
+			}
+			if p.Time.IsZero() {
+				t.Error("new record has no time")
+			}
+			want := provenance{
+				Subject: "config.yaml",
+				Parent:  before,
+				Changes: []valueChange{{Location: "image.tag", Old: "v1", New: "v2"}},
+				Actor:   "Name <name@example.com>",
+				Source:  "command line",
+				Time:    p.Time,
+			}
+			if diff := cmp.Diff(want, p); diff != "" {
+				t.Errorf("new record (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestAttestationSameFile(t *testing.T) {
+	gh, client := newFakeGitHub(t)
+	before := gh.push("o/r", "master", map[string]string{"config.yaml": lines("image:", "  tag: v1")})
+	cfg := &config{
+		GithubOwner:  "o",
+		GithubRepo:   "r",
+		GithubBranch: "master",
+		File:         "config.yaml",
+		Locations:    []string{"image.tag"},
+		Replacement:  "v2",
+		Attestation:  "config.yaml",
+	}
+	if err := run(context.Background(), client, cfg, ioutil.Discard); err == nil {
+		t.Error("expected error")
+	}
+	if after := gh.head("o/r", "master").SHA; after != before {
+		t.Errorf("branch moved from %s to %s", before, after)
+	}
+}
@@ -0,0 +1,207 @@
+package bump
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// A backend is a host other than Github that a file can be read from and an edit to it
+// committed to: GitLab with --provider gitlab, or any git remote with --provider git.  Github
+// repositories are edited by run, which supports much more than a backend can express, like
+// pull requests, forks, and several files in one commit.
+type backend interface {
+	// Fetch reads file from the head of branch.
+	Fetch(ctx context.Context, branch, file string) (*fetched, error)
+	// Commit commits content as the new content of base, a file fetched from branch, to
+	// branch.  It fails with errBranchMoved if the branch has moved on since base was read.
+	Commit(ctx context.Context, branch string, base *fetched, content, message string, id identity) (string, error)
+	// CommitURL returns the URL of commit sha in a browser, or "" if there is none.
+	CommitURL(sha string) string
+	// String describes the repository, for messages.
+	String() string
+}
+
+// fetched is a file read by a backend.
+type fetched struct {
+	File    string
+	Content string
+	// CommitSHA is the commit the file was read from.
+	CommitSHA string
+	// Attributes holds the content of the .gitattributes files that apply to the file, keyed
+	// by their paths, if the backend reads them.
+	Attributes map[string]string
+
+	// parent is what the backend needs to commit on top of the file, if anything: the last
+	// commit that changed the file for GitLab.
+	parent string
+}
+
+// newBackend returns the backend for cfg.Provider, other than github, authenticating with a.
+func newBackend(cfg *config, a *auth) (backend, error) {
+	switch cfg.Provider {
+	case "gitlab":
+		token := a.GitlabToken
+		if token == "" {
+			token = a.AccessToken
+		}
+		return newGitLabBackend(&http.Client{Transport: apiTransport(http.DefaultTransport, a)}, a.GitlabURL, token, cfg.GithubOwner, cfg.GithubRepo), nil
+	case "git":
+		if cfg.RemoteURL == "" {
+			return nil, errors.New("--provider git needs --remote-url")
+		}
+		method, err := gitAuth(a)
+		if err != nil {
+			return nil, err
+		}
+		return newGitBackend(cfg.RemoteURL, method), nil
+	}
+	return nil, fmt.Errorf("unknown provider %q", cfg.Provider)
+}
+
+// checkBackend returns an error naming the flags in cfg that only the Github provider supports,
+// if cfg uses another provider.
+func checkBackend(cfg *config) error {
+	if cfg.Provider == "" || cfg.Provider == "github" {
+		return nil
+	}
+	var unsupported []string
+	for flag, set := range map[string]bool{
+		"--pull-request":            cfg.PullRequest,
+		"--fork":                    cfg.Fork != "",
+		"--dependency-graph":        cfg.Graph != "",
+		"--helm-chart":              cfg.HelmChart != "",
+		"--report":                  cfg.Report,
+		"--concurrency-safe-lock":   cfg.Lock,
+		"--upstream":                cfg.Upstream != "",
+		"--replacement-is-location": cfg.FromLocation,
+		"--tag-source":              cfg.TagSource != "",
+		"--create-from":             cfg.CreateFrom != "",
+		"--create-branch":           cfg.CreateBranch,
+		"--backup-branch":           cfg.BackupBranch != "",
+		"--force":                   cfg.Force,
+		"--state-file":              cfg.StateFile != "",
+		"--signing-key-file":        cfg.SigningKey != "" && cfg.Provider != "git",
+		"--dry-run-apply":           cfg.DryRunApply,
+		"--changed-since":           cfg.ChangedSince != "",
+		"--path-prefix":             cfg.PathPrefix != "",
+		"--ref":                     cfg.Ref != "",
+		"--resolver-cmd":            cfg.Resolver != "",
+		"a second --file":           len(cfg.ExtraFiles) > 0,
+	} {
+		if set {
+			unsupported = append(unsupported, flag)
+		}
+	}
+	if len(unsupported) > 0 {
+		sort.Strings(unsupported)
+		return fmt.Errorf("--provider %s does not support %s", cfg.Provider, strings.Join(unsupported, ", "))
+	}
+	if id := resolveIdentity(cfg); cfg.Provider == "git" && !cfg.DryRun && (id.Author.Name == "" || id.Author.Email == "") {
+		return errors.New("--provider git needs --author-name and --author-email, or --author-username, to make commits as")
+	}
+	return nil
+}
+
+// runBackend is like run, for a repository on any backend: it reads cfg.File from the branch,
+// edits it, and commits the edit, or prints it with --dry-run.  Only the flags that every
+// backend supports are allowed.
+func runBackend(ctx context.Context, b backend, cfg *config, w io.Writer) error {
+	cfg, err := splitFiles(cfg)
+	if err != nil {
+		return err
+	}
+	cfg, err = pairReplacements(cfg)
+	if err != nil {
+		return err
+	}
+	cfg = trimReplacement(cfg)
+	if err := checkBackend(cfg); err != nil {
+		return err
+	}
+	if err := checkIdentity(cfg); err != nil {
+		return err
+	}
+	if err := checkRegex(cfg); err != nil {
+		return err
+	}
+	if cfg.Schema != "" {
+		if _, err := loadSchema(cfg.Schema); err != nil {
+			return err
+		}
+	}
+	for _, r := range cfg.replacements() {
+		if err := checkAllowed(cfg, r); err != nil {
+			return err
+		}
+	}
+	if !cfg.DryRun {
+		if err := checkWindows(cfg.AllowWindows, time.Now()); err != nil {
+			return err
+		}
+	}
+
+	orig, err := b.Fetch(ctx, cfg.GithubBranch, cfg.File)
+	if err != nil {
+		return inPhase(phaseFetch, fmt.Errorf("fetch %s from %v@%s: %w", cfg.File, b, cfg.GithubBranch, err))
+	}
+	new, _, err := editChecked(ctx, cfg, orig.Content, nil)
+	if err != nil {
+		return inPhase(phaseEdit, err)
+	}
+	text, known := isText(orig.Attributes, cfg.File)
+	new = fixLineEndings(orig.Content, new, text, known)
+	if new == orig.Content && cfg.FailUnchanged {
+		return fmt.Errorf("edit did not change %s, and --fail-if-unchanged is set", cfg.File)
+	}
+	if cfg.DryRun {
+		fmt.Fprintf(os.Stderr, "Using content from commit %s\n", orig.CommitSHA)
+		if !cfg.PrintSHAOnly {
+			writeDryRun(w, cfg, cfg.File, orig.Content, new)
+		}
+		return pendingChange(cfg, orig.Content, new)
+	}
+	if new == orig.Content && !cfg.AllowEmpty {
+		log.Printf("the edit does not change %s; nothing to commit", cfg.File)
+		return writeResult(w, cfg, result{})
+	}
+
+	id, err := commitIdentity(cfg)
+	if err != nil {
+		return err
+	}
+	sha, err := b.Commit(ctx, cfg.GithubBranch, orig, new, cfg.CommitMessage, id)
+	retries := cfg.Retries
+	if cfg.RetryConflict && retries == 0 {
+		retries = defaultConflictRetries
+	}
+	for retry := 1; err != nil && errors.Is(err, errBranchMoved) && retry <= retries; retry++ {
+		log.Printf("%s moved on from commit %s; reading %s again and re-applying the edit (retry %d of %d)", cfg.GithubBranch, orig.CommitSHA, cfg.File, retry, retries)
+		orig, err = b.Fetch(ctx, cfg.GithubBranch, cfg.File)
+		if err != nil {
+			return inPhase(phaseFetch, fmt.Errorf("fetch %s again: %w", cfg.File, err))
+		}
+		new, _, err = editChecked(ctx, cfg, orig.Content, nil)
+		if err != nil {
+			return inPhase(phaseEdit, fmt.Errorf("re-apply edit to commit %s: %w", orig.CommitSHA, err))
+		}
+		new = fixLineEndings(orig.Content, new, text, known)
+		sha, err = b.Commit(ctx, cfg.GithubBranch, orig, new, cfg.CommitMessage, id)
+	}
+	if err != nil {
+		return inPhase(phaseCommit, fmt.Errorf("commit edit: %w", explainConflict(err, cfg.GithubBranch, orig.CommitSHA)))
+	}
+	logWith(logFields{CommitSHA: sha}, "created commit %s", sha)
+	url := b.CommitURL(sha)
+	if url != "" {
+		log.Printf("commit: %s", url)
+	}
+	return writeResult(w, cfg, result{Changed: true, SHA: sha, URL: url})
+}
@@ -0,0 +1,107 @@
+package bump
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// memBackend is a backend holding a single branch in memory.  The first commit made to it fails
+// as if someone else had pushed moved just before, if moved is set.
+type memBackend struct {
+	content string
+	head    int
+	moved   string
+	commits int
+}
+
+func (b *memBackend) Fetch(ctx context.Context, branch, file string) (*fetched, error) {
+	return &fetched{File: file, Content: b.content, CommitSHA: fmt.Sprint(b.head)}, nil
+}
+
+func (b *memBackend) Commit(ctx context.Context, branch string, base *fetched, content, message string, id identity) (string, error) {
+	if b.moved != "" {
+		b.content, b.moved = b.moved, ""
+		b.head++
+	}
+	if base.CommitSHA != fmt.Sprint(b.head) {
+		return "", fmt.Errorf("%s is now at commit %d: %w", branch, b.head, errBranchMoved)
+	}
+	b.content = content
+	b.head++
+	b.commits++
+	return fmt.Sprint(b.head), nil
+}
+
+func (b *memBackend) CommitURL(sha string) string { return "" }
+
+func (b *memBackend) String() string { return "memory" }
+
+func TestRunBackendRetryChecked(t *testing.T) {
+	testData := []struct {
+		name         string
+		moved        string
+		replacement  string
+		noDowngrade  bool
+		requireMatch bool
+		want         string
+		wantErr      string
+	}{
+		{
+			name:        "retry",
+			moved:       lines("replicas: 3", "image:", "  tag: v1.0.0"),
+			replacement: "v2.0.0",
+			want:        lines("replicas: 3", "image:", "  tag: v2.0.0"),
+		},
+		{
+			name:        "retry refuses a downgrade",
+			moved:       lines("image:", "  tag: v3.0.0"),
+			replacement: "v2.0.0",
+			noDowngrade: true,
+			wantErr:     "refusing to downgrade it to v2.0.0",
+		},
+		{
+			name:         "retry requires a match",
+			moved:        lines("image:", "  version: v1.0.0"),
+			replacement:  "v2.0.0",
+			requireMatch: true,
+			wantErr:      "no match in config.yaml for image.tag",
+		},
+	}
+	for _, test := range testData {
+		t.Run(test.name, func(t *testing.T) {
+			b := &memBackend{content: lines("image:", "  tag: v1.0.0"), moved: test.moved}
+			cfg := &config{
+				Provider:      "gitlab",
+				GithubBranch:  "master",
+				File:          "config.yaml",
+				Locations:     []string{"image.tag"},
+				Replacement:   test.replacement,
+				CommitMessage: "Bump image.tag",
+				RetryConflict: true,
+				NoDowngrade:   test.noDowngrade,
+				RequireMatch:  test.requireMatch,
+			}
+			err := runBackend(context.Background(), b, cfg, ioutil.Discard)
+			if test.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+					t.Fatalf("want error containing %q, got %v", test.wantErr, err)
+				}
+				if b.commits != 0 {
+					t.Errorf("made %d commits despite the error", b.commits)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("run: %v", err)
+			}
+			if diff := cmp.Diff(test.want, b.content); diff != "" {
+				t.Errorf("content (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
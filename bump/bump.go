@@ -0,0 +1,2517 @@
+// Package bump edits values in YAML and JSON files in Github repositories, and commits the
+// results.  It is the version-bump command, which is a thin wrapper around Main, and can be
+// used as a library through Fetch, EditYAML, and Commit.
+package bump
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/bradleyfalzon/ghinstallation"
+	"github.com/google/go-github/v32/github"
+	"github.com/jessevdk/go-flags"
+	"golang.org/x/oauth2"
+	"sigs.k8s.io/kustomize/kyaml/yaml"
+)
+
+type auth struct {
+	AccessToken    string        `long:"token" env:"GITHUB_TOKEN" description:"If authenticating as a user, the Personal Access Token to use to access Github."`
+	TokenFile      string        `long:"token-file" description:"If authenticating as a user, a file containing the Personal Access Token.  Takes precedence over $GITHUB_TOKEN, but not over --token."`
+	AppID          int64         `long:"app-id" env:"GITHUB_APP_ID" description:"If authenticating as a Github app, the App ID provided by Github."`
+	InstallationID int64         `long:"installation-id" env:"GITHUB_INSTALLATION_ID" description:"If authenticating as a Github app, the Installation ID provided by Github."`
+	PrivateKey     string        `long:"private-key" env:"GITHUB_PRIVATE_KEY" description:"If authenticating as a Github app, the full private key provided by Github."`
+	PrivateKeyFile string        `long:"private-key-file" description:"If authenticating as a Github app, a file containing the private key provided by Github.  Takes precedence over --private-key."`
+	BaseURL        string        `long:"github-url" description:"The URL of a Github Enterprise server to use instead of github.com, like https://github.example.com.  The API is expected under /api/v3/."`
+	UploadURL      string        `long:"github-upload-url" description:"With --github-url, the URL to upload to, if different.  Uploads are expected under /api/uploads/."`
+	GitlabURL      string        `long:"gitlab-url" default:"https://gitlab.com" description:"With --provider gitlab, the URL of the GitLab server.  The API is expected under /api/v4/."`
+	GitlabToken    string        `long:"gitlab-token" env:"GITLAB_TOKEN" description:"With --provider gitlab, the access token to use.  Defaults to --token."`
+	GitUsername    string        `long:"git-username" default:"git" description:"With --provider git and an HTTPS --remote-url, the username to send with the token as the password."`
+	SSHKeyFile     string        `long:"ssh-key-file" description:"With --provider git and an SSH --remote-url, a private key file to authenticate with.  Without it, the SSH agent is used."`
+	SSHKeyPass     string        `long:"ssh-key-passphrase" env:"VERSION_BUMP_SSH_KEY_PASSPHRASE" description:"The passphrase of --ssh-key-file, if it is encrypted."`
+	MaxRetries     int           `long:"max-retries" default:"3" description:"How many times to retry a request to Github that fails with a server error or the secondary rate limit.  POST requests are not retried after a server error, as they may have taken effect.  Other failures are not retried."`
+	RequestTimeout time.Duration `long:"request-timeout" description:"How long to wait for each request to Github, including its retries, so that one slow request can't use up all of --timeout.  Defaults to --timeout."`
+	RateLimitWait  bool          `long:"rate-limit-wait" description:"When Github's rate limit is nearly used up, wait for it to reset before each write, rather than fail partway through.  Useful when running many bumps in a row.  The wait is bounded by --timeout."`
+}
+
+type config struct {
+	ConfigFile    string        `long:"config" description:"A YAML or JSON file of flags, mapping their long names to values, like \"owner: pachyderm\".  A list gives a repeatable flag several times.  Flags on the command line override the file."`
+	Timeout       time.Duration `long:"timeout" description:"How long to wait for Github, for the whole run.  Each request is also limited by --request-timeout." default:"30s"`
+	Provider      string        `long:"provider" choice:"github" choice:"gitlab" choice:"git" default:"github" description:"Where the repository is hosted.  With gitlab, --owner is the group and --repo the project.  With git, --remote-url is cloned and pushed to with git itself, over HTTPS or SSH, so any host will do.  Other than with github, only flags that edit --file and commit to --branch are supported; pull requests, forks, and the like need github."`
+	RemoteURL     string        `long:"remote-url" description:"With --provider git, the URL of the repository, like https://git.example.com/app.git or ssh://git@git.example.com/app.git."`
+	GithubOwner   string        `long:"owner" description:"The owner of the repository to edit."`
+	GithubRepo    string        `long:"repo" description:"The repository to edit."`
+	GithubBranch  string        `long:"branch" description:"The branch to edit."`
+	Files         []string      `long:"file" description:"The file to edit, relative to the root of the repository, like deploy/prod/app.yaml.  A leading ./ or / is dropped, and backslashes are taken as slashes.  Repeatable: every file is edited the same way, and they are all committed together, in one commit."`
+	PathPrefix    string        `long:"path-prefix" description:"A directory containing --file.  If set, only the trees along the path to this directory are read, which is much faster for deep files in large repositories."`
+	Locations     []string      `long:"location" description:"The location in the YAML file to replace.  Repeatable.  Escape dots in keys with a backslash, like data.application\\.yaml, or quote the key, like data[\"application.yaml\"]."`
+	Replacements  []string      `long:"replacement" description:"The content to replace the text at the provided locations with.  Repeatable: given once, it replaces every location; given once per --location, each replaces the location in the same position."`
+	Sets          []string      `long:"set" description:"A location and the value to replace it with, as location=value, like spec.a.tag=v2.  Repeatable, to give each location its own value.  May be combined with --location and --replacement."`
+	NoTrim        bool          `long:"no-trim-replacement" description:"Use --replacement exactly as given.  By default, whitespace around it is removed; with this flag, it is kept, and the value is quoted to preserve it."`
+	Object        string        `long:"replacement-object" description:"A mapping, in YAML or JSON, to merge into the mapping at each location instead of replacing it, like a JSON merge patch: keys are added or overwritten, and keys set to null are removed."`
+	ObjectMerge   string        `long:"object-merge" choice:"deep" choice:"shallow" default:"deep" description:"With --replacement-object, whether mappings in the object are merged into the mappings they overwrite (deep), or replace them (shallow)."`
+	Comment       string        `long:"set-comment" description:"A line comment to attach to each edited value, like \"bumped by CI\".  It is marked as written by this tool, and replaces the comment a previous run left rather than adding another.  Other comments on the line are kept."`
+	HelmChart     string        `long:"helm-chart" description:"A Helm chart directory to release --replacement as the new app version of: its Chart.yaml's appVersion is set, along with image.tag in its values.yaml, in one commit.  --file and --location default to those."`
+	ChartBump     string        `long:"helm-bump-version" choice:"major" choice:"minor" choice:"patch" description:"With --helm-chart, also bump this part of the chart's version in Chart.yaml."`
+	FromLocation  bool          `long:"replacement-is-location" description:"Treat --replacement as a location to copy the value from, rather than the value itself.  Give it as file:location to copy from another file in the same commit."`
+	Resolver      string        `long:"resolver-cmd" description:"A command that finds the replacement, like a script that asks a private registry for the latest matching tag.  It is run with two more arguments, the current value at the first --location and --resolver-pattern, and must print the new value.  It is killed after --timeout."`
+	ResolverPat   string        `long:"resolver-pattern" description:"With --resolver-cmd, a pattern to pass the command, describing the values it may choose from."`
+	Semver        bool          `long:"semver" description:"Refuse any replacement that is not a semantic version, like 1.2.3 or v1.2.3-rc.1, before anything is edited, exiting with status 3.  Replacing a semantic version with a lower one is warned about."`
+	NoDowngrade   bool          `long:"no-downgrade" description:"Refuse to replace a semantic version at a location with a lower one.  Locations whose value or replacement is not a semantic version are not checked, with a warning."`
+	Allowed       []string      `long:"allowed-value" description:"A value that --replacement may be.  Repeatable.  If set, any other replacement is refused before anything is read."`
+	AllowedFile   string        `long:"allowed-values-file" description:"A file listing the values that --replacement may be, one per line, in addition to any --allowed-value."`
+	Upstream      string        `long:"upstream" description:"A repository, given as owner/repo or owner/repo@branch, to copy the replacement from, keeping a value in lockstep with an upstream source of truth."`
+	UpstreamFile  string        `long:"upstream-file" description:"With --upstream, the file to read the replacement from.  Defaults to --file."`
+	UpstreamLoc   string        `long:"upstream-location" description:"With --upstream, the location in --upstream-file to read the replacement from.  Defaults to the first --location."`
+	Format        string        `long:"format" choice:"yaml" choice:"json" choice:"toml" choice:"dotenv" choice:"auto" description:"The format of --file.  With auto, it is detected from the content: JSON if it parses as strict JSON, then YAML, then dotenv, then TOML.  The file is written back in the same format.  Defaults to json for files ending in .json, toml for .toml, dotenv for .env and .properties, and yaml for anything else.  In TOML, tables and arrays of tables are located like mappings and sequences, as in [[package]] version being package[0].version.  In dotenv files, of KEY=value lines, each location is a key."`
+	Regex         bool          `long:"regex" description:"Treat each --location as a Go regular expression with a capture group, and replace what its first group matches with --replacement, in a file of any format, like ARG VERSION=(.*) in a Dockerfile.  Only the first match of each pattern is replaced, unless --regex-all is set."`
+	RegexAll      bool          `long:"regex-all" description:"With --regex, replace every match of each pattern, rather than only the first."`
+	Schema        string        `long:"schema" description:"A JSON schema file to validate the edited --file, or each file edited with --dependency-graph, against, converting YAML and TOML to JSON first.  If it does not match, nothing is committed and the validation errors are printed.  Each document of a YAML file is validated on its own.  --dry-run reports the result too."`
+	ValidateCmd   string        `long:"validate-cmd" description:"A command to run before committing, like kubectl apply --dry-run=client -f -, with the edited --file on its stdin.  It is split into words and run directly, not by a shell.  If it exits nonzero, nothing is committed, and what it printed to stderr is reported.  It is killed after --timeout."`
+	ValidateDry   bool          `long:"validate-in-dry-run" description:"Run --validate-cmd in dry runs too, which skip it otherwise."`
+	LintIndent    bool          `long:"lint-indent" description:"Before editing, check that --file is indented consistently, only with spaces and by the same amount at every level, and refuse to edit it if not."`
+	Strict        bool          `long:"strict-yaml" description:"Before editing, check that --file is strictly valid YAML, with no duplicate keys or tabs in its indentation, and refuse to edit it if not."`
+	DryRunApply   bool          `long:"dry-run-apply" description:"Print the commit that would be made, with its parent, message, and tree entries, and where it would go, rather than making it.  Nothing is written to Github.  Use --output json for a machine-readable plan."`
+	DryRun        bool          `long:"dry-run" description:"Print the diff of the edit we would like to commit, rather than committing it."`
+	DryRunFormat  string        `long:"dry-run-format" choice:"diff" choice:"full" default:"diff" description:"What --dry-run prints: a unified diff of the edit, or the full edited file."`
+	ExitCode      bool          `long:"exit-code" description:"With --dry-run or --local-repo, exit with status 5 if the edit would change the file and 0 if it would not, like git diff --exit-code, so that CI can tell whether a bump is pending.  Errors still exit with status 1."`
+	Force         bool          `long:"force" description:"Move --branch to the new commit even if the branch has moved on since it was read, discarding those changes."`
+	Retries       int           `long:"conflict-retries" description:"If --branch moves on between reading --file and committing the edit, read it again and re-apply the edit up to this many times before giving up.  Each location is looked up again in the new content, so the edit applies even if the file changed in other ways."`
+	RetryConflict bool          `long:"retry-on-conflict" description:"If --branch moves on between reading --file and committing the edit, read it again and re-apply the edit, as --conflict-retries does, up to 3 times unless --conflict-retries says otherwise.  Without it, or once retries run out, the run exits with status 6."`
+	BackupBranch  string        `long:"backup-branch" description:"Before moving --branch, point this branch at its current head, so the edit can be undone by restoring it.  If it already exists, it is moved only with --force."`
+	Protected     []string      `long:"protect-branch" description:"A branch, or glob matching branches, that may never be updated with --force.  Repeatable."`
+	AuthorUser    string        `long:"author-username" description:"The Github user to attribute commits to.  The author's name defaults to the username, and their email address to the user's noreply address."`
+	AuthorName    string        `long:"author-name" description:"The full name of the user that will generate the commit.  Overrides the name derived from --author-username."`
+	AuthorEmail   string        `long:"author-email" description:"The email address of the user that will generate the commit.  Overrides the noreply address of --author-username."`
+	CommitterUser string        `long:"committer-username" description:"The Github user to make commits as, like a bot account, if not the author.  The committer's name defaults to the username, and their email address to the user's noreply address."`
+	CommitterName string        `long:"committer-name" description:"The full name of the committer, if not the author, or if not --committer-username."`
+	CommitterMail string        `long:"committer-email" description:"The email address of the committer, if not the author's, or if not the noreply address of --committer-username."`
+	SigningKey    string        `long:"signing-key-file" description:"A file containing an armored OpenPGP private key to sign commits with, so that Github shows them as verified if the key belongs to the committer."`
+	SigningPass   string        `long:"signing-passphrase" env:"VERSION_BUMP_SIGNING_PASSPHRASE" description:"The passphrase of the key in --signing-key-file, if it is encrypted."`
+	CommitMessage string        `long:"message" description:"The desired text of the commit message."`
+	References    []string      `long:"references" description:"An issue that the edit addresses, as #123 or owner/repo#123, to reference in the commit message and pull request so that Github links them.  Repeatable."`
+	Closes        bool          `long:"closes" description:"With --references, reference the issues with \"Closes\", so that Github closes them when the commit reaches the default branch."`
+	Scale         float64       `long:"scale" description:"Multiply the Kubernetes resource quantities (like 512Mi) at the provided locations by this factor, keeping their units, instead of replacing them."`
+	Base64        bool          `long:"base64" description:"Treat the values at the provided locations as base64, and edit the decoded content with --inner-location or --inner-pattern.  The result is encoded the same way as the original."`
+	InnerLocation string        `long:"inner-location" description:"With --base64, the location in the decoded content, parsed as YAML, to replace with --replacement."`
+	InnerPattern  string        `long:"inner-pattern" description:"With --base64, a regular expression whose first group is replaced with --replacement in the decoded content."`
+	FirstOnly     bool          `long:"first-match-only" description:"In files with several documents, edit each location only in the first document that contains it.  A location with wildcards is only edited at its first match."`
+	Mirrors       []string      `long:"mirror" description:"Copy the value at one location to another after editing, given as source=target.  Repeatable."`
+	RenameKeys    []string      `long:"rename-key" description:"Rename the key at a location, keeping its value, given as location=newName.  Repeatable."`
+	RangeBound    string        `long:"constraint-bound" choice:"lower" choice:"upper" description:"Treat the values at the provided locations as version ranges like \">=1.2.0 <2.0.0\", and replace only this bound with --replacement."`
+	AllowEmpty    bool          `long:"allow-empty" description:"Commit even if the edit changes nothing, like git commit --allow-empty.  Without it, an edit that leaves the file as it was is not committed."`
+	RequireMatch  bool          `long:"require-match" description:"Fail if any --location or --set matches nothing in the file, rather than warning about it.  Catches typos in locations."`
+	FailUnchanged bool          `long:"fail-if-unchanged" description:"Exit with an error, rather than succeeding quietly, if the edit does not change the file."`
+	PrintIdentity bool          `long:"print-identity" description:"Print the author and committer that commits would carry, in --output format, and exit without reading or writing anything."`
+	PrintSHAOnly  bool          `long:"print-sha-only" description:"Print only the SHA of the new commit to stdout, followed by a newline, and nothing at all if no commit was made.  Diagnostics still go to stderr."`
+	Touch         bool          `long:"touch" description:"Edit no values, but re-serialize the file in canonical formatting.  Useful for a one-time normalization commit."`
+	MatchLabels   []string      `long:"match-labels" description:"Only edit documents whose metadata.labels include these labels, given as key=value.  Repeatable, or comma-separated."`
+	ChangedSince  string        `long:"changed-since" description:"Only edit the files given with --file that have changed between this commit and the head of --branch.  Nothing is done if none of them have.  Fails if 300 or more files have changed, as Github lists no more than that."`
+	BaseTree      string        `long:"base-tree" description:"The SHA of the tree to build the new commit's tree on.  Defaults to the tree of the head of --branch."`
+	Verbose       bool          `long:"verbose" short:"v" description:"Print each location in each document to stderr as it is edited or skipped, with its old and new values."`
+	TagSource     string        `long:"tag-source" description:"A repository, given as owner/repo, in which --replacement must be a tag.  The edit fails if it is not."`
+	MaxTagAge     time.Duration `long:"max-tag-age" description:"With --tag-source, refuse to pin a tag whose commit is older than this, like 720h."`
+	PrintLocs     bool          `long:"print-normalized-locations" description:"Print the canonical form of each --location, one per line, and exit without reading or editing anything.  Useful for migrating between location syntaxes."`
+	CreateFrom    string        `long:"create-from" description:"If --branch does not exist, create it from this branch, with the edit committed on top.  If it does exist, this has no effect."`
+	CreateBranch  bool          `long:"create-branch" description:"If --branch does not exist, create it from --create-from, or from the repository's default branch, with the edit committed on top.  If it does exist, this has no effect."`
+	Local         bool          `long:"local" description:"Read --file from the local filesystem instead of Github, edit it, and write it back in place rather than committing it.  With --dry-run, the edit is printed instead.  No token is needed, and it works offline."`
+	LocalRepo     string        `long:"local-repo" description:"Read --file from this local git repository instead of Github, and print the edit as --dry-run does rather than committing it.  Works offline."`
+	Ref           string        `long:"ref" description:"The ref to read --file from: heads/<branch>, the same as --branch, or tags/<tag> or a commit SHA, in which case the edit is committed to --branch, a new branch created from it.  With --local-repo, any branch, tag, or commit, defaulting to the working tree."`
+	Report        bool          `long:"report" description:"Edit nothing, but print how the value at each location in each --target compares with --replacement."`
+	Targets       []string      `long:"target" description:"With --report, a repository to read --file from, given as owner/repo or owner/repo@branch.  Repeatable.  Defaults to --owner/--repo."`
+	Output        string        `long:"output" choice:"text" choice:"json" default:"text" description:"The format of reports, of --dry-run-apply plans, and of errors, which are printed to stderr.  With json, the outcome of a run is also printed to stdout, as an object with changed, and the sha, url, and compare_url of any new commit, and pull_request_url of any pull request."`
+	LogFormat     string        `long:"log-format" choice:"text" choice:"json" default:"text" description:"The format of the log printed to stderr: text, or json, one object per line with time, level, message, file, and locations, and commit_sha once a commit is made.  Errors are logged as entries at level error, unless --output json prints them."`
+	DumpFilters   bool          `long:"dump-filters" description:"Print the kyaml filters that would be applied to each document, one per line, and exit without reading or editing anything."`
+	MergeQueue    bool          `long:"merge-queue" description:"Add the pull request to the merge queue of --branch.  Branches with a merge queue always get a pull request, rather than a direct commit."`
+	Graph         string        `long:"dependency-graph" description:"A YAML file describing where each component's version is pinned, and which components depend on which.  Bumps --component to --replacement everywhere it is pinned, in one commit.  See dependencyGraph in graph.go for the format."`
+	Component     string        `long:"component" description:"With --dependency-graph, the component to bump."`
+	AllowWindows  []string      `long:"allow-window" description:"Refuse to commit outside this change window, given as days, times, and a time zone, like \"Mon-Fri 09:00-17:00 America/New_York\".  Repeatable; committing is allowed inside any of them.  Dry runs are always allowed."`
+	Lock          bool          `long:"concurrency-safe-lock" description:"Hold an advisory lock on --branch, the ref refs/version-bump/lock/<branch>, from reading --file until the commit is made, so that concurrent runs take turns instead of racing."`
+	LockWait      time.Duration `long:"lock-wait" description:"With --concurrency-safe-lock, how long to wait for another run to release the lock before giving up.  By default, give up immediately."`
+	LockTTL       time.Duration `long:"lock-ttl" default:"10m" description:"With --concurrency-safe-lock, how old a lock must be before it is assumed to be left over from a run that died, and broken."`
+	StateFile     string        `long:"state-file" description:"A file to remember the last read of --file in between runs.  If --branch has not changed since, Github answers 304 Not Modified and the file is not downloaded again."`
+	Attestation   string        `long:"attestation" description:"A file in the repository to append a JSON provenance record of the edit to, in the same commit: the file and locations edited, their old and new values, who made the edit, and where the replacement came from."`
+	Fork          string        `long:"fork" description:"A fork of --owner/--repo, given as owner/repo or owner/repo@branch, to read --file from and commit to, opening a pull request against --branch upstream.  The fork's branch defaults to --branch."`
+	SyncFork      bool          `long:"sync-fork" description:"With --fork, bring the fork's branch up to date with upstream before editing, so the pull request is based on the current upstream."`
+	PullRequest   bool          `long:"pull-request" description:"Commit to a new branch and open a pull request against --branch, rather than committing to --branch directly.  No pull request is opened if the edit only changes whitespace, and nothing is pushed if one is already open with the same edit."`
+	PRBranch      string        `long:"pr-branch" description:"With --pull-request, the branch to propose the pull request from.  Defaults to a name derived from the edit.  If it exists, the edit is committed on top of it, and a pull request already open from it is kept rather than opening another; a branch with no open pull request is only touched with --force."`
+
+	// Replacement is the replacement for every location: the only --replacement, or one
+	// found by another means, like --upstream.
+	Replacement string
+	// File is the first --file, and ExtraFiles the rest.
+	File       string
+	ExtraFiles []string
+}
+
+type fileInTree struct {
+	Tree      *github.Tree
+	CommitSHA string
+	Content   string
+
+	// Attributes holds the content of the .gitattributes files that apply to the file, keyed
+	// by their paths.
+	Attributes map[string]string
+
+	// ETag is the ETag of the branch response the file was read through.
+	ETag string
+	// NotModified is set if the branch has not changed since the cached copy of the file
+	// was read, and the file was not read again.
+	NotModified bool
+}
+
+// fetch reads file from the head of branch.  Unless byTree is set, file is read directly with
+// the contents API, and Tree in the result has only a SHA.  With byTree, the whole tree of the
+// repository is read to find file, which saves requests when other files are to be read from
+// the same commit.  If pathPrefix is set, it must be a directory containing file; only the
+// trees along the way to it are read, rather than the whole tree of the repository.
+func fetch(ctx context.Context, client *github.Client, owner, repo, branch, file, pathPrefix string, byTree bool) (*fileInTree, error) {
+	return fetchIfModified(ctx, client, owner, repo, branch, file, pathPrefix, byTree, nil)
+}
+
+// fetchIfModified is like fetch, but if cached describes the same file on the same branch, the
+// branch is requested conditionally with its ETag.  If Github replies 304 Not Modified, the
+// file is returned from cached, with NotModified set, without reading any trees or blobs.
+func fetchIfModified(ctx context.Context, client *github.Client, owner, repo, branch, file, pathPrefix string, byTree bool, cached *state) (*fileInTree, error) {
+	req, err := client.NewRequest("GET", fmt.Sprintf("repos/%v/%v/branches/%v", owner, repo, branch), nil)
+	if err != nil {
+		return nil, fmt.Errorf("get branch: %w", err)
+	}
+	useCache := cached.describes(owner, repo, branch, file)
+	if useCache {
+		req.Header.Set("If-None-Match", cached.ETag)
+	}
+	br := new(github.Branch)
+	resp, err := client.Do(ctx, req, br)
+	if useCache && resp != nil && resp.StatusCode == http.StatusNotModified {
+		return &fileInTree{
+			Tree:        &github.Tree{SHA: github.String(cached.TreeSHA)},
+			CommitSHA:   cached.CommitSHA,
+			Content:     cached.Content,
+			Attributes:  cached.Attributes,
+			ETag:        cached.ETag,
+			NotModified: true,
+		}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get branch: %w", err)
+	}
+	commit := br.GetCommit()
+	if commit == nil {
+		return nil, errors.New("no commit on branch")
+	}
+	treeRef := commit.GetCommit().GetTree().GetSHA()
+	if treeRef == "" {
+		return nil, fmt.Errorf("no tree in commit %s", commit.GetSHA())
+	}
+	f, err := fetchFromCommit(ctx, client, owner, repo, commit.GetSHA(), treeRef, file, pathPrefix, byTree)
+	if err != nil {
+		return nil, err
+	}
+	f.ETag = resp.Header.Get("ETag")
+	return f, nil
+}
+
+// fetchFromCommit reads file from commit sha, whose tree is treeRef, like fetch.
+func fetchFromCommit(ctx context.Context, client *github.Client, owner, repo, sha, treeRef, file, pathPrefix string, byTree bool) (*fileInTree, error) {
+	if pathPrefix == "" && !byTree {
+		content, attributes, err := readContents(ctx, client, owner, repo, sha, file)
+		if err != nil {
+			return nil, err
+		}
+		return &fileInTree{
+			Tree:       &github.Tree{SHA: &treeRef},
+			CommitSHA:  sha,
+			Content:    content,
+			Attributes: attributes,
+		}, nil
+	}
+	var tree, searched *github.Tree
+	var prefix string
+	var err error
+	if pathPrefix == "" {
+		tree, _, err = client.Git.GetTree(ctx, owner, repo, treeRef, true)
+		if err != nil {
+			return nil, fmt.Errorf("fetch tree %s from commit %s: %w", treeRef, sha, err)
+		}
+		searched = tree
+		// Github truncates the trees of big repositories.  The directory containing file is
+		// usually small enough to read whole, so read just that, and the trees along the way.
+		if tree.GetTruncated() {
+			log.Printf("github truncated tree %s; reading only the directory containing %s", treeRef, file)
+			tree, searched, prefix, err = walkToFile(ctx, client, owner, repo, treeRef, file)
+			if err != nil {
+				return nil, fmt.Errorf("fetch %s from commit %s: %w", file, sha, err)
+			}
+		}
+	} else {
+		prefix = strings.Trim(pathPrefix, "/") + "/"
+		if !strings.HasPrefix(file, prefix) {
+			return nil, fmt.Errorf("file %s is not inside path prefix %s", file, pathPrefix)
+		}
+		tree, searched, err = walkTree(ctx, client, owner, repo, treeRef, prefix)
+		if err != nil {
+			return nil, fmt.Errorf("fetch %s from commit %s: %w", prefix, sha, err)
+		}
+	}
+	if searched.Truncated == nil || searched.GetTruncated() {
+		return nil, fmt.Errorf("github truncated tree %s, aborting", searched.GetSHA())
+	}
+	var blobSHA string
+	for _, e := range searched.Entries {
+		if prefix+e.GetPath() == file {
+			blobSHA = e.GetSHA()
+			break
+		}
+	}
+	if blobSHA == "" {
+		return nil, fmt.Errorf("file not found in commit %s", sha)
+	}
+	content, err := readBlob(ctx, client, owner, repo, blobSHA)
+	if err != nil {
+		return nil, err
+	}
+
+	// Read any .gitattributes files that apply to file and are in the trees we have.  In the
+	// root tree, paths are relative to the root; in the searched tree, to the prefix.
+	blobs := map[string]string{}
+	for _, e := range tree.Entries {
+		blobs[e.GetPath()] = e.GetSHA()
+	}
+	for _, e := range searched.Entries {
+		blobs[prefix+e.GetPath()] = e.GetSHA()
+	}
+	attributes := map[string]string{}
+	for _, p := range attributeFiles(file) {
+		if sha, ok := blobs[p]; ok {
+			attributes[p], err = readBlob(ctx, client, owner, repo, sha)
+			if err != nil {
+				return nil, fmt.Errorf("read %s: %w", p, err)
+			}
+		}
+	}
+	return &fileInTree{
+		Tree:       tree,
+		CommitSHA:  sha,
+		Content:    content,
+		Attributes: attributes,
+	}, nil
+}
+
+// readBlob reads the content of a blob.
+func readBlob(ctx context.Context, client *github.Client, owner, repo, sha string) (string, error) {
+	blob, _, err := client.Git.GetBlob(ctx, owner, repo, sha)
+	if err != nil {
+		return "", fmt.Errorf("fetch blob %s: %w", sha, err)
+	}
+	switch e, c := blob.GetEncoding(), blob.GetContent(); e {
+	case "utf-8":
+		return c, nil
+	case "base64":
+		c, err := base64.StdEncoding.DecodeString(c)
+		if err != nil {
+			return "", fmt.Errorf("decode blob %s: %w", sha, err)
+		}
+		return string(c), nil
+	default:
+		return "", fmt.Errorf("unknown content type %q in blob %s", e, sha)
+	}
+}
+
+// readContents reads file, and the .gitattributes files that apply to it, from commit with the
+// contents API, without reading any trees.
+func readContents(ctx context.Context, client *github.Client, owner, repo, commit, file string) (string, map[string]string, error) {
+	content, _, err := getContents(ctx, client, owner, repo, commit, file)
+	if errors.Is(err, errNotFound) {
+		return "", nil, fmt.Errorf("file not found in commit %s", commit)
+	}
+	if err != nil {
+		return "", nil, err
+	}
+	attributes := map[string]string{}
+	for _, p := range attributeFiles(file) {
+		a, _, err := getContents(ctx, client, owner, repo, commit, p)
+		if errors.Is(err, errNotFound) {
+			continue
+		}
+		if err != nil {
+			return "", nil, fmt.Errorf("read %s: %w", p, err)
+		}
+		attributes[p] = a
+	}
+	return content, attributes, nil
+}
+
+// getContents reads the content and blob SHA of file at ref with the contents API.  Files too
+// big for the contents API to return are read as blobs.
+func getContents(ctx context.Context, client *github.Client, owner, repo, ref, file string) (string, string, error) {
+	fc, _, resp, err := client.Repositories.GetContents(ctx, owner, repo, file, &github.RepositoryContentGetOptions{Ref: ref})
+	if resp != nil && resp.StatusCode == http.StatusNotFound {
+		return "", "", fmt.Errorf("%w: %s at %s", errNotFound, file, ref)
+	}
+	if err != nil {
+		return "", "", fmt.Errorf("get contents of %s: %w", file, err)
+	}
+	if fc == nil || fc.GetType() != "file" {
+		return "", "", fmt.Errorf("%s is not a file", file)
+	}
+	if fc.GetEncoding() == "none" {
+		content, err := readBlob(ctx, client, owner, repo, fc.GetSHA())
+		return content, fc.GetSHA(), err
+	}
+	content, err := fc.GetContent()
+	if err != nil {
+		return "", "", fmt.Errorf("decode contents of %s: %w", file, err)
+	}
+	return content, fc.GetSHA(), nil
+}
+
+// errNotFound is returned by readFile and walkTree when the file or directory does not exist.
+var errNotFound = errors.New("not found")
+
+// readFile reads name from the commit whose root tree is root.  If root was read recursively,
+// name is found in it directly; otherwise the trees along the way to name are read.
+func readFile(ctx context.Context, client *github.Client, owner, repo string, root *github.Tree, name string) (string, error) {
+	for _, e := range root.Entries {
+		if e.GetPath() == name && e.GetType() == "blob" {
+			return readBlob(ctx, client, owner, repo, e.GetSHA())
+		}
+	}
+	var entries []*github.TreeEntry
+	var prefix string
+	if dir := path.Dir(name); dir == "." {
+		tree, _, err := client.Git.GetTree(ctx, owner, repo, root.GetSHA(), false)
+		if err != nil {
+			return "", fmt.Errorf("fetch tree %s: %w", root.GetSHA(), err)
+		}
+		entries = tree.Entries
+	} else {
+		_, sub, err := walkTree(ctx, client, owner, repo, root.GetSHA(), dir)
+		if err != nil {
+			return "", err
+		}
+		entries, prefix = sub.Entries, dir+"/"
+	}
+	for _, e := range entries {
+		if prefix+e.GetPath() == name && e.GetType() == "blob" {
+			return readBlob(ctx, client, owner, repo, e.GetSHA())
+		}
+	}
+	return "", fmt.Errorf("%w: %s in tree %s", errNotFound, name, root.GetSHA())
+}
+
+// editOptions adjust how editYAML chooses what to edit.
+type editOptions struct {
+	// MatchLabels, if non-empty, restricts edits to documents whose metadata.labels contain
+	// every one of these labels.  Other documents are left exactly as they were.
+	MatchLabels map[string]string
+
+	// RangeBound, if set, treats each location as a version range and replaces only its
+	// "lower" or "upper" bound with the replacement.
+	RangeBound string
+
+	// Scale, if non-zero, multiplies the Kubernetes resource quantity at each location by this
+	// factor, instead of replacing it.
+	Scale float64
+
+	// Base64, if set, treats the value at each location as base64-encoded content, and
+	// applies Inner to the decoded content, rather than replacing the value.
+	Base64 bool
+	Inner  innerEdit
+
+	// Object, if set, is a mapping to merge into the mapping at each location, rather than
+	// replacing its value.  ShallowMerge replaces nested mappings rather than merging them.
+	Object       *yaml.RNode
+	ShallowMerge bool
+
+	// Comment, if set, is attached to each edited scalar as a line comment, replacing the one
+	// a previous edit attached.
+	Comment string
+
+	// Mirrors copy the value at one location to another.  They are applied after the
+	// locations have been edited, so a mirrored location sees its new value.
+	Mirrors []mirror
+
+	// Renames are keys to rename.  They are applied after the locations and mirrors have been
+	// edited.
+	Renames []rename
+
+	// FirstMatchOnly, if set, edits each location only in the first document it is found in,
+	// leaving it alone in later documents, and a location with wildcards only at its first match.
+	FirstMatchOnly bool
+
+	// Anchors, if non-nil, are used to follow sequence elements that locations point at by
+	// index when they move, and are updated with the elements edited this time.
+	Anchors anchors
+
+	// Trace, if set, receives a line for each location in each document, describing what was
+	// changed or why it was skipped.
+	Trace io.Writer
+}
+
+// change describes the effect of editing one location in one document.
+type change struct {
+	Location string
+	Found    bool
+	Document int // The number of the document, from 1, if found.
+	Line     int
+	Old, New string
+}
+
+// setScalar is a yaml.Filter that replaces the value of the scalar node it's given with the
+// result of calling replace on the old value, recording both in change.
+type setScalar struct {
+	replace  func(old string) (string, error)
+	describe string
+	comment  string
+	change   *change
+}
+
+func (s setScalar) Filter(rn *yaml.RNode) (*yaml.RNode, error) {
+	s.change.Found = true
+	s.change.Line = rn.YNode().Line
+	s.change.Old = rn.YNode().Value
+	value, err := s.replace(rn.YNode().Value)
+	if err != nil {
+		return nil, err
+	}
+	old := rn.YNode()
+	// A block scalar keeps its chomping: one that ended in a newline, written with "|" rather
+	// than "|-", still does.
+	if old.Style&(yaml.LiteralStyle|yaml.FoldedStyle) != 0 && strings.HasSuffix(old.Value, "\n") && !strings.HasSuffix(value, "\n") {
+		value += "\n"
+	}
+	node := yaml.NewScalarRNode(value)
+	if needsQuotes(old, value) {
+		node.YNode().Style = yaml.SingleQuotedStyle
+	}
+	// An explicit tag, like "!!str" in "!!str 1.0" or a custom "!secret", is kept along with the
+	// tagged style copied from old; without it the tag would be dropped.
+	if old.Style&yaml.TaggedStyle != 0 {
+		node.YNode().Tag = old.Tag
+	}
+	if s.comment != "" {
+		node.YNode().LineComment = setComment(rn.YNode().LineComment, s.comment)
+	}
+	if _, err := rn.Pipe(yaml.FieldSetter{Value: node, OverrideStyle: true}); err != nil {
+		return nil, err
+	}
+	s.change.New = rn.YNode().Value
+	return rn, nil
+}
+
+// commentMarker marks the part of a line comment that --set-comment wrote.
+const commentMarker = "# version-bump: "
+
+// setComment returns the line comment old with comment attached in place of any comment
+// attached by an earlier edit.  The rest of old is kept ahead of it.
+func setComment(old, comment string) string {
+	if i := strings.Index(old, commentMarker); i >= 0 {
+		old = strings.TrimSpace(old[:i])
+	}
+	if old == "" {
+		return commentMarker + comment
+	}
+	return old + " " + commentMarker + comment
+}
+
+// needsQuotes returns true if value must be quoted to replace old, a plain string, and still
+// read back as a string.  Values like "true", "1.2", "" and, for YAML 1.1 readers like the
+// Kubernetes API server, "yes" would otherwise change the type of the field.  Values that are
+// not strings to begin with, like replica counts, are left plain.  Values that cannot be plain
+// scalars at all, like "foo: bar" or "*star", are quoted by the encoder regardless.
+func needsQuotes(old *yaml.Node, value string) bool {
+	if old.Style != 0 || old.Tag != yaml.NodeTagString || yaml.IsValueNonString(old.Value) {
+		return false
+	}
+	return value == "" || yaml.IsValueNonString(value)
+}
+
+// walkTree descends from the root tree to the directory dir, reading each tree along the way
+// non-recursively.  It returns the (non-recursive) root tree, and the recursive listing of dir.
+func walkTree(ctx context.Context, client *github.Client, owner, repo, rootSHA, dir string) (*github.Tree, *github.Tree, error) {
+	var root *github.Tree
+	sha := rootSHA
+	for _, name := range strings.Split(strings.Trim(dir, "/"), "/") {
+		tree, _, err := client.Git.GetTree(ctx, owner, repo, sha, false)
+		if err != nil {
+			return nil, nil, fmt.Errorf("fetch tree %s: %w", sha, err)
+		}
+		if root == nil {
+			root = tree
+		}
+		sha = ""
+		for _, e := range tree.Entries {
+			if e.GetPath() == name && e.GetType() == "tree" {
+				sha = e.GetSHA()
+				break
+			}
+		}
+		if sha == "" {
+			return nil, nil, fmt.Errorf("%w: directory %s in tree %s", errNotFound, name, tree.GetSHA())
+		}
+	}
+	sub, _, err := client.Git.GetTree(ctx, owner, repo, sha, true)
+	if err != nil {
+		return nil, nil, fmt.Errorf("fetch tree %s: %w", sha, err)
+	}
+	return root, sub, nil
+}
+
+// walkToFile is walkTree for the directory containing file, returning the prefix that the
+// paths of the entries of the directory's tree lack.  If file is at the root, the root tree is
+// read, not recursively, as the directory.
+func walkToFile(ctx context.Context, client *github.Client, owner, repo, rootSHA, file string) (*github.Tree, *github.Tree, string, error) {
+	dir := path.Dir(file)
+	if dir == "." {
+		root, _, err := client.Git.GetTree(ctx, owner, repo, rootSHA, false)
+		if err != nil {
+			return nil, nil, "", fmt.Errorf("fetch tree %s: %w", rootSHA, err)
+		}
+		return root, root, "", nil
+	}
+	root, sub, err := walkTree(ctx, client, owner, repo, rootSHA, dir)
+	if err != nil {
+		return nil, nil, "", err
+	}
+	return root, sub, dir + "/", nil
+}
+
+// A rename asks for the key at Location to be renamed to NewName.
+type rename struct {
+	Location, NewName string
+}
+
+// parseRenames parses renames of the form "location=newName".
+func parseRenames(specs []string) ([]rename, error) {
+	var renames []rename
+	for _, spec := range specs {
+		i := strings.LastIndex(spec, "=")
+		if i <= 0 || i == len(spec)-1 {
+			return nil, fmt.Errorf("invalid rename %q: want location=newName", spec)
+		}
+		renames = append(renames, rename{Location: spec[:i], NewName: spec[i+1:]})
+	}
+	return renames, nil
+}
+
+type mirror struct {
+	Source, Target string
+}
+
+// parseMirrors parses mirrors of the form "source=target".  Either location may contain
+// matches like [name=value], so the two are separated by the first "=" outside brackets.
+func parseMirrors(specs []string) ([]mirror, error) {
+	var mirrors []mirror
+	for _, spec := range specs {
+		i, depth := -1, 0
+		for j, c := range spec {
+			if c == '[' {
+				depth++
+			} else if c == ']' {
+				depth--
+			} else if c == '=' && depth == 0 {
+				i = j
+				break
+			}
+		}
+		if i <= 0 || i == len(spec)-1 {
+			return nil, fmt.Errorf("invalid mirror %q: want source=target", spec)
+		}
+		mirrors = append(mirrors, mirror{Source: spec[:i], Target: spec[i+1:]})
+	}
+	return mirrors, nil
+}
+
+// copyScalar is a yaml.Filter that sets the scalar at the path to to the value of the scalar at
+// the path from, recording the edit in change.  Nothing happens if either is missing.
+type copyScalar struct {
+	source   string
+	from, to []string
+	change   *change
+}
+
+func (c copyScalar) Filter(rn *yaml.RNode) (*yaml.RNode, error) {
+	src, err := rn.Pipe(lookup(c.from)...)
+	if err != nil {
+		return nil, err
+	}
+	if src == nil {
+		return rn, nil
+	}
+	if err := yaml.ErrorIfInvalid(src, yaml.ScalarNode); err != nil {
+		return nil, fmt.Errorf("mirror source %s: %w", c.source, err)
+	}
+	value := src.YNode().Value
+	set := setScalar{replace: func(string) (string, error) { return value, nil }, change: c.change}
+	if _, err := rn.Pipe(append(lookup(c.to), set)...); err != nil {
+		return nil, err
+	}
+	return rn, nil
+}
+
+// renameKey is a yaml.Filter that renames the key name of the mapping node it's given to newName,
+// keeping its value, comments, and position, and recording the rename in change.
+type renameKey struct {
+	name, newName string
+	change        *change
+}
+
+func (r renameKey) Filter(rn *yaml.RNode) (*yaml.RNode, error) {
+	if err := yaml.ErrorIfInvalid(rn, yaml.MappingNode); err != nil {
+		return nil, err
+	}
+	var key, existing *yaml.Node
+	content := rn.YNode().Content
+	for i := 0; i < len(content); i += 2 {
+		switch content[i].Value {
+		case r.newName:
+			existing = content[i]
+		case r.name:
+			key = content[i]
+		}
+	}
+	if key == nil {
+		return rn, nil
+	}
+	if existing != nil {
+		return nil, fmt.Errorf("cannot rename %s to %s: key %s already exists", r.change.Location, r.newName, r.newName)
+	}
+	r.change.Found = true
+	r.change.Line = key.Line
+	r.change.Old = key.Value
+	key.Value = r.newName
+	r.change.New = key.Value
+	return rn, nil
+}
+
+// newSetScalar returns the setScalar that edits a location with replacement, as opts
+// describes.
+func newSetScalar(replacement string, opts editOptions) setScalar {
+	set := setScalar{describe: fmt.Sprintf("Set(%q)", replacement)}
+	set.replace = func(string) (string, error) { return replacement, nil }
+	if opts.RangeBound != "" {
+		set.describe = fmt.Sprintf("UpdateConstraint(%s, %q)", opts.RangeBound, replacement)
+		set.replace = func(old string) (string, error) { return updateConstraint(old, opts.RangeBound, replacement) }
+	}
+	if opts.Scale != 0 {
+		set.describe = fmt.Sprintf("ScaleQuantity(%v)", opts.Scale)
+		set.replace = func(old string) (string, error) { return scaleQuantity(old, opts.Scale) }
+	}
+	if opts.Base64 {
+		set.describe = fmt.Sprintf("EditBase64(%s, %q)", opts.Inner, replacement)
+		set.replace = func(old string) (string, error) {
+			return editBase64(old, func(decoded string) (string, error) { return opts.Inner.apply(decoded, replacement) })
+		}
+	}
+	if opts.Comment != "" {
+		set.comment = opts.Comment
+		set.describe = fmt.Sprintf("%s.Comment(%q)", set.describe, opts.Comment)
+	}
+	return set
+}
+
+// editFilters returns the filters that apply edits and opts to one document, and record their
+// effects in changes, which has an element for each edit, mirror, and rename.  If doc is nil,
+// anchors are not consulted.
+func editFilters(doc *yaml.RNode, docNum int, edits []locationEdit, opts editOptions, changes []change) ([]yaml.Filter, error) {
+	var filters []yaml.Filter
+	for j, e := range edits {
+		location := e.Path
+		changes[j].Location = location
+		path, err := locate(doc, docNum, location)
+		if err != nil {
+			return nil, err
+		}
+		var apply yaml.Filter
+		if opts.Object != nil {
+			apply = mergeObject{object: opts.Object, shallow: opts.ShallowMerge, change: &changes[j]}
+		} else {
+			set := newSetScalar(e.Value, opts)
+			set.change = &changes[j]
+			apply = set
+		}
+		if doc != nil && hasWildcard(path) {
+			// One filter per location, which edits every match.
+			paths, err := expandWildcards(doc, path)
+			if err != nil {
+				return nil, fmt.Errorf("expand %s in document %d: %w", location, docNum, err)
+			}
+			var each []yaml.Filter
+			for _, p := range paths {
+				if opts.FirstMatchOnly {
+					// Only the first match that is there gets the edit.
+					found, err := doc.Pipe(lookup(p)...)
+					if err != nil {
+						return nil, fmt.Errorf("look up %s in document %d: %w", location, docNum, err)
+					}
+					if found == nil {
+						continue
+					}
+					each = append(each, yaml.Tee(append(lookup(p), apply)...))
+					break
+				}
+				each = append(each, yaml.Tee(append(lookup(p), apply)...))
+			}
+			filters = append(filters, yaml.Tee(each...))
+			continue
+		}
+		if opts.Anchors != nil && doc != nil {
+			path, err = opts.Anchors.resolve(doc, docNum, path, opts.Trace)
+			if err != nil {
+				return nil, fmt.Errorf("resolve %s in document %d: %w", location, docNum, err)
+			}
+		}
+		filters = append(filters, yaml.Tee(append(lookup(path), apply)...))
+	}
+	for j, m := range opts.Mirrors {
+		c := &changes[len(edits)+j]
+		c.Location = m.Target
+		from, err := locate(doc, docNum, m.Source)
+		if err != nil {
+			return nil, err
+		}
+		to, err := locate(doc, docNum, m.Target)
+		if err != nil {
+			return nil, err
+		}
+		filters = append(filters, copyScalar{source: m.Source, from: from, to: to, change: c})
+	}
+	for j, r := range opts.Renames {
+		c := &changes[len(edits)+len(opts.Mirrors)+j]
+		c.Location = r.Location
+		path, err := locate(doc, docNum, r.Location)
+		if err != nil {
+			return nil, err
+		}
+		name := path[len(path)-1]
+		if yaml.IsListIndex(name) {
+			return nil, fmt.Errorf("cannot rename %s: %s is a sequence element, not a key", r.Location, name)
+		}
+		filters = append(filters, yaml.Tee(append(lookup(path[:len(path)-1]), renameKey{name: name, newName: r.NewName, change: c})...))
+	}
+	return filters, nil
+}
+
+// A locationEdit is a location to edit, and the value to replace what is there with.
+type locationEdit struct {
+	Path  string
+	Value string
+}
+
+// editsFor returns edits replacing each of locations with value.
+func editsFor(locations []string, value string) []locationEdit {
+	var edits []locationEdit
+	for _, l := range locations {
+		edits = append(edits, locationEdit{Path: l, Value: value})
+	}
+	return edits
+}
+
+// editYAML applies edits and opts to each document in input.  Documents in which none of the
+// locations are found are left exactly as they were written.  Along with the edited input, it
+// returns a change for each location edited in each document, in order, followed by one with
+// Found unset for each of edits, mirrors, and renames that was not found in any document.
+func editYAML(input string, edits []locationEdit, opts editOptions) (string, []change, error) {
+	docs, separators := splitDocuments(input)
+	// editedIn is the number of the first document each location was found in, and appliedIn
+	// the same for each mirror and then each rename.
+	editedIn := make([]int, len(edits))
+	appliedIn := make([]int, len(opts.Mirrors)+len(opts.Renames))
+	var results []change
+	for i, doc := range docs {
+		nodes, err := yaml.Parse(doc)
+		if errors.Is(err, io.EOF) {
+			// Nothing but whitespace and comments; leave it alone.
+			continue
+		}
+		if err != nil {
+			return "", nil, fmt.Errorf("parse yaml document %d: %w", i+1, err)
+		}
+		if ok, err := matchLabels(nodes, opts.MatchLabels); err != nil {
+			return "", nil, fmt.Errorf("read labels of document %d: %w", i+1, err)
+		} else if !ok {
+			if opts.Trace != nil {
+				fmt.Fprintf(opts.Trace, "document %d: labels do not match; skipped\n", i+1)
+			}
+			continue
+		}
+
+		changes := make([]change, len(edits)+len(opts.Mirrors)+len(opts.Renames))
+		filters, err := editFilters(nodes, i+1, edits, opts, changes)
+		if err != nil {
+			return "", nil, err
+		}
+		if opts.FirstMatchOnly {
+			// The first len(edits) filters edit the locations, in order.
+			var kept []yaml.Filter
+			for j, f := range filters {
+				if j < len(edits) && editedIn[j] != 0 {
+					continue
+				}
+				kept = append(kept, f)
+			}
+			filters = kept
+		}
+		if _, err := nodes.Pipe(filters...); err != nil {
+			return "", nil, fmt.Errorf("apply edits to document %d: %w", i+1, err)
+		}
+		for j := range edits {
+			if changes[j].Found && editedIn[j] == 0 {
+				editedIn[j] = i + 1
+			}
+		}
+		for j := range appliedIn {
+			if changes[len(edits)+j].Found && appliedIn[j] == 0 {
+				appliedIn[j] = i + 1
+			}
+		}
+		if opts.Trace != nil {
+			for j, c := range changes {
+				if j < len(edits) && opts.FirstMatchOnly && editedIn[j] != i+1 && editedIn[j] != 0 {
+					fmt.Fprintf(opts.Trace, "document %d: %s: already edited in document %d; skipped\n", i+1, c.Location, editedIn[j])
+					continue
+				}
+				if !c.Found {
+					fmt.Fprintf(opts.Trace, "document %d: %s: not found; skipped\n", i+1, c.Location)
+					continue
+				}
+				fmt.Fprintf(opts.Trace, "document %d: %s (line %d): %q -> %q\n", i+1, c.Location, c.Line, c.Old, c.New)
+			}
+		}
+		for _, c := range changes {
+			if c.Found {
+				c.Document = i + 1
+				results = append(results, c)
+			}
+		}
+		if len(changes) > 0 && !anyFound(changes) {
+			// Nothing to edit in this document; leave it exactly as it was written.
+			continue
+		}
+		out, err := nodes.String()
+		if err != nil {
+			return "", nil, fmt.Errorf("format yaml document %d: %w", i+1, err)
+		}
+		docs[i] = out
+	}
+	for j, e := range edits {
+		if editedIn[j] == 0 {
+			results = append(results, change{Location: e.Path})
+		}
+	}
+	for j, m := range opts.Mirrors {
+		if appliedIn[j] == 0 {
+			results = append(results, change{Location: m.Source + "=" + m.Target})
+		}
+	}
+	for j, r := range opts.Renames {
+		if appliedIn[len(opts.Mirrors)+j] == 0 {
+			results = append(results, change{Location: r.Location})
+		}
+	}
+	return joinDocuments(docs, separators), results, nil
+}
+
+// anyFound returns true if any of changes found its location.
+func anyFound(changes []change) bool {
+	for _, c := range changes {
+		if c.Found {
+			return true
+		}
+	}
+	return false
+}
+
+// splitDocuments splits a stream of YAML documents into the documents and the "---" lines that
+// separate them.  joinDocuments reverses the split exactly.
+func splitDocuments(input string) (docs []string, separators []string) {
+	var doc strings.Builder
+	for _, line := range strings.SplitAfter(input, "\n") {
+		if isDocumentSeparator(line) {
+			docs = append(docs, doc.String())
+			separators = append(separators, line)
+			doc.Reset()
+			continue
+		}
+		doc.WriteString(line)
+	}
+	return append(docs, doc.String()), separators
+}
+
+func isDocumentSeparator(line string) bool {
+	if !strings.HasPrefix(line, "---") {
+		return false
+	}
+	rest := strings.TrimSpace(line[3:])
+	return rest == "" || strings.HasPrefix(rest, "#")
+}
+
+func joinDocuments(docs []string, separators []string) string {
+	var b strings.Builder
+	for i, doc := range docs {
+		b.WriteString(doc)
+		if i < len(separators) {
+			b.WriteString(separators[i])
+		}
+	}
+	return b.String()
+}
+
+// matchLabels returns true if the document's metadata.labels contain every label in labels.
+func matchLabels(doc *yaml.RNode, labels map[string]string) (bool, error) {
+	if len(labels) > 0 && doc.YNode().Kind != yaml.MappingNode {
+		// Only mappings have metadata.
+		return false, nil
+	}
+	for k, v := range labels {
+		label, err := doc.Pipe(yaml.Lookup("metadata", "labels", k))
+		if err != nil {
+			return false, err
+		}
+		if label == nil || label.YNode().Value != v {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// parseLabels parses label selectors of the form "key=value".  Each selector may contain several
+// comma-separated labels.
+func parseLabels(selectors []string) (map[string]string, error) {
+	labels := map[string]string{}
+	for _, selector := range selectors {
+		for _, label := range strings.Split(selector, ",") {
+			parts := strings.SplitN(label, "=", 2)
+			if len(parts) != 2 || parts[0] == "" {
+				return nil, fmt.Errorf("invalid label selector %q: want key=value", label)
+			}
+			labels[parts[0]] = parts[1]
+		}
+	}
+	return labels, nil
+}
+
+// normalize re-serializes input exactly as editYAML would, without editing any
+// values.
+func normalize(input string) (string, error) {
+	out, _, err := editYAML(input, nil, editOptions{})
+	return out, err
+}
+
+// commit writes files in a new commit on top of baseCommit, and moves branch to point at it.
+// Unless force is set, the branch must not have moved away from baseCommit: it is checked just
+// before it is moved, returning errBranchMoved, and Github refuses to move it if it moves on
+// in between.
+func commit(ctx context.Context, client *github.Client, baseTreeSHA, baseCommit, owner, repo, branch string, files map[string]string, commitMsg string, id identity, force bool) (string, error) {
+	sha, err := createCommit(ctx, client, baseTreeSHA, baseCommit, owner, repo, files, commitMsg, id)
+	if err != nil {
+		return "", err
+	}
+	head := fmt.Sprintf("heads/%s", branch)
+	if !force {
+		ref, _, err := client.Git.GetRef(ctx, owner, repo, head)
+		if err != nil {
+			return "", fmt.Errorf("check head of %s: %w", branch, err)
+		}
+		if cur := ref.GetObject().GetSHA(); cur != baseCommit {
+			return "", fmt.Errorf("%s is now at commit %s, not %s: %w", branch, cur, baseCommit, errBranchMoved)
+		}
+	}
+	_, _, err = client.Git.UpdateRef(ctx, owner, repo, &github.Reference{Ref: &head, Object: &github.GitObject{SHA: &sha}}, force)
+	if err != nil {
+		return "", fmt.Errorf("move %s to commit %s: %w", head, sha, err)
+	}
+	return sha, nil
+}
+
+// createBranch is like commit, but creates branch at the new commit rather than moving it
+// there.  It fails if branch already exists.
+func createBranch(ctx context.Context, client *github.Client, baseTreeSHA, baseCommit, owner, repo, branch string, files map[string]string, commitMsg string, id identity) (string, error) {
+	sha, err := createCommit(ctx, client, baseTreeSHA, baseCommit, owner, repo, files, commitMsg, id)
+	if err != nil {
+		return "", err
+	}
+	ref := fmt.Sprintf("refs/heads/%s", branch)
+	if _, _, err := client.Git.CreateRef(ctx, owner, repo, &github.Reference{Ref: &ref, Object: &github.GitObject{SHA: &sha}}); err != nil {
+		return "", fmt.Errorf("create %s at commit %s: %w", ref, sha, err)
+	}
+	return sha, nil
+}
+
+// backupBranch points branch at sha, the head of the branch about to be edited, so that it can
+// be restored if the edit goes wrong.  If branch already exists, it is moved only if force is
+// set.
+func backupBranch(ctx context.Context, client *github.Client, owner, repo, branch, sha string, force bool, protected []string) error {
+	ref := fmt.Sprintf("refs/heads/%s", branch)
+	_, resp, err := client.Git.CreateRef(ctx, owner, repo, &github.Reference{Ref: &ref, Object: &github.GitObject{SHA: &sha}})
+	if err == nil {
+		return nil
+	}
+	if resp == nil || resp.StatusCode != http.StatusUnprocessableEntity {
+		return fmt.Errorf("create backup branch %s at commit %s: %w", branch, sha, err)
+	}
+	if exists, eerr := branchExists(ctx, client, owner, repo, branch); eerr != nil || !exists {
+		return fmt.Errorf("create backup branch %s at commit %s: %w", branch, sha, err)
+	}
+	if !force {
+		return fmt.Errorf("backup branch %s already exists; pass --force to move it", branch)
+	}
+	if err := checkForce(branch, protected); err != nil {
+		return err
+	}
+	head := fmt.Sprintf("heads/%s", branch)
+	if _, _, err := client.Git.UpdateRef(ctx, owner, repo, &github.Reference{Ref: &head, Object: &github.GitObject{SHA: &sha}}, true); err != nil {
+		return fmt.Errorf("move backup branch %s to commit %s: %w", branch, sha, err)
+	}
+	return nil
+}
+
+// createBase returns the branch to create --branch from: --create-from, or the default branch
+// of the repository.  It must exist.
+func createBase(ctx context.Context, client *github.Client, cfg *config) (string, error) {
+	base := cfg.CreateFrom
+	if base == "" {
+		r, _, err := client.Repositories.Get(ctx, cfg.GithubOwner, cfg.GithubRepo)
+		if err != nil {
+			return "", fmt.Errorf("look up default branch of %s/%s: %w", cfg.GithubOwner, cfg.GithubRepo, err)
+		}
+		base = r.GetDefaultBranch()
+	}
+	exists, err := branchExists(ctx, client, cfg.GithubOwner, cfg.GithubRepo, base)
+	if err != nil {
+		return "", fmt.Errorf("check for branch %s: %w", base, err)
+	}
+	if !exists {
+		return "", fmt.Errorf("cannot create branch %s from %s: branch %s does not exist", cfg.GithubBranch, base, base)
+	}
+	return base, nil
+}
+
+// branchExists returns true if branch exists in the repository.
+func branchExists(ctx context.Context, client *github.Client, owner, repo, branch string) (bool, error) {
+	_, resp, err := client.Repositories.GetBranch(ctx, owner, repo, branch)
+	if resp != nil && resp.StatusCode == http.StatusNotFound {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// createCommit writes files, a map from path to content, on top of baseTreeSHA and creates a
+// commit whose parent is baseCommit.  No refs are moved to point at the new commit.
+func createCommit(ctx context.Context, client *github.Client, baseTreeSHA, baseCommit, owner, repo string, files map[string]string, commitMsg string, id identity) (string, error) {
+	plan := newCommitPlan(baseTreeSHA, baseCommit, files, commitMsg, id)
+	blobs, err := createBlobs(ctx, client, owner, repo, plan.Entries, files)
+	if err != nil {
+		return "", err
+	}
+	// Entries are in the order of the plan, whichever blob was created first.
+	var entries []*github.TreeEntry
+	for i, e := range plan.Entries {
+		e := e
+		entries = append(entries, &github.TreeEntry{
+			Path: &e.Path,
+			Mode: &e.Mode,
+			Type: &e.Type,
+			SHA:  &blobs[i],
+		})
+	}
+	tree, _, err := client.Git.CreateTree(ctx, owner, repo, baseTreeSHA, entries)
+	if err != nil {
+		return "", fmt.Errorf("create tree with blobs %s: %w", strings.Join(blobs, ", "), err)
+	}
+
+	now := time.Now()
+	commit, _, err := client.Git.CreateCommit(ctx, owner, repo, &github.Commit{
+		Author:     id.Author.commitAuthor(now),
+		Committer:  id.Committer.commitAuthor(now),
+		Message:    &commitMsg,
+		Parents:    []*github.Commit{{SHA: &baseCommit}},
+		Tree:       tree,
+		SigningKey: id.SigningKey,
+	})
+	if err != nil {
+		return "", fmt.Errorf("create commit from tree %s and parent %s: %w", tree.GetSHA(), baseCommit, err)
+	}
+	return commit.GetSHA(), nil
+}
+
+// maxBlobUploads is how many blobs createBlobs creates at once.
+const maxBlobUploads = 8
+
+// createBlobs creates a blob for each entry, with its content taken from files, and returns
+// their SHAs in the same order as entries.  Up to maxBlobUploads blobs are created at once.
+func createBlobs(ctx context.Context, client *github.Client, owner, repo string, entries []planEntry, files map[string]string) ([]string, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	shas := make([]string, len(entries))
+	errs := make([]error, len(entries))
+	sem := make(chan struct{}, maxBlobUploads)
+	var wg sync.WaitGroup
+	for i, e := range entries {
+		i, e := i, e
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer func() { <-sem; wg.Done() }()
+			encoding, content := blobContent(files[e.Path])
+			blob, _, err := client.Git.CreateBlob(ctx, owner, repo, &github.Blob{
+				Encoding: &encoding,
+				Content:  &content,
+			})
+			if err != nil {
+				errs[i] = fmt.Errorf("create blob for %s: %w", e.Path, err)
+				cancel()
+				return
+			}
+			shas[i] = blob.GetSHA()
+		}()
+	}
+	wg.Wait()
+	// Report the first failure in path order; later ones may only be the cancellation.
+	for _, err := range errs {
+		if err != nil && !errors.Is(err, context.Canceled) {
+			return nil, err
+		}
+	}
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+	return shas, nil
+}
+
+// blobContent returns the encoding and content to send Github to create a blob containing
+// content.  Text is sent as it is, since base64 makes it a third bigger; content that is not
+// valid UTF-8, or contains NUL bytes, would not survive that and is sent as base64.
+func blobContent(content string) (encoding, encoded string) {
+	if utf8.ValidString(content) && !strings.ContainsRune(content, 0) {
+		return "utf-8", content
+	}
+	return "base64", base64.StdEncoding.EncodeToString([]byte(content))
+}
+
+// sortedPaths returns the paths in files in order.
+func sortedPaths(files map[string]string) []string {
+	var paths []string
+	for p := range files {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// pullRequest commits files to branch in owner/repo, forked from baseCommit, and opens a pull
+// request asking for that branch to be merged into base, which may be in another repository
+// when owner/repo is a fork.  It returns the SHA of the new commit and the pull request.  If
+// branch already exists, the edit is committed on top of its head, so that commits already on
+// it are kept.  If it has an open pull request into base, that pull request is returned rather
+// than opening another; otherwise branch is only touched with force, and protected branches
+// are refused.  If the pull request can't be opened, a newly created branch is deleted again.
+func pullRequest(ctx context.Context, client *github.Client, baseTreeSHA, baseCommit, owner, repo string, base target, branch string, files map[string]string, commitMsg string, id identity, force bool, protected []string) (string, *github.PullRequest, error) {
+	if owner == base.Owner && repo == base.Repo && branch == base.Branch {
+		return "", nil, fmt.Errorf("cannot propose a pull request from %s into itself; choose another --pr-branch", branch)
+	}
+	head := fmt.Sprintf("heads/%s", branch)
+	existing, resp, err := client.Git.GetRef(ctx, owner, repo, head)
+	existed := err == nil
+	if !existed && (resp == nil || resp.StatusCode != http.StatusNotFound) {
+		return "", nil, fmt.Errorf("check for branch %s: %w", branch, err)
+	}
+	var sha string
+	if existed {
+		open, _, err := client.PullRequests.List(ctx, base.Owner, base.Repo, &github.PullRequestListOptions{State: "open", Head: owner + ":" + branch, Base: base.Branch})
+		if err != nil {
+			return "", nil, fmt.Errorf("look for an open pull request from %s: %w", branch, err)
+		}
+		if len(open) == 0 {
+			if !force {
+				return "", nil, fmt.Errorf("branch %s already exists and has no open pull request into %s; pass --force to commit the edit on top of it", branch, base.Branch)
+			}
+			if err := checkForce(branch, protected); err != nil {
+				return "", nil, err
+			}
+		}
+		parent := existing.GetObject().GetSHA()
+		c, _, err := client.Git.GetCommit(ctx, owner, repo, parent)
+		if err != nil {
+			return "", nil, fmt.Errorf("get head %s of branch %s: %w", parent, branch, err)
+		}
+		sha, err = createCommit(ctx, client, c.GetTree().GetSHA(), parent, owner, repo, files, commitMsg, id)
+		if err != nil {
+			return "", nil, err
+		}
+		if _, _, err := client.Git.UpdateRef(ctx, owner, repo, &github.Reference{Ref: &head, Object: &github.GitObject{SHA: &sha}}, false); err != nil {
+			return "", nil, fmt.Errorf("move existing branch %s to commit %s: %w", branch, sha, err)
+		}
+		if len(open) > 0 {
+			log.Printf("pull request #%d from %s is already open; added commit %s to its branch", open[0].GetNumber(), branch, sha)
+			return sha, open[0], nil
+		}
+	} else {
+		sha, err = createCommit(ctx, client, baseTreeSHA, baseCommit, owner, repo, files, commitMsg, id)
+		if err != nil {
+			return "", nil, err
+		}
+		ref := fmt.Sprintf("refs/heads/%s", branch)
+		if _, _, err := client.Git.CreateRef(ctx, owner, repo, &github.Reference{Ref: &ref, Object: &github.GitObject{SHA: &sha}}); err != nil {
+			return "", nil, fmt.Errorf("create branch %s at commit %s: %w", branch, sha, err)
+		}
+	}
+	prHead := branch
+	if owner != base.Owner || repo != base.Repo {
+		prHead = owner + ":" + branch
+	}
+	title, body := splitCommitMessage(commitMsg)
+	pr, _, err := client.PullRequests.Create(ctx, base.Owner, base.Repo, &github.NewPullRequest{
+		Title: &title,
+		Head:  &prHead,
+		Base:  &base.Branch,
+		Body:  &body,
+	})
+	if err != nil {
+		if !existed {
+			if _, derr := client.Git.DeleteRef(ctx, owner, repo, head); derr != nil {
+				log.Printf("clean up branch %s: %v", branch, derr)
+			}
+		}
+		return "", nil, fmt.Errorf("open pull request from %s into %s: %w", prHead, base.Branch, err)
+	}
+	log.Printf("opened pull request #%d: %s", pr.GetNumber(), pr.GetHTMLURL())
+	return sha, pr, nil
+}
+
+// openPullRequestWith returns the pull request from branch in owner/repo into base that is
+// already open, and the head of branch, if branch already contains files.  Running again with
+// the same edit then leaves the pull request alone, rather than pushing the same change to it.
+// It returns a nil pull request if there is none, or if it proposes something else.
+func openPullRequestWith(ctx context.Context, client *github.Client, owner, repo string, base target, branch string, files map[string]string) (*github.PullRequest, string, error) {
+	open, _, err := client.PullRequests.List(ctx, base.Owner, base.Repo, &github.PullRequestListOptions{State: "open", Head: owner + ":" + branch, Base: base.Branch})
+	if err != nil {
+		return nil, "", fmt.Errorf("look for an open pull request from %s: %w", branch, err)
+	}
+	if len(open) == 0 {
+		return nil, "", nil
+	}
+	br, _, err := client.Repositories.GetBranch(ctx, owner, repo, branch)
+	if err != nil {
+		return nil, "", fmt.Errorf("get branch %s of pull request #%d: %w", branch, open[0].GetNumber(), err)
+	}
+	head := br.GetCommit().GetSHA()
+	for _, p := range sortedPaths(files) {
+		content, _, err := getContents(ctx, client, owner, repo, head, p)
+		if errors.Is(err, errNotFound) {
+			return nil, "", nil
+		}
+		if err != nil {
+			return nil, "", fmt.Errorf("read %s from branch %s: %w", p, branch, err)
+		}
+		if content != files[p] {
+			return nil, "", nil
+		}
+	}
+	return open[0], head, nil
+}
+
+// prBranch returns the branch to propose a pull request writing files from: --pr-branch, or a
+// name derived from files.
+func prBranch(cfg *config, files map[string]string) string {
+	if cfg.PRBranch != "" {
+		return cfg.PRBranch
+	}
+	return prBranchName(files)
+}
+
+// prBranchName returns the name of the branch that a pull request writing files is proposed
+// from, unless --pr-branch is set.
+func prBranchName(files map[string]string) string {
+	var parts []string
+	for _, p := range sortedPaths(files) {
+		parts = append(parts, p, files[p])
+	}
+	h := sha1.Sum([]byte(strings.Join(parts, "\x00")))
+	return fmt.Sprintf("version-bump/%x", h[:6])
+}
+
+// splitCommitMessage splits a commit message into its subject line and the remainder.
+func splitCommitMessage(msg string) (string, string) {
+	parts := strings.SplitN(msg, "\n", 2)
+	if len(parts) == 1 {
+		return parts[0], ""
+	}
+	return parts[0], strings.TrimSpace(parts[1])
+}
+
+// issueReference matches a reference to a Github issue, like #123 or owner/repo#123.
+var issueReference = regexp.MustCompile(`^([A-Za-z0-9_.-]+/[A-Za-z0-9_.-]+)?#[0-9]+$`)
+
+// withReferences returns msg with a line for each of refs appended after a blank line, in the
+// form Github links to the issue: "Refs: #123", or, if closes is set, "Closes #123".
+func withReferences(msg string, refs []string, closes bool) (string, error) {
+	if len(refs) == 0 {
+		if closes {
+			return "", errors.New("--closes needs --references")
+		}
+		return msg, nil
+	}
+	var lines []string
+	for _, ref := range refs {
+		ref = strings.TrimSpace(ref)
+		if !issueReference.MatchString(ref) {
+			return "", fmt.Errorf("invalid issue reference %q: want #123 or owner/repo#123", ref)
+		}
+		if closes {
+			lines = append(lines, "Closes "+ref)
+		} else {
+			lines = append(lines, "Refs: "+ref)
+		}
+	}
+	return strings.TrimRight(msg, "\n") + "\n\n" + strings.Join(lines, "\n"), nil
+}
+
+// onlyWhitespaceChanged returns true if a and b differ at most in trailing whitespace, line
+// endings, or trailing blank lines.
+func onlyWhitespaceChanged(a, b string) bool {
+	return trimWhitespace(a) == trimWhitespace(b)
+}
+
+func trimWhitespace(s string) string {
+	lines := strings.Split(strings.ReplaceAll(s, "\r\n", "\n"), "\n")
+	for i, l := range lines {
+		lines[i] = strings.TrimRight(l, " \t")
+	}
+	return strings.TrimRight(strings.Join(lines, "\n"), "\n")
+}
+
+// changedOnly returns cfg with the files to edit, --file and any after it, limited to those in
+// changed, logging the rest.  It returns nil if none of them changed.
+func changedOnly(cfg *config, changed map[string]bool) *config {
+	var files []string
+	for _, f := range append([]string{cfg.File}, cfg.ExtraFiles...) {
+		if changed[f] {
+			files = append(files, f)
+		} else {
+			log.Printf("%s has not changed since %s; not editing it", f, cfg.ChangedSince)
+		}
+	}
+	if len(files) == 0 {
+		return nil
+	}
+	c := *cfg
+	c.File, c.ExtraFiles = files[0], files[1:]
+	return &c
+}
+
+// maxComparedFiles is the most files Github lists in a comparison of two commits.
+const maxComparedFiles = 300
+
+// changedFiles returns the names of the files that differ between the base and head commits.
+// Github lists at most maxComparedFiles files in a comparison, so a comparison listing that
+// many may be missing some, and is refused.
+func changedFiles(ctx context.Context, client *github.Client, owner, repo, base, head string) (map[string]bool, error) {
+	cmp, _, err := client.Repositories.CompareCommits(ctx, owner, repo, base, head)
+	if err != nil {
+		return nil, fmt.Errorf("compare %s...%s: %w", base, head, err)
+	}
+	if len(cmp.Files) >= maxComparedFiles {
+		return nil, fmt.Errorf("compare %s...%s: github lists at most %d changed files, so some may be missing; use a more recent --changed-since", base, head, maxComparedFiles)
+	}
+	changed := map[string]bool{}
+	for _, f := range cmp.Files {
+		changed[f.GetFilename()] = true
+	}
+	return changed, nil
+}
+
+// resolveToken returns the Personal Access Token to authenticate with.  A token passed
+// explicitly on the command line wins, then the contents of tokenFile, and finally a token
+// from the environment.  Whitespace around the token, like the newline that ends a file, is
+// removed.
+func resolveToken(token string, explicit bool, tokenFile string) (string, error) {
+	if explicit || tokenFile == "" {
+		return strings.TrimSpace(token), nil
+	}
+	content, err := ioutil.ReadFile(tokenFile)
+	if err != nil {
+		return "", fmt.Errorf("read token file: %w", err)
+	}
+	token = strings.TrimSpace(string(content))
+	if token == "" {
+		return "", fmt.Errorf("token file %s is empty", tokenFile)
+	}
+	return token, nil
+}
+
+// newClient returns a Github client that authenticates with the provided credentials: as a
+// Github app installation if any of the app's credentials are given, and with a token
+// otherwise.  An installation token is minted before returning, so that bad app credentials
+// are found before anything is read.
+func newClient(ctx context.Context, auth *auth) (*github.Client, error) {
+	key := []byte(auth.PrivateKey)
+	if auth.PrivateKeyFile != "" {
+		var err error
+		key, err = ioutil.ReadFile(auth.PrivateKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("read private key file: %w", err)
+		}
+	}
+	if auth.AppID != 0 || auth.InstallationID != 0 || len(key) > 0 {
+		var missing []string
+		if auth.AppID == 0 {
+			missing = append(missing, "--app-id")
+		}
+		if auth.InstallationID == 0 {
+			missing = append(missing, "--installation-id")
+		}
+		if len(key) == 0 {
+			missing = append(missing, "--private-key or --private-key-file")
+		}
+		if len(missing) > 0 {
+			return nil, fmt.Errorf("authenticating as a Github app also needs %s", strings.Join(missing, " and "))
+		}
+		log.Println("Authenticating to Github as an app installation")
+		tr := http.DefaultTransport
+		itr, err := ghinstallation.New(tr, auth.AppID, auth.InstallationID, key)
+		if err != nil {
+			return nil, fmt.Errorf("new github apps key: %w", err)
+		}
+		client, err := githubClient(&http.Client{Transport: apiTransport(itr, auth)}, auth.BaseURL, auth.UploadURL)
+		if err != nil {
+			return nil, err
+		}
+		// Installation tokens come from the same API as everything else.
+		itr.BaseURL = strings.TrimSuffix(client.BaseURL.String(), "/")
+		if _, err := itr.Token(ctx); err != nil {
+			return nil, fmt.Errorf("mint installation token: %w", err)
+		}
+		return client, nil
+	} else if auth.AccessToken != "" {
+		log.Println("Authenticating to Github with a token")
+		ts := oauth2.StaticTokenSource(&oauth2.Token{AccessToken: auth.AccessToken})
+		tc := oauth2.NewClient(ctx, ts)
+		tc.Transport = apiTransport(tc.Transport, auth)
+		return githubClient(tc, auth.BaseURL, auth.UploadURL)
+	}
+	return nil, errors.New("no Github credentials: pass a token with --token, --token-file, or $GITHUB_TOKEN, or authenticate as an app with --app-id, --installation-id, and --private-key")
+}
+
+// githubClient returns a client for the Github Enterprise server at baseURL, uploading to
+// uploadURL, which defaults to the same server.  If baseURL is empty, the client is for github.com.
+func githubClient(hc *http.Client, baseURL, uploadURL string) (*github.Client, error) {
+	if baseURL == "" {
+		if uploadURL != "" {
+			return nil, errors.New("--github-upload-url needs --github-url")
+		}
+		return github.NewClient(hc), nil
+	}
+	if uploadURL == "" {
+		uploadURL = strings.TrimSuffix(strings.TrimSuffix(baseURL, "/"), "/api/v3")
+	}
+	for _, raw := range []string{baseURL, uploadURL} {
+		u, err := url.Parse(raw)
+		if err != nil {
+			return nil, fmt.Errorf("parse github url: %w", err)
+		}
+		if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return nil, fmt.Errorf("github url %q must start with http:// or https:// and a host", raw)
+		}
+	}
+	client, err := github.NewEnterpriseClient(baseURL, uploadURL, hc)
+	if err != nil {
+		return nil, fmt.Errorf("new github enterprise client: %w", err)
+	}
+	return client, nil
+}
+
+// checkForce returns an error if branch matches any of the protected branch patterns, and so
+// must never be force-updated.
+func checkForce(branch string, protected []string) error {
+	for _, pattern := range protected {
+		ok, err := path.Match(pattern, branch)
+		if err != nil {
+			return fmt.Errorf("protected branch pattern %q: %w", pattern, err)
+		}
+		if ok {
+			return fmt.Errorf("refusing to force-update protected branch %s", branch)
+		}
+	}
+	return nil
+}
+
+// webURL returns the root URL of the Github web interface whose API client talks to, without a
+// trailing slash.  Github Enterprise serves its API from /api/v3/ on the same host.
+func webURL(client *github.Client) string {
+	u := *client.BaseURL
+	if u.Host == "api.github.com" {
+		return "https://github.com"
+	}
+	u.Path = strings.TrimSuffix(strings.TrimSuffix(u.Path, "/"), "/api/v3")
+	u.RawPath = ""
+	return strings.TrimSuffix(u.String(), "/")
+}
+
+// compareURL returns the URL of the Github page showing the difference between two commits.
+func compareURL(web, owner, repo, base, head string) string {
+	return fmt.Sprintf("%s/%s/%s/compare/%s...%s", web, owner, repo, base, head)
+}
+
+// printLocations prints the canonical form of each location to w.
+func printLocations(locations []string, w io.Writer) error {
+	for _, location := range locations {
+		path, err := parseLocation(location)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(w, formatLocation(path))
+	}
+	return nil
+}
+
+// newEditOptions validates the flags in cfg that describe the edit, and returns them as
+// editOptions.
+func newEditOptions(cfg *config, a anchors) (editOptions, error) {
+	labels, err := parseLabels(cfg.MatchLabels)
+	if err != nil {
+		return editOptions{}, err
+	}
+	renames, err := parseRenames(cfg.RenameKeys)
+	if err != nil {
+		return editOptions{}, err
+	}
+	mirrors, err := parseMirrors(cfg.Mirrors)
+	if err != nil {
+		return editOptions{}, err
+	}
+	if cfg.Scale != 0 && cfg.RangeBound != "" {
+		return editOptions{}, errors.New("--scale cannot be combined with --constraint-bound")
+	}
+	opts := editOptions{MatchLabels: labels, RangeBound: cfg.RangeBound, Scale: cfg.Scale, Mirrors: mirrors, Renames: renames, Anchors: a, FirstMatchOnly: cfg.FirstOnly}
+	if cfg.Base64 {
+		if cfg.Scale != 0 || cfg.RangeBound != "" {
+			return editOptions{}, errors.New("--base64 cannot be combined with --scale or --constraint-bound")
+		}
+		opts.Base64 = true
+		opts.Inner, err = newInnerEdit(cfg.InnerLocation, cfg.InnerPattern)
+		if err != nil {
+			return editOptions{}, err
+		}
+	}
+	if cfg.Object != "" {
+		if cfg.Replacement != "" || cfg.Scale != 0 || cfg.RangeBound != "" || cfg.Base64 {
+			return editOptions{}, errors.New("--replacement-object cannot be combined with --replacement, --scale, --constraint-bound, or --base64")
+		}
+		opts.Object, err = parseObject(cfg.Object)
+		if err != nil {
+			return editOptions{}, err
+		}
+		opts.ShallowMerge = cfg.ObjectMerge == "shallow"
+	}
+	if cfg.Comment != "" {
+		if cfg.Object != "" {
+			return editOptions{}, errors.New("--set-comment cannot be combined with --replacement-object")
+		}
+		if strings.Contains(cfg.Comment, "\n") {
+			return editOptions{}, errors.New("--set-comment must be a single line")
+		}
+		opts.Comment = cfg.Comment
+	}
+	if cfg.Verbose {
+		opts.Trace = os.Stderr
+	}
+	return opts, nil
+}
+
+// edit applies the edit described by cfg to content, the content of cfg.File, and returns what
+// changed, like editYAML.  If a is non-nil, it is used and updated as editOptions.Anchors.
+func edit(cfg *config, content string, a anchors) (string, []change, error) {
+	if cfg.Regex {
+		// Patterns match the text, whatever format it is in.
+		edits, err := locationEdits(cfg)
+		if err != nil {
+			return "", nil, err
+		}
+		regexEdits, err := compileRegexEdits(edits)
+		if err != nil {
+			return "", nil, err
+		}
+		return editRegex(content, regexEdits, cfg.RegexAll)
+	}
+	format, err := fileFormat(cfg, content)
+	if err != nil {
+		return "", nil, err
+	}
+	if format != formatYAML {
+		if cfg.Strict || cfg.LintIndent {
+			return "", nil, fmt.Errorf("--strict-yaml and --lint-indent only check YAML, but %s is %s", cfg.File, format)
+		}
+	}
+	if cfg.Strict {
+		if err := checkStrict(content); err != nil {
+			return "", nil, fmt.Errorf("%s is not strictly valid YAML: %w", cfg.File, err)
+		}
+	}
+	if cfg.LintIndent {
+		if err := lintIndent(content); err != nil {
+			return "", nil, fmt.Errorf("inconsistent indentation in %s: %w", cfg.File, err)
+		}
+	}
+	if cfg.Touch {
+		if len(cfg.Locations) > 0 || len(cfg.Sets) > 0 {
+			return "", nil, errors.New("--touch does not edit any values; do not pass --location or --set")
+		}
+		new, _, err := editFormat(format, content, nil, editOptions{})
+		if err != nil {
+			return "", nil, fmt.Errorf("normalize file %s: %w", cfg.File, err)
+		}
+		return new, nil, nil
+	}
+	opts, err := newEditOptions(cfg, a)
+	if err != nil {
+		return "", nil, err
+	}
+	edits, err := locationEdits(cfg)
+	if err != nil {
+		return "", nil, err
+	}
+	new, changes, err := editFormat(format, content, edits, opts)
+	if err != nil {
+		return "", nil, fmt.Errorf("replace content at %s in file %s: %w", describeEdits(edits), cfg.File, err)
+	}
+	return new, changes, nil
+}
+
+// logChanges logs what editing file changed at each location found, like
+// "image.tag: v1 -> v2".
+func logChanges(file string, changes []change) {
+	for _, c := range changes {
+		switch {
+		case !c.Found:
+			continue
+		case c.Old == c.New:
+			log.Printf("%s: %s: already %s", file, c.Location, c.New)
+		default:
+			log.Printf("%s: %s: %s -> %s", file, c.Location, c.Old, c.New)
+		}
+	}
+}
+
+// checkMatches returns an error naming the locations in changes, the changes from editing
+// file, that matched nothing if --require-match is set, and logs a warning naming them if not.
+func checkMatches(cfg *config, file string, changes []change) error {
+	var unmatched []string
+	for _, c := range changes {
+		if !c.Found {
+			unmatched = append(unmatched, c.Location)
+		}
+	}
+	if len(unmatched) == 0 {
+		return nil
+	}
+	if cfg.RequireMatch {
+		return fmt.Errorf("no match in %s for %s, and --require-match is set", file, strings.Join(unmatched, ", "))
+	}
+	log.Printf("warning: no match in %s for %s", file, strings.Join(unmatched, ", "))
+	return nil
+}
+
+// editChecked is edit, followed by the checks every edit must pass before it is committed: it
+// refuses downgrades with --semver or --no-downgrade, logs what changed and checks that each
+// location matched, and validates the result against --schema and --validate-cmd.  It is used
+// for every file a run edits, and again whenever the edit is re-applied after a conflict.
+func editChecked(ctx context.Context, cfg *config, content string, a anchors) (string, []change, error) {
+	if cfg.Semver || cfg.NoDowngrade {
+		if err := checkDowngrades(cfg, content); err != nil {
+			return "", nil, err
+		}
+	}
+	new, changes, err := edit(cfg, content, a)
+	if err != nil {
+		return "", nil, err
+	}
+	logChanges(cfg.File, changes)
+	if err := checkMatches(cfg, cfg.File, changes); err != nil {
+		return "", nil, err
+	}
+	if err := checkSchema(cfg, new); err != nil {
+		return "", nil, err
+	}
+	if validates(cfg) {
+		if err := runValidator(ctx, cfg, new); err != nil {
+			return "", nil, err
+		}
+	}
+	return new, changes, nil
+}
+
+// trimReplacement returns cfg with any whitespace around --replacement removed, unless
+// --no-trim-replacement is set.  Values piped in from other tools often end in stray spaces.
+func trimReplacement(cfg *config) *config {
+	if cfg.NoTrim {
+		return cfg
+	}
+	c := *cfg
+	c.Replacement = strings.TrimSpace(cfg.Replacement)
+	c.Replacements = nil
+	for _, r := range cfg.Replacements {
+		c.Replacements = append(c.Replacements, strings.TrimSpace(r))
+	}
+	return &c
+}
+
+// splitFiles returns cfg with its --file values sorted out: the first is File, and the rest are
+// ExtraFiles, edited along with it.
+func splitFiles(cfg *config) (*config, error) {
+	if len(cfg.Files) == 0 {
+		return cfg, nil
+	}
+	if cfg.File != "" {
+		return nil, errors.New("cannot combine File with --file")
+	}
+	var files []string
+	seen := map[string]bool{}
+	for _, f := range cfg.Files {
+		p := filepath.Clean(f)
+		if !cfg.Local {
+			var err error
+			p, err = repoPath(f)
+			if err != nil {
+				return nil, err
+			}
+		}
+		if seen[p] {
+			return nil, fmt.Errorf("--file %s given twice", f)
+		}
+		seen[p] = true
+		files = append(files, p)
+	}
+	c := *cfg
+	c.File, c.ExtraFiles, c.Files = files[0], files[1:], nil
+	if len(c.ExtraFiles) > 0 && (cfg.LocalRepo != "" || cfg.Local || cfg.Report || cfg.HelmChart != "" || cfg.Graph != "") {
+		return nil, errors.New("only one --file may be given with --local, --local-repo, --report, --helm-chart, or --dependency-graph")
+	}
+	return &c, nil
+}
+
+// repoPath returns file as a path from the root of the repository, as git trees spell it:
+// backslashes become slashes, and it is cleaned, so that "./deploy/app.yaml",
+// "/deploy/app.yaml", and "deploy\app.yaml" are all "deploy/app.yaml".  Paths that leave the
+// repository are rejected.
+func repoPath(file string) (string, error) {
+	p := strings.TrimLeft(path.Clean(strings.ReplaceAll(file, `\`, "/")), "/")
+	if p == "" || p == "." || p == ".." || strings.HasPrefix(p, "../") {
+		return "", fmt.Errorf("--file %q is not a file in the repository", file)
+	}
+	return p, nil
+}
+
+// pairReplacements returns cfg with its --replacement values sorted out: a single one is the
+// Replacement for every location, and several are paired with the locations in order.
+func pairReplacements(cfg *config) (*config, error) {
+	if len(cfg.Sets) > 0 && (cfg.Object != "" || cfg.Component != "" || cfg.Report) {
+		return nil, errors.New("--set cannot be combined with --replacement-object, --component, or --report")
+	}
+	if len(cfg.Replacements) == 0 {
+		return cfg, nil
+	}
+	if cfg.Replacement != "" {
+		return nil, errors.New("cannot combine a replacement for every location with one per location")
+	}
+	c := *cfg
+	if len(cfg.Replacements) == 1 {
+		c.Replacement, c.Replacements = cfg.Replacements[0], nil
+		return &c, nil
+	}
+	if len(cfg.Replacements) != len(cfg.Locations) {
+		return nil, fmt.Errorf("got %d --replacement values for %d --location values; give one for every location, or one per location", len(cfg.Replacements), len(cfg.Locations))
+	}
+	if cfg.FromLocation || cfg.Object != "" || cfg.Upstream != "" || cfg.Resolver != "" || cfg.TagSource != "" || cfg.Component != "" || cfg.Report {
+		return nil, errors.New("one --replacement per --location cannot be combined with --replacement-is-location, --replacement-object, --upstream, --resolver-cmd, --tag-source, --component, or --report")
+	}
+	return &c, nil
+}
+
+// replacements returns each replacement that cfg would write.  Malformed --set flags are
+// left for locationEdits to report.
+func (cfg *config) replacements() []string {
+	values := cfg.Replacements
+	if values == nil {
+		values = []string{cfg.Replacement}
+	}
+	for _, set := range cfg.Sets {
+		if e, err := parseSet(set); err == nil {
+			values = append(values, e.Value)
+		}
+	}
+	return values
+}
+
+// locationEdits returns the edits that cfg describes: each --location with its replacement,
+// then each --set.
+func locationEdits(cfg *config) ([]locationEdit, error) {
+	edits := editsFor(cfg.Locations, cfg.Replacement)
+	if cfg.Replacements != nil {
+		if len(cfg.Replacements) != len(cfg.Locations) {
+			return nil, fmt.Errorf("%d replacements for %d locations", len(cfg.Replacements), len(cfg.Locations))
+		}
+		for i := range edits {
+			edits[i].Value = cfg.Replacements[i]
+		}
+	}
+	for _, set := range cfg.Sets {
+		e, err := parseSet(set)
+		if err != nil {
+			return nil, err
+		}
+		if !cfg.NoTrim {
+			e.Value = strings.TrimSpace(e.Value)
+		}
+		edits = append(edits, e)
+	}
+	return edits, nil
+}
+
+// parseSet parses the value of a --set flag, location=value.  The location ends at the first
+// "=" outside brackets, so that it may contain matches like [name=app].
+func parseSet(set string) (locationEdit, error) {
+	depth := 0
+	for i, c := range set {
+		switch c {
+		case '[':
+			depth++
+		case ']':
+			depth--
+		case '=':
+			if depth > 0 {
+				continue
+			}
+			if i == 0 {
+				return locationEdit{}, fmt.Errorf("--set %q has no location; want location=value", set)
+			}
+			return locationEdit{Path: set[:i], Value: set[i+1:]}, nil
+		}
+	}
+	return locationEdit{}, fmt.Errorf("--set %q has no value; want location=value", set)
+}
+
+// describeEdits returns edits as location=value pairs, for messages.
+func describeEdits(edits []locationEdit) string {
+	var pairs []string
+	for _, e := range edits {
+		pairs = append(pairs, fmt.Sprintf("%s=%q", e.Path, e.Value))
+	}
+	return strings.Join(pairs, ", ")
+}
+
+// runLocal is like run in dry-run mode, but reads the file from the git repository in
+// cfg.LocalRepo rather than from Github, and prints the edit as --dry-run-format says.  With cfg.Local, it reads the file from the filesystem
+// instead, and writes the edit back to it unless cfg.DryRun is set.
+func runLocal(cfg *config, w io.Writer) error {
+	if cfg.Local && cfg.LocalRepo != "" {
+		return errors.New("--local cannot be combined with --local-repo")
+	}
+	cfg, err := splitFiles(cfg)
+	if err != nil {
+		return err
+	}
+	cfg, err = pairReplacements(cfg)
+	if err != nil {
+		return err
+	}
+	cfg = trimReplacement(cfg)
+	if cfg.Resolver == "" {
+		for _, r := range cfg.replacements() {
+			if err := checkAllowed(cfg, r); err != nil {
+				return err
+			}
+		}
+	}
+	var content, sha string
+	mode := os.FileMode(0644)
+	if cfg.Local {
+		info, err := os.Stat(cfg.File)
+		if err != nil {
+			return err
+		}
+		mode = info.Mode()
+		b, err := ioutil.ReadFile(cfg.File)
+		if err != nil {
+			return err
+		}
+		content = string(b)
+	} else {
+		content, sha, err = readLocal(cfg.LocalRepo, cfg.Ref, cfg.File)
+		if err != nil {
+			return fmt.Errorf("read %s from %s: %w", cfg.File, cfg.LocalRepo, err)
+		}
+	}
+	if cfg.Resolver != "" {
+		ctx, cancel := context.WithTimeout(context.Background(), cfg.Timeout)
+		defer cancel()
+		value, err := resolveReplacement(ctx, cfg, content)
+		if err != nil {
+			return err
+		}
+		if err := checkAllowed(cfg, value); err != nil {
+			return err
+		}
+		withValue := *cfg
+		withValue.Replacement = value
+		cfg = &withValue
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.Timeout)
+	defer cancel()
+	new, _, err := editChecked(ctx, cfg, content, nil)
+	if err != nil {
+		return err
+	}
+	new = fixLineEndings(content, new, false, false)
+	if new == content && cfg.FailUnchanged {
+		return fmt.Errorf("edit did not change %s, and --fail-if-unchanged is set", cfg.File)
+	}
+	if cfg.Local {
+		if cfg.DryRun {
+			if !cfg.PrintSHAOnly {
+				writeDryRun(w, cfg, cfg.File, content, new)
+			}
+			return pendingChange(cfg, content, new)
+		}
+		if new == content {
+			log.Printf("the edit does not change %s; nothing to write", cfg.File)
+			return nil
+		}
+		if err := ioutil.WriteFile(cfg.File, []byte(new), mode); err != nil {
+			return err
+		}
+		log.Printf("wrote %s", cfg.File)
+		return nil
+	}
+	if sha == "" {
+		fmt.Fprintf(os.Stderr, "Using content from the working tree of %s\n", cfg.LocalRepo)
+	} else {
+		fmt.Fprintf(os.Stderr, "Using content from commit %s\n", sha)
+	}
+	if !cfg.PrintSHAOnly {
+		writeDryRun(w, cfg, cfg.File, content, new)
+	}
+	return pendingChange(cfg, content, new)
+}
+
+// readReplacement reads the value that cfg.Replacement points to when
+// --replacement-is-location is set: a location in cfg.File, or file:location to read it from
+// another file in the same commit as orig.
+func readReplacement(ctx context.Context, client *github.Client, cfg *config, orig *fileInTree) (string, error) {
+	file, location := cfg.File, cfg.Replacement
+	content := orig.Content
+	if i := strings.Index(location, ":"); i >= 0 && !strings.Contains(location[:i], "[") {
+		file, location = location[:i], location[i+1:]
+		var err error
+		content, err = readFile(ctx, client, cfg.GithubOwner, cfg.GithubRepo, orig.Tree, file)
+		if err != nil {
+			return "", fmt.Errorf("read replacement from %s: %w", file, err)
+		}
+	}
+	value, found, err := valueAt(content, location)
+	if err != nil {
+		return "", fmt.Errorf("read replacement from %s in %s: %w", location, file, err)
+	}
+	if !found {
+		return "", fmt.Errorf("replacement location %s not found in %s", location, file)
+	}
+	log.Printf("using %q from %s in %s as the replacement", value, location, file)
+	return value, nil
+}
+
+// run fetches the file described by cfg, edits it, and then commits the result, opens a pull
+// request with it, or (in dry-run mode) prints it to w.
+func run(ctx context.Context, client *github.Client, cfg *config, w io.Writer) error {
+	cfg, err := splitFiles(cfg)
+	if err != nil {
+		return err
+	}
+	cfg, err = pairReplacements(cfg)
+	if err != nil {
+		return err
+	}
+	cfg = trimReplacement(cfg)
+	source := replacementSource(cfg)
+	if len(cfg.References) > 0 || cfg.Closes {
+		msg, err := withReferences(cfg.CommitMessage, cfg.References, cfg.Closes)
+		if err != nil {
+			return err
+		}
+		withRefs := *cfg
+		withRefs.CommitMessage = msg
+		cfg = &withRefs
+	}
+	cfg, err = helmDefaults(cfg)
+	if err != nil {
+		return err
+	}
+	cfg, err = checkRef(cfg)
+	if err != nil {
+		return err
+	}
+	if err := checkIdentity(cfg); err != nil {
+		return err
+	}
+	if err := checkRegex(cfg); err != nil {
+		return err
+	}
+	if cfg.Schema != "" {
+		if _, err := loadSchema(cfg.Schema); err != nil {
+			return err
+		}
+	}
+	if cfg.BackupBranch != "" && cfg.BackupBranch == cfg.GithubBranch {
+		return errors.New("--backup-branch must be different from --branch")
+	}
+	if cfg.Upstream != "" {
+		value, err := readUpstream(ctx, client, cfg)
+		if err != nil {
+			return inPhase(phaseFetch, err)
+		}
+		withValue := *cfg
+		withValue.Replacement = value
+		cfg = &withValue
+	}
+	if cfg.Report {
+		rows, err := report(ctx, client, cfg)
+		if err != nil {
+			return err
+		}
+		return writeReport(w, cfg.Output, rows)
+	}
+
+	if !cfg.FromLocation && cfg.Resolver == "" {
+		for _, r := range cfg.replacements() {
+			if err := checkAllowed(cfg, r); err != nil {
+				return err
+			}
+		}
+	}
+
+	if !cfg.DryRun && !cfg.DryRunApply {
+		if err := checkWindows(cfg.AllowWindows, time.Now()); err != nil {
+			return err
+		}
+	}
+	if cfg.Graph != "" {
+		return runGraph(ctx, client, cfg, w)
+	}
+
+	// prBase is where pull requests are opened.  With --fork, everything else happens in the
+	// fork, so cfg is switched over to it.
+	prBase := target{Owner: cfg.GithubOwner, Repo: cfg.GithubRepo, Branch: cfg.GithubBranch}
+	if cfg.Fork != "" {
+		if cfg.CreateFrom != "" || cfg.CreateBranch {
+			return errors.New("--fork cannot be combined with --create-from or --create-branch")
+		}
+		fork, err := parseTarget(ctx, client, cfg.Fork, cfg.GithubBranch)
+		if err != nil {
+			return fmt.Errorf("fork: %w", err)
+		}
+		if cfg.SyncFork {
+			if err := syncFork(ctx, client, fork); err != nil {
+				return err
+			}
+		}
+		inFork := *cfg
+		inFork.GithubOwner, inFork.GithubRepo, inFork.GithubBranch = fork.Owner, fork.Repo, fork.Branch
+		inFork.PullRequest = true
+		cfg = &inFork
+	} else if cfg.SyncFork {
+		return errors.New("--sync-fork requires --fork")
+	}
+	if cfg.PRBranch != "" && cfg.PRBranch == cfg.GithubBranch {
+		return fmt.Errorf("--pr-branch must be different from %s, the branch the pull request is based on", cfg.GithubBranch)
+	}
+
+	// from is the branch the file is read from; it is only different from --branch when
+	// --branch is about to be created.  With --ref naming a tag or commit, it is that commit.
+	from := cfg.GithubBranch
+	var base *github.Commit
+	if cfg.Ref != "" {
+		base, err = resolveRef(ctx, client, cfg.GithubOwner, cfg.GithubRepo, cfg.Ref)
+		if err != nil {
+			return inPhase(phaseFetch, fmt.Errorf("resolve --ref: %w", err))
+		}
+		from = base.GetSHA()
+		log.Printf("%s is commit %s", cfg.Ref, from)
+		if !cfg.DryRun {
+			exists, err := branchExists(ctx, client, cfg.GithubOwner, cfg.GithubRepo, cfg.GithubBranch)
+			if err != nil {
+				return fmt.Errorf("check for branch %s: %w", cfg.GithubBranch, err)
+			}
+			if exists {
+				return fmt.Errorf("branch %s already exists; with --ref %s, --branch must name a new branch to create from it", cfg.GithubBranch, cfg.Ref)
+			}
+		}
+	}
+	if cfg.CreateFrom != "" || cfg.CreateBranch {
+		if cfg.PullRequest {
+			return errors.New("--create-from and --create-branch cannot be combined with --pull-request")
+		}
+		exists, err := branchExists(ctx, client, cfg.GithubOwner, cfg.GithubRepo, cfg.GithubBranch)
+		if err != nil {
+			return fmt.Errorf("check for branch %s: %w", cfg.GithubBranch, err)
+		}
+		if !exists {
+			from, err = createBase(ctx, client, cfg)
+			if err != nil {
+				return err
+			}
+			log.Printf("branch %s does not exist; creating it from %s", cfg.GithubBranch, from)
+		}
+	}
+
+	if cfg.ChangedSince != "" {
+		changed, err := changedFiles(ctx, client, cfg.GithubOwner, cfg.GithubRepo, cfg.ChangedSince, from)
+		if err != nil {
+			return fmt.Errorf("list files changed since %s: %w", cfg.ChangedSince, err)
+		}
+		only := changedOnly(cfg, changed)
+		if only == nil {
+			log.Printf("no file has changed since %s; nothing to edit", cfg.ChangedSince)
+			return nil
+		}
+		cfg = only
+	}
+
+	if cfg.MaxTagAge != 0 && cfg.TagSource == "" {
+		return errors.New("--max-tag-age requires --tag-source")
+	}
+	if cfg.TagSource != "" {
+		tagged, err := resolveTag(ctx, client, cfg.TagSource, cfg.Replacement)
+		if err != nil {
+			return fmt.Errorf("resolve replacement: %w", err)
+		}
+		log.Printf("tag %s is commit %s in %s, made %s", cfg.Replacement, tagged.SHA, cfg.TagSource, tagged.Date.Format(time.RFC3339))
+		if cfg.MaxTagAge != 0 {
+			if err := checkTagAge(cfg.Replacement, tagged, cfg.MaxTagAge, time.Now()); err != nil {
+				return err
+			}
+		}
+	}
+
+	if cfg.Lock && !cfg.DryRun && !cfg.DryRunApply {
+		release, err := acquireLock(ctx, client, cfg.GithubOwner, cfg.GithubRepo, cfg.GithubBranch, from, cfg.LockTTL, cfg.LockWait)
+		if err != nil {
+			return fmt.Errorf("lock %s: %w", cfg.GithubBranch, err)
+		}
+		defer release()
+	}
+
+	var cached *state
+	if cfg.StateFile != "" {
+		var err error
+		cached, err = loadState(cfg.StateFile)
+		if err != nil {
+			return err
+		}
+	}
+	var orig *fileInTree
+	if base != nil {
+		orig, err = fetchFromCommit(ctx, client, cfg.GithubOwner, cfg.GithubRepo, from, base.GetTree().GetSHA(), cfg.File, cfg.PathPrefix, readsTree(cfg))
+	} else {
+		orig, err = fetchIfModified(ctx, client, cfg.GithubOwner, cfg.GithubRepo, from, cfg.File, cfg.PathPrefix, readsTree(cfg), cached)
+	}
+	if err != nil {
+		return inPhase(phaseFetch, fmt.Errorf("fetch %s from github.com/%s/%s@%s: %w", cfg.File, cfg.GithubOwner, cfg.GithubRepo, from, err))
+	}
+	if orig.NotModified {
+		log.Printf("%s@%s has not changed since commit %s; using cached content", cfg.GithubRepo, from, orig.CommitSHA)
+	}
+
+	if cfg.FromLocation {
+		value, err := readReplacement(ctx, client, cfg, orig)
+		if err != nil {
+			return inPhase(phaseFetch, err)
+		}
+		if err := checkAllowed(cfg, value); err != nil {
+			return err
+		}
+		withValue := *cfg
+		withValue.Replacement = value
+		cfg = &withValue
+	}
+	if cfg.Resolver != "" {
+		value, err := resolveReplacement(ctx, cfg, orig.Content)
+		if err != nil {
+			return err
+		}
+		if err := checkAllowed(cfg, value); err != nil {
+			return err
+		}
+		withValue := *cfg
+		withValue.Replacement = value
+		cfg = &withValue
+	}
+
+	var next *state
+	if cfg.StateFile != "" {
+		next = &state{
+			Owner:      cfg.GithubOwner,
+			Repo:       cfg.GithubRepo,
+			Branch:     from,
+			File:       cfg.File,
+			ETag:       orig.ETag,
+			CommitSHA:  orig.CommitSHA,
+			TreeSHA:    orig.Tree.GetSHA(),
+			Content:    orig.Content,
+			Anchors:    anchors{},
+			Attributes: orig.Attributes,
+		}
+		if cached.sameFile(cfg.GithubOwner, cfg.GithubRepo, from, cfg.File) && cached.Anchors != nil {
+			next.Anchors = cached.Anchors
+		}
+	}
+	var a anchors
+	if next != nil {
+		a = next.Anchors
+	}
+	new, _, err := editChecked(ctx, cfg, orig.Content, a)
+	if err != nil {
+		return inPhase(phaseEdit, err)
+	}
+	text, known := isText(orig.Attributes, cfg.File)
+	new = fixLineEndings(orig.Content, new, text, known)
+	if next != nil {
+		if err := saveState(cfg.StateFile, next); err != nil {
+			return err
+		}
+	}
+	if new == orig.Content && cfg.FailUnchanged {
+		return fmt.Errorf("edit did not change %s, and --fail-if-unchanged is set", cfg.File)
+	}
+	if cfg.Touch && new == orig.Content {
+		log.Printf("%s is already in canonical form; nothing to commit", cfg.File)
+		return writeResult(w, cfg, result{})
+	}
+	if cfg.DryRun {
+		fmt.Fprintf(os.Stderr, "Using content from commit %s\n", orig.CommitSHA)
+		if !cfg.PrintSHAOnly {
+			writeDryRun(w, cfg, cfg.File, orig.Content, new)
+		}
+		return pendingChange(cfg, orig.Content, new)
+	}
+	if orig.NotModified && new == orig.Content && !cfg.AllowEmpty {
+		log.Printf("%s is unchanged and the edit does not change it; nothing to commit", cfg.File)
+		return writeResult(w, cfg, result{})
+	}
+	// Other files given with --file may change even when this one doesn't.
+	if new == orig.Content && len(cfg.ExtraFiles) == 0 && !cfg.AllowEmpty {
+		log.Printf("the edit does not change %s; nothing to commit", cfg.File)
+		return writeResult(w, cfg, result{})
+	}
+
+	id, err := commitIdentity(cfg)
+	if err != nil {
+		return err
+	}
+	baseTree := orig.Tree.GetSHA()
+	if cfg.BaseTree != "" {
+		baseTree = cfg.BaseTree
+	}
+	openPR := cfg.PullRequest
+	if from == cfg.GithubBranch {
+		queued, err := hasMergeQueue(ctx, client, prBase.Owner, prBase.Repo, prBase.Branch)
+		if err != nil {
+			return err
+		}
+		if queued && !openPR {
+			log.Printf("branch %s uses a merge queue; opening a pull request instead of committing to it", prBase.Branch)
+			openPR = true
+		}
+		if cfg.MergeQueue && !queued {
+			return fmt.Errorf("--merge-queue is set, but branch %s does not use a merge queue", prBase.Branch)
+		}
+	}
+	files, err := commitFiles(ctx, client, cfg, source, orig, new)
+	if err != nil {
+		return err
+	}
+	if openPR {
+		if onlyWhitespaceChanged(orig.Content, new) {
+			log.Printf("edit to %s changes nothing but whitespace; no PR needed", cfg.File)
+			return writeResult(w, cfg, result{})
+		}
+		if cfg.DryRunApply {
+			plan := newCommitPlan(baseTree, orig.CommitSHA, files, cfg.CommitMessage, id)
+			plan.Action, plan.Branch, plan.Base = "pull-request", prBranch(cfg, files), prBase.Branch
+			return writePlan(w, cfg.Output, plan)
+		}
+		existing, head, err := openPullRequestWith(ctx, client, cfg.GithubOwner, cfg.GithubRepo, prBase, prBranch(cfg, files), files)
+		if err != nil {
+			return inPhase(phaseCommit, err)
+		}
+		if existing != nil {
+			log.Printf("pull request #%d already makes this edit; nothing to push: %s", existing.GetNumber(), existing.GetHTMLURL())
+			return writeResult(w, cfg, result{URL: commitURL(webURL(client), cfg.GithubOwner, cfg.GithubRepo, head), CompareURL: compareURL(webURL(client), cfg.GithubOwner, cfg.GithubRepo, orig.CommitSHA, head), PullRequestURL: existing.GetHTMLURL()})
+		}
+		sha, pr, err := pullRequest(ctx, client, baseTree, orig.CommitSHA, cfg.GithubOwner, cfg.GithubRepo, prBase, prBranch(cfg, files), files, cfg.CommitMessage, id, cfg.Force, cfg.Protected)
+		if err != nil {
+			return inPhase(phaseCommit, fmt.Errorf("open pull request with new yaml: %w", err))
+		}
+		if cfg.MergeQueue {
+			if err := enqueuePullRequest(ctx, client, pr.GetNodeID()); err != nil {
+				return fmt.Errorf("add pull request #%d to the merge queue: %w", pr.GetNumber(), err)
+			}
+			log.Printf("added pull request #%d to the merge queue", pr.GetNumber())
+		}
+		return writeResult(w, cfg, result{Changed: true, SHA: sha, URL: commitURL(webURL(client), cfg.GithubOwner, cfg.GithubRepo, sha), CompareURL: compareURL(webURL(client), cfg.GithubOwner, cfg.GithubRepo, orig.CommitSHA, sha), PullRequestURL: pr.GetHTMLURL()})
+	}
+
+	if cfg.Force {
+		if err := checkForce(cfg.GithubBranch, cfg.Protected); err != nil {
+			return err
+		}
+	}
+	if cfg.DryRunApply {
+		plan := newCommitPlan(baseTree, orig.CommitSHA, files, cfg.CommitMessage, id)
+		plan.Action, plan.Branch, plan.Force = "update-branch", cfg.GithubBranch, cfg.Force
+		if from != cfg.GithubBranch {
+			plan.Action = "create-branch"
+		}
+		return writePlan(w, cfg.Output, plan)
+	}
+	if cfg.BackupBranch != "" && from == cfg.GithubBranch {
+		if err := backupBranch(ctx, client, cfg.GithubOwner, cfg.GithubRepo, cfg.BackupBranch, orig.CommitSHA, cfg.Force, cfg.Protected); err != nil {
+			return inPhase(phaseCommit, err)
+		}
+		log.Printf("backed up %s at commit %s to branch %s", cfg.GithubBranch, orig.CommitSHA, cfg.BackupBranch)
+	}
+	var sha string
+	if from != cfg.GithubBranch {
+		sha, err = createBranch(ctx, client, baseTree, orig.CommitSHA, cfg.GithubOwner, cfg.GithubRepo, cfg.GithubBranch, files, cfg.CommitMessage, id)
+	} else {
+		sha, err = commit(ctx, client, baseTree, orig.CommitSHA, cfg.GithubOwner, cfg.GithubRepo, cfg.GithubBranch, files, cfg.CommitMessage, id, cfg.Force)
+		retries := cfg.Retries
+		if cfg.RetryConflict && retries == 0 {
+			retries = defaultConflictRetries
+		}
+		for retry := 1; err != nil && isConflict(err) && retry <= retries; retry++ {
+			log.Printf("%s moved on from commit %s; reading %s again and re-applying the edit (retry %d of %d)", cfg.GithubBranch, orig.CommitSHA, cfg.File, retry, retries)
+			orig, files, err = reapply(ctx, client, cfg, source)
+			if err != nil {
+				return err
+			}
+			sha, err = commit(ctx, client, orig.Tree.GetSHA(), orig.CommitSHA, cfg.GithubOwner, cfg.GithubRepo, cfg.GithubBranch, files, cfg.CommitMessage, id, cfg.Force)
+		}
+	}
+	if err != nil {
+		return inPhase(phaseCommit, fmt.Errorf("commit new yaml: %w", explainConflict(err, cfg.GithubBranch, orig.CommitSHA)))
+	}
+
+	web := webURL(client)
+	logWith(logFields{CommitSHA: sha}, "created commit %s", sha)
+	log.Printf("commit: %s", commitURL(web, cfg.GithubOwner, cfg.GithubRepo, sha))
+	log.Printf("compare: %s", compareURL(web, cfg.GithubOwner, cfg.GithubRepo, orig.CommitSHA, sha))
+	return writeResult(w, cfg, result{Changed: true, SHA: sha, URL: commitURL(web, cfg.GithubOwner, cfg.GithubRepo, sha), CompareURL: compareURL(web, cfg.GithubOwner, cfg.GithubRepo, orig.CommitSHA, sha)})
+}
+
+// Main runs the version-bump command with the flags in os.Args, and exits.
+func Main() {
+	var cfg config
+	var auth auth
+
+	fp := newParser(&auth, &cfg)
+	if _, err := fp.Parse(); err != nil {
+		if ferr, ok := err.(*flags.Error); ok && ferr.Type == flags.ErrHelp {
+			fmt.Fprintf(os.Stderr, ferr.Message)
+			os.Exit(2)
+		}
+		fmt.Fprintf(os.Stderr, "flag parsing: %v\n", err)
+		os.Exit(3)
+	}
+	if cfg.ConfigFile != "" {
+		var err error
+		fp, err = withConfigFile(fp, os.Args[1:], &auth, &cfg)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "flag parsing: %v\n", err)
+			os.Exit(3)
+		}
+	}
+
+	if cfg.LogFormat == "json" {
+		log.SetFlags(0)
+		log.SetOutput(newJSONLog(os.Stderr, &cfg))
+	}
+
+	if cfg.PrintLocs {
+		if err := printLocations(cfg.Locations, os.Stdout); err != nil {
+			os.Exit(fail(os.Stderr, &cfg, err))
+		}
+		return
+	}
+	if cfg.PrintIdentity {
+		if err := writeIdentity(os.Stdout, cfg.Output, resolveIdentity(&cfg)); err != nil {
+			os.Exit(fail(os.Stderr, &cfg, err))
+		}
+		return
+	}
+	if cfg.DumpFilters {
+		if err := dumpFilters(&cfg, os.Stdout); err != nil {
+			os.Exit(fail(os.Stderr, &cfg, err))
+		}
+		return
+	}
+	if cfg.LocalRepo != "" || cfg.Local {
+		if err := runLocal(&cfg, os.Stdout); err != nil {
+			os.Exit(fail(os.Stderr, &cfg, err))
+		}
+		return
+	}
+
+	ctx, c := context.WithTimeout(context.Background(), cfg.Timeout)
+	defer c()
+	if auth.RequestTimeout == 0 {
+		auth.RequestTimeout = cfg.Timeout
+	}
+
+	tokenOpt := fp.FindOptionByLongName("token")
+	token, err := resolveToken(auth.AccessToken, tokenOpt.IsSet() && !tokenOpt.IsSetDefault(), auth.TokenFile)
+	if err != nil {
+		os.Exit(fail(os.Stderr, &cfg, inPhase(phaseAuth, err)))
+	}
+	auth.AccessToken = token
+
+	if cfg.Provider != "github" {
+		b, err := newBackend(&cfg, &auth)
+		if err != nil {
+			os.Exit(fail(os.Stderr, &cfg, inPhase(phaseAuth, err)))
+		}
+		if err := runBackend(ctx, b, &cfg, os.Stdout); err != nil {
+			os.Exit(fail(os.Stderr, &cfg, err))
+		}
+		return
+	}
+
+	client, err := newClient(ctx, &auth)
+	if err != nil {
+		os.Exit(fail(os.Stderr, &cfg, inPhase(phaseAuth, err)))
+	}
+
+	if err := run(ctx, client, &cfg, os.Stdout); err != nil {
+		os.Exit(fail(os.Stderr, &cfg, err))
+	}
+}
@@ -0,0 +1,2699 @@
+package bump
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-github/v32/github"
+	"sigs.k8s.io/kustomize/kyaml/yaml"
+)
+
+func lines(lines ...string) string {
+	return strings.Join(lines, "\n") + "\n"
+}
+
+func TestEdit(t *testing.T) {
+	testData := []struct {
+		name        string
+		input       string
+		paths       []string
+		replacement string
+		want        string
+	}{
+		{
+			name: "simple",
+			input: lines(
+				"# this is a test!",
+				"apiVersion: v1",
+				"kind: Foo",
+			),
+			paths:       []string{"kind"},
+			replacement: "Bar",
+			want: lines(
+				"# this is a test!",
+				"apiVersion: v1",
+				"kind: Bar",
+			),
+		},
+		{
+			name: "missing",
+			input: lines(
+				"# this is a test!",
+				"apiVersion: v1",
+				"kind: Foo",
+			),
+			paths:       []string{"kindergarten"},
+			replacement: "Bar",
+			want: lines(
+				"# this is a test!",
+				"apiVersion: v1",
+				"kind: Foo",
+			),
+		},
+		{
+			name: "nested",
+			input: lines(
+				"apiVersion: v1",
+				"kind: Foo",
+				"spec:",
+				"  values:",
+				"    a:",
+				"      image:",
+				"        repository: abc",
+				"        # tag is automatically updated!",
+				"        tag: v1",
+				"  somethingElse: true",
+			),
+			paths:       []string{"spec.values.a.image.tag"},
+			replacement: "v2",
+			want: lines(
+				"apiVersion: v1",
+				"kind: Foo",
+				"spec:",
+				"  values:",
+				"    a:",
+				"      image:",
+				"        repository: abc",
+				"        # tag is automatically updated!",
+				"        tag: v2",
+				"  somethingElse: true",
+			),
+		},
+		{
+			name: "nested with lookalike sections",
+			input: lines(
+				"apiVersion: v1",
+				"kind: Foo",
+				"spec:",
+				"  values:",
+				"    a:",
+				"      image:",
+				"        repository: abc",
+				"        # tag is automatically updated!",
+				"        tag: v1",
+				"    b:",
+				"      image:",
+				"        repository: abc",
+				"        # tag is not automatically updated!",
+				"        tag: v1",
+				"  somethingElse: true",
+			),
+			paths:       []string{"spec.values.a.image.tag"},
+			replacement: "v2",
+			want: lines(
+				"apiVersion: v1",
+				"kind: Foo",
+				"spec:",
+				"  values:",
+				"    a:",
+				"      image:",
+				"        repository: abc",
+				"        # tag is automatically updated!",
+				"        tag: v2",
+				"    b:",
+				"      image:",
+				"        repository: abc",
+				"        # tag is not automatically updated!",
+				"        tag: v1",
+				"  somethingElse: true",
+			),
+		},
+		{
+			name: "multiple edits",
+			input: lines(
+				"apiVersion: v1",
+				"kind: Foo",
+				"spec:",
+				"  values:",
+				"    a:",
+				"      image:",
+				"        repository: abc",
+				"        # tag is automatically updated!",
+				"        tag: v1",
+				"    b:",
+				"      image:",
+				"        repository: abc",
+				"        # tag is automatically updated!",
+				"        tag: v1",
+				"  somethingElse: true",
+			),
+			paths:       []string{"spec.values.a.image.tag", "spec.values.b.image.tag"},
+			replacement: "v2",
+			want: lines(
+				"apiVersion: v1",
+				"kind: Foo",
+				"spec:",
+				"  values:",
+				"    a:",
+				"      image:",
+				"        repository: abc",
+				"        # tag is automatically updated!",
+				"        tag: v2",
+				"    b:",
+				"      image:",
+				"        repository: abc",
+				"        # tag is automatically updated!",
+				"        tag: v2",
+				"  somethingElse: true",
+			),
+		},
+	}
+
+	for _, test := range testData {
+		t.Run(test.name, func(t *testing.T) {
+			got, _, err := editYAML(test.input, editsFor(test.paths, test.replacement), editOptions{})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if diff := cmp.Diff(got, test.want); diff != "" {
+				t.Errorf("unexpected yaml generated:\n%s", diff)
+			}
+		})
+	}
+
+}
+
+func TestNormalize(t *testing.T) {
+	testData := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{
+			name: "already canonical",
+			input: lines(
+				"# this is a test!",
+				"apiVersion: v1",
+				"spec:",
+				"  items:",
+				"  - a",
+				"  - b",
+			),
+			want: lines(
+				"# this is a test!",
+				"apiVersion: v1",
+				"spec:",
+				"  items:",
+				"  - a",
+				"  - b",
+			),
+		},
+		{
+			name: "not canonical",
+			input: lines(
+				"# this is a test!",
+				"apiVersion:    v1",
+				"spec:",
+				"    items:",
+				"        - a",
+				"        - b",
+			),
+			want: lines(
+				"# this is a test!",
+				"apiVersion: v1",
+				"spec:",
+				"  items:",
+				"  - a",
+				"  - b",
+			),
+		},
+	}
+
+	for _, test := range testData {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := normalize(test.input)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if diff := cmp.Diff(got, test.want); diff != "" {
+				t.Errorf("unexpected yaml generated:\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestOnlyWhitespaceChanged(t *testing.T) {
+	testData := []struct {
+		name string
+		a, b string
+		want bool
+	}{
+		{name: "identical", a: lines("a: b"), b: lines("a: b"), want: true},
+		{name: "trailing spaces", a: lines("a: b  ", "c: d"), b: lines("a: b", "c: d"), want: true},
+		{name: "line endings", a: "a: b\r\nc: d\r\n", b: lines("a: b", "c: d"), want: true},
+		{name: "trailing blank lines", a: lines("a: b", "", ""), b: lines("a: b"), want: true},
+		{name: "value", a: lines("a: b"), b: lines("a: c"), want: false},
+		{name: "indentation", a: lines("a:", "  b: c"), b: lines("a:", "b: c"), want: false},
+	}
+	for _, test := range testData {
+		t.Run(test.name, func(t *testing.T) {
+			if got := onlyWhitespaceChanged(test.a, test.b); got != test.want {
+				t.Errorf("onlyWhitespaceChanged(%q, %q) = %v, want %v", test.a, test.b, got, test.want)
+			}
+		})
+	}
+}
+
+func TestWithReferences(t *testing.T) {
+	testData := []struct {
+		name    string
+		msg     string
+		refs    []string
+		closes  bool
+		want    string
+		wantErr bool
+	}{
+		{name: "none", msg: "Bump app to v2", want: "Bump app to v2"},
+		{name: "subject only", msg: "Bump app to v2", refs: []string{"#123"}, want: "Bump app to v2\n\nRefs: #123"},
+		{name: "with body", msg: "Bump app to v2\n\nFixes the crash.\n", refs: []string{"#123"}, want: "Bump app to v2\n\nFixes the crash.\n\nRefs: #123"},
+		{name: "other repository", msg: "Bump app to v2", refs: []string{"o/other-repo#7"}, want: "Bump app to v2\n\nRefs: o/other-repo#7"},
+		{name: "closes", msg: "Bump app to v2", refs: []string{"#1", "o/r#2"}, closes: true, want: "Bump app to v2\n\nCloses #1\nCloses o/r#2"},
+		{name: "not an issue", msg: "Bump app to v2", refs: []string{"123"}, wantErr: true},
+		{name: "closes nothing", msg: "Bump app to v2", closes: true, wantErr: true},
+	}
+	for _, test := range testData {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := withReferences(test.msg, test.refs, test.closes)
+			if test.wantErr {
+				if err == nil {
+					t.Fatalf("expected error, got %q", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("withReferences: %v", err)
+			}
+			if diff := cmp.Diff(test.want, got); diff != "" {
+				t.Errorf("message (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestPullRequest(t *testing.T) {
+	input := lines(
+		"apiVersion: v1",
+		"kind: Foo",
+	)
+	testData := []struct {
+		name        string
+		replacement string
+		wantPR      bool
+	}{
+		{name: "changed", replacement: "Bar", wantPR: true},
+		{name: "unchanged", replacement: "Foo", wantPR: false},
+	}
+	for _, test := range testData {
+		t.Run(test.name, func(t *testing.T) {
+			gh, client := newFakeGitHub(t)
+			gh.push("o/r", "master", map[string]string{"test.yaml": input})
+			cfg := &config{
+				GithubOwner:   "o",
+				GithubRepo:    "r",
+				GithubBranch:  "master",
+				File:          "test.yaml",
+				Locations:     []string{"kind"},
+				Replacement:   test.replacement,
+				CommitMessage: "bump kind\n\nautomatically",
+				PullRequest:   true,
+			}
+			if err := run(context.Background(), client, cfg, ioutil.Discard); err != nil {
+				t.Fatalf("run: %v", err)
+			}
+			if got, _ := gh.file("o/r", "master", "test.yaml"); got != input {
+				t.Errorf("base branch was modified:\n%s", got)
+			}
+			pulls := gh.pulls("o/r")
+			if !test.wantPR {
+				if len(pulls) != 0 {
+					t.Errorf("unexpected pull requests: %v", pulls)
+				}
+				if n := gh.called("POST", "/repos/o/r/git/refs"); n != 0 {
+					t.Errorf("created %d branches, want none", n)
+				}
+				return
+			}
+			if len(pulls) != 1 {
+				t.Fatalf("want exactly one pull request, got %v", pulls)
+			}
+			pr := pulls[0]
+			if got, want := pr.GetTitle(), "bump kind"; got != want {
+				t.Errorf("title: got %q, want %q", got, want)
+			}
+			if got, want := pr.GetBody(), "automatically"; got != want {
+				t.Errorf("body: got %q, want %q", got, want)
+			}
+			if got, want := pr.GetBase().GetRef(), "master"; got != want {
+				t.Errorf("base: got %q, want %q", got, want)
+			}
+			got, ok := gh.file("o/r", pr.GetHead().GetRef(), "test.yaml")
+			if !ok {
+				t.Fatalf("pull request branch %s does not contain test.yaml", pr.GetHead().GetRef())
+			}
+			if diff := cmp.Diff(got, lines("apiVersion: v1", "kind: Bar")); diff != "" {
+				t.Errorf("unexpected yaml on pull request branch:\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestPullRequestBranch(t *testing.T) {
+	testData := []struct {
+		name      string
+		prBranch  string
+		closed    bool // close the first pull request before the second run
+		force     bool
+		protected []string
+		wantTitle string
+		wantErr   string
+	}{
+		{name: "open pull request", prBranch: "bump/image", wantTitle: "bump image to v2"},
+		{name: "closed pull request", prBranch: "bump/image", closed: true, wantErr: "branch bump/image already exists and has no open pull request into master; pass --force"},
+		{name: "closed pull request with --force", prBranch: "bump/image", closed: true, force: true, wantTitle: "bump image to v3"},
+		{name: "protected branch", prBranch: "bump/image", closed: true, force: true, protected: []string{"bump/*"}, wantErr: "refusing to force-update protected branch bump/image"},
+		{name: "base branch", prBranch: "master", force: true, wantErr: "--pr-branch must be different from master"},
+	}
+	for _, test := range testData {
+		t.Run(test.name, func(t *testing.T) {
+			gh, client := newFakeGitHub(t)
+			gh.push("o/r", "master", map[string]string{"config.yaml": lines("image:", "  tag: v1")})
+			for _, replacement := range []string{"v2", "v3"} {
+				cfg := &config{
+					GithubOwner:   "o",
+					GithubRepo:    "r",
+					GithubBranch:  "master",
+					File:          "config.yaml",
+					Locations:     []string{"image.tag"},
+					Replacement:   replacement,
+					CommitMessage: "bump image to " + replacement,
+					PullRequest:   true,
+					PRBranch:      "bump/image",
+				}
+				if replacement == "v3" {
+					cfg.PRBranch, cfg.Force, cfg.Protected = test.prBranch, test.force, test.protected
+					if test.closed {
+						gh.repo("o/r").pulls[0].State = github.String("closed")
+					}
+				}
+				err := run(context.Background(), client, cfg, ioutil.Discard)
+				if replacement == "v3" && test.wantErr != "" {
+					if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+						t.Fatalf("want error containing %q, got %v", test.wantErr, err)
+					}
+					if got, _ := gh.file("o/r", "bump/image", "config.yaml"); got != lines("image:", "  tag: v2") {
+						t.Errorf("pull request branch was changed despite the error:\n%s", got)
+					}
+					if got, _ := gh.file("o/r", "master", "config.yaml"); got != lines("image:", "  tag: v1") {
+						t.Errorf("base branch was changed:\n%s", got)
+					}
+					return
+				}
+				if err != nil {
+					t.Fatalf("run with %s: %v", replacement, err)
+				}
+			}
+			var pulls []*github.PullRequest
+			for _, pr := range gh.pulls("o/r") {
+				if pr.GetState() == "open" {
+					pulls = append(pulls, pr)
+				}
+			}
+			if len(pulls) != 1 {
+				t.Fatalf("want exactly one open pull request, got %v", pulls)
+			}
+			if got, want := pulls[0].GetHead().GetRef(), "bump/image"; got != want {
+				t.Errorf("head: got %q, want %q", got, want)
+			}
+			if got := pulls[0].GetTitle(); got != test.wantTitle {
+				t.Errorf("title: got %q, want %q", got, test.wantTitle)
+			}
+			if got, _ := gh.file("o/r", "bump/image", "config.yaml"); got != lines("image:", "  tag: v3") {
+				t.Errorf("pull request branch was not moved to the second edit:\n%s", got)
+			}
+			// The second edit goes on top of the first, rather than replacing it.
+			head := gh.head("o/r", "bump/image")
+			if len(head.Parents) != 1 {
+				t.Fatalf("parents of the pull request branch: got %v, want one", head.Parents)
+			}
+			if first := gh.commits[head.Parents[0]]; len(first.Parents) != 1 || first.Parents[0] != gh.head("o/r", "master").SHA {
+				t.Errorf("pull request branch does not keep the first edit on top of master: parents %v", first.Parents)
+			}
+		})
+	}
+}
+
+func TestPullRequestUpToDate(t *testing.T) {
+	gh, client := newFakeGitHub(t)
+	gh.push("o/r", "master", map[string]string{"config.yaml": lines("image:", "  tag: v1")})
+	cfg := &config{
+		GithubOwner:   "o",
+		GithubRepo:    "r",
+		GithubBranch:  "master",
+		File:          "config.yaml",
+		Locations:     []string{"image.tag"},
+		Replacement:   "v2",
+		CommitMessage: "bump image to v2",
+		PullRequest:   true,
+		Output:        "json",
+	}
+	if err := run(context.Background(), client, cfg, ioutil.Discard); err != nil {
+		t.Fatalf("first run: %v", err)
+	}
+	pulls := gh.pulls("o/r")
+	if len(pulls) != 1 {
+		t.Fatalf("want exactly one pull request, got %v", pulls)
+	}
+	branch := pulls[0].GetHead().GetRef()
+	head := gh.head("o/r", branch).SHA
+	commits, refs := gh.called("POST", "/repos/o/r/git/commits"), gh.called("PATCH", "/repos/o/r/git/refs")
+
+	out := new(bytes.Buffer)
+	if err := run(context.Background(), client, cfg, out); err != nil {
+		t.Fatalf("second run: %v", err)
+	}
+	if got := gh.called("POST", "/repos/o/r/git/commits"); got != commits {
+		t.Errorf("second run made %d commits, want none", got-commits)
+	}
+	if got := gh.called("PATCH", "/repos/o/r/git/refs"); got != refs {
+		t.Errorf("second run moved %d refs, want none", got-refs)
+	}
+	if got := gh.head("o/r", branch).SHA; got != head {
+		t.Errorf("pull request branch moved from %s to %s", head, got)
+	}
+	if got := gh.pulls("o/r"); len(got) != 1 {
+		t.Errorf("want exactly one pull request, got %v", got)
+	}
+	var res result
+	if err := json.Unmarshal(out.Bytes(), &res); err != nil {
+		t.Fatalf("unmarshal result %q: %v", out.String(), err)
+	}
+	// Nothing was pushed, so there is no new commit to report.
+	want := result{
+		URL:            commitURL(webURL(client), "o", "r", head),
+		CompareURL:     compareURL(webURL(client), "o", "r", gh.head("o/r", "master").SHA, head),
+		PullRequestURL: pulls[0].GetHTMLURL(),
+	}
+	if diff := cmp.Diff(want, res); diff != "" {
+		t.Errorf("result (-want +got):\n%s", diff)
+	}
+
+	cfg.Output, cfg.PrintSHAOnly = "", true
+	out.Reset()
+	if err := run(context.Background(), client, cfg, out); err != nil {
+		t.Fatalf("run with --print-sha-only: %v", err)
+	}
+	if got := out.String(); got != "" {
+		t.Errorf("--print-sha-only printed %q, want nothing", got)
+	}
+}
+
+func TestEditMatchLabels(t *testing.T) {
+	input := lines(
+		"# api server",
+		"apiVersion: apps/v1",
+		"kind: Deployment",
+		"metadata:",
+		"  name: api",
+		"  labels:",
+		"    app: api",
+		"    tier:   backend",
+		"spec:",
+		"  image: api:v1",
+		"---",
+		"apiVersion: apps/v1",
+		"kind: Deployment",
+		"metadata:",
+		"  name: web",
+		"  labels:",
+		"    app: web",
+		"    tier:   backend",
+		"spec:",
+		"  image: web:v1",
+		"--- # unlabelled",
+		"apiVersion: v1",
+		"kind: ConfigMap",
+		"spec:",
+		"  image: other:v1",
+	)
+	testData := []struct {
+		name      string
+		selectors []string
+		want      string
+	}{
+		{
+			name:      "one match",
+			selectors: []string{"app=api"},
+			want: lines(
+				"# api server",
+				"apiVersion: apps/v1",
+				"kind: Deployment",
+				"metadata:",
+				"  name: api",
+				"  labels:",
+				"    app: api",
+				"    tier: backend",
+				"spec:",
+				"  image: api:v2",
+				"---",
+				"apiVersion: apps/v1",
+				"kind: Deployment",
+				"metadata:",
+				"  name: web",
+				"  labels:",
+				"    app: web",
+				"    tier:   backend",
+				"spec:",
+				"  image: web:v1",
+				"--- # unlabelled",
+				"apiVersion: v1",
+				"kind: ConfigMap",
+				"spec:",
+				"  image: other:v1",
+			),
+		},
+		{
+			name:      "all labels must match",
+			selectors: []string{"app=web,tier=frontend"},
+			want:      input,
+		},
+		{
+			name:      "several matches",
+			selectors: []string{"tier=backend"},
+			want: lines(
+				"# api server",
+				"apiVersion: apps/v1",
+				"kind: Deployment",
+				"metadata:",
+				"  name: api",
+				"  labels:",
+				"    app: api",
+				"    tier: backend",
+				"spec:",
+				"  image: api:v2",
+				"---",
+				"apiVersion: apps/v1",
+				"kind: Deployment",
+				"metadata:",
+				"  name: web",
+				"  labels:",
+				"    app: web",
+				"    tier: backend",
+				"spec:",
+				"  image: api:v2",
+				"--- # unlabelled",
+				"apiVersion: v1",
+				"kind: ConfigMap",
+				"spec:",
+				"  image: other:v1",
+			),
+		},
+	}
+	for _, test := range testData {
+		t.Run(test.name, func(t *testing.T) {
+			labels, err := parseLabels(test.selectors)
+			if err != nil {
+				t.Fatalf("parse labels: %v", err)
+			}
+			got, _, err := editYAML(input, editsFor([]string{"spec.image"}, "api:v2"), editOptions{MatchLabels: labels})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if diff := cmp.Diff(got, test.want); diff != "" {
+				t.Errorf("unexpected yaml generated:\n%s", diff)
+			}
+		})
+	}
+
+	if _, err := parseLabels([]string{"app"}); err == nil {
+		t.Error("expected an error parsing a label without a value")
+	}
+}
+
+func TestEditMultipleDocuments(t *testing.T) {
+	testData := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{
+			name: "location in both documents",
+			input: lines(
+				"# the deployment",
+				"kind: Deployment",
+				"image:",
+				"  tag: v1",
+				"--- # the job",
+				"kind: Job",
+				"image:",
+				"  tag: v0",
+			),
+			want: lines(
+				"# the deployment",
+				"kind: Deployment",
+				"image:",
+				"  tag: v2",
+				"--- # the job",
+				"kind: Job",
+				"image:",
+				"  tag: v2",
+			),
+		},
+		{
+			name: "location in one document",
+			input: lines(
+				"---",
+				"kind: Service",
+				"port:   80",
+				"---",
+				"# the deployment",
+				"kind: Deployment",
+				"image:",
+				"  tag: v1",
+			),
+			want: lines(
+				"---",
+				"kind: Service",
+				"port:   80",
+				"---",
+				"# the deployment",
+				"kind: Deployment",
+				"image:",
+				"  tag: v2",
+			),
+		},
+	}
+	for _, test := range testData {
+		t.Run(test.name, func(t *testing.T) {
+			got, _, err := editYAML(test.input, editsFor([]string{"image.tag"}, "v2"), editOptions{})
+			if err != nil {
+				t.Fatalf("edit: %v", err)
+			}
+			if diff := cmp.Diff(got, test.want); diff != "" {
+				t.Errorf("output:\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestEditTrace(t *testing.T) {
+	input := lines(
+		"spec:",
+		"  a:",
+		"    tag: v1",
+		"  b:",
+		"    tag: v1.5",
+		"---",
+		"metadata:",
+		"  labels:",
+		"    app: other",
+	)
+	var trace strings.Builder
+	opts := editOptions{Trace: &trace}
+	if _, _, err := editYAML(input, editsFor([]string{"spec.a.tag", "spec.b.tag", "spec.c.tag"}, "v2"), opts); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := lines(
+		`document 1: spec.a.tag (line 3): "v1" -> "v2"`,
+		`document 1: spec.b.tag (line 5): "v1.5" -> "v2"`,
+		`document 1: spec.c.tag: not found; skipped`,
+		`document 2: spec.a.tag: not found; skipped`,
+		`document 2: spec.b.tag: not found; skipped`,
+		`document 2: spec.c.tag: not found; skipped`,
+	)
+	if diff := cmp.Diff(trace.String(), want); diff != "" {
+		t.Errorf("unexpected trace:\n%s", diff)
+	}
+}
+
+func TestEditChanges(t *testing.T) {
+	input := lines(
+		"spec:",
+		"  a:",
+		"    tag: v1",
+		"  b:",
+		"    tag: v2",
+		"---",
+		"spec:",
+		"  a:",
+		"    tag: v1.5",
+	)
+	_, got, err := editYAML(input, editsFor([]string{"spec.a.tag", "spec.b.tag", "spec.c.tag"}, "v2"), editOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := []change{
+		{Location: "spec.a.tag", Found: true, Document: 1, Line: 3, Old: "v1", New: "v2"},
+		{Location: "spec.b.tag", Found: true, Document: 1, Line: 5, Old: "v2", New: "v2"},
+		{Location: "spec.a.tag", Found: true, Document: 2, Line: 3, Old: "v1.5", New: "v2"},
+		{Location: "spec.c.tag"},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("changes (-want +got):\n%s", diff)
+	}
+}
+
+func TestChangedSince(t *testing.T) {
+	testData := []struct {
+		name       string
+		files      []string
+		wantEdited []string
+	}{
+		{name: "changed", files: []string{"a.yaml"}, wantEdited: []string{"a.yaml"}},
+		{name: "unchanged", files: []string{"b.yaml"}},
+		{name: "only a later file changed", files: []string{"b.yaml", "a.yaml"}, wantEdited: []string{"a.yaml"}},
+		{name: "some changed", files: []string{"a.yaml", "b.yaml", "c.yaml"}, wantEdited: []string{"a.yaml", "c.yaml"}},
+	}
+	for _, test := range testData {
+		t.Run(test.name, func(t *testing.T) {
+			gh, client := newFakeGitHub(t)
+			since := gh.push("o/r", "master", map[string]string{
+				"a.yaml": lines("kind: Foo"),
+				"b.yaml": lines("kind: Foo"),
+				"c.yaml": lines("kind: Foo"),
+			})
+			gh.push("o/r", "master", map[string]string{
+				"a.yaml": lines("kind: Foo", "version: 1"),
+				"c.yaml": lines("kind: Foo", "version: 1"),
+			})
+			before := gh.head("o/r", "master").SHA
+			cfg := &config{
+				GithubOwner:  "o",
+				GithubRepo:   "r",
+				GithubBranch: "master",
+				Files:        test.files,
+				Locations:    []string{"kind"},
+				Replacement:  "Bar",
+				ChangedSince: since,
+			}
+			if err := run(context.Background(), client, cfg, ioutil.Discard); err != nil {
+				t.Fatalf("run: %v", err)
+			}
+			wantCommit := len(test.wantEdited) > 0
+			if got := gh.head("o/r", "master").SHA != before; got != wantCommit {
+				t.Errorf("committed: got %v, want %v", got, wantCommit)
+			}
+			if !wantCommit && gh.called("GET", "/repos/o/r/git/") > 0 {
+				t.Errorf("read the tree of a file that is excluded from the edit")
+			}
+			var edited []string
+			for _, f := range []string{"a.yaml", "b.yaml", "c.yaml"} {
+				if got, _ := gh.file("o/r", "master", f); strings.HasPrefix(got, "kind: Bar") {
+					edited = append(edited, f)
+				}
+			}
+			if diff := cmp.Diff(test.wantEdited, edited); diff != "" {
+				t.Errorf("edited files (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestChangedFilesTruncated(t *testing.T) {
+	gh, client := newFakeGitHub(t)
+	since := gh.push("o/r", "master", map[string]string{"a.yaml": lines("kind: Foo")})
+	files := map[string]string{}
+	for i := 0; i < maxComparedFiles; i++ {
+		files[fmt.Sprintf("f%d.yaml", i)] = lines("kind: Foo")
+	}
+	head := gh.push("o/r", "master", files)
+	_, err := changedFiles(context.Background(), client, "o", "r", since, head)
+	if want := "github lists at most 300 changed files"; err == nil || !strings.Contains(err.Error(), want) {
+		t.Errorf("want an error containing %q, got %v", want, err)
+	}
+}
+
+func TestCommitBaseTree(t *testing.T) {
+	gh, client := newFakeGitHub(t)
+	gh.push("o/r", "master", map[string]string{"test.yaml": lines("kind: Foo")})
+	gh.push("o/r", "staging", map[string]string{"test.yaml": lines("kind: Foo"), "other.yaml": lines("a: b")})
+	base := gh.head("o/r", "staging").Tree
+
+	cfg := &config{
+		GithubOwner:  "o",
+		GithubRepo:   "r",
+		GithubBranch: "master",
+		File:         "test.yaml",
+		Locations:    []string{"kind"},
+		Replacement:  "Bar",
+		BaseTree:     base,
+	}
+	if err := run(context.Background(), client, cfg, ioutil.Discard); err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	if got, _ := gh.file("o/r", "master", "test.yaml"); got != lines("kind: Bar") {
+		t.Errorf("test.yaml was not edited:\n%s", got)
+	}
+	if _, ok := gh.file("o/r", "master", "other.yaml"); !ok {
+		t.Errorf("new commit was not built on the provided base tree %s", base)
+	}
+}
+
+func TestCreateBlobs(t *testing.T) {
+	gh, client := newFakeGitHub(t)
+	before := gh.push("o/r", "master", map[string]string{"README.md": "hi\n"})
+	files := map[string]string{}
+	for i := 0; i < 3*maxBlobUploads; i++ {
+		files[fmt.Sprintf("charts/c%02d/values.yaml", i)] = lines(fmt.Sprintf("version: %d", i))
+	}
+	// Blobs started earlier finish later, so that they complete out of order.
+	var mu sync.Mutex
+	var started, running, maxRunning int
+	gh.before("POST", "/repos/o/r/git/blobs", func() {
+		mu.Lock()
+		started++
+		running++
+		if running > maxRunning {
+			maxRunning = running
+		}
+		delay := time.Duration(len(files)-started) * time.Millisecond
+		mu.Unlock()
+		time.Sleep(delay)
+		mu.Lock()
+		running--
+		mu.Unlock()
+	})
+	plan := newCommitPlan(gh.head("o/r", "master").Tree, before, files, "bump", identity{})
+	shas, err := createBlobs(context.Background(), client, "o", "r", plan.Entries, files)
+	if err != nil {
+		t.Fatalf("createBlobs: %v", err)
+	}
+	var want []string
+	for _, e := range plan.Entries {
+		want = append(want, hashObject("blob", files[e.Path]))
+	}
+	if diff := cmp.Diff(want, shas); diff != "" {
+		t.Errorf("blob SHAs (-want +got):\n%s", diff)
+	}
+	if maxRunning < 2 || maxRunning > maxBlobUploads {
+		t.Errorf("created up to %d blobs at once, want between 2 and %d", maxRunning, maxBlobUploads)
+	}
+
+	sha, err := createCommit(context.Background(), client, gh.head("o/r", "master").Tree, before, "o", "r", files, "bump", identity{})
+	if err != nil {
+		t.Fatalf("createCommit: %v", err)
+	}
+	gh.mu.Lock()
+	got := gh.flatten(gh.commits[sha].Tree, "")
+	gh.mu.Unlock()
+	for path, content := range files {
+		if e, ok := got[path]; !ok || e.SHA != hashObject("blob", content) {
+			t.Errorf("%s: got %+v, want blob of %q", path, e, content)
+		}
+	}
+	if _, ok := got["README.md"]; !ok {
+		t.Error("README.md missing from the new tree")
+	}
+}
+
+func TestCreateBlobsEncoding(t *testing.T) {
+	testData := []struct {
+		name         string
+		content      string
+		wantEncoding string
+	}{
+		{name: "ascii", content: lines("image:", "  tag: v2"), wantEncoding: "utf-8"},
+		{name: "utf-8", content: lines("# Größe: 10Gi ✓", "size: 10Gi"), wantEncoding: "utf-8"},
+		{name: "invalid utf-8", content: "latin1: Gr\xf6\xdfe\n", wantEncoding: "base64"},
+		{name: "nul", content: "a\x00b\n", wantEncoding: "base64"},
+	}
+	for _, test := range testData {
+		t.Run(test.name, func(t *testing.T) {
+			gh, client := newFakeGitHub(t)
+			before := gh.push("o/r", "master", map[string]string{"README.md": "hi\n"})
+			var encodings []string
+			gh.handle("POST", "/repos/o/r/git/blobs", func(w http.ResponseWriter, r *http.Request) {
+				var req struct{ Content, Encoding string }
+				if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+					t.Errorf("decode blob request: %v", err)
+				}
+				encodings = append(encodings, req.Encoding)
+				content := req.Content
+				if req.Encoding == "base64" {
+					b, err := base64.StdEncoding.DecodeString(content)
+					if err != nil {
+						t.Errorf("decode base64 content: %v", err)
+					}
+					content = string(b)
+				}
+				gh.mu.Lock()
+				sha := gh.putBlob(content)
+				gh.mu.Unlock()
+				gh.reply(w, http.StatusCreated, map[string]string{"sha": sha})
+			})
+			files := map[string]string{"data.yaml": test.content}
+			sha, err := createCommit(context.Background(), client, gh.head("o/r", "master").Tree, before, "o", "r", files, "edit", identity{})
+			if err != nil {
+				t.Fatalf("createCommit: %v", err)
+			}
+			if diff := cmp.Diff([]string{test.wantEncoding}, encodings); diff != "" {
+				t.Errorf("encodings (-want +got):\n%s", diff)
+			}
+			gh.mu.Lock()
+			got := gh.flatten(gh.commits[sha].Tree, "")["data.yaml"]
+			gh.mu.Unlock()
+			if want := hashObject("blob", test.content); got.SHA != want {
+				t.Errorf("blob: got %s, want %s", got.SHA, want)
+			}
+		})
+	}
+}
+
+func TestTokenFile(t *testing.T) {
+	dir := t.TempDir()
+	tokenFile := filepath.Join(dir, "token")
+	if err := ioutil.WriteFile(tokenFile, []byte("  from-file\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	testData := []struct {
+		name      string
+		token     string
+		explicit  bool
+		tokenFile string
+		want      string
+		wantErr   string
+	}{
+		{name: "explicit flag wins", token: "from-flag", explicit: true, tokenFile: tokenFile, want: "from-flag"},
+		{name: "file beats environment", token: "from-env", tokenFile: tokenFile, want: "from-file"},
+		{name: "environment", token: "from-env", want: "from-env"},
+		{name: "environment with a newline", token: "from-env\n", want: "from-env"},
+		{name: "unreadable file", token: "from-env", tokenFile: filepath.Join(dir, "missing"), wantErr: "read token file"},
+	}
+	for _, test := range testData {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := resolveToken(test.token, test.explicit, test.tokenFile)
+			if test.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+					t.Fatalf("want error containing %q, got %v", test.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != test.want {
+				t.Errorf("token: got %q, want %q", got, test.want)
+			}
+		})
+	}
+
+	t.Run("no credentials", func(t *testing.T) {
+		token, err := resolveToken("", false, "")
+		if err != nil {
+			t.Fatalf("resolve token: %v", err)
+		}
+		if _, err := newClient(context.Background(), &auth{AccessToken: token}); err == nil || !strings.Contains(err.Error(), "--token-file") {
+			t.Errorf("want an error naming the ways to pass a token, got %v", err)
+		}
+	})
+
+	t.Run("token is used", func(t *testing.T) {
+		var gotAuth string
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			gotAuth = r.Header.Get("Authorization")
+			fmt.Fprint(w, "{}")
+		}))
+		defer srv.Close()
+
+		token, err := resolveToken("", false, tokenFile)
+		if err != nil {
+			t.Fatalf("resolve token: %v", err)
+		}
+		client, err := newClient(context.Background(), &auth{AccessToken: token})
+		if err != nil {
+			t.Fatalf("new client: %v", err)
+		}
+		client.BaseURL, _ = url.Parse(srv.URL + "/")
+		if _, _, err := client.Repositories.Get(context.Background(), "o", "r"); err != nil {
+			t.Fatalf("get repo: %v", err)
+		}
+		if want := "Bearer from-file"; gotAuth != want {
+			t.Errorf("authorization header: got %q, want %q", gotAuth, want)
+		}
+	})
+}
+
+func TestNewClientEnterprise(t *testing.T) {
+	testData := []struct {
+		name       string
+		baseURL    string
+		uploadURL  string
+		wantBase   string
+		wantUpload string
+		wantErr    bool
+	}{
+		{name: "github.com", wantBase: "https://api.github.com/", wantUpload: "https://uploads.github.com/"},
+		{name: "enterprise", baseURL: "https://github.example.com", wantBase: "https://github.example.com/api/v3/", wantUpload: "https://github.example.com/api/uploads/"},
+		{name: "enterprise api path", baseURL: "https://github.example.com/api/v3/", wantBase: "https://github.example.com/api/v3/", wantUpload: "https://github.example.com/api/uploads/"},
+		{name: "separate uploads", baseURL: "https://github.example.com", uploadURL: "https://uploads.example.com", wantBase: "https://github.example.com/api/v3/", wantUpload: "https://uploads.example.com/api/uploads/"},
+		{name: "no scheme", baseURL: "github.example.com", wantErr: true},
+		{name: "unparseable", baseURL: "https://github.example.com/%zz", wantErr: true},
+		{name: "upload url alone", uploadURL: "https://uploads.example.com", wantErr: true},
+	}
+	for _, test := range testData {
+		t.Run(test.name, func(t *testing.T) {
+			client, err := newClient(context.Background(), &auth{AccessToken: "token", BaseURL: test.baseURL, UploadURL: test.uploadURL})
+			if test.wantErr {
+				if err == nil {
+					t.Fatalf("expected error, got client for %s", client.BaseURL)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("new client: %v", err)
+			}
+			if got := client.BaseURL.String(); got != test.wantBase {
+				t.Errorf("base url: got %s, want %s", got, test.wantBase)
+			}
+			if got := client.UploadURL.String(); got != test.wantUpload {
+				t.Errorf("upload url: got %s, want %s", got, test.wantUpload)
+			}
+		})
+	}
+}
+
+func TestNewClientApp(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyFile := filepath.Join(t.TempDir(), "app.pem")
+	if err := ioutil.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)}), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	var mu sync.Mutex
+	var requests []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requests = append(requests, r.Method+" "+r.URL.Path+" "+strings.Fields(r.Header.Get("Authorization") + " -")[0])
+		mu.Unlock()
+		if r.Method == "POST" {
+			fmt.Fprintf(w, `{"token": "installation-token", "expires_at": %q}`, time.Now().Add(time.Hour).Format(time.RFC3339))
+			return
+		}
+		fmt.Fprintf(w, `{"authorization": %q}`, r.Header.Get("Authorization"))
+	}))
+	defer srv.Close()
+
+	testData := []struct {
+		name    string
+		auth    auth
+		wantErr string
+	}{
+		{name: "key file", auth: auth{AppID: 1, InstallationID: 7, PrivateKeyFile: keyFile}},
+		{name: "no installation", auth: auth{AppID: 1, PrivateKeyFile: keyFile}, wantErr: "needs --installation-id"},
+		{name: "no key", auth: auth{AppID: 1, InstallationID: 7, AccessToken: "token"}, wantErr: "needs --private-key or --private-key-file"},
+		{name: "missing key file", auth: auth{AppID: 1, InstallationID: 7, PrivateKeyFile: keyFile + ".missing"}, wantErr: "read private key file"},
+	}
+	for _, test := range testData {
+		t.Run(test.name, func(t *testing.T) {
+			mu.Lock()
+			requests = nil
+			mu.Unlock()
+			test.auth.BaseURL = srv.URL
+			client, err := newClient(context.Background(), &test.auth)
+			if test.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+					t.Fatalf("want an error containing %q, got %v", test.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("new client: %v", err)
+			}
+			// The installation token is minted before any other request, and used for them.
+			req, err := client.NewRequest("GET", "repos/o/r", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got map[string]string
+			if _, err := client.Do(context.Background(), req, &got); err != nil {
+				t.Fatalf("get repo: %v", err)
+			}
+			if want := "token installation-token"; got["authorization"] != want {
+				t.Errorf("authorization: got %q, want %q", got["authorization"], want)
+			}
+			want := []string{"POST /api/v3/app/installations/7/access_tokens Bearer", "GET /api/v3/repos/o/r token"}
+			if diff := cmp.Diff(want, requests); diff != "" {
+				t.Errorf("requests (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestPrintSHAOnly(t *testing.T) {
+	testData := []struct {
+		name    string
+		input   string
+		wantSHA bool
+	}{
+		{name: "commit", input: lines("kind:   Foo"), wantSHA: true},
+		{name: "no-op", input: lines("kind: Foo"), wantSHA: false},
+	}
+	for _, test := range testData {
+		t.Run(test.name, func(t *testing.T) {
+			gh, client := newFakeGitHub(t)
+			gh.push("o/r", "master", map[string]string{"test.yaml": test.input})
+			cfg := &config{
+				GithubOwner:  "o",
+				GithubRepo:   "r",
+				GithubBranch: "master",
+				File:         "test.yaml",
+				Touch:        true,
+				PrintSHAOnly: true,
+			}
+			var stdout strings.Builder
+			if err := run(context.Background(), client, cfg, &stdout); err != nil {
+				t.Fatalf("run: %v", err)
+			}
+			want := ""
+			if test.wantSHA {
+				want = gh.head("o/r", "master").SHA + "\n"
+			}
+			if got := stdout.String(); got != want {
+				t.Errorf("stdout: got %q, want %q", got, want)
+			}
+		})
+	}
+}
+
+func TestFetchPathPrefix(t *testing.T) {
+	gh, client := newFakeGitHub(t)
+	gh.push("o/r", "master", map[string]string{
+		"deploy/prod/app.yaml":    lines("kind: Foo"),
+		"deploy/staging/app.yaml": lines("kind: Foo"),
+		"src/main.go":             "package main\n",
+	})
+	root := gh.head("o/r", "master").Tree
+
+	got, err := fetch(context.Background(), client, "o", "r", "master", "deploy/prod/app.yaml", "deploy/prod", false)
+	if err != nil {
+		t.Fatalf("fetch: %v", err)
+	}
+	if want := lines("kind: Foo"); got.Content != want {
+		t.Errorf("content: got %q, want %q", got.Content, want)
+	}
+	if got.Tree.GetSHA() != root {
+		t.Errorf("tree: got %s, want root tree %s", got.Tree.GetSHA(), root)
+	}
+	if n := gh.called("GET", "/repos/o/r/git/trees/"+root+"?recursive"); n != 0 {
+		t.Errorf("read the root tree recursively %d times", n)
+	}
+
+	if _, err := fetch(context.Background(), client, "o", "r", "master", "deploy/prod/app.yaml", "deploy/dev", false); err == nil {
+		t.Error("expected an error fetching a file from outside the path prefix")
+	}
+}
+
+func TestFetchTruncatedTree(t *testing.T) {
+	testData := []struct {
+		name, file string
+		wantErr    string
+	}{
+		{name: "in a directory", file: "deploy/prod/app.yaml"},
+		{name: "at the root", file: "root.yaml"},
+		{name: "directory truncated too", file: "big/a.yaml", wantErr: "github truncated tree"},
+	}
+	for _, test := range testData {
+		t.Run(test.name, func(t *testing.T) {
+			gh, client := newFakeGitHub(t)
+			gh.push("o/r", "master", map[string]string{
+				"deploy/prod/app.yaml":    lines("kind: Foo"),
+				"deploy/staging/app.yaml": lines("kind: Foo"),
+				"root.yaml":               lines("kind: Foo"),
+				"big/a.yaml":              lines("kind: Foo"),
+				"big/b.yaml":              lines("kind: Foo"),
+				"big/c.yaml":              lines("kind: Foo"),
+				"big/d.yaml":              lines("kind: Foo"),
+			})
+			gh.maxEntries = 3
+			root := gh.head("o/r", "master").Tree
+
+			got, err := fetch(context.Background(), client, "o", "r", "master", test.file, "", true)
+			if test.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+					t.Fatalf("want error containing %q, got %v", test.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("fetch: %v", err)
+			}
+			if want := lines("kind: Foo"); got.Content != want {
+				t.Errorf("content: got %q, want %q", got.Content, want)
+			}
+			if got.Tree.GetSHA() != root {
+				t.Errorf("tree: got %s, want root tree %s", got.Tree.GetSHA(), root)
+			}
+		})
+	}
+}
+
+func TestFetchContents(t *testing.T) {
+	big := lines("kind: Foo", "data: "+strings.Repeat("x", 1<<20))
+	testData := []struct {
+		name, file string
+		want       string
+		wantErr    string
+	}{
+		{name: "file", file: "deploy/app.yaml", want: lines("kind: Foo")},
+		{name: "too big for the contents api", file: "deploy/big.yaml", want: big},
+		{name: "missing", file: "deploy/missing.yaml", wantErr: "file not found in commit"},
+	}
+	for _, test := range testData {
+		t.Run(test.name, func(t *testing.T) {
+			gh, client := newFakeGitHub(t)
+			gh.push("o/r", "master", map[string]string{
+				".gitattributes":        "* text=auto\n",
+				"deploy/app.yaml":       lines("kind: Foo"),
+				"deploy/big.yaml":       big,
+				"deploy/.gitattributes": "*.yaml eol=lf\n",
+			})
+			head := gh.head("o/r", "master")
+
+			got, err := fetch(context.Background(), client, "o", "r", "master", test.file, "", false)
+			if test.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+					t.Fatalf("want error containing %q, got %v", test.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("fetch: %v", err)
+			}
+			if got.Content != test.want {
+				t.Errorf("content: got %d bytes, want %d", len(got.Content), len(test.want))
+			}
+			if got.CommitSHA != head.SHA || got.Tree.GetSHA() != head.Tree {
+				t.Errorf("read from commit %s with tree %s, want %s with tree %s", got.CommitSHA, got.Tree.GetSHA(), head.SHA, head.Tree)
+			}
+			wantAttributes := map[string]string{".gitattributes": "* text=auto\n", "deploy/.gitattributes": "*.yaml eol=lf\n"}
+			if diff := cmp.Diff(wantAttributes, got.Attributes); diff != "" {
+				t.Errorf("attributes (-want +got):\n%s", diff)
+			}
+			if n := gh.called("GET", "/repos/o/r/git/trees/"); n != 0 {
+				t.Errorf("read %d trees", n)
+			}
+		})
+	}
+}
+
+// BenchmarkFetch compares reading one file from a big repository by listing its tree with
+// reading it with the contents API.
+func BenchmarkFetch(b *testing.B) {
+	gh, client := newFakeGitHub(b)
+	files := map[string]string{"deploy/app.yaml": lines("kind: Foo")}
+	for i := 0; i < 5000; i++ {
+		files[fmt.Sprintf("src/pkg%d/file%d.go", i%100, i)] = "package main\n"
+	}
+	gh.push("o/r", "master", files)
+	for _, byTree := range []bool{true, false} {
+		name := "contents"
+		if byTree {
+			name = "tree"
+		}
+		b.Run(name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				if _, err := fetch(context.Background(), client, "o", "r", "master", "deploy/app.yaml", "", byTree); err != nil {
+					b.Fatalf("fetch: %v", err)
+				}
+			}
+		})
+	}
+}
+
+func TestCompareURL(t *testing.T) {
+	enterprise, err := github.NewEnterpriseClient("https://github.example.com/api/v3/", "https://github.example.com/api/uploads/", nil)
+	if err != nil {
+		t.Fatalf("new enterprise client: %v", err)
+	}
+	testData := []struct {
+		name   string
+		client *github.Client
+		want   string
+	}{
+		{name: "github.com", client: github.NewClient(nil), want: "https://github.com/o/r/compare/abc...def"},
+		{name: "enterprise", client: enterprise, want: "https://github.example.com/o/r/compare/abc...def"},
+	}
+	for _, test := range testData {
+		t.Run(test.name, func(t *testing.T) {
+			if got := compareURL(webURL(test.client), "o", "r", "abc", "def"); got != test.want {
+				t.Errorf("got %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestEditRenameKey(t *testing.T) {
+	input := lines(
+		"apiVersion: v1",
+		"spec:",
+		"  # the old group",
+		"  extensions:",
+		"    version: v1beta1 # keep me",
+		"  other: true",
+	)
+	testData := []struct {
+		name    string
+		renames []string
+		want    string
+		wantErr string
+	}{
+		{
+			name:    "nested",
+			renames: []string{"spec.extensions=apps"},
+			want: lines(
+				"apiVersion: v1",
+				"spec:",
+				"  # the old group",
+				"  apps:",
+				"    version: v1beta1 # keep me",
+				"  other: true",
+			),
+		},
+		{
+			name:    "top level",
+			renames: []string{"apiVersion=version"},
+			want: lines(
+				"version: v1",
+				"spec:",
+				"  # the old group",
+				"  extensions:",
+				"    version: v1beta1 # keep me",
+				"  other: true",
+			),
+		},
+		{
+			name:    "missing",
+			renames: []string{"spec.extensionz=apps"},
+			want:    input,
+		},
+		{
+			name:    "collision",
+			renames: []string{"spec.extensions=other"},
+			wantErr: "key other already exists",
+		},
+		{
+			name:    "already renamed",
+			renames: []string{"spec.apps=extensions"},
+			want:    input,
+		},
+	}
+	for _, test := range testData {
+		t.Run(test.name, func(t *testing.T) {
+			renames, err := parseRenames(test.renames)
+			if err != nil {
+				t.Fatalf("parse renames: %v", err)
+			}
+			got, _, err := editYAML(input, nil, editOptions{Renames: renames})
+			if test.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+					t.Fatalf("want error containing %q, got %v", test.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if diff := cmp.Diff(got, test.want); diff != "" {
+				t.Errorf("unexpected yaml generated:\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestForce(t *testing.T) {
+	testData := []struct {
+		name    string
+		branch  string
+		force   bool
+		wantErr string
+	}{
+		{name: "fast-forward required", branch: "feature", wantErr: "branch moved, please retry"},
+		{name: "conflict explained", branch: "feature", wantErr: "feature has moved on from commit"},
+		{name: "force suggested", branch: "feature", wantErr: "pass --force to discard the changes made since"},
+		{name: "force", branch: "feature", force: true},
+		{name: "protected", branch: "main", force: true, wantErr: "refusing to force-update protected branch main"},
+		{name: "protected by glob", branch: "release/v1", force: true, wantErr: "refusing to force-update protected branch release/v1"},
+	}
+	for _, test := range testData {
+		t.Run(test.name, func(t *testing.T) {
+			gh, client := newFakeGitHub(t)
+			gh.push("o/r", test.branch, map[string]string{"test.yaml": lines("kind: Foo")})
+			// Someone else pushes to the branch after we've read it.
+			gh.before("POST", "/repos/o/r/git/commits", func() {
+				gh.push("o/r", test.branch, map[string]string{"other.yaml": lines("a: b")})
+			})
+			cfg := &config{
+				GithubOwner:  "o",
+				GithubRepo:   "r",
+				GithubBranch: test.branch,
+				File:         "test.yaml",
+				Locations:    []string{"kind"},
+				Replacement:  "Bar",
+				Force:        test.force,
+				Protected:    []string{"main", "release/*"},
+			}
+			err := run(context.Background(), client, cfg, ioutil.Discard)
+			if test.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+					t.Fatalf("want error containing %q, got %v", test.wantErr, err)
+				}
+				if got, _ := gh.file("o/r", test.branch, "test.yaml"); got != lines("kind: Foo") {
+					t.Errorf("branch was updated despite the error:\n%s", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("run: %v", err)
+			}
+			if got, _ := gh.file("o/r", test.branch, "test.yaml"); got != lines("kind: Bar") {
+				t.Errorf("branch was not force-updated:\n%s", got)
+			}
+		})
+	}
+}
+
+func TestNestedFile(t *testing.T) {
+	testData := []struct {
+		name, file string
+		wantErr    string
+	}{
+		{name: "plain", file: "deploy/prod/app.yaml"},
+		{name: "leading dot", file: "./deploy/prod/app.yaml"},
+		{name: "leading slash", file: "/deploy/prod/app.yaml"},
+		{name: "backslashes", file: `deploy\prod\app.yaml`},
+		{name: "outside the repository", file: "../deploy/prod/app.yaml", wantErr: "is not a file in the repository"},
+	}
+	for _, test := range testData {
+		t.Run(test.name, func(t *testing.T) {
+			gh, client := newFakeGitHub(t)
+			gh.push("o/r", "master", map[string]string{
+				"deploy/prod/app.yaml":    lines("image:", "  tag: v1"),
+				"deploy/prod/other.yaml":  lines("image:", "  tag: v1"),
+				"deploy/staging/app.yaml": lines("image:", "  tag: v1"),
+				"README.md":               lines("# app"),
+			})
+			cfg := &config{
+				GithubOwner:  "o",
+				GithubRepo:   "r",
+				GithubBranch: "master",
+				Files:        []string{test.file},
+				Locations:    []string{"image.tag"},
+				Replacement:  "v2",
+			}
+			err := run(context.Background(), client, cfg, ioutil.Discard)
+			if test.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+					t.Fatalf("want error containing %q, got %v", test.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("run: %v", err)
+			}
+			// The new tree is built on the old one, so the files beside the edited one, and in
+			// the directories around it, are all still there.
+			head := gh.head("o/r", "master")
+			want := map[string]string{
+				"deploy/prod/app.yaml":    lines("image:", "  tag: v2"),
+				"deploy/prod/other.yaml":  lines("image:", "  tag: v1"),
+				"deploy/staging/app.yaml": lines("image:", "  tag: v1"),
+				"README.md":               lines("# app"),
+			}
+			got := map[string]string{}
+			for path, e := range gh.flatten(head.Tree, "") {
+				if e.Type == "blob" {
+					got[path], _ = gh.file("o/r", "master", path)
+				}
+			}
+			if diff := cmp.Diff(want, got); diff != "" {
+				t.Errorf("files in the new commit (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestMultipleFiles(t *testing.T) {
+	gh, client := newFakeGitHub(t)
+	before := gh.push("o/r", "master", map[string]string{
+		"deploy/deployment.yaml":    lines("spec:", "  image:", "    tag: v1"),
+		"deploy/hpa.yaml":           lines("metadata:", "  labels:", "    version: v1", "spec:", "  image:", "    tag: v1"),
+		"deploy/kustomization.yaml": lines("images:", "- name: app", "  newTag: v1"),
+		"README.md":                 lines("# app"),
+	})
+	cfg := &config{
+		GithubOwner:  "o",
+		GithubRepo:   "r",
+		GithubBranch: "master",
+		Files:        []string{"deploy/deployment.yaml", "deploy/hpa.yaml", "deploy/kustomization.yaml"},
+		Locations:    []string{"spec.image.tag", "images[0].newTag"},
+		Replacement:  "v2",
+	}
+	if err := run(context.Background(), client, cfg, ioutil.Discard); err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	if n := gh.called("POST", "/repos/o/r/git/trees"); n != 1 {
+		t.Errorf("created %d trees, want 1", n)
+	}
+	if n := gh.called("POST", "/repos/o/r/git/commits"); n != 1 {
+		t.Errorf("created %d commits, want 1", n)
+	}
+	head := gh.head("o/r", "master")
+	if diff := cmp.Diff([]string{before}, head.Parents); diff != "" {
+		t.Errorf("parents of the new head (-want +got):\n%s", diff)
+	}
+	want := map[string]string{
+		"deploy/deployment.yaml":    lines("spec:", "  image:", "    tag: v2"),
+		"deploy/hpa.yaml":           lines("metadata:", "  labels:", "    version: v1", "spec:", "  image:", "    tag: v2"),
+		"deploy/kustomization.yaml": lines("images:", "- name: app", "  newTag: v2"),
+		"README.md":                 lines("# app"),
+	}
+	got := map[string]string{}
+	for path, e := range gh.flatten(head.Tree, "") {
+		if e.Type == "blob" {
+			got[path], _ = gh.file("o/r", "master", path)
+		}
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("files in the new commit (-want +got):\n%s", diff)
+	}
+}
+
+func TestMultipleFilesChecked(t *testing.T) {
+	gh, client := newFakeGitHub(t)
+	gh.push("o/r", "master", map[string]string{
+		"a.yaml": lines("image:", "  tag: v1.0.0"),
+		"b.yaml": lines("image:", "  tag: v3.0.0"),
+	})
+	cfg := &config{
+		GithubOwner:  "o",
+		GithubRepo:   "r",
+		GithubBranch: "master",
+		Files:        []string{"a.yaml", "b.yaml"},
+		Locations:    []string{"image.tag"},
+		Replacement:  "v2.0.0",
+		NoDowngrade:  true,
+	}
+	err := run(context.Background(), client, cfg, ioutil.Discard)
+	if want := "image.tag in b.yaml is v3.0.0; refusing to downgrade"; err == nil || !strings.Contains(err.Error(), want) {
+		t.Fatalf("want error containing %q, got %v", want, err)
+	}
+	if n := gh.called("POST", "/repos/o/r/git/commits"); n != 0 {
+		t.Errorf("created %d commits, want none", n)
+	}
+}
+
+func TestConflictRetry(t *testing.T) {
+	testData := []struct {
+		name            string
+		retries         int
+		retryOnConflict bool
+		moved           string // what someone else pushes; defaults to the file reshaped
+		replacement     string // defaults to v2
+		noDowngrade     bool
+		requireMatch    bool
+		want            string
+		wantErr         string
+	}{
+		{name: "no retries", wantErr: "branch moved, please retry"},
+		{
+			name:            "retry refuses a downgrade",
+			retryOnConflict: true,
+			moved:           lines("image:", "  tag: v3.0.0"),
+			replacement:     "v2.0.0",
+			noDowngrade:     true,
+			wantErr:         "refusing to downgrade it to v2.0.0",
+		},
+		{
+			name:            "retry requires a match",
+			retryOnConflict: true,
+			moved:           lines("image:", "  version: v1"),
+			requireMatch:    true,
+			wantErr:         "no match in config.yaml for image.tag",
+		},
+		{
+			name:    "retry re-resolves the location",
+			retries: 1,
+			want:    lines("replicas: 3", "image:", "  pullPolicy: Always", "  tag: v2"),
+		},
+		{
+			name:            "retry on conflict",
+			retryOnConflict: true,
+			want:            lines("replicas: 3", "image:", "  pullPolicy: Always", "  tag: v2"),
+		},
+	}
+	for _, test := range testData {
+		t.Run(test.name, func(t *testing.T) {
+			gh, client := newFakeGitHub(t)
+			gh.push("o/r", "master", map[string]string{"config.yaml": lines("image:", "  tag: v1")})
+			// Someone else reshapes the file after we've read it, once.
+			moved := test.moved
+			if moved == "" {
+				moved = lines("replicas: 3", "image:", "  pullPolicy: Always", "  tag: v1")
+			}
+			var pushed string
+			gh.before("POST", "/repos/o/r/git/commits", func() {
+				if pushed == "" {
+					pushed = gh.push("o/r", "master", map[string]string{"config.yaml": moved})
+				}
+			})
+			replacement := test.replacement
+			if replacement == "" {
+				replacement = "v2"
+			}
+			cfg := &config{
+				GithubOwner:   "o",
+				GithubRepo:    "r",
+				GithubBranch:  "master",
+				File:          "config.yaml",
+				Locations:     []string{"image.tag"},
+				Replacement:   replacement,
+				Retries:       test.retries,
+				RetryConflict: test.retryOnConflict,
+				NoDowngrade:   test.noDowngrade,
+				RequireMatch:  test.requireMatch,
+			}
+			err := run(context.Background(), client, cfg, ioutil.Discard)
+			if test.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+					t.Fatalf("want error containing %q, got %v", test.wantErr, err)
+				}
+				if pushed != "" && gh.head("o/r", "master").SHA != pushed {
+					t.Errorf("master moved on from %s despite the error", pushed)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("run: %v", err)
+			}
+			if diff := cmp.Diff([]string{pushed}, gh.head("o/r", "master").Parents); diff != "" {
+				t.Errorf("parents of the new head (-want +got):\n%s", diff)
+			}
+			if got, _ := gh.file("o/r", "master", "config.yaml"); got != test.want {
+				t.Errorf("config.yaml:\n%s", cmp.Diff(test.want, got))
+			}
+		})
+	}
+}
+
+func TestStateFileETag(t *testing.T) {
+	gh, client := newFakeGitHub(t)
+	gh.push("o/r", "master", map[string]string{"config.yaml": lines("image:", "  tag: v1")})
+	head := gh.head("o/r", "master").SHA
+	stateFile := filepath.Join(t.TempDir(), "state.json")
+	cfg := &config{
+		GithubOwner:  "o",
+		GithubRepo:   "r",
+		GithubBranch: "master",
+		File:         "config.yaml",
+		Locations:    []string{"image.tag"},
+		Replacement:  "v1",
+		StateFile:    stateFile,
+	}
+
+	// The first run reads the file and records the branch's ETag.
+	cfg.DryRun = true
+	if err := run(context.Background(), client, cfg, ioutil.Discard); err != nil {
+		t.Fatalf("first run: %v", err)
+	}
+	cfg.DryRun = false
+	st, err := loadState(stateFile)
+	if err != nil {
+		t.Fatalf("load state: %v", err)
+	}
+	if st.ETag == "" || st.CommitSHA != head {
+		t.Fatalf("state after first run: got etag %q at %s, want an etag at %s", st.ETag, st.CommitSHA, head)
+	}
+
+	// The second run gets 304 Not Modified, and reuses the cached content without reading
+	// any trees or blobs or committing anything.
+	trees, blobs := gh.called("GET", "/repos/o/r/git/trees/"), gh.called("GET", "/repos/o/r/git/blobs/")
+	if err := run(context.Background(), client, cfg, ioutil.Discard); err != nil {
+		t.Fatalf("second run: %v", err)
+	}
+	if n := gh.called("GET", "/repos/o/r/git/trees/") - trees; n != 0 {
+		t.Errorf("read %d trees despite an unchanged branch", n)
+	}
+	if n := gh.called("GET", "/repos/o/r/git/blobs/") - blobs; n != 0 {
+		t.Errorf("read %d blobs despite an unchanged branch", n)
+	}
+	if n := gh.called("POST", "/repos/o/r/git/"); n != 0 {
+		t.Errorf("made %d writes for a no-op edit", n)
+	}
+	if got := gh.head("o/r", "master").SHA; got != head {
+		t.Errorf("branch moved to %s", got)
+	}
+
+	// An edit of the cached content is committed on top of the cached commit.
+	cfg.Replacement = "v2"
+	if err := run(context.Background(), client, cfg, ioutil.Discard); err != nil {
+		t.Fatalf("third run: %v", err)
+	}
+	if got, _ := gh.file("o/r", "master", "config.yaml"); got != lines("image:", "  tag: v2") {
+		t.Errorf("content: got %q", got)
+	}
+	if got := gh.head("o/r", "master").Parents; len(got) != 1 || got[0] != head {
+		t.Errorf("parents: got %v, want [%s]", got, head)
+	}
+}
+
+func TestCreateFrom(t *testing.T) {
+	testData := []struct {
+		name       string
+		existing   bool
+		wantParent string // "main" or "release"
+	}{
+		{name: "existing branch", existing: true, wantParent: "release"},
+		{name: "missing branch", existing: false, wantParent: "main"},
+	}
+	for _, test := range testData {
+		t.Run(test.name, func(t *testing.T) {
+			gh, client := newFakeGitHub(t)
+			gh.push("o/r", "main", map[string]string{"config.yaml": lines("image:", "  tag: v1")})
+			heads := map[string]string{"main": gh.head("o/r", "main").SHA}
+			if test.existing {
+				heads["release"] = gh.push("o/r", "release", map[string]string{"config.yaml": lines("image:", "  tag: v0")})
+			}
+			cfg := &config{
+				GithubOwner:  "o",
+				GithubRepo:   "r",
+				GithubBranch: "release",
+				CreateFrom:   "main",
+				File:         "config.yaml",
+				Locations:    []string{"image.tag"},
+				Replacement:  "v2",
+			}
+			if err := run(context.Background(), client, cfg, ioutil.Discard); err != nil {
+				t.Fatalf("run: %v", err)
+			}
+			head := gh.head("o/r", "release")
+			if head == nil {
+				t.Fatal("branch release does not exist")
+			}
+			if want := heads[test.wantParent]; len(head.Parents) != 1 || head.Parents[0] != want {
+				t.Errorf("parents: got %v, want [%s]", head.Parents, want)
+			}
+			if got, _ := gh.file("o/r", "release", "config.yaml"); got != lines("image:", "  tag: v2") {
+				t.Errorf("content: got %q", got)
+			}
+			if got := gh.head("o/r", "main").SHA; got != heads["main"] {
+				t.Errorf("main moved to %s", got)
+			}
+		})
+	}
+}
+
+func TestCreateBranch(t *testing.T) {
+	testData := []struct {
+		name       string
+		existing   bool
+		createFrom string
+		wantParent string // "main", "develop", or "release"
+		wantErr    string
+	}{
+		{name: "existing branch", existing: true, wantParent: "release"},
+		{name: "from the default branch", wantParent: "main"},
+		{name: "from --create-from", createFrom: "develop", wantParent: "develop"},
+		{name: "missing base", createFrom: "nope", wantErr: "branch nope does not exist"},
+	}
+	for _, test := range testData {
+		t.Run(test.name, func(t *testing.T) {
+			gh, client := newFakeGitHub(t)
+			heads := map[string]string{
+				"main":    gh.push("o/r", "main", map[string]string{"config.yaml": lines("image:", "  tag: v1")}),
+				"develop": gh.push("o/r", "develop", map[string]string{"config.yaml": lines("image:", "  tag: v1.5")}),
+			}
+			if test.existing {
+				heads["release"] = gh.push("o/r", "release", map[string]string{"config.yaml": lines("image:", "  tag: v0")})
+			}
+			cfg := &config{
+				GithubOwner:  "o",
+				GithubRepo:   "r",
+				GithubBranch: "release",
+				CreateBranch: true,
+				CreateFrom:   test.createFrom,
+				File:         "config.yaml",
+				Locations:    []string{"image.tag"},
+				Replacement:  "v2",
+			}
+			err := run(context.Background(), client, cfg, ioutil.Discard)
+			if test.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+					t.Fatalf("want error containing %q, got %v", test.wantErr, err)
+				}
+				if gh.head("o/r", "release") != nil {
+					t.Error("branch release was created despite the error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("run: %v", err)
+			}
+			head := gh.head("o/r", "release")
+			if head == nil {
+				t.Fatal("branch release does not exist")
+			}
+			if want := heads[test.wantParent]; len(head.Parents) != 1 || head.Parents[0] != want {
+				t.Errorf("parents: got %v, want [%s]", head.Parents, want)
+			}
+			if got, _ := gh.file("o/r", "release", "config.yaml"); got != lines("image:", "  tag: v2") {
+				t.Errorf("content: got %q", got)
+			}
+		})
+	}
+}
+
+func TestEditMirror(t *testing.T) {
+	input := lines(
+		"metadata:",
+		"  labels:",
+		"    version: v1",
+		"image:",
+		"  tag: v1",
+		"containers:",
+		"- name: app",
+		"  image: app:v1",
+	)
+	testData := []struct {
+		name      string
+		locations []string
+		mirrors   []string
+		want      string
+		wantErr   string
+	}{
+		{
+			name:      "edited tag into label",
+			locations: []string{"image.tag"},
+			mirrors:   []string{"image.tag=metadata.labels.version"},
+			want: lines(
+				"metadata:",
+				"  labels:",
+				"    version: v2",
+				"image:",
+				"  tag: v2",
+				"containers:",
+				"- name: app",
+				"  image: app:v1",
+			),
+		},
+		{
+			name:    "match in source",
+			mirrors: []string{"containers[name=app].image=image.tag"},
+			want: lines(
+				"metadata:",
+				"  labels:",
+				"    version: v1",
+				"image:",
+				"  tag: app:v1",
+				"containers:",
+				"- name: app",
+				"  image: app:v1",
+			),
+		},
+		{
+			name:    "missing source",
+			mirrors: []string{"image.digest=metadata.labels.version"},
+			want:    input,
+		},
+		{
+			name:    "missing target",
+			mirrors: []string{"image.tag=metadata.annotations.version"},
+			want:    input,
+		},
+		{
+			name:    "non-scalar source",
+			mirrors: []string{"image=metadata.labels.version"},
+			wantErr: "mirror source image",
+		},
+	}
+	for _, test := range testData {
+		t.Run(test.name, func(t *testing.T) {
+			mirrors, err := parseMirrors(test.mirrors)
+			if err != nil {
+				t.Fatalf("parse mirrors: %v", err)
+			}
+			got, _, err := editYAML(input, editsFor(test.locations, "v2"), editOptions{Mirrors: mirrors})
+			if test.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+					t.Fatalf("want error containing %q, got %v", test.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("edit: %v", err)
+			}
+			if diff := cmp.Diff(got, test.want); diff != "" {
+				t.Errorf("output:\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestParseMirrors(t *testing.T) {
+	got, err := parseMirrors([]string{"a.b=c.d", "x[name=y].z=w"})
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	want := []mirror{{Source: "a.b", Target: "c.d"}, {Source: "x[name=y].z", Target: "w"}}
+	if diff := cmp.Diff(got, want); diff != "" {
+		t.Errorf("mirrors:\n%s", diff)
+	}
+	for _, bad := range []string{"a.b", "=c", "a=", "x[name=y]"} {
+		if _, err := parseMirrors([]string{bad}); err == nil {
+			t.Errorf("expected error parsing %q", bad)
+		}
+	}
+}
+
+func TestFailIfUnchanged(t *testing.T) {
+	testData := []struct {
+		name        string
+		replacement string
+		wantErr     bool
+	}{
+		{name: "changed", replacement: "v2"},
+		{name: "identical value", replacement: "v1", wantErr: true},
+	}
+	for _, test := range testData {
+		t.Run(test.name, func(t *testing.T) {
+			gh, client := newFakeGitHub(t)
+			before := gh.push("o/r", "master", map[string]string{"config.yaml": lines("image:", "  tag: v1")})
+			cfg := &config{
+				GithubOwner:   "o",
+				GithubRepo:    "r",
+				GithubBranch:  "master",
+				File:          "config.yaml",
+				Locations:     []string{"image.tag"},
+				Replacement:   test.replacement,
+				FailUnchanged: true,
+			}
+			err := run(context.Background(), client, cfg, ioutil.Discard)
+			if !test.wantErr {
+				if err != nil {
+					t.Fatalf("run: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), "--fail-if-unchanged") {
+				t.Errorf("want an error mentioning --fail-if-unchanged, got %v", err)
+			}
+			if after := gh.head("o/r", "master").SHA; after != before {
+				t.Errorf("branch moved from %s to %s", before, after)
+			}
+		})
+	}
+}
+
+func TestSkipNoOp(t *testing.T) {
+	testData := []struct {
+		name        string
+		replacement string
+		allowEmpty  bool
+		wantCommit  bool
+	}{
+		{name: "changed", replacement: "v2", wantCommit: true},
+		{name: "no-op", replacement: "v1"},
+		{name: "no-op with --allow-empty", replacement: "v1", allowEmpty: true, wantCommit: true},
+	}
+	for _, test := range testData {
+		t.Run(test.name, func(t *testing.T) {
+			gh, client := newFakeGitHub(t)
+			before := gh.push("o/r", "master", map[string]string{"config.yaml": lines("image:", "  tag: v1")})
+			cfg := &config{
+				GithubOwner:  "o",
+				GithubRepo:   "r",
+				GithubBranch: "master",
+				File:         "config.yaml",
+				Locations:    []string{"image.tag"},
+				Replacement:  test.replacement,
+				AllowEmpty:   test.allowEmpty,
+			}
+			if err := run(context.Background(), client, cfg, ioutil.Discard); err != nil {
+				t.Fatalf("run: %v", err)
+			}
+			if moved := gh.head("o/r", "master").SHA != before; moved != test.wantCommit {
+				t.Errorf("committed: got %v, want %v", moved, test.wantCommit)
+			}
+			if !test.wantCommit {
+				for _, path := range []string{"/repos/o/r/git/blobs", "/repos/o/r/git/trees", "/repos/o/r/git/commits"} {
+					if n := gh.called("POST", path); n != 0 {
+						t.Errorf("POST %s called %d times for a no-op", path, n)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestStateFileAnchors(t *testing.T) {
+	gh, client := newFakeGitHub(t)
+	gh.push("o/r", "master", map[string]string{"pod.yaml": lines(
+		"spec:",
+		"  containers:",
+		"  - name: app",
+		"    image: app:v1",
+		"  - name: sidecar",
+		"    image: sidecar:v1",
+	)})
+	cfg := &config{
+		GithubOwner:  "o",
+		GithubRepo:   "r",
+		GithubBranch: "master",
+		File:         "pod.yaml",
+		Locations:    []string{"spec.containers[1].image"},
+		Replacement:  "sidecar:v2",
+		StateFile:    filepath.Join(t.TempDir(), "state.json"),
+	}
+	if err := run(context.Background(), client, cfg, ioutil.Discard); err != nil {
+		t.Fatalf("first run: %v", err)
+	}
+	st, err := loadState(cfg.StateFile)
+	if err != nil {
+		t.Fatalf("load state: %v", err)
+	}
+	if diff := cmp.Diff(st.Anchors, anchors{"1:spec.containers[1]": "[name=sidecar]"}); diff != "" {
+		t.Errorf("anchors:\n%s", diff)
+	}
+
+	// Someone reorders the containers; index 1 is now the app.
+	gh.push("o/r", "master", map[string]string{"pod.yaml": lines(
+		"spec:",
+		"  containers:",
+		"  - name: sidecar",
+		"    image: sidecar:v2",
+		"  - name: app",
+		"    image: app:v1",
+	)})
+	cfg.Replacement = "sidecar:v3"
+	if err := run(context.Background(), client, cfg, ioutil.Discard); err != nil {
+		t.Fatalf("second run: %v", err)
+	}
+	want := lines(
+		"spec:",
+		"  containers:",
+		"  - name: sidecar",
+		"    image: sidecar:v3",
+		"  - name: app",
+		"    image: app:v1",
+	)
+	if got, _ := gh.file("o/r", "master", "pod.yaml"); got != want {
+		t.Errorf("content after reorder:\n%s", cmp.Diff(got, want))
+	}
+}
+
+func TestEditFirstMatchOnly(t *testing.T) {
+	input := lines(
+		"# examples follow the real thing",
+		"kind: Config",
+		"metadata:",
+		"  name: none",
+		"---",
+		"image:",
+		"  tag: v1",
+		"---",
+		"image:",
+		"  tag: v0 # example",
+		"pull: Always",
+		"---",
+		"pull: Never",
+	)
+	var trace strings.Builder
+	got, _, err := editYAML(input, editsFor([]string{"image.tag", "pull"}, "v2"), editOptions{FirstMatchOnly: true, Trace: &trace})
+	if err != nil {
+		t.Fatalf("edit: %v", err)
+	}
+	want := lines(
+		"# examples follow the real thing",
+		"kind: Config",
+		"metadata:",
+		"  name: none",
+		"---",
+		"image:",
+		"  tag: v2",
+		"---",
+		"image:",
+		"  tag: v0 # example",
+		"pull: v2",
+		"---",
+		"pull: Never",
+	)
+	if diff := cmp.Diff(got, want); diff != "" {
+		t.Errorf("output:\n%s", diff)
+	}
+	wantTrace := lines(
+		`document 1: image.tag: not found; skipped`,
+		`document 1: pull: not found; skipped`,
+		`document 2: image.tag (line 2): "v1" -> "v2"`,
+		`document 2: pull: not found; skipped`,
+		`document 3: image.tag: already edited in document 2; skipped`,
+		`document 3: pull (line 3): "Always" -> "v2"`,
+		`document 4: image.tag: already edited in document 2; skipped`,
+		`document 4: pull: already edited in document 3; skipped`,
+	)
+	if diff := cmp.Diff(trace.String(), wantTrace); diff != "" {
+		t.Errorf("trace:\n%s", diff)
+	}
+
+	// Only the first match of a wildcard that is there is edited, too.
+	got, _, err = editYAML(lines("c:", "- name: a", "- tag: a", "- tag: b"), editsFor([]string{"c[*].tag"}, "v2"), editOptions{FirstMatchOnly: true})
+	if err != nil {
+		t.Fatalf("edit wildcard: %v", err)
+	}
+	if diff := cmp.Diff(got, lines("c:", "- name: a", "- tag: v2", "- tag: b")); diff != "" {
+		t.Errorf("wildcard output:\n%s", diff)
+	}
+}
+
+func TestReplacementIsLocation(t *testing.T) {
+	testData := []struct {
+		name        string
+		replacement string
+		pathPrefix  string
+		want        string
+		wantErr     bool
+	}{
+		{name: "other file", replacement: "images.yaml:app.tag", want: lines("image:", "  tag: v7", "version: v1")},
+		{name: "other file with path prefix", replacement: "images.yaml:app.tag", pathPrefix: "deploy", want: lines("image:", "  tag: v7", "version: v1")},
+		{name: "nested file", replacement: "deploy/staging.yaml:image.tag", want: lines("image:", "  tag: v3", "version: v1")},
+		{name: "same file", replacement: "version", want: lines("image:", "  tag: v1", "version: v1")},
+		{name: "missing file", replacement: "nope.yaml:app.tag", wantErr: true},
+		{name: "missing location", replacement: "images.yaml:app.digest", wantErr: true},
+	}
+	for _, test := range testData {
+		t.Run(test.name, func(t *testing.T) {
+			gh, client := newFakeGitHub(t)
+			before := gh.push("o/r", "master", map[string]string{
+				"images.yaml":         lines("app:", "  tag: v7"),
+				"deploy/prod.yaml":    lines("image:", "  tag: v0", "version: v1"),
+				"deploy/staging.yaml": lines("image:", "  tag: v3"),
+			})
+			cfg := &config{
+				GithubOwner:  "o",
+				GithubRepo:   "r",
+				GithubBranch: "master",
+				File:         "deploy/prod.yaml",
+				PathPrefix:   test.pathPrefix,
+				Locations:    []string{"image.tag"},
+				Replacement:  test.replacement,
+				FromLocation: true,
+			}
+			err := run(context.Background(), client, cfg, ioutil.Discard)
+			if test.wantErr {
+				if err == nil {
+					t.Error("expected error")
+				}
+				if after := gh.head("o/r", "master").SHA; after != before {
+					t.Errorf("branch moved from %s to %s", before, after)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("run: %v", err)
+			}
+			head := gh.head("o/r", "master")
+			if len(head.Parents) != 1 || head.Parents[0] != before {
+				t.Errorf("want one commit on top of %s, got parents %v", before, head.Parents)
+			}
+			if got, _ := gh.file("o/r", "master", "deploy/prod.yaml"); got != test.want {
+				t.Errorf("content: got %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestEditQuoting(t *testing.T) {
+	testData := []struct {
+		name        string
+		input       string
+		replacement string
+		want        string
+		wantValue   interface{}
+	}{
+		{name: "mapping indicator", input: "a: x", replacement: "foo: bar", want: "a: 'foo: bar'", wantValue: "foo: bar"},
+		{name: "alias indicator", input: "a: x", replacement: "*star", want: "a: '*star'", wantValue: "*star"},
+		{name: "anchor indicator", input: "a: x", replacement: "&anchor", want: "a: '&anchor'", wantValue: "&anchor"},
+		{name: "comment", input: "a: x", replacement: "x #y", want: "a: 'x #y'", wantValue: "x #y"},
+		{name: "flow mapping", input: "a: x", replacement: "{b: c}", want: "a: '{b: c}'", wantValue: "{b: c}"},
+		{name: "boolean", input: "a: x", replacement: "true", want: "a: 'true'", wantValue: "true"},
+		{name: "number", input: "a: x", replacement: "1.20", want: "a: '1.20'", wantValue: "1.20"},
+		{name: "yaml 1.1 boolean", input: "a: x", replacement: "on", want: "a: 'on'", wantValue: "on"},
+		{name: "empty", input: "a: x", replacement: "", want: "a: ''", wantValue: ""},
+		{name: "already quoted", input: `a: "x"`, replacement: "true", want: `a: "true"`, wantValue: "true"},
+		{name: "number stays a number", input: "a: 1", replacement: "2", want: "a: 2", wantValue: 2},
+		{name: "boolean stays a boolean", input: "a: yes", replacement: "no", want: "a: no", wantValue: "no"},
+		{name: "plain string", input: "a: x", replacement: "v1.2.3", want: "a: v1.2.3", wantValue: "v1.2.3"},
+	}
+	for _, test := range testData {
+		t.Run(test.name, func(t *testing.T) {
+			got, _, err := editYAML(lines(test.input), editsFor([]string{"a"}, test.replacement), editOptions{})
+			if err != nil {
+				t.Fatalf("edit: %v", err)
+			}
+			if diff := cmp.Diff(got, lines(test.want)); diff != "" {
+				t.Errorf("output:\n%s", diff)
+			}
+			var parsed map[string]interface{}
+			if err := yaml.Unmarshal([]byte(got), &parsed); err != nil {
+				t.Fatalf("parse output: %v", err)
+			}
+			if diff := cmp.Diff(parsed["a"], test.wantValue); diff != "" {
+				t.Errorf("value read back:\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestEditPreserveStyle(t *testing.T) {
+	testData := []struct {
+		name      string
+		input     string
+		location  string
+		want      string
+		wantValue interface{}
+	}{
+		{name: "plain", input: "a: v1", want: "a: v2", wantValue: "v2"},
+		{name: "double quoted", input: `a: "v1"`, want: `a: "v2"`, wantValue: "v2"},
+		{name: "single quoted", input: "a: 'v1'", want: "a: 'v2'", wantValue: "v2"},
+		{name: "literal", input: "a: |\n  v1", want: "a: |\n  v2", wantValue: "v2\n"},
+		{name: "literal strip", input: "a: |-\n  v1", want: "a: |-\n  v2", wantValue: "v2"},
+		// yaml.v3 writes a blank line after a folded scalar that ends in a newline; it reads back
+		// the same.
+		{name: "folded", input: "a: >\n  v1", want: "a: >\n  v2\n", wantValue: "v2\n"},
+		{name: "folded strip", input: "a: >-\n  v1", want: "a: >-\n  v2", wantValue: "v2"},
+		{name: "flow sequence", input: "a: [v1]", location: "a[0]", want: "a: [v2]", wantValue: []interface{}{"v2"}},
+		{name: "explicit string tag", input: "a: !!str 1.0", want: "a: !!str v2", wantValue: "v2"},
+		{name: "custom tag", input: "a: !secret v1", want: "a: !secret v2", wantValue: "v2"},
+	}
+	for _, test := range testData {
+		t.Run(test.name, func(t *testing.T) {
+			location := test.location
+			if location == "" {
+				location = "a"
+			}
+			got, _, err := editYAML(lines(test.input), editsFor([]string{location}, "v2"), editOptions{})
+			if err != nil {
+				t.Fatalf("edit: %v", err)
+			}
+			if diff := cmp.Diff(got, lines(test.want)); diff != "" {
+				t.Errorf("output:\n%s", diff)
+			}
+			var parsed map[string]interface{}
+			if err := yaml.Unmarshal([]byte(got), &parsed); err != nil {
+				t.Fatalf("parse output: %v", err)
+			}
+			if diff := cmp.Diff(parsed["a"], test.wantValue); diff != "" {
+				t.Errorf("value read back:\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestEditSetComment(t *testing.T) {
+	testData := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{
+			name:  "no comment",
+			input: lines("image:", "  tag: v1"),
+			want:  lines("image:", "  tag: v2 # version-bump: bumped by CI"),
+		},
+		{
+			name:  "other comment",
+			input: lines("image:", "  tag: v1 # pinned for #123"),
+			want:  lines("image:", "  tag: v2 # pinned for #123 # version-bump: bumped by CI"),
+		},
+		{
+			name:  "previous comment",
+			input: lines("image:", "  tag: v1 # version-bump: bumped to v1"),
+			want:  lines("image:", "  tag: v2 # version-bump: bumped by CI"),
+		},
+		{
+			name:  "previous comment after another",
+			input: lines("image:", "  # the app", "  tag: v1 # pinned # version-bump: bumped to v1"),
+			want:  lines("image:", "  # the app", "  tag: v2 # pinned # version-bump: bumped by CI"),
+		},
+	}
+	for _, test := range testData {
+		t.Run(test.name, func(t *testing.T) {
+			opts := editOptions{Comment: "bumped by CI"}
+			got, _, err := editYAML(test.input, editsFor([]string{"image.tag"}, "v2"), opts)
+			if err != nil {
+				t.Fatalf("edit: %v", err)
+			}
+			if diff := cmp.Diff(test.want, got); diff != "" {
+				t.Errorf("output (-want +got):\n%s", diff)
+			}
+			again, _, err := editYAML(got, editsFor([]string{"image.tag"}, "v2"), opts)
+			if err != nil {
+				t.Fatalf("edit again: %v", err)
+			}
+			if diff := cmp.Diff(test.want, again); diff != "" {
+				t.Errorf("output of editing again (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestBackupBranch(t *testing.T) {
+	testData := []struct {
+		name    string
+		exists  bool
+		force   bool
+		backup  string
+		wantErr bool
+	}{
+		{name: "new", backup: "backup"},
+		{name: "exists", backup: "backup", exists: true, wantErr: true},
+		{name: "exists, forced", backup: "backup", exists: true, force: true},
+		{name: "same as branch", backup: "master", wantErr: true},
+	}
+	for _, test := range testData {
+		t.Run(test.name, func(t *testing.T) {
+			gh, client := newFakeGitHub(t)
+			var oldBackup string
+			if test.exists {
+				oldBackup = gh.push("o/r", "backup", map[string]string{"old.txt": "old\n"})
+			}
+			before := gh.push("o/r", "master", map[string]string{"config.yaml": lines("image:", "  tag: v1")})
+			cfg := &config{
+				GithubOwner:  "o",
+				GithubRepo:   "r",
+				GithubBranch: "master",
+				File:         "config.yaml",
+				Locations:    []string{"image.tag"},
+				Replacement:  "v2",
+				BackupBranch: test.backup,
+				Force:        test.force,
+			}
+			err := run(context.Background(), client, cfg, ioutil.Discard)
+			if test.wantErr {
+				if err == nil {
+					t.Error("expected error")
+				}
+				if after := gh.head("o/r", "master").SHA; after != before {
+					t.Errorf("branch moved from %s to %s", before, after)
+				}
+				if test.exists {
+					if got := gh.head("o/r", "backup").SHA; got != oldBackup {
+						t.Errorf("backup branch moved from %s to %s", oldBackup, got)
+					}
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("run: %v", err)
+			}
+			if got := gh.head("o/r", test.backup).SHA; got != before {
+				t.Errorf("backup branch: got %s, want the original head %s", got, before)
+			}
+			if got, _ := gh.file("o/r", "master", "config.yaml"); got != lines("image:", "  tag: v2") {
+				t.Errorf("content: got %q", got)
+			}
+		})
+	}
+}
+
+func TestTrimReplacement(t *testing.T) {
+	testData := []struct {
+		name        string
+		replacement string
+		noTrim      bool
+		want        string
+	}{
+		{name: "trailing spaces", replacement: "v2  ", want: lines("image:", "  tag: v2")},
+		{name: "surrounding whitespace", replacement: "\t v2\n", want: lines("image:", "  tag: v2")},
+		{name: "kept", replacement: " v2  ", noTrim: true, want: lines("image:", "  tag: ' v2  '")},
+	}
+	for _, test := range testData {
+		t.Run(test.name, func(t *testing.T) {
+			gh, client := newFakeGitHub(t)
+			gh.push("o/r", "master", map[string]string{"config.yaml": lines("image:", "  tag: v1")})
+			cfg := &config{
+				GithubOwner:  "o",
+				GithubRepo:   "r",
+				GithubBranch: "master",
+				File:         "config.yaml",
+				Locations:    []string{"image.tag"},
+				Replacement:  test.replacement,
+				NoTrim:       test.noTrim,
+			}
+			if err := run(context.Background(), client, cfg, ioutil.Discard); err != nil {
+				t.Fatalf("run: %v", err)
+			}
+			got, _ := gh.file("o/r", "master", "config.yaml")
+			if diff := cmp.Diff(got, test.want); diff != "" {
+				t.Errorf("content:\n%s", diff)
+			}
+			if cfg.Replacement != test.replacement {
+				t.Errorf("run modified its config: replacement is now %q", cfg.Replacement)
+			}
+		})
+	}
+}
+
+func TestPairReplacements(t *testing.T) {
+	testData := []struct {
+		name         string
+		locations    []string
+		replacements []string
+		upstream     string
+		want         string
+		wantErr      bool
+	}{
+		{
+			name:         "one for every location",
+			locations:    []string{"image.tag", "sidecar.tag"},
+			replacements: []string{"v2"},
+			want:         lines("image:", "  tag: v2", "sidecar:", "  tag: v2"),
+		},
+		{
+			name:         "one per location",
+			locations:    []string{"image.tag", "sidecar.tag"},
+			replacements: []string{"v2", " 1.5 "},
+			want:         lines("image:", "  tag: v2", "sidecar:", "  tag: '1.5'"),
+		},
+		{
+			name:         "too few",
+			locations:    []string{"image.tag", "sidecar.tag", "other"},
+			replacements: []string{"v2", "1.5"},
+			wantErr:      true,
+		},
+		{
+			name:         "too many",
+			locations:    []string{"image.tag"},
+			replacements: []string{"v2", "1.5"},
+			wantErr:      true,
+		},
+		{
+			name:         "one per location from upstream",
+			locations:    []string{"image.tag", "sidecar.tag"},
+			replacements: []string{"v2", "1.5"},
+			upstream:     "o/upstream",
+			wantErr:      true,
+		},
+	}
+	for _, test := range testData {
+		t.Run(test.name, func(t *testing.T) {
+			gh, client := newFakeGitHub(t)
+			before := gh.push("o/r", "master", map[string]string{"config.yaml": lines("image:", "  tag: v1", "sidecar:", "  tag: v1")})
+			cfg := &config{
+				GithubOwner:  "o",
+				GithubRepo:   "r",
+				GithubBranch: "master",
+				File:         "config.yaml",
+				Locations:    test.locations,
+				Replacements: test.replacements,
+				Upstream:     test.upstream,
+			}
+			err := run(context.Background(), client, cfg, ioutil.Discard)
+			if test.wantErr {
+				if err == nil {
+					t.Error("expected error")
+				}
+				if after := gh.head("o/r", "master").SHA; after != before {
+					t.Errorf("branch moved from %s to %s", before, after)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("run: %v", err)
+			}
+			got, _ := gh.file("o/r", "master", "config.yaml")
+			if diff := cmp.Diff(got, test.want); diff != "" {
+				t.Errorf("content:\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestParseSet(t *testing.T) {
+	testData := []struct {
+		set     string
+		want    locationEdit
+		wantErr bool
+	}{
+		{set: "spec.a.tag=v2", want: locationEdit{Path: "spec.a.tag", Value: "v2"}},
+		{set: "spec.containers[name=app].image=app:v2", want: locationEdit{Path: "spec.containers[name=app].image", Value: "app:v2"}},
+		{set: "data.args=--level=debug", want: locationEdit{Path: "data.args", Value: "--level=debug"}},
+		{set: "spec.a.tag=", want: locationEdit{Path: "spec.a.tag", Value: ""}},
+		{set: "spec.a.tag", wantErr: true},
+		{set: "=v2", wantErr: true},
+		{set: "hosts[name=a].port", wantErr: true},
+	}
+	for _, test := range testData {
+		t.Run(test.set, func(t *testing.T) {
+			got, err := parseSet(test.set)
+			if test.wantErr {
+				if err == nil {
+					t.Errorf("expected error, got %+v", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parse: %v", err)
+			}
+			if diff := cmp.Diff(test.want, got); diff != "" {
+				t.Errorf("edit (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestSet(t *testing.T) {
+	input := lines("spec:", "  a:", "    tag: v1", "  b:", "    tag: v1", "  c:", "    tag: v1")
+	testData := []struct {
+		name        string
+		locations   []string
+		replacement string
+		sets        []string
+		want        string
+	}{
+		{
+			name: "set only",
+			sets: []string{"spec.a.tag=v2", "spec.b.tag= v3 "},
+			want: lines("spec:", "  a:", "    tag: v2", "  b:", "    tag: v3", "  c:", "    tag: v1"),
+		},
+		{
+			name:        "location and replacement",
+			locations:   []string{"spec.a.tag", "spec.c.tag"},
+			replacement: "v2",
+			want:        lines("spec:", "  a:", "    tag: v2", "  b:", "    tag: v1", "  c:", "    tag: v2"),
+		},
+		{
+			name:        "both",
+			locations:   []string{"spec.a.tag"},
+			replacement: "v2",
+			sets:        []string{"spec.b.tag=v3"},
+			want:        lines("spec:", "  a:", "    tag: v2", "  b:", "    tag: v3", "  c:", "    tag: v1"),
+		},
+	}
+	for _, test := range testData {
+		t.Run(test.name, func(t *testing.T) {
+			gh, client := newFakeGitHub(t)
+			gh.push("o/r", "master", map[string]string{"config.yaml": input})
+			cfg := &config{
+				GithubOwner:  "o",
+				GithubRepo:   "r",
+				GithubBranch: "master",
+				File:         "config.yaml",
+				Locations:    test.locations,
+				Replacement:  test.replacement,
+				Sets:         test.sets,
+			}
+			if err := run(context.Background(), client, cfg, ioutil.Discard); err != nil {
+				t.Fatalf("run: %v", err)
+			}
+			got, _ := gh.file("o/r", "master", "config.yaml")
+			if diff := cmp.Diff(test.want, got); diff != "" {
+				t.Errorf("content (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestRequireMatch(t *testing.T) {
+	testData := []struct {
+		name         string
+		locations    []string
+		renames      []string
+		mirrors      []string
+		requireMatch bool
+		wantErr      string
+		wantWarning  string
+	}{
+		{name: "all match", locations: []string{"image.tag", "spec.containers[*].image"}, requireMatch: true},
+		{name: "rename matches", locations: []string{"image.tag"}, renames: []string{"image.tag=version"}, requireMatch: true},
+		{name: "rename typo fails", locations: []string{"image.tag"}, renames: []string{"image.tga=version"}, requireMatch: true, wantErr: "no match in config.yaml for image.tga"},
+		{name: "typo warns", locations: []string{"image.tga"}, wantWarning: "no match in config.yaml for image.tga"},
+		{name: "typo fails", locations: []string{"image.tag", "image.tga"}, requireMatch: true, wantErr: "no match in config.yaml for image.tga"},
+		{name: "mirror matches", locations: []string{"image.tag"}, mirrors: []string{"image.tag=spec.containers[0].image"}, requireMatch: true},
+		{name: "mirror source typo fails", locations: []string{"image.tag"}, mirrors: []string{"image.tga=spec.containers[0].image"}, requireMatch: true, wantErr: "no match in config.yaml for image.tga=spec.containers[0].image"},
+		{name: "wildcard matching nothing", locations: []string{"spec.sidecars[*].image"}, requireMatch: true, wantErr: "no match in config.yaml for spec.sidecars[*].image"},
+	}
+	for _, test := range testData {
+		t.Run(test.name, func(t *testing.T) {
+			gh, client := newFakeGitHub(t)
+			before := gh.push("o/r", "master", map[string]string{"config.yaml": lines(
+				"image:",
+				"  tag: v1",
+				"spec:",
+				"  containers:",
+				"  - image: app:v1",
+				"  sidecars: []",
+			)})
+			cfg := &config{
+				GithubOwner:  "o",
+				GithubRepo:   "r",
+				GithubBranch: "master",
+				File:         "config.yaml",
+				Locations:    test.locations,
+				RenameKeys:   test.renames,
+				Mirrors:      test.mirrors,
+				Replacement:  "v2",
+				RequireMatch: test.requireMatch,
+			}
+			var logs strings.Builder
+			log.SetOutput(&logs)
+			defer log.SetOutput(os.Stderr)
+			err := run(context.Background(), client, cfg, ioutil.Discard)
+			if test.wantWarning != "" && !strings.Contains(logs.String(), "warning: "+test.wantWarning) {
+				t.Errorf("want a warning containing %q, got logs:\n%s", test.wantWarning, logs.String())
+			}
+			if test.wantErr == "" {
+				if err != nil {
+					t.Fatalf("run: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+				t.Fatalf("want an error containing %q, got %v", test.wantErr, err)
+			}
+			if after := gh.head("o/r", "master").SHA; after != before {
+				t.Errorf("branch moved from %s to %s", before, after)
+			}
+		})
+	}
+}
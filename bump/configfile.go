@@ -0,0 +1,109 @@
+package bump
+
+import (
+	"fmt"
+	"io/ioutil"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/jessevdk/go-flags"
+	"sigs.k8s.io/kustomize/kyaml/yaml"
+)
+
+// newParser returns a parser for the command line that fills in auth and cfg.
+func newParser(auth *auth, cfg *config) *flags.Parser {
+	fp := flags.NewParser(nil, flags.HelpFlag|flags.PassDoubleDash)
+	if _, err := fp.AddGroup("Authentication", "", auth); err != nil {
+		panic(err)
+	}
+	if _, err := fp.AddGroup("Configuration", "", cfg); err != nil {
+		panic(err)
+	}
+	return fp
+}
+
+// configArgs reads file, a --config file, and returns it as command-line arguments.  The file
+// is a YAML or JSON mapping from the long names of flags to their values, like:
+//
+//	owner: pachyderm
+//	repo: pachyderm
+//	location:
+//	- spec.template.spec.containers[name=pachd].image
+//
+// A list gives a repeatable flag once for each item, and a boolean flag is given if it is true.
+// Flags that fp, which has already parsed the command line, found set there are left out, so
+// that the command line overrides the file.  Keys that are not flags are an error.
+func configArgs(fp *flags.Parser, file string) ([]string, error) {
+	content, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	if strings.TrimSpace(string(content)) == "" {
+		return nil, nil
+	}
+	root, err := yaml.Parse(string(content))
+	if err != nil {
+		return nil, fmt.Errorf("parse %s: %w", file, err)
+	}
+	if root.YNode().Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("%s: want a mapping from flag names to values", file)
+	}
+	var args, unknown []string
+	fields := root.YNode().Content
+	for i := 0; i+1 < len(fields); i += 2 {
+		key, value := fields[i].Value, fields[i+1]
+		opt := fp.FindOptionByLongName(key)
+		if opt == nil || key == "config" {
+			unknown = append(unknown, key)
+			continue
+		}
+		if opt.IsSet() && !opt.IsSetDefault() {
+			continue
+		}
+		kind := opt.Field().Type.Kind()
+		values := []*yaml.Node{value}
+		if value.Kind == yaml.SequenceNode {
+			if kind != reflect.Slice {
+				return nil, fmt.Errorf("%s, line %d: %s takes one value, not a list", file, value.Line, key)
+			}
+			values = value.Content
+		}
+		for _, v := range values {
+			if v.Kind != yaml.ScalarNode {
+				return nil, fmt.Errorf("%s, line %d: the value of %s must be a string, number, or boolean", file, v.Line, key)
+			}
+			if kind != reflect.Bool {
+				args = append(args, "--"+key+"="+v.Value)
+				continue
+			}
+			switch v.Value {
+			case "true":
+				args = append(args, "--"+key)
+			case "false":
+			default:
+				return nil, fmt.Errorf("%s, line %d: %s must be true or false, not %q", file, v.Line, key, v.Value)
+			}
+		}
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return nil, fmt.Errorf("%s: unknown keys, which are not flags: %s", file, strings.Join(unknown, ", "))
+	}
+	return args, nil
+}
+
+// withConfigFile parses args, which fp has parsed into a and cfg, again, with the flags from
+// cfg.ConfigFile before them.  It returns the parser that parsed them.
+func withConfigFile(fp *flags.Parser, args []string, a *auth, cfg *config) (*flags.Parser, error) {
+	fileArgs, err := configArgs(fp, cfg.ConfigFile)
+	if err != nil {
+		return nil, fmt.Errorf("config file: %w", err)
+	}
+	*a, *cfg = auth{}, config{}
+	fp = newParser(a, cfg)
+	if _, err := fp.ParseArgs(append(fileArgs, args...)); err != nil {
+		return nil, err
+	}
+	return fp, nil
+}
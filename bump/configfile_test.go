@@ -0,0 +1,84 @@
+package bump
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestConfigFile(t *testing.T) {
+	testData := []struct {
+		name    string
+		file    string
+		args    []string
+		want    config
+		wantErr string
+	}{
+		{
+			name: "yaml",
+			file: lines("owner: o", "repo: r", "location:", "- a.b", "- c.d", "dry-run: true", "force: false", "timeout: 1m"),
+			want: config{GithubOwner: "o", GithubRepo: "r", Locations: []string{"a.b", "c.d"}, DryRun: true, Timeout: time.Minute},
+		},
+		{
+			name: "json",
+			file: `{"owner": "o", "location": ["a.b"], "conflict-retries": 2}`,
+			want: config{GithubOwner: "o", Locations: []string{"a.b"}, Retries: 2, Timeout: 30 * time.Second},
+		},
+		{
+			name: "command line overrides",
+			file: lines("owner: o", "repo: r", "location:", "- a.b"),
+			args: []string{"--repo", "other", "--location", "x.y"},
+			want: config{GithubOwner: "o", GithubRepo: "other", Locations: []string{"x.y"}, Timeout: 30 * time.Second},
+		},
+		{
+			name:    "unknown keys",
+			file:    lines("owner: o", "repository: r", "locations: [a.b]"),
+			wantErr: "unknown keys, which are not flags: locations, repository",
+		},
+		{
+			name:    "list for a single flag",
+			file:    lines("owner: [o, p]"),
+			wantErr: "owner takes one value, not a list",
+		},
+		{
+			name:    "not a boolean",
+			file:    lines("dry-run: yes please"),
+			wantErr: `dry-run must be true or false, not "yes please"`,
+		},
+	}
+	for _, test := range testData {
+		t.Run(test.name, func(t *testing.T) {
+			file := filepath.Join(t.TempDir(), "bump.yaml")
+			if err := ioutil.WriteFile(file, []byte(test.file), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			args := append([]string{"--config", file}, test.args...)
+			var a auth
+			var cfg config
+			fp := newParser(&a, &cfg)
+			if _, err := fp.ParseArgs(args); err != nil {
+				t.Fatalf("parse args: %v", err)
+			}
+			_, err := withConfigFile(fp, args, &a, &cfg)
+			if test.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+					t.Errorf("want an error containing %q, got %v", test.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("with config file: %v", err)
+			}
+			test.want.ConfigFile = file
+			test.want.ObjectMerge, test.want.DryRunFormat, test.want.Output, test.want.LogFormat, test.want.LockTTL = "deep", "diff", "text", "text", 10*time.Minute
+			test.want.Provider = "github"
+			if diff := cmp.Diff(test.want, cfg); diff != "" {
+				t.Errorf("config (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,78 @@
+package bump
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// comparatorRE matches one comparator of a version range, like ">=1.2.0".
+var comparatorRE = regexp.MustCompile(`(>=|<=|>|<)\s*([^\s,]+)`)
+
+// updateConstraint replaces one bound of a simple version range, like ">=1.2.0 <2.0.0", with
+// newVersion.  bound is "lower" to replace the >= or > comparator, or "upper" to replace the <
+// or <= comparator.  Everything else in the range, including its separators, is kept as is.
+// Caret, tilde, and "||" ranges are not supported.
+func updateConstraint(constraint, bound, newVersion string) (string, error) {
+	if strings.ContainsAny(constraint, "^~|") {
+		return "", fmt.Errorf("version range %q: only >, >=, <, and <= comparators are supported", constraint)
+	}
+	var want string
+	switch bound {
+	case "lower":
+		want = ">"
+	case "upper":
+		want = "<"
+	default:
+		return "", fmt.Errorf("unknown bound %q: want lower or upper", bound)
+	}
+	newV, err := parseVersion(newVersion)
+	if err != nil {
+		return "", err
+	}
+
+	matches := comparatorRE.FindAllStringSubmatchIndex(constraint, -1)
+	if len(matches) == 0 || strings.Trim(comparatorRE.ReplaceAllString(constraint, ""), " ,") != "" {
+		return "", fmt.Errorf("version range %q: not a list of comparators", constraint)
+	}
+	target := -1
+	// other holds the other comparators, which the new bound must leave room for.
+	type comparator struct {
+		op string
+		v  version
+	}
+	var other []comparator
+	for i, m := range matches {
+		op := constraint[m[2]:m[3]]
+		v, err := parseVersion(constraint[m[4]:m[5]])
+		if err != nil {
+			return "", fmt.Errorf("version range %q: %w", constraint, err)
+		}
+		if strings.HasPrefix(op, want) {
+			if target >= 0 {
+				return "", fmt.Errorf("version range %q has more than one %s bound", constraint, bound)
+			}
+			target = i
+			continue
+		}
+		other = append(other, comparator{op, v})
+	}
+	if target < 0 {
+		return "", fmt.Errorf("version range %q has no %s bound", constraint, bound)
+	}
+	m := matches[target]
+	op := constraint[m[2]:m[3]]
+	for _, o := range other {
+		// c > 0 if the new bound is past the other one.
+		c := newV.compare(o.v)
+		if bound == "upper" {
+			c = -c
+		}
+		// Two inclusive bounds that meet still both allow the version they meet at.
+		inclusive := strings.HasSuffix(op, "=") && strings.HasSuffix(o.op, "=")
+		if c > 0 || (c == 0 && !inclusive) {
+			return "", fmt.Errorf("new %s bound %s would make version range %q empty", bound, newVersion, constraint)
+		}
+	}
+	return constraint[:m[4]] + newVersion + constraint[m[5]:], nil
+}
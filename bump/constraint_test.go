@@ -0,0 +1,68 @@
+package bump
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestUpdateConstraint(t *testing.T) {
+	testData := []struct {
+		name       string
+		constraint string
+		bound      string
+		version    string
+		want       string
+		wantErr    string
+	}{
+		{name: "lower", constraint: ">=1.2.0 <2.0.0", bound: "lower", version: "1.3.0", want: ">=1.3.0 <2.0.0"},
+		{name: "upper", constraint: ">=1.2.0 <2.0.0", bound: "upper", version: "3.0.0", want: ">=1.2.0 <3.0.0"},
+		{name: "exclusive lower", constraint: ">1.2.0, <=2.0.0", bound: "lower", version: "v1.4.1", want: ">v1.4.1, <=2.0.0"},
+		{name: "spacing preserved", constraint: ">= 1.2.0  < 2.0.0", bound: "lower", version: "1.2.1", want: ">= 1.2.1  < 2.0.0"},
+		{name: "only a lower bound", constraint: ">=1.2.0", bound: "lower", version: "1.5.0", want: ">=1.5.0"},
+		{name: "missing bound", constraint: ">=1.2.0", bound: "upper", version: "2.0.0", wantErr: "no upper bound"},
+		{name: "empty range", constraint: ">=1.2.0 <2.0.0", bound: "lower", version: "2.0.0", wantErr: "empty"},
+		{name: "inclusive bounds meet", constraint: ">=1.2.0 <=2.0.0", bound: "lower", version: "2.0.0", want: ">=2.0.0 <=2.0.0"},
+		{name: "inclusive upper bound meets", constraint: ">=2.0.0 <=3.0.0", bound: "upper", version: "2.0.0", want: ">=2.0.0 <=2.0.0"},
+		{name: "exclusive lower bound meets inclusive", constraint: ">1.2.0 <=2.0.0", bound: "lower", version: "2.0.0", wantErr: "empty"},
+		{name: "inclusive bounds cross", constraint: ">=1.2.0 <=2.0.0", bound: "lower", version: "2.0.1", wantErr: "empty"},
+		{name: "caret", constraint: "^1.2.0", bound: "lower", version: "1.3.0", wantErr: "only >, >=, <, and <="},
+		{name: "tilde", constraint: "~1.2.0", bound: "lower", version: "1.3.0", wantErr: "only >, >=, <, and <="},
+		{name: "not a range", constraint: "1.2.0", bound: "lower", version: "1.3.0", wantErr: "not a list of comparators"},
+		{name: "invalid version", constraint: ">=1.2.0 <2.0.0", bound: "lower", version: "latest", wantErr: "not a semantic version"},
+	}
+	for _, test := range testData {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := updateConstraint(test.constraint, test.bound, test.version)
+			if test.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+					t.Fatalf("want error containing %q, got %v (result %q)", test.wantErr, err, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != test.want {
+				t.Errorf("got %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestEditConstraint(t *testing.T) {
+	input := lines(
+		"dependencies:",
+		"  api: '>=1.2.0 <2.0.0'",
+	)
+	want := lines(
+		"dependencies:",
+		"  api: '>=1.4.0 <2.0.0'",
+	)
+	got, _, err := editYAML(input, editsFor([]string{"dependencies.api"}, "1.4.0"), editOptions{RangeBound: "lower"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
@@ -0,0 +1,192 @@
+package bump
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// diffContext is the number of unchanged lines shown around each change in a unified diff.
+const diffContext = 3
+
+// diffOp is one line of an edit script: kept (' '), deleted ('-'), or inserted ('+').
+type diffOp struct {
+	kind byte
+	line string
+}
+
+// unifiedDiff returns a unified diff from old to new, the content of file before and after an
+// edit, like diff -u.  It returns "" if they are the same.
+func unifiedDiff(file, old, new string) string {
+	if old == new {
+		return ""
+	}
+	a, b := splitLines(old), splitLines(new)
+	ops := diffLines(a, b)
+	var out strings.Builder
+	fmt.Fprintf(&out, "--- a/%s\n+++ b/%s\n", file, file)
+	// Each hunk runs from the first change not yet shown, with context, to the last change
+	// that is within twice the context of the one before.
+	aLine, bLine := 0, 0
+	for i := 0; i < len(ops); {
+		if ops[i].kind == ' ' {
+			aLine, bLine, i = aLine+1, bLine+1, i+1
+			continue
+		}
+		start := i - diffContext
+		if start < 0 {
+			start = 0
+		}
+		end := i
+		for j, kept := i, 0; j < len(ops) && kept <= 2*diffContext; j++ {
+			if ops[j].kind == ' ' {
+				kept++
+				continue
+			}
+			end, kept = j+1, 0
+		}
+		end += diffContext
+		if end > len(ops) {
+			end = len(ops)
+		}
+		aStart, bStart := aLine-(i-start), bLine-(i-start)
+		var aCount, bCount int
+		for _, op := range ops[start:end] {
+			if op.kind != '+' {
+				aCount++
+			}
+			if op.kind != '-' {
+				bCount++
+			}
+		}
+		fmt.Fprintf(&out, "@@ -%s +%s @@\n", hunkRange(aStart, aCount), hunkRange(bStart, bCount))
+		for _, op := range ops[start:end] {
+			out.WriteByte(op.kind)
+			if strings.HasSuffix(op.line, "\n") {
+				out.WriteString(op.line)
+			} else {
+				out.WriteString(op.line + "\n\\ No newline at end of file\n")
+			}
+		}
+		for _, op := range ops[i:end] {
+			if op.kind != '+' {
+				aLine++
+			}
+			if op.kind != '-' {
+				bLine++
+			}
+		}
+		i = end
+	}
+	return out.String()
+}
+
+// hunkRange formats the range of count lines from the 0-based line start for a hunk header.  An
+// empty range is given as the line before it, as diff does.
+func hunkRange(start, count int) string {
+	if count == 0 {
+		return fmt.Sprintf("%d,0", start)
+	}
+	if count == 1 {
+		return fmt.Sprint(start + 1)
+	}
+	return fmt.Sprintf("%d,%d", start+1, count)
+}
+
+// splitLines splits s into lines, each with its newline, except perhaps the last.
+func splitLines(s string) []string {
+	lines := strings.SplitAfter(s, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// diffLines returns the shortest edit script turning a into b, with Myers' algorithm.
+func diffLines(a, b []string) []diffOp {
+	n, m := len(a), len(b)
+	max := n + m
+	offset := max + 1
+	v := make([]int, 2*max+3)
+	// trace holds v as it was before each round d, to walk back through the edits.
+	var trace [][]int
+	for d := 0; d <= max; d++ {
+		trace = append(trace, append([]int(nil), v...))
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1]
+			} else {
+				x = v[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x, y = x+1, y+1
+			}
+			v[offset+k] = x
+			if x >= n && y >= m {
+				return backtrack(a, b, trace, d, offset)
+			}
+		}
+	}
+	panic("unreachable: the edit script is at most len(a)+len(b) long")
+}
+
+// backtrack recovers the edit script from the trace diffLines kept, ending in round d.
+func backtrack(a, b []string, trace [][]int, d, offset int) []diffOp {
+	var ops []diffOp
+	x, y := len(a), len(b)
+	for ; d > 0; d-- {
+		v := trace[d]
+		k := x - y
+		var prevK int
+		if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+			prevK = k + 1
+		} else {
+			prevK = k - 1
+		}
+		prevX := v[offset+prevK]
+		prevY := prevX - prevK
+		for x > prevX && y > prevY {
+			x, y = x-1, y-1
+			ops = append(ops, diffOp{' ', a[x]})
+		}
+		if x == prevX {
+			y--
+			ops = append(ops, diffOp{'+', b[y]})
+		} else {
+			x--
+			ops = append(ops, diffOp{'-', a[x]})
+		}
+	}
+	for x > 0 {
+		x, y = x-1, y-1
+		ops = append(ops, diffOp{' ', a[x]})
+	}
+	for i, j := 0, len(ops)-1; i < j; i, j = i+1, j-1 {
+		ops[i], ops[j] = ops[j], ops[i]
+	}
+	return ops
+}
+
+// writeDryRun writes what --dry-run shows of an edit of file from old to new to w: a unified
+// diff, or, with --dry-run-format full, the whole of new.
+func writeDryRun(w io.Writer, cfg *config, file, old, new string) {
+	if cfg.DryRunFormat == "full" {
+		fmt.Fprint(w, new)
+		return
+	}
+	fmt.Fprint(w, unifiedDiff(file, old, new))
+}
+
+// errPendingChange is returned by a dry run with --exit-code when the edit would change the file.
+var errPendingChange = errors.New("the edit would change the file")
+
+// pendingChange returns errPendingChange if --exit-code is set and new differs from old.
+func pendingChange(cfg *config, old, new string) error {
+	if cfg.ExitCode && new != old {
+		return errPendingChange
+	}
+	return nil
+}
@@ -0,0 +1,151 @@
+package bump
+
+import (
+	"context"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestUnifiedDiff(t *testing.T) {
+	testData := []struct {
+		name string
+		old  string
+		new  string
+		want string
+	}{
+		{
+			name: "unchanged",
+			old:  lines("a", "b"),
+			new:  lines("a", "b"),
+			want: "",
+		},
+		{
+			name: "middle",
+			old:  lines("1", "2", "3", "4", "5", "6", "7", "8", "9"),
+			new:  lines("1", "2", "3", "4", "five", "6", "7", "8", "9"),
+			want: lines("--- a/f.yaml", "+++ b/f.yaml", "@@ -2,7 +2,7 @@", " 2", " 3", " 4", "-5", "+five", " 6", " 7", " 8"),
+		},
+		{
+			name: "start",
+			old:  lines("1", "2", "3", "4", "5"),
+			new:  lines("one", "2", "3", "4", "5"),
+			want: lines("--- a/f.yaml", "+++ b/f.yaml", "@@ -1,4 +1,4 @@", "-1", "+one", " 2", " 3", " 4"),
+		},
+		{
+			name: "two hunks",
+			old:  lines("1", "2", "3", "4", "5", "6", "7", "8", "9", "10"),
+			new:  lines("one", "2", "3", "4", "5", "6", "7", "8", "9", "ten"),
+			want: lines("--- a/f.yaml", "+++ b/f.yaml",
+				"@@ -1,4 +1,4 @@", "-1", "+one", " 2", " 3", " 4",
+				"@@ -7,4 +7,4 @@", " 7", " 8", " 9", "-10", "+ten"),
+		},
+		{
+			name: "one hunk",
+			old:  lines("1", "2", "3", "4", "5", "6", "7", "8"),
+			new:  lines("one", "2", "3", "4", "5", "6", "7", "eight"),
+			want: lines("--- a/f.yaml", "+++ b/f.yaml", "@@ -1,8 +1,8 @@", "-1", "+one", " 2", " 3", " 4", " 5", " 6", " 7", "-8", "+eight"),
+		},
+		{
+			name: "no newline at end of file",
+			old:  "a\nb",
+			new:  "a\nc",
+			want: lines("--- a/f.yaml", "+++ b/f.yaml", "@@ -1,2 +1,2 @@", " a", "-b", `\ No newline at end of file`, "+c", `\ No newline at end of file`),
+		},
+		{
+			name: "empty file",
+			old:  "",
+			new:  lines("a"),
+			want: lines("--- a/f.yaml", "+++ b/f.yaml", "@@ -0,0 +1 @@", "+a"),
+		},
+	}
+	for _, test := range testData {
+		t.Run(test.name, func(t *testing.T) {
+			got := unifiedDiff("f.yaml", test.old, test.new)
+			if diff := cmp.Diff(test.want, got); diff != "" {
+				t.Errorf("diff (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestDryRunFormat(t *testing.T) {
+	testData := []struct {
+		format string
+		want   string
+	}{
+		{format: "diff", want: lines("--- a/test.yaml", "+++ b/test.yaml", "@@ -1,2 +1,2 @@", " image:", "-  tag: v1", "+  tag: v2")},
+		{format: "full", want: lines("image:", "  tag: v2")},
+	}
+	for _, test := range testData {
+		t.Run(test.format, func(t *testing.T) {
+			gh, client := newFakeGitHub(t)
+			gh.push("o/r", "master", map[string]string{"test.yaml": lines("image:", "  tag: v1")})
+			head := gh.head("o/r", "master").SHA
+			cfg := &config{
+				GithubOwner:  "o",
+				GithubRepo:   "r",
+				GithubBranch: "master",
+				File:         "test.yaml",
+				Locations:    []string{"image.tag"},
+				Replacement:  "v2",
+				DryRun:       true,
+				DryRunFormat: test.format,
+			}
+			var stdout strings.Builder
+			if err := run(context.Background(), client, cfg, &stdout); err != nil {
+				t.Fatalf("run: %v", err)
+			}
+			if diff := cmp.Diff(test.want, stdout.String()); diff != "" {
+				t.Errorf("stdout (-want +got):\n%s", diff)
+			}
+			if got := gh.head("o/r", "master").SHA; got != head {
+				t.Errorf("dry run committed %s", got)
+			}
+		})
+	}
+}
+
+func TestExitCode(t *testing.T) {
+	testData := []struct {
+		name        string
+		replacement string
+		exitCode    bool
+		want        int
+	}{
+		{name: "pending", replacement: "v2", exitCode: true, want: 5},
+		{name: "up to date", replacement: "v1", exitCode: true, want: 0},
+		{name: "without --exit-code", replacement: "v2", want: 0},
+	}
+	for _, test := range testData {
+		t.Run(test.name, func(t *testing.T) {
+			gh, client := newFakeGitHub(t)
+			gh.push("o/r", "master", map[string]string{"test.yaml": lines("image:", "  tag: v1")})
+			cfg := &config{
+				GithubOwner:  "o",
+				GithubRepo:   "r",
+				GithubBranch: "master",
+				File:         "test.yaml",
+				Locations:    []string{"image.tag"},
+				Replacement:  test.replacement,
+				DryRun:       true,
+				ExitCode:     test.exitCode,
+				Output:       "json",
+			}
+			err := run(context.Background(), client, cfg, ioutil.Discard)
+			got := 0
+			var stderr strings.Builder
+			if err != nil {
+				got = fail(&stderr, cfg, err)
+			}
+			if got != test.want {
+				t.Errorf("exit code: got %d (%v), want %d", got, err, test.want)
+			}
+			if stderr.Len() != 0 {
+				t.Errorf("a pending change is not a failure, but got %q", stderr.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,109 @@
+package bump
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// dotenvAssignment matches a KEY=value line of a dotenv file, capturing everything before the
+// key, the key, the = and the space around it, and the value with anything after it.
+var dotenvAssignment = regexp.MustCompile(`^(\s*(?:export\s+)?)([A-Za-z_][A-Za-z0-9_.]*)(\s*=\s*)(.*)$`)
+
+// editDotenv is like editYAML, for a dotenv file of KEY=value lines, or a Java properties file
+// written the same way.  Each location is the name of a key, taken literally, and its value is
+// replaced on every line that sets it, or only the first with FirstMatchOnly.  New values are
+// quoted like the old ones were, single or double, or left unquoted if they were and can be.
+// Comments, blank lines, and the order of keys are left alone.
+func editDotenv(input string, edits []locationEdit, opts editOptions) (string, []change, error) {
+	if len(opts.MatchLabels) > 0 || opts.RangeBound != "" || opts.Scale != 0 || opts.Base64 || opts.Object != nil || opts.Comment != "" || len(opts.Mirrors) > 0 || len(opts.Renames) > 0 {
+		return "", nil, fmt.Errorf("dotenv files only support replacing values")
+	}
+	lines := strings.Split(input, "\n")
+	var changes []change
+	for _, e := range edits {
+		found := false
+		for i, line := range lines {
+			if found && opts.FirstMatchOnly {
+				break
+			}
+			cr := strings.HasSuffix(line, "\r")
+			m := dotenvAssignment.FindStringSubmatch(strings.TrimSuffix(line, "\r"))
+			if m == nil || m[2] != e.Path {
+				continue
+			}
+			old, quote, rest, err := parseDotenvValue(m[4])
+			if err != nil {
+				return "", nil, fmt.Errorf("line %d: %w", i+1, err)
+			}
+			found = true
+			lines[i] = m[1] + m[2] + m[3] + quoteDotenv(e.Value, quote) + rest
+			if cr {
+				lines[i] += "\r"
+			}
+			changes = append(changes, change{Location: e.Path, Found: true, Document: 1, Line: i + 1, Old: old, New: e.Value})
+		}
+		if !found {
+			changes = append(changes, change{Location: e.Path})
+		}
+	}
+	return strings.Join(lines, "\n"), changes, nil
+}
+
+// parseDotenvValue splits s, what follows the = of an assignment, into the value, the quote it
+// is in, if any, and the rest of the line, like a comment.
+func parseDotenvValue(s string) (value string, quote byte, rest string, err error) {
+	if s == "" {
+		return "", 0, "", nil
+	}
+	switch q := s[0]; q {
+	case '\'':
+		end := strings.IndexByte(s[1:], '\'')
+		if end < 0 {
+			return "", 0, "", fmt.Errorf("unterminated quote in %s", s)
+		}
+		return s[1 : end+1], q, s[end+2:], nil
+	case '"':
+		var b strings.Builder
+		for i := 1; i < len(s); i++ {
+			switch c := s[i]; {
+			case c == '"':
+				return b.String(), q, s[i+1:], nil
+			case c == '\\' && i+1 < len(s):
+				i++
+				if s[i] == 'n' {
+					b.WriteByte('\n')
+				} else {
+					b.WriteByte(s[i])
+				}
+			default:
+				b.WriteByte(c)
+			}
+		}
+		return "", 0, "", fmt.Errorf("unterminated quote in %s", s)
+	}
+	// An unquoted value ends at a comment, which must follow whitespace.
+	end := len(s)
+	if i := strings.Index(s, " #"); i >= 0 {
+		end = i
+	}
+	if i := strings.Index(s, "\t#"); i >= 0 && i < end {
+		end = i
+	}
+	value = strings.TrimRight(s[:end], " \t")
+	return value, 0, s[len(value):], nil
+}
+
+// quoteDotenv returns value as it should be written in a dotenv file, in quote if it is not
+// zero.  Unquoted values with whitespace, quotes, comments, or line breaks in them are
+// double-quoted, and single-quoted values that can't be are double-quoted instead.
+func quoteDotenv(value string, quote byte) string {
+	switch {
+	case quote == 0 && !strings.ContainsAny(value, " \t\r\n\"'#\\"):
+		return value
+	case quote == '\'' && !strings.ContainsAny(value, "'\r\n"):
+		return "'" + value + "'"
+	}
+	r := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+	return `"` + r.Replace(value) + `"`
+}
@@ -0,0 +1,119 @@
+package bump
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestEditDotenv(t *testing.T) {
+	testData := []struct {
+		name        string
+		input       string
+		location    string
+		replacement string
+		firstOnly   bool
+		want        string
+		wantChanges []change
+	}{
+		{
+			name:        "unquoted, keeping comments and blank lines",
+			input:       lines("# Pins", "IMAGE_TAG=v1 # bumped by CI", "", "OTHER=x"),
+			location:    "IMAGE_TAG",
+			replacement: "v2",
+			want:        lines("# Pins", "IMAGE_TAG=v2 # bumped by CI", "", "OTHER=x"),
+			wantChanges: []change{{Location: "IMAGE_TAG", Found: true, Document: 1, Line: 2, Old: "v1", New: "v2"}},
+		},
+		{
+			name:        "export and spaces",
+			input:       lines("export IMAGE_TAG = v1"),
+			location:    "IMAGE_TAG",
+			replacement: "v2",
+			want:        lines("export IMAGE_TAG = v2"),
+		},
+		{
+			name:        "double quoted",
+			input:       lines(`IMAGE_TAG="v1" # pinned`),
+			location:    "IMAGE_TAG",
+			replacement: `say "hi"`,
+			want:        lines(`IMAGE_TAG="say \"hi\"" # pinned`),
+		},
+		{
+			name:        "single quoted",
+			input:       lines("IMAGE_TAG='v1'"),
+			location:    "IMAGE_TAG",
+			replacement: "v2",
+			want:        lines("IMAGE_TAG='v2'"),
+		},
+		{
+			name:        "unquoted value that needs quotes",
+			input:       lines("MOTD=hello"),
+			location:    "MOTD",
+			replacement: "hello world",
+			want:        lines(`MOTD="hello world"`),
+		},
+		{
+			name:        "properties key",
+			input:       lines("app.version=1.0", "app.name=x"),
+			location:    "app.version",
+			replacement: "1.1",
+			want:        lines("app.version=1.1", "app.name=x"),
+		},
+		{
+			name:        "every occurrence",
+			input:       lines("TAG=v1", "TAG=v1"),
+			location:    "TAG",
+			replacement: "v2",
+			want:        lines("TAG=v2", "TAG=v2"),
+		},
+		{
+			name:        "first occurrence",
+			input:       lines("TAG=v1", "TAG=v1"),
+			location:    "TAG",
+			replacement: "v2",
+			firstOnly:   true,
+			want:        lines("TAG=v2", "TAG=v1"),
+		},
+		{
+			name:        "CRLF",
+			input:       "TAG=v1\r\nOTHER=x\r\n",
+			location:    "TAG",
+			replacement: "v2",
+			want:        "TAG=v2\r\nOTHER=x\r\n",
+		},
+		{
+			name:        "missing key leaves the file alone",
+			input:       lines("TAG_SUFFIX=v1"),
+			location:    "TAG",
+			replacement: "v2",
+			want:        lines("TAG_SUFFIX=v1"),
+			wantChanges: []change{{Location: "TAG"}},
+		},
+	}
+	for _, test := range testData {
+		t.Run(test.name, func(t *testing.T) {
+			got, changes, err := editDotenv(test.input, editsFor([]string{test.location}, test.replacement), editOptions{FirstMatchOnly: test.firstOnly})
+			if err != nil {
+				t.Fatalf("edit: %v", err)
+			}
+			if diff := cmp.Diff(test.want, got); diff != "" {
+				t.Errorf("output (-want +got):\n%s", diff)
+			}
+			if test.wantChanges != nil {
+				if diff := cmp.Diff(test.wantChanges, changes); diff != "" {
+					t.Errorf("changes (-want +got):\n%s", diff)
+				}
+			}
+		})
+	}
+}
+
+func TestEditDotenvErrors(t *testing.T) {
+	if _, _, err := editDotenv(lines(`TAG="v1`), editsFor([]string{"TAG"}, "v2"), editOptions{}); err == nil || !strings.Contains(err.Error(), "line 1: unterminated quote") {
+		t.Errorf("want an unterminated quote error, got %v", err)
+	}
+	if _, _, err := editDotenv(lines("TAG=v1"), editsFor([]string{"TAG"}, "v2"), editOptions{Base64: true}); err == nil || !strings.Contains(err.Error(), "only support replacing values") {
+		t.Errorf("want an unsupported option error, got %v", err)
+	}
+}
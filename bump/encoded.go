@@ -0,0 +1,105 @@
+package bump
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// innerEdit describes an edit to content that is encoded inside a value, like a config file in
+// a Secret.  Exactly one of Location and Pattern is set.
+type innerEdit struct {
+	// Location is a location in the decoded content, which is parsed as YAML.
+	Location string
+	// Pattern is a regular expression with at least one group; the first group of each
+	// match is replaced.  The decoded content is otherwise left exactly as it was.
+	Pattern *regexp.Regexp
+}
+
+func (e innerEdit) String() string {
+	if e.Pattern != nil {
+		return fmt.Sprintf("pattern %q", e.Pattern)
+	}
+	return fmt.Sprintf("location %q", e.Location)
+}
+
+// newInnerEdit validates and compiles the inner edit flags.
+func newInnerEdit(location, pattern string) (innerEdit, error) {
+	if (location == "") == (pattern == "") {
+		return innerEdit{}, errors.New("exactly one of --inner-location and --inner-pattern is required with --base64")
+	}
+	if location != "" {
+		return innerEdit{Location: location}, nil
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return innerEdit{}, fmt.Errorf("compile --inner-pattern: %w", err)
+	}
+	if re.NumSubexp() < 1 {
+		return innerEdit{}, fmt.Errorf("--inner-pattern %q has no group to replace", pattern)
+	}
+	return innerEdit{Pattern: re}, nil
+}
+
+// apply edits decoded, replacing the value the edit points at with replacement.
+func (e innerEdit) apply(decoded, replacement string) (string, error) {
+	if e.Pattern == nil {
+		out, _, err := editYAML(decoded, []locationEdit{{Path: e.Location, Value: replacement}}, editOptions{})
+		return out, err
+	}
+	var b strings.Builder
+	var last int
+	for _, m := range e.Pattern.FindAllStringSubmatchIndex(decoded, -1) {
+		if m[2] < 0 {
+			continue
+		}
+		b.WriteString(decoded[last:m[2]])
+		b.WriteString(replacement)
+		last = m[3]
+	}
+	b.WriteString(decoded[last:])
+	return b.String(), nil
+}
+
+// editBase64 decodes value, edits it, and encodes the result the way value was encoded: with
+// the same alphabet, padding, line length, and trailing newline.  If the edit changes nothing,
+// value is returned exactly as it was.
+func editBase64(value string, edit func(string) (string, error)) (string, error) {
+	compact := strings.Join(strings.Fields(value), "")
+	enc := base64.StdEncoding
+	if strings.ContainsAny(compact, "-_") {
+		enc = base64.URLEncoding
+	}
+	if !strings.HasSuffix(compact, "=") && len(compact)%4 != 0 {
+		enc = enc.WithPadding(base64.NoPadding)
+	}
+	decoded, err := enc.DecodeString(compact)
+	if err != nil {
+		return "", fmt.Errorf("decode base64: %w", err)
+	}
+	edited, err := edit(string(decoded))
+	if err != nil {
+		return "", err
+	}
+	if edited == string(decoded) {
+		return value, nil
+	}
+	encoded := enc.EncodeToString([]byte(edited))
+
+	// Wrap the result at the length of the original's first line, if it was wrapped.
+	if lines := strings.Split(strings.TrimRight(value, "\n"), "\n"); len(lines) > 1 {
+		width := len(strings.TrimSpace(lines[0]))
+		var wrapped []string
+		for len(encoded) > width {
+			wrapped = append(wrapped, encoded[:width])
+			encoded = encoded[width:]
+		}
+		encoded = strings.Join(append(wrapped, encoded), "\n")
+	}
+	if strings.HasSuffix(value, "\n") {
+		encoded += "\n"
+	}
+	return encoded, nil
+}
@@ -0,0 +1,126 @@
+package bump
+
+import (
+	"encoding/base64"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestEditBase64(t *testing.T) {
+	std := base64.StdEncoding.EncodeToString
+	raw := base64.RawStdEncoding.EncodeToString
+	long := strings.Repeat("# padding to force wrapping\n", 4)
+
+	testData := []struct {
+		name           string
+		encoded        string // the value of data.config, as it appears in the file
+		inner          innerEdit
+		want           string // the decoded result
+		wantWrapped    bool
+		wantUnpadded   bool
+		wantUnmodified bool
+	}{
+		{
+			name:    "nested yaml",
+			encoded: std([]byte(lines("name: app", "version: 1.2.3"))),
+			inner:   innerEdit{Location: "version"},
+			want:    lines("name: app", "version: 1.3.0"),
+		},
+		{
+			name:    "pattern",
+			encoded: std([]byte(lines("APP_NAME=app", "APP_VERSION=1.2.3"))),
+			inner:   innerEdit{Pattern: regexp.MustCompile(`APP_VERSION=(.*)`)},
+			want:    lines("APP_NAME=app", "APP_VERSION=1.3.0"),
+		},
+		{
+			name:         "unpadded",
+			encoded:      raw([]byte("APP_VERSION=1.2.3")),
+			inner:        innerEdit{Pattern: regexp.MustCompile(`APP_VERSION=(.*)`)},
+			want:         "APP_VERSION=1.3.0",
+			wantUnpadded: true,
+		},
+		{
+			name:        "wrapped",
+			encoded:     wrap(std([]byte(long+"version: 1.2.3\n")), 40),
+			inner:       innerEdit{Location: "version"},
+			want:        long + "version: 1.3.0\n",
+			wantWrapped: true,
+		},
+		{
+			name:           "no match",
+			encoded:        wrap(std([]byte(long+"version: 1.2.3\n")), 40),
+			inner:          innerEdit{Location: "release"},
+			want:           long + "version: 1.2.3\n",
+			wantWrapped:    true,
+			wantUnmodified: true,
+		},
+	}
+	for _, test := range testData {
+		t.Run(test.name, func(t *testing.T) {
+			input := lines("kind: Secret", "data:", "  config: |") + indent(test.encoded, "    ")
+			if !strings.Contains(test.encoded, "\n") {
+				input = lines("kind: Secret", "data:", "  config: "+test.encoded)
+			}
+			got, _, err := editYAML(input, editsFor([]string{"data.config"}, "1.3.0"), editOptions{Base64: true, Inner: test.inner})
+			if err != nil {
+				t.Fatalf("edit: %v", err)
+			}
+			if test.wantUnmodified && got != input {
+				t.Errorf("unmatched edit changed the file:\n%s", cmp.Diff(got, input))
+			}
+			value, _, err := valueAt(got, "data.config")
+			if err != nil {
+				t.Fatalf("read result: %v", err)
+			}
+			if wrapped := strings.Count(value, "\n") > 1; wrapped != test.wantWrapped {
+				t.Errorf("wrapped: got %v, want %v:\n%s", wrapped, test.wantWrapped, value)
+			}
+			if first := strings.SplitN(value, "\n", 2)[0]; test.wantWrapped && len(first) != 40 {
+				t.Errorf("first line is %d characters long, want 40", len(first))
+			}
+			enc := base64.StdEncoding
+			if test.wantUnpadded {
+				enc = base64.RawStdEncoding
+			}
+			decoded, err := enc.DecodeString(strings.Join(strings.Fields(value), ""))
+			if err != nil {
+				t.Fatalf("decode result %q: %v", value, err)
+			}
+			if diff := cmp.Diff(string(decoded), test.want); diff != "" {
+				t.Errorf("decoded result:\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestNewInnerEdit(t *testing.T) {
+	for _, test := range []struct{ location, pattern string }{{"", ""}, {"a", "b(c)"}, {"", "no group"}, {"", "("}} {
+		if _, err := newInnerEdit(test.location, test.pattern); err == nil {
+			t.Errorf("expected error for location %q and pattern %q", test.location, test.pattern)
+		}
+	}
+}
+
+// wrap splits s into lines of width characters, each followed by a newline.
+func wrap(s string, width int) string {
+	var b strings.Builder
+	for len(s) > width {
+		b.WriteString(s[:width] + "\n")
+		s = s[width:]
+	}
+	return b.String() + s + "\n"
+}
+
+// indent prefixes every line of s with prefix.
+func indent(s, prefix string) string {
+	var b strings.Builder
+	for _, line := range strings.SplitAfter(s, "\n") {
+		if line != "" {
+			b.WriteString(prefix + line)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,107 @@
+package bump
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/google/go-github/v32/github"
+)
+
+// The phases of a run that an error can come from.  Errors not from any of them, like invalid
+// flags, are in phase "config".
+const (
+	phaseAuth   = "auth"
+	phaseFetch  = "fetch"
+	phaseEdit   = "edit"
+	phaseCommit = "commit"
+	phaseConfig = "config"
+)
+
+// phaseError is an error from one phase of a run.
+type phaseError struct {
+	phase string
+	err   error
+}
+
+func (e *phaseError) Error() string { return e.err.Error() }
+func (e *phaseError) Unwrap() error { return e.err }
+
+// inPhase returns err marked as coming from phase.  It returns nil if err is nil.
+func inPhase(phase string, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &phaseError{phase: phase, err: err}
+}
+
+// failure is the JSON object printed to stderr for an error with --output json.  Code is one of
+// outside_window, invalid_replacement, conflict, not_found, or the phase followed by _failed,
+// like fetch_failed.
+type failure struct {
+	Code      string   `json:"code"`
+	Phase     string   `json:"phase"`
+	Message   string   `json:"message"`
+	Repo      string   `json:"repo,omitempty"`
+	Branch    string   `json:"branch,omitempty"`
+	File      string   `json:"file,omitempty"`
+	Locations []string `json:"locations,omitempty"`
+}
+
+// newFailure describes err, returned by a run with cfg.
+func newFailure(cfg *config, err error) failure {
+	f := failure{Phase: phaseConfig, Message: err.Error(), Branch: cfg.GithubBranch, File: cfg.File, Locations: cfg.Locations}
+	if f.File == "" && len(cfg.Files) > 0 {
+		f.File = cfg.Files[0]
+	}
+	if cfg.GithubOwner != "" || cfg.GithubRepo != "" {
+		f.Repo = cfg.GithubOwner + "/" + cfg.GithubRepo
+	}
+	var pe *phaseError
+	if errors.As(err, &pe) {
+		f.Phase = pe.phase
+	}
+	var resp *github.ErrorResponse
+	switch {
+	case errors.Is(err, errOutsideWindow):
+		f.Code = "outside_window"
+	case errors.Is(err, errInvalidReplacement):
+		f.Code = "invalid_replacement"
+	case isConflict(err):
+		f.Code = "conflict"
+	case errors.Is(err, errNotFound), errors.As(err, &resp) && resp.Response != nil && resp.Response.StatusCode == http.StatusNotFound:
+		f.Code = "not_found"
+	default:
+		f.Code = f.Phase + "_failed"
+	}
+	return f
+}
+
+// fail reports err, returned by a run with cfg, to w: as a JSON failure with --output json,
+// and as a log line otherwise.  It returns the status to exit with.  errPendingChange is not a
+// failure, and is only reported by its status.
+func fail(w io.Writer, cfg *config, err error) int {
+	if errors.Is(err, errPendingChange) {
+		return 5
+	}
+	if cfg.Output == "json" {
+		out, merr := json.Marshal(newFailure(cfg, err))
+		if merr != nil {
+			panic(merr) // Can't fail for a failure.
+		}
+		fmt.Fprintf(w, "%s\n", out)
+	} else {
+		logError(w, cfg, err)
+	}
+	switch {
+	case errors.Is(err, errInvalidReplacement):
+		return 3
+	case errors.Is(err, errOutsideWindow):
+		return 4
+	case isConflict(err):
+		return 6
+	}
+	return 1
+}
@@ -0,0 +1,94 @@
+package bump
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestFailFetch(t *testing.T) {
+	gh, client := newFakeGitHub(t)
+	gh.push("o/r", "master", map[string]string{"config.yaml": lines("image:", "  tag: v1")})
+	cfg := &config{
+		GithubOwner:  "o",
+		GithubRepo:   "r",
+		GithubBranch: "missing",
+		File:         "config.yaml",
+		Locations:    []string{"image.tag"},
+		Replacement:  "v2",
+		Output:       "json",
+	}
+	err := run(context.Background(), client, cfg, ioutil.Discard)
+	if err == nil {
+		t.Fatal("expected an error fetching from a missing branch")
+	}
+	var stderr bytes.Buffer
+	if code := fail(&stderr, cfg, err); code != 1 {
+		t.Errorf("exit code: got %d, want 1", code)
+	}
+	if !strings.HasSuffix(stderr.String(), "}\n") || strings.Count(stderr.String(), "\n") != 1 {
+		t.Errorf("want one line of JSON, got %q", stderr.String())
+	}
+	var got failure
+	if err := json.Unmarshal(stderr.Bytes(), &got); err != nil {
+		t.Fatalf("unmarshal %q: %v", stderr.String(), err)
+	}
+	want := failure{
+		Code:      "not_found",
+		Phase:     phaseFetch,
+		Message:   err.Error(),
+		Repo:      "o/r",
+		Branch:    "missing",
+		File:      "config.yaml",
+		Locations: []string{"image.tag"},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("failure (-want +got):\n%s", diff)
+	}
+}
+
+func TestFail(t *testing.T) {
+	testData := []struct {
+		name      string
+		output    string
+		err       error
+		wantCode  string
+		wantPhase string
+		wantExit  int
+	}{
+		{name: "config", output: "json", err: errors.New("bad flag"), wantCode: "config_failed", wantPhase: phaseConfig, wantExit: 1},
+		{name: "edit", output: "json", err: inPhase(phaseEdit, errors.New("parse yaml")), wantCode: "edit_failed", wantPhase: phaseEdit, wantExit: 1},
+		{name: "auth", output: "json", err: inPhase(phaseAuth, errors.New("bad key")), wantCode: "auth_failed", wantPhase: phaseAuth, wantExit: 1},
+		{name: "outside window", output: "json", err: fmt.Errorf("commit: %w", errOutsideWindow), wantCode: "outside_window", wantPhase: phaseConfig, wantExit: 4},
+		{name: "branch moved", output: "json", err: inPhase(phaseCommit, fmt.Errorf("commit: %w", errBranchMoved)), wantCode: "conflict", wantPhase: phaseCommit, wantExit: 6},
+		{name: "text", output: "text", err: inPhase(phaseEdit, errors.New("parse yaml")), wantExit: 1},
+	}
+	for _, test := range testData {
+		t.Run(test.name, func(t *testing.T) {
+			var stderr bytes.Buffer
+			if got := fail(&stderr, &config{Output: test.output}, test.err); got != test.wantExit {
+				t.Errorf("exit code: got %d, want %d", got, test.wantExit)
+			}
+			if test.output == "text" {
+				if !strings.HasSuffix(stderr.String(), test.err.Error()+"\n") {
+					t.Errorf("want a log line ending in the error, got %q", stderr.String())
+				}
+				return
+			}
+			var got failure
+			if err := json.Unmarshal(stderr.Bytes(), &got); err != nil {
+				t.Fatalf("unmarshal %q: %v", stderr.String(), err)
+			}
+			if got.Code != test.wantCode || got.Phase != test.wantPhase {
+				t.Errorf("got code %q in phase %q, want %q in phase %q", got.Code, got.Phase, test.wantCode, test.wantPhase)
+			}
+		})
+	}
+}
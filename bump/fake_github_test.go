@@ -0,0 +1,687 @@
+package bump
+
+import (
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/go-github/v32/github"
+)
+
+// fakeGitHub is an in-memory implementation of the parts of the Github REST API that
+// version-bump uses.  Git objects are shared between all repositories, like they would be
+// in a fork network; refs and pull requests belong to a single repository.
+type fakeGitHub struct {
+	t   testing.TB
+	url string
+
+	mu       sync.Mutex
+	blobs    map[string]string
+	trees    map[string][]fakeTreeEntry
+	commits  map[string]*fakeCommit
+	tags     map[string]*github.Tag
+	repos    map[string]*fakeRepo
+	handlers map[string]http.HandlerFunc
+	hooks    map[string]func()
+	requests []string
+
+	// maxEntries, if set, is how many entries a tree read recursively may have before it is
+	// truncated, like Github does for large trees.
+	maxEntries int
+}
+
+type fakeTreeEntry struct {
+	Name, Mode, Type, SHA string
+}
+
+type fakeCommit struct {
+	SHA, Tree, Message string
+	Parents            []string
+	Author, Committer  *github.CommitAuthor
+	Signature          string
+}
+
+type fakeRepo struct {
+	defaultBranch string
+	refs          map[string]string
+	pulls         []*github.PullRequest
+}
+
+// newFakeGitHub starts a fake Github server, and returns it along with a client configured to
+// talk to it.
+func newFakeGitHub(t testing.TB) (*fakeGitHub, *github.Client) {
+	t.Helper()
+	f := &fakeGitHub{
+		t:        t,
+		blobs:    map[string]string{},
+		trees:    map[string][]fakeTreeEntry{},
+		commits:  map[string]*fakeCommit{},
+		tags:     map[string]*github.Tag{},
+		repos:    map[string]*fakeRepo{},
+		handlers: map[string]http.HandlerFunc{},
+		hooks:    map[string]func(){},
+	}
+	srv := httptest.NewServer(f)
+	t.Cleanup(srv.Close)
+	f.url = srv.URL
+
+	client := github.NewClient(srv.Client())
+	u, err := url.Parse(srv.URL + "/")
+	if err != nil {
+		t.Fatalf("parse fake server url: %v", err)
+	}
+	client.BaseURL = u
+	client.UploadURL = u
+	return f, client
+}
+
+// handle overrides the handler for requests with the given method and path.
+func (f *fakeGitHub) handle(method, path string, h http.HandlerFunc) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.handlers[method+" "+path] = h
+}
+
+// before arranges for hook to be called before each request with the given method and path is
+// handled.
+func (f *fakeGitHub) before(method, path string, hook func()) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.hooks[method+" "+path] = hook
+}
+
+// push creates a commit containing files on top of branch (creating the repository and branch
+// if necessary), and returns the SHA of the new commit.  Files not mentioned in files are
+// carried over from the previous head of the branch.
+func (f *fakeGitHub) push(repo, branch string, files map[string]string) string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	r := f.repo(repo)
+	if r.defaultBranch == "" {
+		r.defaultBranch = branch
+	}
+	flat := map[string]fakeTreeEntry{}
+	var parents []string
+	if head, ok := r.refs["heads/"+branch]; ok {
+		flat = f.flatten(f.commits[head].Tree, "")
+		parents = []string{head}
+	}
+	for name, content := range files {
+		flat[name] = fakeTreeEntry{Mode: "100644", Type: "blob", SHA: f.putBlob(content)}
+	}
+	c := f.putCommit(&fakeCommit{Tree: f.writeTree(flat), Message: "push", Parents: parents})
+	r.refs["heads/"+branch] = c.SHA
+	return c.SHA
+}
+
+// tag creates a commit made at date, and a tag called name in repo pointing to it.  If
+// annotated is set, the tag is an annotated tag object rather than a lightweight tag.
+func (f *fakeGitHub) tag(repo, name string, date time.Time, annotated bool) string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	c := f.putCommit(&fakeCommit{
+		Tree:      f.writeTree(nil),
+		Message:   "release " + name,
+		Committer: &github.CommitAuthor{Name: github.String("releaser"), Date: &date},
+	})
+	target := c.SHA
+	if annotated {
+		target = hashObject("tag", name+" "+c.SHA)
+		f.tags[target] = &github.Tag{
+			Tag:    github.String(name),
+			SHA:    github.String(target),
+			Object: &github.GitObject{Type: github.String("commit"), SHA: github.String(c.SHA)},
+		}
+	}
+	f.repo(repo).refs["tags/"+name] = target
+	return c.SHA
+}
+
+// head returns the commit at the tip of branch, or nil if the branch does not exist.
+func (f *fakeGitHub) head(repo, branch string) *fakeCommit {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	sha, ok := f.repo(repo).refs["heads/"+branch]
+	if !ok {
+		return nil
+	}
+	return f.commits[sha]
+}
+
+// file returns the content of name at the tip of branch.
+func (f *fakeGitHub) file(repo, branch, name string) (string, bool) {
+	c := f.head(repo, branch)
+	if c == nil {
+		return "", false
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	e, ok := f.flatten(c.Tree, "")[name]
+	if !ok {
+		return "", false
+	}
+	return f.blobs[e.SHA], true
+}
+
+// pulls returns the pull requests opened against repo.
+func (f *fakeGitHub) pulls(repo string) []*github.PullRequest {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]*github.PullRequest(nil), f.repo(repo).pulls...)
+}
+
+// called returns the number of requests made with the given method to URIs (paths, followed
+// by any query string) starting with prefix.
+func (f *fakeGitHub) called(method, prefix string) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var n int
+	for _, r := range f.requests {
+		if strings.HasPrefix(r, method+" "+prefix) {
+			n++
+		}
+	}
+	return n
+}
+
+func (f *fakeGitHub) repo(name string) *fakeRepo {
+	r, ok := f.repos[name]
+	if !ok {
+		r = &fakeRepo{refs: map[string]string{}}
+		f.repos[name] = r
+	}
+	return r
+}
+
+func hashObject(kind, content string) string {
+	return fmt.Sprintf("%x", sha1.Sum([]byte(fmt.Sprintf("%s %d\x00%s", kind, len(content), content))))
+}
+
+func (f *fakeGitHub) putBlob(content string) string {
+	sha := hashObject("blob", content)
+	f.blobs[sha] = content
+	return sha
+}
+
+func (f *fakeGitHub) putCommit(c *fakeCommit) *fakeCommit {
+	js, err := json.Marshal(c)
+	if err != nil {
+		f.t.Fatalf("marshal commit: %v", err)
+	}
+	c.SHA = hashObject("commit", string(js))
+	f.commits[c.SHA] = c
+	return c
+}
+
+// writeTree stores the tree described by a flat map of paths to blob entries, along with all
+// of its subtrees, and returns its SHA.
+func (f *fakeGitHub) writeTree(flat map[string]fakeTreeEntry) string {
+	var entries []fakeTreeEntry
+	dirs := map[string]map[string]fakeTreeEntry{}
+	for p, e := range flat {
+		parts := strings.SplitN(p, "/", 2)
+		if len(parts) == 1 {
+			e.Name = p
+			entries = append(entries, e)
+			continue
+		}
+		if dirs[parts[0]] == nil {
+			dirs[parts[0]] = map[string]fakeTreeEntry{}
+		}
+		dirs[parts[0]][parts[1]] = e
+	}
+	for name, sub := range dirs {
+		entries = append(entries, fakeTreeEntry{Name: name, Mode: "040000", Type: "tree", SHA: f.writeTree(sub)})
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name < entries[j].Name })
+	var b strings.Builder
+	for _, e := range entries {
+		fmt.Fprintf(&b, "%s %s %s\t%s\n", e.Mode, e.Type, e.SHA, e.Name)
+	}
+	sha := hashObject("tree", b.String())
+	f.trees[sha] = entries
+	return sha
+}
+
+// flatten returns every blob reachable from tree, keyed by its path relative to tree.
+func (f *fakeGitHub) flatten(tree, prefix string) map[string]fakeTreeEntry {
+	result := map[string]fakeTreeEntry{}
+	for _, e := range f.trees[tree] {
+		p := path.Join(prefix, e.Name)
+		if e.Type == "tree" {
+			for k, v := range f.flatten(e.SHA, p) {
+				result[k] = v
+			}
+			continue
+		}
+		result[p] = e
+	}
+	return result
+}
+
+// isAncestor returns true if ancestor is reachable from sha by following parents.
+func (f *fakeGitHub) isAncestor(ancestor, sha string) bool {
+	if ancestor == sha {
+		return true
+	}
+	c, ok := f.commits[sha]
+	if !ok {
+		return false
+	}
+	for _, p := range c.Parents {
+		if f.isAncestor(ancestor, p) {
+			return true
+		}
+	}
+	return false
+}
+
+func (f *fakeGitHub) reply(w http.ResponseWriter, code int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		f.t.Errorf("encode response: %v", err)
+	}
+}
+
+func (f *fakeGitHub) fail(w http.ResponseWriter, code int, msg string) {
+	f.reply(w, code, map[string]string{"message": msg})
+}
+
+func (f *fakeGitHub) decode(w http.ResponseWriter, r *http.Request, v interface{}) bool {
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		f.fail(w, http.StatusBadRequest, err.Error())
+		return false
+	}
+	return true
+}
+
+func (f *fakeGitHub) treeJSON(sha string, recursive bool) map[string]interface{} {
+	var entries []map[string]interface{}
+	var walk func(tree, prefix string)
+	walk = func(tree, prefix string) {
+		for _, e := range f.trees[tree] {
+			p := path.Join(prefix, e.Name)
+			entries = append(entries, map[string]interface{}{"path": p, "mode": e.Mode, "type": e.Type, "sha": e.SHA})
+			if recursive && e.Type == "tree" {
+				walk(e.SHA, p)
+			}
+		}
+	}
+	walk(sha, "")
+	truncated := recursive && f.maxEntries > 0 && len(entries) > f.maxEntries
+	if truncated {
+		entries = entries[:f.maxEntries]
+	}
+	return map[string]interface{}{"sha": sha, "tree": entries, "truncated": truncated}
+}
+
+func (f *fakeGitHub) commitJSON(c *fakeCommit) map[string]interface{} {
+	var parents []map[string]string
+	for _, p := range c.Parents {
+		parents = append(parents, map[string]string{"sha": p})
+	}
+	return map[string]interface{}{
+		"sha":       c.SHA,
+		"message":   c.Message,
+		"tree":      map[string]string{"sha": c.Tree},
+		"parents":   parents,
+		"author":    c.Author,
+		"committer": c.Committer,
+	}
+}
+
+func (f *fakeGitHub) refJSON(ref, sha string) map[string]interface{} {
+	kind := "commit"
+	if _, ok := f.tags[sha]; ok {
+		kind = "tag"
+	}
+	return map[string]interface{}{"ref": "refs/" + ref, "object": map[string]string{"sha": sha, "type": kind}}
+}
+
+func (f *fakeGitHub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	f.requests = append(f.requests, r.Method+" "+r.URL.RequestURI())
+	h, ok := f.handlers[r.Method+" "+r.URL.Path]
+	hook := f.hooks[r.Method+" "+r.URL.Path]
+	f.mu.Unlock()
+	if hook != nil {
+		hook()
+	}
+	if ok {
+		h(w, r)
+		return
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	parts := strings.SplitN(strings.TrimPrefix(r.URL.Path, "/"), "/", 4)
+	if len(parts) < 3 || parts[0] != "repos" {
+		f.fail(w, http.StatusNotFound, "Not Found")
+		return
+	}
+	repo := f.repo(parts[1] + "/" + parts[2])
+	var rest string
+	if len(parts) == 4 {
+		rest = parts[3]
+	}
+
+	switch {
+	case r.Method == http.MethodGet && rest == "":
+		f.reply(w, http.StatusOK, map[string]string{"default_branch": repo.defaultBranch})
+
+	case r.Method == http.MethodGet && strings.HasPrefix(rest, "branches/"):
+		name := strings.TrimPrefix(rest, "branches/")
+		sha, ok := repo.refs["heads/"+name]
+		if !ok {
+			f.fail(w, http.StatusNotFound, "Branch not found")
+			return
+		}
+		etag := fmt.Sprintf("%q", sha)
+		if r.Header.Get("If-None-Match") == etag {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", etag)
+		c := f.commits[sha]
+		f.reply(w, http.StatusOK, map[string]interface{}{
+			"name":   name,
+			"commit": map[string]interface{}{"sha": sha, "commit": f.commitJSON(c)},
+		})
+
+	case r.Method == http.MethodGet && strings.HasPrefix(rest, "compare/"):
+		revs := strings.SplitN(strings.TrimPrefix(rest, "compare/"), "...", 2)
+		var trees []map[string]fakeTreeEntry
+		for _, rev := range revs {
+			sha, ok := repo.refs["heads/"+rev]
+			if !ok {
+				sha = rev
+			}
+			c, ok := f.commits[sha]
+			if !ok {
+				f.fail(w, http.StatusNotFound, "Not Found")
+				return
+			}
+			trees = append(trees, f.flatten(c.Tree, ""))
+		}
+		var files []map[string]string
+		for p, e := range trees[1] {
+			if old, ok := trees[0][p]; !ok {
+				files = append(files, map[string]string{"filename": p, "status": "added"})
+			} else if old.SHA != e.SHA {
+				files = append(files, map[string]string{"filename": p, "status": "modified"})
+			}
+		}
+		for p := range trees[0] {
+			if _, ok := trees[1][p]; !ok {
+				files = append(files, map[string]string{"filename": p, "status": "removed"})
+			}
+		}
+		f.reply(w, http.StatusOK, map[string]interface{}{"files": files})
+
+	case r.Method == http.MethodGet && strings.HasPrefix(rest, "git/trees/"):
+		sha := strings.TrimPrefix(rest, "git/trees/")
+		if _, ok := f.trees[sha]; !ok {
+			f.fail(w, http.StatusNotFound, "Not Found")
+			return
+		}
+		f.reply(w, http.StatusOK, f.treeJSON(sha, r.URL.Query().Get("recursive") != ""))
+
+	case r.Method == http.MethodPost && rest == "git/trees":
+		var req struct {
+			BaseTree string `json:"base_tree"`
+			Tree     []struct {
+				Path    string  `json:"path"`
+				Mode    string  `json:"mode"`
+				Type    string  `json:"type"`
+				SHA     *string `json:"sha"`
+				Content *string `json:"content"`
+			} `json:"tree"`
+		}
+		if !f.decode(w, r, &req) {
+			return
+		}
+		flat := map[string]fakeTreeEntry{}
+		if req.BaseTree != "" {
+			if _, ok := f.trees[req.BaseTree]; !ok {
+				f.fail(w, http.StatusUnprocessableEntity, "base_tree not found")
+				return
+			}
+			flat = f.flatten(req.BaseTree, "")
+		}
+		for _, e := range req.Tree {
+			switch {
+			case e.Content != nil:
+				flat[e.Path] = fakeTreeEntry{Mode: e.Mode, Type: e.Type, SHA: f.putBlob(*e.Content)}
+			case e.SHA == nil:
+				delete(flat, e.Path)
+			default:
+				if _, ok := f.blobs[*e.SHA]; !ok {
+					f.fail(w, http.StatusUnprocessableEntity, "blob not found")
+					return
+				}
+				flat[e.Path] = fakeTreeEntry{Mode: e.Mode, Type: e.Type, SHA: *e.SHA}
+			}
+		}
+		f.reply(w, http.StatusCreated, f.treeJSON(f.writeTree(flat), false))
+
+	case r.Method == http.MethodGet && strings.HasPrefix(rest, "contents/"):
+		file := strings.TrimPrefix(rest, "contents/")
+		ref := r.URL.Query().Get("ref")
+		if sha, ok := repo.refs["heads/"+ref]; ok {
+			ref = sha
+		}
+		c, ok := f.commits[ref]
+		if !ok {
+			f.fail(w, http.StatusNotFound, "No commit found for the ref "+ref)
+			return
+		}
+		e, ok := f.flatten(c.Tree, "")[file]
+		if !ok {
+			f.fail(w, http.StatusNotFound, "Not Found")
+			return
+		}
+		// Like Github, leave out the content of files over 1MB.
+		content, encoding := f.blobs[e.SHA], "base64"
+		if len(content) > 1<<20 {
+			content, encoding = "", "none"
+		}
+		f.reply(w, http.StatusOK, map[string]interface{}{
+			"type":     "file",
+			"path":     file,
+			"name":     path.Base(file),
+			"sha":      e.SHA,
+			"size":     len(f.blobs[e.SHA]),
+			"encoding": encoding,
+			"content":  base64.StdEncoding.EncodeToString([]byte(content)),
+		})
+
+	case r.Method == http.MethodGet && strings.HasPrefix(rest, "git/blobs/"):
+		sha := strings.TrimPrefix(rest, "git/blobs/")
+		content, ok := f.blobs[sha]
+		if !ok {
+			f.fail(w, http.StatusNotFound, "Not Found")
+			return
+		}
+		f.reply(w, http.StatusOK, map[string]interface{}{
+			"sha":      sha,
+			"encoding": "base64",
+			"content":  base64.StdEncoding.EncodeToString([]byte(content)),
+			"size":     len(content),
+		})
+
+	case r.Method == http.MethodPost && rest == "git/blobs":
+		var req struct {
+			Content  string `json:"content"`
+			Encoding string `json:"encoding"`
+		}
+		if !f.decode(w, r, &req) {
+			return
+		}
+		content := req.Content
+		switch req.Encoding {
+		case "base64":
+			b, err := base64.StdEncoding.DecodeString(req.Content)
+			if err != nil {
+				f.fail(w, http.StatusBadRequest, err.Error())
+				return
+			}
+			content = string(b)
+		case "utf-8":
+		default:
+			f.fail(w, http.StatusUnprocessableEntity, "unknown encoding "+req.Encoding)
+			return
+		}
+		f.reply(w, http.StatusCreated, map[string]string{"sha": f.putBlob(content)})
+
+	case r.Method == http.MethodGet && strings.HasPrefix(rest, "git/commits/"):
+		c, ok := f.commits[strings.TrimPrefix(rest, "git/commits/")]
+		if !ok {
+			f.fail(w, http.StatusNotFound, "Not Found")
+			return
+		}
+		f.reply(w, http.StatusOK, f.commitJSON(c))
+
+	case r.Method == http.MethodGet && strings.HasPrefix(rest, "git/tags/"):
+		t, ok := f.tags[strings.TrimPrefix(rest, "git/tags/")]
+		if !ok {
+			f.fail(w, http.StatusNotFound, "Not Found")
+			return
+		}
+		f.reply(w, http.StatusOK, t)
+
+	case r.Method == http.MethodPost && rest == "git/commits":
+		var req struct {
+			Message   string               `json:"message"`
+			Tree      string               `json:"tree"`
+			Parents   []string             `json:"parents"`
+			Author    *github.CommitAuthor `json:"author"`
+			Committer *github.CommitAuthor `json:"committer"`
+			Signature string               `json:"signature"`
+		}
+		if !f.decode(w, r, &req) {
+			return
+		}
+		if _, ok := f.trees[req.Tree]; !ok {
+			f.fail(w, http.StatusUnprocessableEntity, "tree not found")
+			return
+		}
+		c := f.putCommit(&fakeCommit{Tree: req.Tree, Message: req.Message, Parents: req.Parents, Author: req.Author, Committer: req.Committer, Signature: req.Signature})
+		f.reply(w, http.StatusCreated, f.commitJSON(c))
+
+	case r.Method == http.MethodGet && strings.HasPrefix(rest, "git/ref/"):
+		ref := strings.TrimPrefix(rest, "git/ref/")
+		sha, ok := repo.refs[ref]
+		if !ok {
+			f.fail(w, http.StatusNotFound, "Not Found")
+			return
+		}
+		f.reply(w, http.StatusOK, f.refJSON(ref, sha))
+
+	case r.Method == http.MethodPost && rest == "git/refs":
+		var req struct {
+			Ref string `json:"ref"`
+			SHA string `json:"sha"`
+		}
+		if !f.decode(w, r, &req) {
+			return
+		}
+		ref := strings.TrimPrefix(req.Ref, "refs/")
+		if _, ok := repo.refs[ref]; ok {
+			f.fail(w, http.StatusUnprocessableEntity, "Reference already exists")
+			return
+		}
+		repo.refs[ref] = req.SHA
+		f.reply(w, http.StatusCreated, f.refJSON(ref, req.SHA))
+
+	case r.Method == http.MethodPatch && strings.HasPrefix(rest, "git/refs/"):
+		ref := strings.TrimPrefix(rest, "git/refs/")
+		var req struct {
+			SHA   string `json:"sha"`
+			Force bool   `json:"force"`
+		}
+		if !f.decode(w, r, &req) {
+			return
+		}
+		old, ok := repo.refs[ref]
+		if !ok {
+			f.fail(w, http.StatusUnprocessableEntity, "Reference does not exist")
+			return
+		}
+		if !req.Force && !f.isAncestor(old, req.SHA) {
+			f.fail(w, http.StatusUnprocessableEntity, "Update is not a fast forward")
+			return
+		}
+		repo.refs[ref] = req.SHA
+		f.reply(w, http.StatusOK, f.refJSON(ref, req.SHA))
+
+	case r.Method == http.MethodDelete && strings.HasPrefix(rest, "git/refs/"):
+		ref := strings.TrimPrefix(rest, "git/refs/")
+		if _, ok := repo.refs[ref]; !ok {
+			f.fail(w, http.StatusUnprocessableEntity, "Reference does not exist")
+			return
+		}
+		delete(repo.refs, ref)
+		w.WriteHeader(http.StatusNoContent)
+
+	case r.Method == http.MethodGet && rest == "pulls":
+		head := r.URL.Query().Get("head")
+		var result []*github.PullRequest
+		for _, pr := range repo.pulls {
+			if pr.GetState() != "open" {
+				continue
+			}
+			if head != "" && !strings.HasSuffix(head, ":"+pr.GetHead().GetRef()) {
+				continue
+			}
+			result = append(result, pr)
+		}
+		f.reply(w, http.StatusOK, result)
+
+	case r.Method == http.MethodPost && rest == "pulls":
+		var req github.NewPullRequest
+		if !f.decode(w, r, &req) {
+			return
+		}
+		head := req.GetHead()
+		if i := strings.Index(head, ":"); i >= 0 {
+			head = head[i+1:]
+		}
+		for _, pr := range repo.pulls {
+			if pr.GetState() == "open" && pr.GetHead().GetLabel() == req.GetHead() {
+				f.fail(w, http.StatusUnprocessableEntity, "A pull request already exists")
+				return
+			}
+		}
+		if _, ok := repo.refs["heads/"+req.GetBase()]; !ok {
+			f.fail(w, http.StatusUnprocessableEntity, "base does not exist")
+			return
+		}
+		n := len(repo.pulls) + 1
+		pr := &github.PullRequest{
+			Number:  github.Int(n),
+			NodeID:  github.String(fmt.Sprintf("PR_%d", n)),
+			State:   github.String("open"),
+			Title:   req.Title,
+			Body:    req.Body,
+			HTMLURL: github.String(fmt.Sprintf("%s/%s/%s/pull/%d", f.url, parts[1], parts[2], n)),
+			Head:    &github.PullRequestBranch{Ref: github.String(head), Label: req.Head},
+			Base:    &github.PullRequestBranch{Ref: req.Base},
+		}
+		repo.pulls = append(repo.pulls, pr)
+		f.reply(w, http.StatusCreated, pr)
+
+	default:
+		f.fail(w, http.StatusNotFound, "Not Found")
+	}
+}
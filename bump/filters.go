@@ -0,0 +1,75 @@
+package bump
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"sigs.k8s.io/kustomize/kyaml/yaml"
+)
+
+// dumpFilters prints a description of each filter that editYAML would apply to a document to
+// edit the locations in cfg, one per line.
+func dumpFilters(cfg *config, w io.Writer) error {
+	cfg, err := pairReplacements(cfg)
+	if err != nil {
+		return err
+	}
+	cfg = trimReplacement(cfg)
+	opts, err := newEditOptions(cfg, nil)
+	if err != nil {
+		return err
+	}
+	edits, err := locationEdits(cfg)
+	if err != nil {
+		return err
+	}
+	changes := make([]change, len(edits)+len(opts.Mirrors)+len(opts.Renames))
+	filters, err := editFilters(nil, 0, edits, opts, changes)
+	if err != nil {
+		return err
+	}
+	for _, f := range filters {
+		fmt.Fprintln(w, describeFilter(f))
+	}
+	return nil
+}
+
+// describeFilter returns a human-readable description of a filter built by editFilters, in the
+// form of the Go expression that would build it.
+func describeFilter(f yaml.Filter) string {
+	switch f := f.(type) {
+	case yaml.TeePiper:
+		return fmt.Sprintf("Tee(%s)", describeFilters(f.Filters))
+	case yaml.PathGetter:
+		var quoted []string
+		for _, segment := range f.Path {
+			quoted = append(quoted, fmt.Sprintf("%q", segment))
+		}
+		return fmt.Sprintf("Lookup(%s)", strings.Join(quoted, ", "))
+	case elementAt:
+		return fmt.Sprintf("ElementAt(%d)", int(f))
+	case setScalar:
+		return f.describe
+	case mergeObject:
+		merge := "deep"
+		if f.shallow {
+			merge = "shallow"
+		}
+		return fmt.Sprintf("MergeObject(%s, %s)", merge, flowString(f.object))
+	case copyScalar:
+		return fmt.Sprintf("Copy(from: [%s], to: [%s])", describeFilters(lookup(f.from)), describeFilters(lookup(f.to)))
+	case renameKey:
+		return fmt.Sprintf("RenameKey(%q, %q)", f.name, f.newName)
+	default:
+		return fmt.Sprintf("%T", f)
+	}
+}
+
+func describeFilters(filters []yaml.Filter) string {
+	var descs []string
+	for _, f := range filters {
+		descs = append(descs, describeFilter(f))
+	}
+	return strings.Join(descs, ", ")
+}
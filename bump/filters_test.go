@@ -0,0 +1,40 @@
+package bump
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestDumpFilters(t *testing.T) {
+	cfg := &config{
+		Locations:   []string{"spec.template.metadata.labels.version", "spec.containers[1].image", "/spec/containers/0/image"},
+		Replacement: "v2",
+		Mirrors:     []string{"image.tag=metadata.labels[name=app].version"},
+		RenameKeys:  []string{"spec.extensions=apps"},
+	}
+	var out bytes.Buffer
+	if err := dumpFilters(cfg, &out); err != nil {
+		t.Fatalf("dump filters: %v", err)
+	}
+	want := lines(
+		`Tee(Lookup("spec", "template", "metadata", "labels", "version"), Set("v2"))`,
+		`Tee(Lookup("spec", "containers"), ElementAt(1), Lookup("image"), Set("v2"))`,
+		`Tee(Lookup("spec", "containers"), ElementAt(0), Lookup("image"), Set("v2"))`,
+		`Copy(from: [Lookup("image", "tag")], to: [Lookup("metadata", "labels", "[name=app]", "version")])`,
+		`Tee(Lookup("spec"), RenameKey("extensions", "apps"))`,
+	)
+	if diff := cmp.Diff(out.String(), want); diff != "" {
+		t.Errorf("dump:\n%s", diff)
+	}
+
+	cfg = &config{Locations: []string{"resources.limits.memory"}, Scale: 2}
+	out.Reset()
+	if err := dumpFilters(cfg, &out); err != nil {
+		t.Fatalf("dump filters: %v", err)
+	}
+	if got, want := out.String(), lines(`Tee(Lookup("resources", "limits", "memory"), ScaleQuantity(2))`); got != want {
+		t.Errorf("dump: got %q, want %q", got, want)
+	}
+}
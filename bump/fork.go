@@ -0,0 +1,33 @@
+package bump
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/google/go-github/v32/github"
+)
+
+// syncFork brings fork's branch up to date with the branch of the same name in the repository
+// it was forked from, with Github's merge-upstream API.  It fails if the fork's branch has
+// commits that upstream does not, and can't be merged cleanly.
+func syncFork(ctx context.Context, client *github.Client, fork target) error {
+	req, err := client.NewRequest("POST", fmt.Sprintf("repos/%v/%v/merge-upstream", fork.Owner, fork.Repo), map[string]string{"branch": fork.Branch})
+	if err != nil {
+		return err
+	}
+	var result struct {
+		Message   string `json:"message"`
+		MergeType string `json:"merge_type"`
+	}
+	resp, err := client.Do(ctx, req, &result)
+	if resp != nil && resp.StatusCode == http.StatusConflict {
+		return fmt.Errorf("sync fork %s/%s@%s: the fork's branch conflicts with upstream; sync it by hand", fork.Owner, fork.Repo, fork.Branch)
+	}
+	if err != nil {
+		return fmt.Errorf("sync fork %s/%s@%s: %w", fork.Owner, fork.Repo, fork.Branch, err)
+	}
+	log.Printf("synced fork %s/%s@%s with upstream: %s", fork.Owner, fork.Repo, fork.Branch, result.Message)
+	return nil
+}
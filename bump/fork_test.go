@@ -0,0 +1,67 @@
+package bump
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"testing"
+)
+
+func TestFork(t *testing.T) {
+	testData := []struct {
+		name      string
+		sync      bool
+		wantOther string
+	}{
+		{name: "stale fork", wantOther: "old\n"},
+		{name: "synced fork", sync: true, wantOther: "new\n"},
+	}
+	for _, test := range testData {
+		t.Run(test.name, func(t *testing.T) {
+			gh, client := newFakeGitHub(t)
+			gh.push("me/r", "main", map[string]string{"config.yaml": lines("image:", "  tag: v1"), "other.txt": "old\n"})
+			upstream := gh.push("up/r", "main", map[string]string{"config.yaml": lines("image:", "  tag: v1"), "other.txt": "new\n"})
+			var synced int
+			gh.handle("POST", "/repos/me/r/merge-upstream", func(w http.ResponseWriter, r *http.Request) {
+				synced++
+				gh.mu.Lock()
+				gh.repo("me/r").refs["heads/main"] = upstream
+				gh.mu.Unlock()
+				gh.reply(w, http.StatusOK, map[string]string{"message": "Successfully fetched and fast-forwarded from upstream up:main.", "merge_type": "fast-forward"})
+			})
+			cfg := &config{
+				GithubOwner:  "up",
+				GithubRepo:   "r",
+				GithubBranch: "main",
+				File:         "config.yaml",
+				Locations:    []string{"image.tag"},
+				Replacement:  "v2",
+				Fork:         "me/r",
+				SyncFork:     test.sync,
+			}
+			if err := run(context.Background(), client, cfg, ioutil.Discard); err != nil {
+				t.Fatalf("run: %v", err)
+			}
+			if want := map[bool]int{true: 1}[test.sync]; synced != want {
+				t.Errorf("synced the fork %d times, want %d", synced, want)
+			}
+			if got := gh.head("up/r", "main").SHA; got != upstream {
+				t.Errorf("upstream branch moved from %s to %s", upstream, got)
+			}
+			pulls := gh.pulls("up/r")
+			if len(pulls) != 1 {
+				t.Fatalf("got %d pull requests against upstream, want 1", len(pulls))
+			}
+			pr := pulls[0]
+			if got, want := pr.GetHead().GetLabel(), "me:"+pr.GetHead().GetRef(); got != want {
+				t.Errorf("pull request head: got %s, want %s", got, want)
+			}
+			if got, _ := gh.file("me/r", pr.GetHead().GetRef(), "config.yaml"); got != lines("image:", "  tag: v2") {
+				t.Errorf("config.yaml in the fork's pull request branch: got %q", got)
+			}
+			if got, _ := gh.file("me/r", pr.GetHead().GetRef(), "other.txt"); got != test.wantOther {
+				t.Errorf("other.txt in the fork's pull request branch: got %q, want %q", got, test.wantOther)
+			}
+		})
+	}
+}
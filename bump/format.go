@@ -0,0 +1,397 @@
+package bump
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"regexp"
+	"strings"
+
+	"sigs.k8s.io/kustomize/kyaml/yaml"
+)
+
+// The formats that --format accepts, and that sniffFormat may find.
+const (
+	formatYAML   = "yaml"
+	formatJSON   = "json"
+	formatDotenv = "dotenv"
+	formatTOML   = "toml"
+	formatAuto   = "auto"
+)
+
+// fileFormat returns the format to edit content, the content of cfg.File, as: --format; with
+// --format auto, the format sniffed from content; or, without --format, the format its
+// extension implies.
+func fileFormat(cfg *config, content string) (string, error) {
+	switch cfg.Format {
+	case "":
+		return extensionFormat(cfg.File), nil
+	case formatAuto:
+		format, err := sniffFormat(content)
+		if err != nil {
+			return "", fmt.Errorf("detect the format of %s: %w", cfg.File, err)
+		}
+		return format, nil
+	}
+	return cfg.Format, nil
+}
+
+// extensionFormat returns the format of file from its extension: JSON for .json, TOML for
+// .toml, dotenv for .env, .env.production and the like, and .properties, and YAML for anything
+// else.
+func extensionFormat(file string) string {
+	switch ext, base := path.Ext(file), path.Base(file); {
+	case strings.EqualFold(ext, ".json"):
+		return formatJSON
+	case strings.EqualFold(ext, ".toml"):
+		return formatTOML
+	case strings.EqualFold(ext, ".env"), strings.HasPrefix(base, ".env."), strings.EqualFold(ext, ".properties"):
+		return formatDotenv
+	}
+	return formatYAML
+}
+
+// dotenvLine matches a KEY=value line of a dotenv file.
+var dotenvLine = regexp.MustCompile(`^\s*(export\s+)?[A-Za-z_][A-Za-z0-9_.]*\s*=`)
+
+// sniffFormat guesses the format of content from what it parses as.  JSON is tried first, since
+// any JSON document is also YAML; then YAML, if there is a mapping or sequence at the top of a
+// document; then dotenv, if every line is a KEY=value assignment, a comment, or blank; then
+// TOML, which dotenv files without tables, quoted keys, or the like, would also pass for.
+// Plain text parses as a YAML string, so it is not taken for YAML.
+func sniffFormat(content string) (string, error) {
+	if strings.TrimSpace(content) == "" {
+		return "", errors.New("file is empty")
+	}
+	if json.Valid([]byte(content)) {
+		return formatJSON, nil
+	}
+	if isYAMLCollection(content) {
+		return formatYAML, nil
+	}
+	dotenv := true
+	for _, line := range strings.Split(content, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if trimmed != "" && !strings.HasPrefix(trimmed, "#") && !dotenvLine.MatchString(line) {
+			dotenv = false
+			break
+		}
+	}
+	if dotenv {
+		return formatDotenv, nil
+	}
+	if _, _, err := parseTOML(content); err == nil {
+		return formatTOML, nil
+	}
+	return "", errors.New("content is not JSON, YAML, dotenv, or TOML; pass --format")
+}
+
+// isYAMLCollection returns true if content parses as YAML, and some document in it is a
+// mapping or a sequence.
+func isYAMLCollection(content string) bool {
+	d := yaml.NewDecoder(strings.NewReader(content))
+	collection := false
+	for {
+		var doc yaml.Node
+		err := d.Decode(&doc)
+		if errors.Is(err, io.EOF) {
+			return collection
+		}
+		if err != nil {
+			return false
+		}
+		if len(doc.Content) > 0 && (doc.Content[0].Kind == yaml.MappingNode || doc.Content[0].Kind == yaml.SequenceNode) {
+			collection = true
+		}
+	}
+}
+
+// editFormat applies edits and opts to input, in format, and returns what changed, like editYAML.
+func editFormat(format, input string, edits []locationEdit, opts editOptions) (string, []change, error) {
+	switch format {
+	case formatYAML:
+		return editYAML(input, edits, opts)
+	case formatJSON:
+		return editJSON(input, edits, opts)
+	case formatTOML:
+		return editTOML(input, edits, opts)
+	case formatDotenv:
+		return editDotenv(input, edits, opts)
+	}
+	return "", nil, fmt.Errorf("%s files cannot be edited", format)
+}
+
+// editJSON is like editYAML, for a JSON document.  The document is converted to YAML, edited
+// with editYAML, and converted back.  If only values changed, they are replaced in input where
+// they are, leaving the rest of it exactly as it was; otherwise the whole document is rewritten,
+// keeping the order of keys and the indentation of input.  If the edit changes nothing, input is
+// returned as it was.
+func editJSON(input string, edits []locationEdit, opts editOptions) (string, []change, error) {
+	node, spans, err := parseJSON(input)
+	if err != nil {
+		return "", nil, fmt.Errorf("parse json: %w", err)
+	}
+	before, err := yaml.NewRNode(node).String()
+	if err != nil {
+		return "", nil, fmt.Errorf("convert json to yaml: %w", err)
+	}
+	after, changes, err := editYAML(before, edits, opts)
+	if err != nil {
+		return "", nil, err
+	}
+	if after == before {
+		return input, changes, nil
+	}
+	edited, err := yaml.Parse(after)
+	if err != nil {
+		return "", nil, fmt.Errorf("parse edited yaml: %w", err)
+	}
+	if out, ok := spliceJSON(input, node, edited.YNode(), spans); ok {
+		return out, changes, nil
+	}
+	var compact bytes.Buffer
+	if err := writeJSON(&compact, edited.YNode()); err != nil {
+		return "", nil, err
+	}
+	out := compact.Bytes()
+	if indent := jsonIndent(input); indent != "" {
+		var indented bytes.Buffer
+		if err := json.Indent(&indented, out, "", indent); err != nil {
+			return "", nil, fmt.Errorf("indent json: %w", err)
+		}
+		out = indented.Bytes()
+	}
+	if strings.HasSuffix(input, "\n") {
+		out = append(out, '\n')
+	}
+	return string(out), changes, nil
+}
+
+// span is the position of a scalar in a JSON document, as byte offsets.
+type span struct{ start, end int }
+
+// parseJSON parses the JSON document in input into YAML nodes, with strings double-quoted so
+// that they stay strings, and keys in the order they appear.  It also returns where in input
+// each scalar value is.
+func parseJSON(input string) (*yaml.Node, map[*yaml.Node]span, error) {
+	p := &jsonParser{input: input, d: json.NewDecoder(strings.NewReader(input)), spans: map[*yaml.Node]span{}}
+	p.d.UseNumber()
+	node, err := p.value()
+	if err != nil {
+		return nil, nil, err
+	}
+	if _, err := p.d.Token(); !errors.Is(err, io.EOF) {
+		return nil, nil, errors.New("more than one value")
+	}
+	return node, p.spans, nil
+}
+
+type jsonParser struct {
+	input string
+	d     *json.Decoder
+	spans map[*yaml.Node]span
+}
+
+func (p *jsonParser) value() (*yaml.Node, error) {
+	// The next token starts after any whitespace and separators that follow the last one.
+	start := int(p.d.InputOffset())
+	for start < len(p.input) && strings.IndexByte(" \t\r\n:,", p.input[start]) >= 0 {
+		start++
+	}
+	node, err := p.parse()
+	if err != nil {
+		return nil, err
+	}
+	if node.Kind == yaml.ScalarNode {
+		p.spans[node] = span{start: start, end: int(p.d.InputOffset())}
+	}
+	return node, nil
+}
+
+func (p *jsonParser) parse() (*yaml.Node, error) {
+	d := p.d
+	t, err := d.Token()
+	if err != nil {
+		return nil, err
+	}
+	switch t := t.(type) {
+	case json.Delim:
+		node := &yaml.Node{Kind: yaml.MappingNode, Tag: yaml.NodeTagMap}
+		if t == '[' {
+			node = &yaml.Node{Kind: yaml.SequenceNode, Tag: yaml.NodeTagSeq}
+		}
+		for d.More() {
+			if node.Kind == yaml.MappingNode {
+				key, err := d.Token()
+				if err != nil {
+					return nil, err
+				}
+				node.Content = append(node.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: yaml.NodeTagString, Value: key.(string)})
+			}
+			value, err := p.value()
+			if err != nil {
+				return nil, err
+			}
+			node.Content = append(node.Content, value)
+		}
+		// The closing delimiter.
+		if _, err := d.Token(); err != nil {
+			return nil, err
+		}
+		return node, nil
+	case string:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: yaml.NodeTagString, Value: t, Style: yaml.DoubleQuotedStyle}, nil
+	case json.Number:
+		tag := yaml.NodeTagInt
+		if strings.ContainsAny(string(t), ".eE") {
+			tag = yaml.NodeTagFloat
+		}
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: tag, Value: string(t)}, nil
+	case bool:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: yaml.NodeTagBool, Value: fmt.Sprint(t)}, nil
+	default:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: yaml.NodeTagNull, Value: "null"}, nil
+	}
+}
+
+// spliceJSON returns input, which parsed as orig, with each scalar that differs in edited
+// written in its place.  It returns false if edited differs from orig in more than scalar values,
+// like added or removed keys, so that input can't be edited in place.
+func spliceJSON(input string, orig, edited *yaml.Node, spans map[*yaml.Node]span) (string, bool) {
+	out, ok, err := spliceScalars(input, orig, edited, spans, func(_, b *yaml.Node) (string, error) {
+		var text bytes.Buffer
+		err := writeJSON(&text, b)
+		return text.String(), err
+	})
+	return out, ok && err == nil
+}
+
+// spliceScalars returns input, which parsed as orig with each scalar at its span, with each
+// scalar that differs in edited replaced by what write writes for it.  It returns false if
+// edited differs from orig in more than scalar values, like added or removed keys.
+func spliceScalars(input string, orig, edited *yaml.Node, spans map[*yaml.Node]span, write func(orig, edited *yaml.Node) (string, error)) (string, bool, error) {
+	type splice struct {
+		span
+		text string
+	}
+	var splices []splice
+	var walk func(a, b *yaml.Node) (bool, error)
+	walk = func(a, b *yaml.Node) (bool, error) {
+		if b.Kind == yaml.DocumentNode {
+			return walk(a, b.Content[0])
+		}
+		if a.Kind != b.Kind || len(a.Content) != len(b.Content) {
+			return false, nil
+		}
+		if a.Kind == yaml.ScalarNode {
+			if a.Value == b.Value && a.ShortTag() == b.ShortTag() {
+				return true, nil
+			}
+			text, err := write(a, b)
+			if err != nil {
+				return false, err
+			}
+			splices = append(splices, splice{span: spans[a], text: text})
+			return true, nil
+		}
+		for i := range a.Content {
+			if a.Kind == yaml.MappingNode && i%2 == 0 {
+				if a.Content[i].Value != b.Content[i].Value {
+					return false, nil
+				}
+				continue
+			}
+			if ok, err := walk(a.Content[i], b.Content[i]); !ok || err != nil {
+				return false, err
+			}
+		}
+		return true, nil
+	}
+	if ok, err := walk(orig, edited); !ok || err != nil {
+		return "", false, err
+	}
+	var out strings.Builder
+	last := 0
+	for _, s := range splices {
+		out.WriteString(input[last:s.start])
+		out.WriteString(s.text)
+		last = s.end
+	}
+	out.WriteString(input[last:])
+	return out.String(), true, nil
+}
+
+// writeJSON writes node to w as compact JSON.  Scalars are written as the type YAML reads them
+// as, except that numbers JSON can't represent, like .inf, are written as strings.
+func writeJSON(w *bytes.Buffer, node *yaml.Node) error {
+	switch node.Kind {
+	case yaml.DocumentNode:
+		return writeJSON(w, node.Content[0])
+	case yaml.MappingNode:
+		w.WriteByte('{')
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			if i > 0 {
+				w.WriteByte(',')
+			}
+			writeJSONString(w, node.Content[i].Value)
+			w.WriteByte(':')
+			if err := writeJSON(w, node.Content[i+1]); err != nil {
+				return err
+			}
+		}
+		w.WriteByte('}')
+	case yaml.SequenceNode:
+		w.WriteByte('[')
+		for i, item := range node.Content {
+			if i > 0 {
+				w.WriteByte(',')
+			}
+			if err := writeJSON(w, item); err != nil {
+				return err
+			}
+		}
+		w.WriteByte(']')
+	case yaml.ScalarNode:
+		switch node.ShortTag() {
+		case yaml.NodeTagInt, yaml.NodeTagFloat:
+			if json.Valid([]byte(node.Value)) {
+				w.WriteString(node.Value)
+				return nil
+			}
+		case yaml.NodeTagBool:
+			if node.Value == "true" || node.Value == "false" {
+				w.WriteString(node.Value)
+				return nil
+			}
+		case yaml.NodeTagNull:
+			w.WriteString("null")
+			return nil
+		}
+		writeJSONString(w, node.Value)
+	default:
+		return fmt.Errorf("line %d: cannot write %s node as json", node.Line, node.ShortTag())
+	}
+	return nil
+}
+
+// writeJSONString writes s to w as a JSON string, without escaping HTML.
+func writeJSONString(w *bytes.Buffer, s string) {
+	e := json.NewEncoder(w)
+	e.SetEscapeHTML(false)
+	e.Encode(s) // Can't fail for a string.
+	w.Truncate(w.Len() - 1)
+}
+
+// jsonIndent returns the indentation of the first indented line of input, taken to be one
+// level, or "" if input is all on one line.
+func jsonIndent(input string) string {
+	for _, line := range strings.Split(input, "\n")[1:] {
+		if indent := line[:len(line)-len(strings.TrimLeft(line, " \t"))]; indent != "" {
+			return indent
+		}
+	}
+	return ""
+}
@@ -0,0 +1,180 @@
+package bump
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestSniffFormat(t *testing.T) {
+	testData := []struct {
+		name    string
+		content string
+		want    string
+		wantErr bool
+	}{
+		{name: "json object", content: lines(`{"image": {"tag": "v1"}}`), want: formatJSON},
+		{name: "json array", content: lines(`["v1", "v2"]`), want: formatJSON},
+		{name: "flow mapping that is not json", content: lines("{image: {tag: v1}}"), want: formatYAML},
+		{name: "yaml", content: lines("image:", "  tag: v1"), want: formatYAML},
+		{name: "yaml documents", content: lines("# comment", "---", "a: b"), want: formatYAML},
+		{name: "dotenv", content: lines("# versions", "IMAGE_TAG=v1", "", "export OTHER_TAG=v2"), want: formatDotenv},
+		{name: "toml", content: lines("[package]", `name = "app"`, `"quoted.key" = 1`), want: formatTOML},
+		{name: "plain text", content: lines("hello world"), wantErr: true},
+		{name: "empty", content: "\n", wantErr: true},
+	}
+	for _, test := range testData {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := sniffFormat(test.content)
+			if test.wantErr {
+				if err == nil {
+					t.Fatalf("expected error, got format %s", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("sniff: %v", err)
+			}
+			if got != test.want {
+				t.Errorf("format: got %s, want %s", got, test.want)
+			}
+		})
+	}
+}
+
+func TestEditJSON(t *testing.T) {
+	testData := []struct {
+		name        string
+		input       string
+		location    string
+		replacement string
+		want        string
+	}{
+		{
+			name:        "keeps key order and indentation",
+			input:       lines("{", `    "name": "app",`, `    "image": {"tag": "v1", "pull": "always"},`, `    "replicas": 1`, "}"),
+			location:    "image.tag",
+			replacement: "v2",
+			want:        lines("{", `    "name": "app",`, `    "image": {"tag": "v2", "pull": "always"},`, `    "replicas": 1`, "}"),
+		},
+		{
+			name:        "escapes",
+			input:       lines(`{"a": "caf\u00e9", "b": ["x", "v\"1\""], "c": "v1"}`),
+			location:    "b[1]",
+			replacement: "<v2>",
+			want:        lines(`{"a": "caf\u00e9", "b": ["x", "<v2>"], "c": "v1"}`),
+		},
+		{
+			name:        "compact",
+			input:       `{"b":"<1>","a":["v1"]}`,
+			location:    "a[0]",
+			replacement: "v2",
+			want:        `{"b":"<1>","a":["v2"]}`,
+		},
+		{
+			name:        "string stays a string",
+			input:       lines(`{"version": "1.0"}`),
+			location:    "version",
+			replacement: "1.1",
+			want:        lines(`{"version": "1.1"}`),
+		},
+		{
+			name:        "number stays a number",
+			input:       lines(`{"replicas": 1, "enabled": true, "extra": null}`),
+			location:    "replicas",
+			replacement: "3",
+			want:        lines(`{"replicas": 3, "enabled": true, "extra": null}`),
+		},
+		{
+			name:        "missing location leaves the file alone",
+			input:       lines(`{ "version" : "1.0" }`),
+			location:    "missing",
+			replacement: "1.1",
+			want:        lines(`{ "version" : "1.0" }`),
+		},
+	}
+	for _, test := range testData {
+		t.Run(test.name, func(t *testing.T) {
+			got, _, err := editJSON(test.input, editsFor([]string{test.location}, test.replacement), editOptions{})
+			if err != nil {
+				t.Fatalf("edit: %v", err)
+			}
+			if diff := cmp.Diff(test.want, got); diff != "" {
+				t.Errorf("output (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestEditJSONObject(t *testing.T) {
+	object, err := parseObject(`{"pull": "always", "digest": null}`)
+	if err != nil {
+		t.Fatalf("parse object: %v", err)
+	}
+	input := lines("{", `  "image": {`, `    "tag": "v1",`, `    "digest": "sha256:abc"`, "  },", `  "replicas": 1`, "}")
+	got, _, err := editJSON(input, editsFor([]string{"image"}, ""), editOptions{Object: object})
+	if err != nil {
+		t.Fatalf("edit: %v", err)
+	}
+	// Keys were added and removed, so the document is rewritten.
+	want := lines("{", `  "image": {`, `    "tag": "v1",`, `    "pull": "always"`, "  },", `  "replicas": 1`, "}")
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("output (-want +got):\n%s", diff)
+	}
+}
+
+func TestFileFormat(t *testing.T) {
+	testData := []struct {
+		file   string
+		format string
+		want   string
+	}{
+		{file: "package.json", want: formatJSON},
+		{file: "deploy/VERSIONS.JSON", want: formatJSON},
+		{file: "values.yaml", want: formatYAML},
+		{file: "Chart.yml", want: formatYAML},
+		{file: "Cargo.toml", want: formatTOML},
+		{file: "versions.env", want: formatDotenv},
+		{file: "deploy/.env.production", want: formatDotenv},
+		{file: "gradle.properties", want: formatDotenv},
+		{file: "VERSIONS", want: formatYAML},
+		{file: "package.json", format: formatYAML, want: formatYAML},
+		{file: "values.yaml", format: formatJSON, want: formatJSON},
+	}
+	for _, test := range testData {
+		got, err := fileFormat(&config{File: test.file, Format: test.format}, "")
+		if err != nil {
+			t.Errorf("%s with --format %q: %v", test.file, test.format, err)
+			continue
+		}
+		if got != test.want {
+			t.Errorf("%s with --format %q: got %s, want %s", test.file, test.format, got, test.want)
+		}
+	}
+}
+
+func TestEditFormatAuto(t *testing.T) {
+	cfg := &config{
+		File:        "VERSIONS",
+		Format:      formatAuto,
+		Locations:   []string{"image.tag"},
+		Replacement: "v2",
+	}
+	got, _, err := edit(cfg, lines("{", `  "image": {`, `    "tag": "v1"`, "  }", "}"), nil)
+	if err != nil {
+		t.Fatalf("edit: %v", err)
+	}
+	want := lines("{", `  "image": {`, `    "tag": "v2"`, "  }", "}")
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("output (-want +got):\n%s", diff)
+	}
+
+	cfg.Locations = []string{"IMAGE_TAG"}
+	got, _, err = edit(cfg, lines("# versions", "IMAGE_TAG=v1"), nil)
+	if err != nil {
+		t.Fatalf("edit dotenv: %v", err)
+	}
+	if want := lines("# versions", "IMAGE_TAG=v2"); got != want {
+		t.Errorf("dotenv output: got %q, want %q", got, want)
+	}
+}
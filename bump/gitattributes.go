@@ -0,0 +1,81 @@
+package bump
+
+import (
+	"path"
+	"strings"
+)
+
+// attributeFiles returns the paths of the .gitattributes files that can apply to file, from
+// the root of the repository down to file's directory.
+func attributeFiles(file string) []string {
+	result := []string{".gitattributes"}
+	dir := path.Dir(file)
+	if dir == "." {
+		return result
+	}
+	parts := strings.Split(dir, "/")
+	for i := range parts {
+		result = append(result, path.Join(path.Join(parts[:i+1]...), ".gitattributes"))
+	}
+	return result
+}
+
+// isText returns whether the text attribute is set or unset for file by the given
+// .gitattributes files, keyed by path.  known is false if no line mentions it.  Only the text,
+// eol, and binary attributes are understood; eol implies text, as it does in git.
+func isText(attributes map[string]string, file string) (text, known bool) {
+	for _, p := range attributeFiles(file) {
+		content, ok := attributes[p]
+		if !ok {
+			continue
+		}
+		dir := path.Dir(p)
+		rel := file
+		if dir != "." {
+			rel = strings.TrimPrefix(file, dir+"/")
+		}
+		for _, line := range strings.Split(content, "\n") {
+			fields := strings.Fields(line)
+			if len(fields) < 2 || strings.HasPrefix(fields[0], "#") || !attributePatternMatches(fields[0], rel) {
+				continue
+			}
+			for _, attr := range fields[1:] {
+				switch {
+				case attr == "text" || strings.HasPrefix(attr, "text=") || strings.HasPrefix(attr, "eol="):
+					text, known = true, true
+				case attr == "-text" || attr == "binary":
+					text, known = false, true
+				case attr == "!text":
+					text, known = false, false
+				}
+			}
+		}
+	}
+	return text, known
+}
+
+// attributePatternMatches returns true if a .gitattributes pattern matches rel, a path
+// relative to the directory containing the .gitattributes file.  Patterns without a slash
+// match the file's name at any depth.
+func attributePatternMatches(pattern, rel string) bool {
+	if !strings.Contains(pattern, "/") {
+		ok, _ := path.Match(pattern, path.Base(rel))
+		return ok
+	}
+	ok, _ := path.Match(strings.TrimPrefix(pattern, "/"), rel)
+	return ok
+}
+
+// fixLineEndings returns edited, which has LF line endings, with the line endings the
+// committed file should have.  Text files are stored with LF, as git normalizes them on
+// check-in regardless of their eol attribute.  Otherwise, the line endings of orig are kept.
+func fixLineEndings(orig, edited string, text, known bool) string {
+	lf := strings.ReplaceAll(edited, "\r\n", "\n")
+	if known && text {
+		return lf
+	}
+	if strings.Contains(orig, "\r\n") {
+		return strings.ReplaceAll(lf, "\n", "\r\n")
+	}
+	return edited
+}
@@ -0,0 +1,90 @@
+package bump
+
+import (
+	"context"
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+func TestIsText(t *testing.T) {
+	testData := []struct {
+		name       string
+		attributes map[string]string
+		file       string
+		wantText   bool
+		wantKnown  bool
+	}{
+		{name: "no attributes", file: "a.yaml"},
+		{name: "eol", attributes: map[string]string{".gitattributes": "*.yaml text eol=lf\n"}, file: "deploy/a.yaml", wantText: true, wantKnown: true},
+		{name: "other extension", attributes: map[string]string{".gitattributes": "*.go text\n"}, file: "a.yaml"},
+		{name: "binary", attributes: map[string]string{".gitattributes": "* text=auto\n*.yaml binary\n"}, file: "a.yaml", wantKnown: true},
+		{name: "unspecified", attributes: map[string]string{".gitattributes": "* text\n*.yaml !text\n"}, file: "a.yaml"},
+		{name: "anchored", attributes: map[string]string{".gitattributes": "/deploy/*.yaml -text\n"}, file: "deploy/a.yaml", wantKnown: true},
+		{name: "anchored elsewhere", attributes: map[string]string{".gitattributes": "/a.yaml text\n"}, file: "deploy/a.yaml"},
+		{
+			name: "nested overrides root",
+			attributes: map[string]string{
+				".gitattributes":        "*.yaml -text\n",
+				"deploy/.gitattributes": "# normalize these\n*.yaml text eol=crlf\n",
+			},
+			file:      "deploy/prod/a.yaml",
+			wantText:  true,
+			wantKnown: true,
+		},
+	}
+	for _, test := range testData {
+		t.Run(test.name, func(t *testing.T) {
+			text, known := isText(test.attributes, test.file)
+			if text != test.wantText || known != test.wantKnown {
+				t.Errorf("got text=%v known=%v, want text=%v known=%v", text, known, test.wantText, test.wantKnown)
+			}
+		})
+	}
+}
+
+func TestLineEndings(t *testing.T) {
+	crlf := strings.ReplaceAll(lines("image:", "  tag: v1"), "\n", "\r\n")
+	testData := []struct {
+		name       string
+		attributes string
+		want       string
+	}{
+		{name: "preserved without attributes", want: strings.ReplaceAll(lines("image:", "  tag: v2"), "\n", "\r\n")},
+		{name: "eol=lf", attributes: "*.yaml text eol=lf\n", want: lines("image:", "  tag: v2")},
+		{name: "unrelated attributes", attributes: "*.go text eol=lf\n", want: strings.ReplaceAll(lines("image:", "  tag: v2"), "\n", "\r\n")},
+	}
+	for _, test := range testData {
+		t.Run(test.name, func(t *testing.T) {
+			gh, client := newFakeGitHub(t)
+			files := map[string]string{"deploy/config.yaml": crlf}
+			if test.attributes != "" {
+				files[".gitattributes"] = test.attributes
+			}
+			gh.push("o/r", "master", files)
+			cfg := &config{
+				GithubOwner:  "o",
+				GithubRepo:   "r",
+				GithubBranch: "master",
+				File:         "deploy/config.yaml",
+				Locations:    []string{"image.tag"},
+				Replacement:  "v2",
+			}
+			if err := run(context.Background(), client, cfg, ioutil.Discard); err != nil {
+				t.Fatalf("run: %v", err)
+			}
+			if got, _ := gh.file("o/r", "master", "deploy/config.yaml"); got != test.want {
+				t.Errorf("content: got %q, want %q", got, test.want)
+			}
+
+			// Reading through a path prefix sees the root .gitattributes too.
+			f, err := fetch(context.Background(), client, "o", "r", "master", "deploy/config.yaml", "deploy", false)
+			if err != nil {
+				t.Fatalf("fetch: %v", err)
+			}
+			if got := f.Attributes[".gitattributes"]; got != test.attributes {
+				t.Errorf("attributes: got %q, want %q", got, test.attributes)
+			}
+		})
+	}
+}
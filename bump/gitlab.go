@@ -0,0 +1,171 @@
+package bump
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// gitlabBackend is the backend for a GitLab project, used with --provider gitlab.  It talks to
+// version 4 of the GitLab REST API.
+type gitlabBackend struct {
+	client *http.Client
+	// baseURL is the URL of the GitLab server, like https://gitlab.com.
+	baseURL string
+	token   string
+	// project is the path of the project, like group/project.
+	project string
+}
+
+// newGitLabBackend returns a backend for the GitLab project owner/repo on the server at
+// baseURL, authenticating with token.
+func newGitLabBackend(client *http.Client, baseURL, token, owner, repo string) *gitlabBackend {
+	return &gitlabBackend{client: client, baseURL: strings.TrimSuffix(baseURL, "/"), token: token, project: owner + "/" + repo}
+}
+
+// gitlabError is the body of a GitLab API error.
+type gitlabError struct {
+	Message interface{} `json:"message"`
+	Error   string      `json:"error"`
+}
+
+// do sends a request to path, under the project's API URL, with body encoded as JSON if it is
+// not nil, and decodes the JSON response into out.
+func (b *gitlabBackend) do(ctx context.Context, method, path string, body, out interface{}) error {
+	var r io.Reader
+	if body != nil {
+		js, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		r = bytes.NewReader(js)
+	}
+	u := b.baseURL + "/api/v4/projects/" + url.PathEscape(b.project) + path
+	req, err := http.NewRequestWithContext(ctx, method, u, r)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if b.token != "" {
+		req.Header.Set("PRIVATE-TOKEN", b.token)
+	}
+	resp, err := b.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		var e gitlabError
+		json.NewDecoder(resp.Body).Decode(&e) // The status alone will do if this fails.
+		msg := e.Error
+		if e.Message != nil {
+			msg = fmt.Sprint(e.Message)
+		}
+		return fmt.Errorf("%s %s: %s: %s", method, path, resp.Status, msg)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("%s %s: decode response: %w", method, path, err)
+	}
+	return nil
+}
+
+// head returns the commit at the head of branch.
+func (b *gitlabBackend) head(ctx context.Context, branch string) (string, error) {
+	var br struct {
+		Commit struct {
+			ID string `json:"id"`
+		} `json:"commit"`
+	}
+	if err := b.do(ctx, "GET", "/repository/branches/"+url.PathEscape(branch), nil, &br); err != nil {
+		return "", fmt.Errorf("get branch: %w", err)
+	}
+	if br.Commit.ID == "" {
+		return "", fmt.Errorf("no commit on branch %s", branch)
+	}
+	return br.Commit.ID, nil
+}
+
+func (b *gitlabBackend) Fetch(ctx context.Context, branch, file string) (*fetched, error) {
+	sha, err := b.head(ctx, branch)
+	if err != nil {
+		return nil, err
+	}
+	var f struct {
+		Content      string `json:"content"`
+		Encoding     string `json:"encoding"`
+		LastCommitID string `json:"last_commit_id"`
+	}
+	if err := b.do(ctx, "GET", "/repository/files/"+url.PathEscape(file)+"?ref="+url.QueryEscape(sha), nil, &f); err != nil {
+		return nil, fmt.Errorf("get file: %w", err)
+	}
+	content := f.Content
+	if f.Encoding == "base64" {
+		decoded, err := base64.StdEncoding.DecodeString(f.Content)
+		if err != nil {
+			return nil, fmt.Errorf("decode content of %s: %w", file, err)
+		}
+		content = string(decoded)
+	}
+	return &fetched{File: file, Content: content, CommitSHA: sha, parent: f.LastCommitID}, nil
+}
+
+// gitlabAction is one change to a file in a GitLab commit.
+type gitlabAction struct {
+	Action       string `json:"action"`
+	FilePath     string `json:"file_path"`
+	Content      string `json:"content"`
+	LastCommitID string `json:"last_commit_id,omitempty"`
+}
+
+// gitlabCommit is the body of a request to create a GitLab commit.
+type gitlabCommit struct {
+	Branch      string         `json:"branch"`
+	Message     string         `json:"commit_message"`
+	AuthorName  string         `json:"author_name,omitempty"`
+	AuthorEmail string         `json:"author_email,omitempty"`
+	Actions     []gitlabAction `json:"actions"`
+}
+
+// Commit creates the commit with the commits API.  GitLab refuses it if the file has changed
+// since its last commit was read; other changes to the branch are caught by checking its head
+// first.
+func (b *gitlabBackend) Commit(ctx context.Context, branch string, base *fetched, content, message string, id identity) (string, error) {
+	if cur, err := b.head(ctx, branch); err != nil {
+		return "", fmt.Errorf("check head of %s: %w", branch, err)
+	} else if cur != base.CommitSHA {
+		return "", fmt.Errorf("%s is now at commit %s, not %s: %w", branch, cur, base.CommitSHA, errBranchMoved)
+	}
+	req := gitlabCommit{
+		Branch:      branch,
+		Message:     message,
+		AuthorName:  id.Author.Name,
+		AuthorEmail: id.Author.Email,
+		Actions:     []gitlabAction{{Action: "update", FilePath: base.File, Content: content, LastCommitID: base.parent}},
+	}
+	var c struct {
+		ID string `json:"id"`
+	}
+	if err := b.do(ctx, "POST", "/repository/commits", req, &c); err != nil {
+		if strings.Contains(err.Error(), "has changed since") {
+			return "", fmt.Errorf("%s: %w", err, errBranchMoved)
+		}
+		return "", fmt.Errorf("create commit: %w", err)
+	}
+	return c.ID, nil
+}
+
+func (b *gitlabBackend) CommitURL(sha string) string {
+	return fmt.Sprintf("%s/%s/-/commit/%s", b.baseURL, b.project, sha)
+}
+
+func (b *gitlabBackend) String() string {
+	return b.baseURL + "/" + b.project
+}
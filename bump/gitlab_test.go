@@ -0,0 +1,194 @@
+package bump
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// fakeGitLab is enough of the GitLab API for one project, o/r, with one branch, master.
+type fakeGitLab struct {
+	t   testing.TB
+	url string
+
+	mu sync.Mutex
+	// head is the commit at the head of master, and files its files.
+	head  string
+	files map[string]string
+	// lastCommit is the last commit that changed each file.
+	lastCommit map[string]string
+	commits    []gitlabCommit
+	// before holds hooks to call before answering requests, keyed by their methods and paths
+	// under the project's repository, like "POST commits".
+	before map[string]func()
+}
+
+// newFakeGitLab starts a fake GitLab with files on master, and returns it and a backend for o/r
+// on it.
+func newFakeGitLab(t testing.TB, files map[string]string) (*fakeGitLab, *gitlabBackend) {
+	f := &fakeGitLab{t: t, files: map[string]string{}, lastCommit: map[string]string{}, before: map[string]func(){}}
+	f.commit(files)
+	s := httptest.NewServer(f)
+	t.Cleanup(s.Close)
+	f.url = s.URL
+	return f, newGitLabBackend(s.Client(), s.URL, "secret", "o", "r")
+}
+
+// commit makes a commit to master that sets files.  It must be called with mu held, or before
+// the server starts.
+func (f *fakeGitLab) commit(files map[string]string) string {
+	f.head = fmt.Sprintf("%040d", len(f.commits)+1)
+	for name, content := range files {
+		f.files[name], f.lastCommit[name] = content, f.head
+	}
+	f.commits = append(f.commits, gitlabCommit{})
+	return f.head
+}
+
+func (f *fakeGitLab) reply(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		f.t.Errorf("encode response: %v", err)
+	}
+}
+
+func (f *fakeGitLab) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("PRIVATE-TOKEN") != "secret" {
+		f.reply(w, http.StatusUnauthorized, map[string]string{"message": "401 Unauthorized"})
+		return
+	}
+	p := strings.TrimPrefix(r.URL.EscapedPath(), "/api/v4/projects/o%2Fr/repository/")
+	if hook := f.before[r.Method+" "+p]; hook != nil {
+		hook()
+	}
+	if r.Method == "POST" && p == "commits" {
+		var c gitlabCommit
+		if err := json.NewDecoder(r.Body).Decode(&c); err != nil {
+			f.reply(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+			return
+		}
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		files := map[string]string{}
+		for _, a := range c.Actions {
+			if a.LastCommitID != "" && a.LastCommitID != f.lastCommit[a.FilePath] {
+				f.reply(w, http.StatusBadRequest, map[string]string{"message": "You are attempting to update a file that has changed since you started editing it."})
+				return
+			}
+			files[a.FilePath] = a.Content
+		}
+		sha := f.commit(files)
+		f.commits[len(f.commits)-1] = c
+		f.reply(w, http.StatusCreated, map[string]string{"id": sha})
+		return
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	switch {
+	case r.Method == "GET" && p == "branches/master":
+		f.reply(w, http.StatusOK, map[string]interface{}{"name": "master", "commit": map[string]string{"id": f.head}})
+	case r.Method == "GET" && strings.HasPrefix(p, "files/"):
+		name, err := url.PathUnescape(strings.TrimPrefix(p, "files/"))
+		content, ok := f.files[name]
+		if err != nil || !ok || r.URL.Query().Get("ref") != f.head {
+			f.reply(w, http.StatusNotFound, map[string]string{"message": "404 File Not Found"})
+			return
+		}
+		f.reply(w, http.StatusOK, map[string]string{
+			"file_path":      name,
+			"encoding":       "base64",
+			"content":        base64.StdEncoding.EncodeToString([]byte(content)),
+			"last_commit_id": f.lastCommit[name],
+		})
+	default:
+		f.reply(w, http.StatusNotFound, map[string]string{"message": "404 Not Found"})
+	}
+}
+
+func TestGitLab(t *testing.T) {
+	testData := []struct {
+		name          string
+		cfg           config
+		moveBranch    bool
+		editFile      bool
+		want          string
+		wantCommits   int
+		wantErr       string
+		wantCommitErr bool
+	}{
+		{name: "commit", want: lines("image:", "  tag: v2", "  pull: Always"), wantCommits: 2},
+		{name: "dry run", cfg: config{DryRun: true}, want: lines("image:", "  tag: v1", "  pull: Always"), wantCommits: 1},
+		{name: "branch moved", moveBranch: true, wantErr: "branch moved, please retry", wantCommits: 2},
+		{name: "file changed", editFile: true, wantErr: "has changed since", wantCommits: 2},
+		{name: "retry", cfg: config{RetryConflict: true}, moveBranch: true, want: lines("image:", "  tag: v2", "  pull: Always"), wantCommits: 3},
+		{name: "unsupported", cfg: config{PullRequest: true, Force: true}, wantErr: "--provider gitlab does not support --force, --pull-request", wantCommits: 1},
+		{name: "unsupported flag names", cfg: config{Lock: true, CreateBranch: true}, wantErr: "--provider gitlab does not support --concurrency-safe-lock, --create-branch", wantCommits: 1},
+	}
+	for _, test := range testData {
+		t.Run(test.name, func(t *testing.T) {
+			gl, b := newFakeGitLab(t, map[string]string{"deploy/config.yaml": lines("image:", "  tag: v1", "  pull: Always")})
+			reads := 0
+			gl.before["GET branches/master"] = func() {
+				// Move the branch between reading the file and checking its head.
+				if reads++; reads == 2 && test.moveBranch {
+					gl.mu.Lock()
+					defer gl.mu.Unlock()
+					gl.commit(map[string]string{"README.md": "hello\n"})
+				}
+			}
+			gl.before["POST commits"] = func() {
+				// Change the file without the head moving, as if it raced the check.
+				if test.editFile && len(gl.commits) == 1 {
+					gl.mu.Lock()
+					defer gl.mu.Unlock()
+					head := gl.head
+					gl.commit(map[string]string{"deploy/config.yaml": gl.files["deploy/config.yaml"]})
+					gl.head = head
+				}
+			}
+			cfg := test.cfg
+			cfg.Provider = "gitlab"
+			cfg.GithubOwner, cfg.GithubRepo, cfg.GithubBranch = "o", "r", "master"
+			cfg.File, cfg.Locations, cfg.Replacement = "deploy/config.yaml", []string{"image.tag"}, "v2"
+			cfg.CommitMessage, cfg.AuthorName, cfg.AuthorEmail = "Bump image.tag", "Bot", "bot@example.com"
+			err := runBackend(context.Background(), b, &cfg, ioutil.Discard)
+			if test.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+					t.Fatalf("want error containing %q, got %v", test.wantErr, err)
+				}
+			} else if err != nil {
+				t.Fatalf("run: %v", err)
+			}
+			if n := len(gl.commits); n != test.wantCommits {
+				t.Errorf("commits: got %d, want %d", n, test.wantCommits)
+			}
+			if test.want == "" {
+				return
+			}
+			if diff := cmp.Diff(test.want, gl.files["deploy/config.yaml"]); diff != "" {
+				t.Errorf("content (-want +got):\n%s", diff)
+			}
+			if last := gl.commits[len(gl.commits)-1]; test.wantCommits > 1 && (last.Message != "Bump image.tag" || last.AuthorName != "Bot" || last.AuthorEmail != "bot@example.com") {
+				t.Errorf("commit %+v does not have the message and author given", last)
+			}
+		})
+	}
+}
+
+func TestGitLabCommitURL(t *testing.T) {
+	b := newGitLabBackend(http.DefaultClient, "https://gitlab.example.com/", "", "group", "project")
+	if got, want := b.CommitURL("abc"), "https://gitlab.example.com/group/project/-/commit/abc"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
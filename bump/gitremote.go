@@ -0,0 +1,180 @@
+package bump
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/go-git/go-billy/v5/memfs"
+	"github.com/go-git/go-git/v5"
+	gitconfig "github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	githttp "github.com/go-git/go-git/v5/plumbing/transport/http"
+	gitssh "github.com/go-git/go-git/v5/plumbing/transport/ssh"
+	"github.com/go-git/go-git/v5/storage/memory"
+)
+
+// gitBackend is the backend for any git remote, used with --provider git.  The branch is
+// cloned into memory, the edit committed to it there, and the commit pushed back, so no API is
+// needed, only git over HTTPS or SSH.
+type gitBackend struct {
+	url  string
+	auth transport.AuthMethod
+
+	// repo is the clone made by the last Fetch, which Commit commits to.
+	repo *git.Repository
+}
+
+// newGitBackend returns a backend for the repository at remoteURL, authenticating with auth,
+// which may be nil.
+func newGitBackend(remoteURL string, auth transport.AuthMethod) *gitBackend {
+	return &gitBackend{url: remoteURL, auth: auth}
+}
+
+// gitAuth returns how to authenticate to a git remote with a: with --ssh-key-file, or with the
+// token as the password of --git-username over HTTPS.  It returns nil if neither is set, in
+// which case SSH remotes use the SSH agent.
+func gitAuth(a *auth) (transport.AuthMethod, error) {
+	if a.SSHKeyFile != "" {
+		keys, err := gitssh.NewPublicKeysFromFile(gitssh.DefaultUsername, a.SSHKeyFile, a.SSHKeyPass)
+		if err != nil {
+			return nil, fmt.Errorf("read ssh key %s: %w", a.SSHKeyFile, err)
+		}
+		return keys, nil
+	}
+	if a.AccessToken != "" {
+		return &githttp.BasicAuth{Username: a.GitUsername, Password: a.AccessToken}, nil
+	}
+	return nil, nil
+}
+
+func (b *gitBackend) Fetch(ctx context.Context, branch, file string) (*fetched, error) {
+	repo, err := git.CloneContext(ctx, memory.NewStorage(), memfs.New(), &git.CloneOptions{
+		URL:           b.url,
+		Auth:          b.auth,
+		ReferenceName: plumbing.NewBranchReferenceName(branch),
+		SingleBranch:  true,
+		Depth:         1,
+		Tags:          git.NoTags,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("clone: %w", err)
+	}
+	head, err := repo.Head()
+	if err != nil {
+		return nil, fmt.Errorf("read head: %w", err)
+	}
+	wt, err := repo.Worktree()
+	if err != nil {
+		return nil, fmt.Errorf("open working tree: %w", err)
+	}
+	content, err := readWorktreeFile(wt, file)
+	if err != nil {
+		return nil, fmt.Errorf("read %s: %w", file, err)
+	}
+	attributes := map[string]string{}
+	for _, p := range attributeFiles(file) {
+		if a, err := readWorktreeFile(wt, p); err == nil {
+			attributes[p] = a
+		}
+	}
+	b.repo = repo
+	return &fetched{File: file, Content: content, CommitSHA: head.Hash().String(), Attributes: attributes}, nil
+}
+
+// readWorktreeFile reads file, a path from the root of the repository, from wt.
+func readWorktreeFile(wt *git.Worktree, file string) (string, error) {
+	f, err := wt.Filesystem.Open(file)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	content, err := ioutil.ReadAll(f)
+	return string(content), err
+}
+
+// Commit writes content to the working tree of the clone made by Fetch, commits it, and pushes
+// the commit to branch.  The remote's head is checked first, so that a branch that has moved on
+// is reported as errBranchMoved before pushing.
+func (b *gitBackend) Commit(ctx context.Context, branch string, base *fetched, content, message string, id identity) (string, error) {
+	if b.repo == nil {
+		return "", errors.New("commit before fetch")
+	}
+	wt, err := b.repo.Worktree()
+	if err != nil {
+		return "", fmt.Errorf("open working tree: %w", err)
+	}
+	if err := writeWorktreeFile(wt, base.File, content); err != nil {
+		return "", fmt.Errorf("write %s: %w", base.File, err)
+	}
+	if _, err := wt.Add(base.File); err != nil {
+		return "", fmt.Errorf("add %s: %w", base.File, err)
+	}
+	now := time.Now()
+	hash, err := wt.Commit(message, &git.CommitOptions{
+		Author:    &object.Signature{Name: id.Author.Name, Email: id.Author.Email, When: now},
+		Committer: &object.Signature{Name: id.Committer.Name, Email: id.Committer.Email, When: now},
+		SignKey:   id.SigningKey,
+	})
+	if err != nil {
+		return "", fmt.Errorf("commit: %w", err)
+	}
+
+	remote, err := b.repo.Remote(git.DefaultRemoteName)
+	if err != nil {
+		return "", err
+	}
+	refs, err := remote.List(&git.ListOptions{Auth: b.auth})
+	if err != nil {
+		return "", fmt.Errorf("check head of %s: %w", branch, err)
+	}
+	ref := plumbing.NewBranchReferenceName(branch)
+	for _, r := range refs {
+		if r.Name() == ref && r.Hash().String() != base.CommitSHA {
+			return "", fmt.Errorf("%s is now at commit %s, not %s: %w", branch, r.Hash(), base.CommitSHA, errBranchMoved)
+		}
+	}
+	err = b.repo.PushContext(ctx, &git.PushOptions{
+		Auth:     b.auth,
+		RefSpecs: []gitconfig.RefSpec{gitconfig.RefSpec(fmt.Sprintf("%s:%s", ref, ref))},
+	})
+	if err != nil {
+		if strings.Contains(err.Error(), "non-fast-forward") {
+			return "", fmt.Errorf("push: %v: %w", err, errBranchMoved)
+		}
+		return "", fmt.Errorf("push: %w", err)
+	}
+	return hash.String(), nil
+}
+
+// writeWorktreeFile replaces the content of file, a path from the root of the repository, in wt.
+func writeWorktreeFile(wt *git.Worktree, file, content string) error {
+	f, err := wt.Filesystem.Create(file)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write([]byte(content)); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// CommitURL returns nothing, since a plain git remote has no web pages for commits.
+func (b *gitBackend) CommitURL(sha string) string {
+	return ""
+}
+
+// String returns the URL of the remote, without any password in it.
+func (b *gitBackend) String() string {
+	if u, err := url.Parse(b.url); err == nil {
+		return u.Redacted()
+	}
+	return b.url
+}
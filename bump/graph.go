@@ -0,0 +1,262 @@
+package bump
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"sort"
+
+	"github.com/google/go-github/v32/github"
+	"sigs.k8s.io/kustomize/kyaml/yaml"
+)
+
+// dependencyGraph describes where the version of each of a set of components is pinned, and
+// which components depend on which.  It is read from a YAML file like:
+//
+//	components:
+//	  api:
+//	    file: api/version.yaml
+//	    location: version
+//	  web:
+//	    file: web/version.yaml
+//	    location: version
+//	    depends:
+//	      api:
+//	        file: web/deps.yaml
+//	        location: dependencies.api
+//
+// Bumping api to a new version sets version in api/version.yaml and dependencies.api in
+// web/deps.yaml.  If a dependency's file is omitted, it is the depending component's file.
+// The versions of dependents themselves are left alone.
+type dependencyGraph struct {
+	Components map[string]component `yaml:"components"`
+}
+
+// component is a component in a dependencyGraph.
+type component struct {
+	// File and Location point at the component's own version.
+	File     string `yaml:"file"`
+	Location string `yaml:"location"`
+	// Depends maps the name of each component this one depends on to where that dependency's
+	// version is pinned.
+	Depends map[string]pin `yaml:"depends"`
+}
+
+// pin is a location where a component pins the version of one of its dependencies.
+type pin struct {
+	File     string `yaml:"file"`
+	Location string `yaml:"location"`
+}
+
+// loadGraph reads and validates a dependency graph.
+func loadGraph(file string) (*dependencyGraph, error) {
+	content, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("read dependency graph: %w", err)
+	}
+	g := new(dependencyGraph)
+	if err := yaml.Unmarshal(content, g); err != nil {
+		return nil, fmt.Errorf("parse dependency graph %s: %w", file, err)
+	}
+	if len(g.Components) == 0 {
+		return nil, fmt.Errorf("dependency graph %s has no components", file)
+	}
+	for name, c := range g.Components {
+		if c.File == "" || c.Location == "" {
+			return nil, fmt.Errorf("dependency graph %s: component %s needs a file and a location", file, name)
+		}
+		for dep, p := range c.Depends {
+			if _, ok := g.Components[dep]; !ok {
+				return nil, fmt.Errorf("dependency graph %s: component %s depends on unknown component %s", file, name, dep)
+			}
+			if p.Location == "" {
+				return nil, fmt.Errorf("dependency graph %s: component %s has no location for its dependency on %s", file, name, dep)
+			}
+		}
+	}
+	return g, nil
+}
+
+// edits returns the locations to set to the new version of the named component, grouped by
+// file: the component's own version, and every pin of it by a component that depends on it.
+func (g *dependencyGraph) edits(name string) (map[string][]string, error) {
+	c, ok := g.Components[name]
+	if !ok {
+		return nil, fmt.Errorf("component %s is not in the dependency graph", name)
+	}
+	result := map[string][]string{c.File: {c.Location}}
+	var dependents []string
+	for other := range g.Components {
+		dependents = append(dependents, other)
+	}
+	sort.Strings(dependents)
+	for _, other := range dependents {
+		d := g.Components[other]
+		p, ok := d.Depends[name]
+		if !ok {
+			continue
+		}
+		file := p.File
+		if file == "" {
+			file = d.File
+		}
+		result[file] = append(result[file], p.Location)
+	}
+	return result, nil
+}
+
+// runGraph bumps cfg.Component to cfg.Replacement, along with every pin of it in the
+// dependency graph, in a single commit.
+func runGraph(ctx context.Context, client *github.Client, cfg *config, w io.Writer) error {
+	if cfg.Component == "" {
+		return errors.New("--dependency-graph requires --component")
+	}
+	if cfg.File != "" || len(cfg.Locations) > 0 || len(cfg.Mirrors) > 0 || len(cfg.RenameKeys) > 0 || cfg.Touch {
+		return errors.New("--dependency-graph chooses the files and locations to edit; do not pass --file, --location, --mirror, --rename-key, or --touch")
+	}
+	g, err := loadGraph(cfg.Graph)
+	if err != nil {
+		return err
+	}
+	edits, err := g.edits(cfg.Component)
+	if err != nil {
+		return err
+	}
+
+	if cfg.Lock && !cfg.DryRun && !cfg.DryRunApply {
+		release, err := acquireLock(ctx, client, cfg.GithubOwner, cfg.GithubRepo, cfg.GithubBranch, cfg.GithubBranch, cfg.LockTTL, cfg.LockWait)
+		if err != nil {
+			return fmt.Errorf("lock %s: %w", cfg.GithubBranch, err)
+		}
+		defer release()
+	}
+
+	br, _, err := client.Repositories.GetBranch(ctx, cfg.GithubOwner, cfg.GithubRepo, cfg.GithubBranch)
+	if err != nil {
+		return fmt.Errorf("get branch %s: %w", cfg.GithubBranch, err)
+	}
+	head := br.GetCommit().GetSHA()
+	treeSHA := br.GetCommit().GetCommit().GetTree().GetSHA()
+	if treeSHA == "" {
+		return fmt.Errorf("no tree in commit %s", head)
+	}
+
+	var paths []string
+	for file := range edits {
+		paths = append(paths, file)
+	}
+	sort.Strings(paths)
+	files, originals := map[string]string{}, map[string]string{}
+	for _, file := range paths {
+		content, attributes, err := readContents(ctx, client, cfg.GithubOwner, cfg.GithubRepo, head, file)
+		if err != nil {
+			return fmt.Errorf("read %s: %w", file, err)
+		}
+		fileCfg := *cfg
+		fileCfg.File = file
+		fileCfg.Locations = edits[file]
+		new, _, err := editChecked(ctx, &fileCfg, content, nil)
+		if err != nil {
+			return err
+		}
+		text, known := isText(attributes, file)
+		new = fixLineEndings(content, new, text, known)
+		if new == content {
+			log.Printf("%s already pins %s at %s", file, cfg.Component, cfg.Replacement)
+			continue
+		}
+		files[file], originals[file] = new, content
+	}
+	if len(files) == 0 {
+		if cfg.FailUnchanged {
+			return fmt.Errorf("bumping %s changed no files, and --fail-if-unchanged is set", cfg.Component)
+		}
+		log.Printf("every pin of %s is already %s; nothing to commit", cfg.Component, cfg.Replacement)
+		return writeResult(w, cfg, result{})
+	}
+	if cfg.DryRun {
+		fmt.Fprintf(os.Stderr, "Using content from commit %s\n", head)
+		for _, file := range sortedPaths(files) {
+			if cfg.DryRunFormat == "full" {
+				fmt.Fprintf(w, "# %s\n", file)
+			}
+			writeDryRun(w, cfg, file, originals[file], files[file])
+		}
+		// Every file in files changed.
+		if cfg.ExitCode {
+			return errPendingChange
+		}
+		return nil
+	}
+
+	id, err := commitIdentity(cfg)
+	if err != nil {
+		return err
+	}
+	openPR := cfg.PullRequest
+	queued, err := hasMergeQueue(ctx, client, cfg.GithubOwner, cfg.GithubRepo, cfg.GithubBranch)
+	if err != nil {
+		return err
+	}
+	if queued && !openPR {
+		log.Printf("branch %s uses a merge queue; opening a pull request instead of committing to it", cfg.GithubBranch)
+		openPR = true
+	}
+	if cfg.MergeQueue && !queued {
+		return fmt.Errorf("--merge-queue is set, but branch %s does not use a merge queue", cfg.GithubBranch)
+	}
+	if cfg.DryRunApply {
+		plan := newCommitPlan(treeSHA, head, files, cfg.CommitMessage, id)
+		plan.Action, plan.Branch, plan.Force = "update-branch", cfg.GithubBranch, cfg.Force
+		if openPR {
+			plan.Action, plan.Branch, plan.Base, plan.Force = "pull-request", prBranch(cfg, files), cfg.GithubBranch, false
+		}
+		return writePlan(w, cfg.Output, plan)
+	}
+	if openPR {
+		base := target{Owner: cfg.GithubOwner, Repo: cfg.GithubRepo, Branch: cfg.GithubBranch}
+		existing, prHead, err := openPullRequestWith(ctx, client, cfg.GithubOwner, cfg.GithubRepo, base, prBranch(cfg, files), files)
+		if err != nil {
+			return fmt.Errorf("open pull request bumping %s: %w", cfg.Component, err)
+		}
+		if existing != nil {
+			log.Printf("pull request #%d already makes this edit; nothing to push: %s", existing.GetNumber(), existing.GetHTMLURL())
+			return writeResult(w, cfg, result{URL: commitURL(webURL(client), cfg.GithubOwner, cfg.GithubRepo, prHead), CompareURL: compareURL(webURL(client), cfg.GithubOwner, cfg.GithubRepo, head, prHead), PullRequestURL: existing.GetHTMLURL()})
+		}
+		sha, pr, err := pullRequest(ctx, client, treeSHA, head, cfg.GithubOwner, cfg.GithubRepo, base, prBranch(cfg, files), files, cfg.CommitMessage, id, cfg.Force, cfg.Protected)
+		if err != nil {
+			return fmt.Errorf("open pull request bumping %s: %w", cfg.Component, err)
+		}
+		if cfg.MergeQueue {
+			if err := enqueuePullRequest(ctx, client, pr.GetNodeID()); err != nil {
+				return fmt.Errorf("add pull request #%d to the merge queue: %w", pr.GetNumber(), err)
+			}
+			log.Printf("added pull request #%d to the merge queue", pr.GetNumber())
+		}
+		return writeResult(w, cfg, result{Changed: true, SHA: sha, URL: commitURL(webURL(client), cfg.GithubOwner, cfg.GithubRepo, sha), CompareURL: compareURL(webURL(client), cfg.GithubOwner, cfg.GithubRepo, head, sha), PullRequestURL: pr.GetHTMLURL()})
+	}
+	if cfg.Force {
+		if err := checkForce(cfg.GithubBranch, cfg.Protected); err != nil {
+			return err
+		}
+	}
+	if cfg.BackupBranch != "" {
+		if err := backupBranch(ctx, client, cfg.GithubOwner, cfg.GithubRepo, cfg.BackupBranch, head, cfg.Force, cfg.Protected); err != nil {
+			return err
+		}
+		log.Printf("backed up %s at commit %s to branch %s", cfg.GithubBranch, head, cfg.BackupBranch)
+	}
+	sha, err := commit(ctx, client, treeSHA, head, cfg.GithubOwner, cfg.GithubRepo, cfg.GithubBranch, files, cfg.CommitMessage, id, cfg.Force)
+	if err != nil {
+		return fmt.Errorf("commit bump of %s: %w", cfg.Component, explainConflict(err, cfg.GithubBranch, head))
+	}
+	web := webURL(client)
+	logWith(logFields{CommitSHA: sha}, "created commit %s, editing %d files", sha, len(files))
+	log.Printf("commit: %s", commitURL(web, cfg.GithubOwner, cfg.GithubRepo, sha))
+	log.Printf("compare: %s", compareURL(web, cfg.GithubOwner, cfg.GithubRepo, head, sha))
+	return writeResult(w, cfg, result{Changed: true, SHA: sha, URL: commitURL(web, cfg.GithubOwner, cfg.GithubRepo, sha), CompareURL: compareURL(web, cfg.GithubOwner, cfg.GithubRepo, head, sha)})
+}
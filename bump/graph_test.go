@@ -0,0 +1,214 @@
+package bump
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+const testGraph = `components:
+  api:
+    file: api/version.yaml
+    location: version
+  web:
+    file: web/version.yaml
+    location: version
+    depends:
+      api:
+        file: web/deps.yaml
+        location: dependencies.api
+  worker:
+    file: worker/values.yaml
+    location: image.tag
+    depends:
+      api:
+        location: api.tag
+`
+
+func TestDependencyGraph(t *testing.T) {
+	graph := filepath.Join(t.TempDir(), "graph.yaml")
+	if err := ioutil.WriteFile(graph, []byte(testGraph), 0644); err != nil {
+		t.Fatalf("write graph: %v", err)
+	}
+	gh, client := newFakeGitHub(t)
+	before := gh.push("o/r", "master", map[string]string{
+		"api/version.yaml":   lines("version: v1.0.0"),
+		"web/version.yaml":   lines("version: v3.1.0"),
+		"web/deps.yaml":      lines("dependencies:", "  api: v1.0.0", "  db: v9"),
+		"worker/values.yaml": lines("image:", "  tag: v2.0.0", "api:", "  tag: v1.0.0"),
+	})
+	cfg := &config{
+		GithubOwner:   "o",
+		GithubRepo:    "r",
+		GithubBranch:  "master",
+		Graph:         graph,
+		Component:     "api",
+		Replacement:   "v1.1.0",
+		CommitMessage: "Bump api to v1.1.0",
+	}
+	if err := run(context.Background(), client, cfg, ioutil.Discard); err != nil {
+		t.Fatalf("run: %v", err)
+	}
+
+	head := gh.head("o/r", "master")
+	if diff := cmp.Diff([]string{before}, head.Parents); diff != "" {
+		t.Errorf("want a single commit on top of the original head:\n%s", diff)
+	}
+	want := map[string]string{
+		"api/version.yaml":   lines("version: v1.1.0"),
+		"web/version.yaml":   lines("version: v3.1.0"),
+		"web/deps.yaml":      lines("dependencies:", "  api: v1.1.0", "  db: v9"),
+		"worker/values.yaml": lines("image:", "  tag: v2.0.0", "api:", "  tag: v1.1.0"),
+	}
+	got := map[string]string{}
+	for name := range want {
+		got[name], _ = gh.file("o/r", "master", name)
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("files:\n%s", diff)
+	}
+}
+
+func TestDependencyGraphChecked(t *testing.T) {
+	testData := []struct {
+		name         string
+		files        map[string]string // added to, or replacing, the files pushed to master
+		requireMatch bool
+		schema       string
+		queue        bool
+		wantErr      string
+		wantPR       bool
+		want         map[string]string // on the branch committed to
+	}{
+		{
+			name:         "missing pin with --require-match",
+			files:        map[string]string{"web/deps.yaml": lines("dependencies:", "  db: v9")},
+			requireMatch: true,
+			wantErr:      "no match in web/deps.yaml for dependencies.api",
+		},
+		{
+			name:    "schema",
+			schema:  `{"properties": {"version": {"pattern": "^v1\\.0\\."}}}`,
+			wantErr: "edited api/version.yaml does not match schema",
+		},
+		{
+			name:   "schema matching every file",
+			schema: `{"type": "object"}`,
+			want:   map[string]string{"api/version.yaml": lines("version: v1.1.0")},
+		},
+		{
+			name: "eol from .gitattributes",
+			files: map[string]string{
+				".gitattributes":   "*.yaml text eol=lf\n",
+				"api/version.yaml": "version: v1.0.0\r\n",
+			},
+			want: map[string]string{"api/version.yaml": lines("version: v1.1.0")},
+		},
+		{
+			name:   "merge queue",
+			queue:  true,
+			wantPR: true,
+			want:   map[string]string{"api/version.yaml": lines("version: v1.1.0")},
+		},
+	}
+	for _, test := range testData {
+		t.Run(test.name, func(t *testing.T) {
+			graph := filepath.Join(t.TempDir(), "graph.yaml")
+			if err := ioutil.WriteFile(graph, []byte(testGraph), 0644); err != nil {
+				t.Fatalf("write graph: %v", err)
+			}
+			gh, client := newFakeGitHub(t)
+			files := map[string]string{
+				"api/version.yaml":   lines("version: v1.0.0"),
+				"web/version.yaml":   lines("version: v3.1.0"),
+				"web/deps.yaml":      lines("dependencies:", "  api: v1.0.0", "  db: v9"),
+				"worker/values.yaml": lines("image:", "  tag: v2.0.0", "api:", "  tag: v1.0.0"),
+			}
+			for name, content := range test.files {
+				files[name] = content
+			}
+			before := gh.push("o/r", "master", files)
+			var schema string
+			if test.schema != "" {
+				schema = filepath.Join(t.TempDir(), "schema.json")
+				if err := ioutil.WriteFile(schema, []byte(test.schema), 0644); err != nil {
+					t.Fatalf("write schema: %v", err)
+				}
+			}
+			if test.queue {
+				gh.handle("GET", "/repos/o/r/rules/branches/master", func(w http.ResponseWriter, r *http.Request) {
+					gh.reply(w, http.StatusOK, []map[string]string{{"type": "merge_queue"}})
+				})
+			}
+			cfg := &config{
+				GithubOwner:   "o",
+				GithubRepo:    "r",
+				GithubBranch:  "master",
+				Graph:         graph,
+				Component:     "api",
+				Replacement:   "v1.1.0",
+				CommitMessage: "Bump api to v1.1.0",
+				RequireMatch:  test.requireMatch,
+				Schema:        schema,
+			}
+			err := run(context.Background(), client, cfg, ioutil.Discard)
+			if test.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+					t.Fatalf("want an error containing %q, got %v", test.wantErr, err)
+				}
+				if after := gh.head("o/r", "master").SHA; after != before {
+					t.Errorf("master moved from %s to %s", before, after)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("run: %v", err)
+			}
+			branch := "master"
+			if pulls := gh.pulls("o/r"); test.wantPR {
+				if len(pulls) != 1 {
+					t.Fatalf("opened %d pull requests, want 1", len(pulls))
+				}
+				if after := gh.head("o/r", "master").SHA; after != before {
+					t.Errorf("master moved from %s to %s", before, after)
+				}
+				branch = pulls[0].GetHead().GetRef()
+			}
+			got := map[string]string{}
+			for name := range test.want {
+				got[name], _ = gh.file("o/r", branch, name)
+			}
+			if diff := cmp.Diff(test.want, got); diff != "" {
+				t.Errorf("files on %s:\n%s", branch, diff)
+			}
+		})
+	}
+}
+
+func TestLoadGraphErrors(t *testing.T) {
+	testData := []struct {
+		name  string
+		graph string
+	}{
+		{name: "empty", graph: "components: {}\n"},
+		{name: "no location", graph: "components:\n  api:\n    file: api.yaml\n"},
+		{name: "unknown dependency", graph: "components:\n  web:\n    file: web.yaml\n    location: version\n    depends:\n      api:\n        location: api\n"},
+		{name: "dependency without location", graph: "components:\n  api:\n    file: api.yaml\n    location: version\n  web:\n    file: web.yaml\n    location: version\n    depends:\n      api: {}\n"},
+	}
+	for _, test := range testData {
+		t.Run(test.name, func(t *testing.T) {
+			file := filepath.Join(t.TempDir(), "graph.yaml")
+			if err := ioutil.WriteFile(file, []byte(test.graph), 0644); err != nil {
+				t.Fatalf("write graph: %v", err)
+			}
+			if _, err := loadGraph(file); err == nil {
+				t.Error("expected error")
+			}
+		})
+	}
+}
@@ -0,0 +1,85 @@
+package bump
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"path"
+	"strings"
+
+	"github.com/google/go-github/v32/github"
+)
+
+// helmDefaults returns cfg with --file and --location defaulted for --helm-chart: the chart's
+// values.yaml, and image.tag in it.
+func helmDefaults(cfg *config) (*config, error) {
+	if cfg.HelmChart == "" {
+		if cfg.ChartBump != "" {
+			return nil, errors.New("--helm-bump-version needs --helm-chart")
+		}
+		return cfg, nil
+	}
+	if cfg.Object != "" || cfg.Replacements != nil {
+		return nil, errors.New("--helm-chart needs a single new app version, not --replacement-object or one --replacement per --location")
+	}
+	c := *cfg
+	if c.File == "" {
+		c.File = path.Join(cfg.HelmChart, "values.yaml")
+	}
+	if len(c.Locations) == 0 {
+		c.Locations = []string{"image.tag"}
+	}
+	if c.File == chartFile(cfg.HelmChart) {
+		return nil, fmt.Errorf("--file must not be %s; --helm-chart edits it", c.File)
+	}
+	return &c, nil
+}
+
+// chartFile returns the path of Chart.yaml in the chart in dir.
+func chartFile(dir string) string {
+	return path.Join(dir, "Chart.yaml")
+}
+
+// editChart returns the path and new content of Chart.yaml in --helm-chart, in the same commit
+// as orig, for a release of --replacement: appVersion is set to it, and, if
+// --helm-bump-version is set, version is bumped.
+func editChart(ctx context.Context, client *github.Client, cfg *config, orig *fileInTree) (string, string, error) {
+	name := chartFile(cfg.HelmChart)
+	content, err := readFile(ctx, client, cfg.GithubOwner, cfg.GithubRepo, orig.Tree, name)
+	if err != nil {
+		return "", "", fmt.Errorf("read chart: %w", err)
+	}
+	if _, found, err := valueAt(content, "appVersion"); err != nil || !found {
+		return "", "", fmt.Errorf("%s has no appVersion to set", name)
+	}
+	edits := []locationEdit{{Path: "appVersion", Value: cfg.Replacement}}
+	if cfg.ChartBump != "" {
+		old, found, err := valueAt(content, "version")
+		if err != nil {
+			return "", "", fmt.Errorf("read version from %s: %w", name, err)
+		}
+		if !found {
+			return "", "", fmt.Errorf("%s has no version to bump", name)
+		}
+		v, err := parseVersion(old)
+		if err != nil {
+			return "", "", fmt.Errorf("chart version in %s: %w", name, err)
+		}
+		v, err = v.bump(cfg.ChartBump)
+		if err != nil {
+			return "", "", err
+		}
+		bumped := v.String()
+		if strings.HasPrefix(old, "v") {
+			bumped = "v" + bumped
+		}
+		log.Printf("bumping chart version in %s from %s to %s", name, old, bumped)
+		edits = append(edits, locationEdit{Path: "version", Value: bumped})
+	}
+	new, _, err := editYAML(content, edits, editOptions{})
+	if err != nil {
+		return "", "", fmt.Errorf("edit %s: %w", name, err)
+	}
+	return name, fixLineEndings(content, new, false, false), nil
+}
@@ -0,0 +1,97 @@
+package bump
+
+import (
+	"context"
+	"io/ioutil"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestHelmChart(t *testing.T) {
+	chart := lines(
+		"apiVersion: v2",
+		"name: app",
+		"# the chart's own version",
+		"version: 1.2.3",
+		"appVersion: \"2.0.0\"",
+	)
+	testData := []struct {
+		name      string
+		bump      string
+		chart     string
+		wantChart string
+		wantErr   bool
+	}{
+		{
+			name:      "app version only",
+			chart:     chart,
+			wantChart: lines("apiVersion: v2", "name: app", "# the chart's own version", "version: 1.2.3", "appVersion: \"2.1.0\""),
+		},
+		{
+			name:      "patch",
+			bump:      "patch",
+			chart:     chart,
+			wantChart: lines("apiVersion: v2", "name: app", "# the chart's own version", "version: 1.2.4", "appVersion: \"2.1.0\""),
+		},
+		{
+			name:      "minor",
+			bump:      "minor",
+			chart:     chart,
+			wantChart: lines("apiVersion: v2", "name: app", "# the chart's own version", "version: 1.3.0", "appVersion: \"2.1.0\""),
+		},
+		{
+			name:    "no app version",
+			chart:   lines("apiVersion: v2", "name: app", "version: 1.2.3"),
+			wantErr: true,
+		},
+		{
+			name:    "version is not semver",
+			bump:    "patch",
+			chart:   lines("apiVersion: v2", "name: app", "version: latest", "appVersion: 2.0.0"),
+			wantErr: true,
+		},
+	}
+	for _, test := range testData {
+		t.Run(test.name, func(t *testing.T) {
+			gh, client := newFakeGitHub(t)
+			before := gh.push("o/r", "master", map[string]string{
+				"charts/app/Chart.yaml":  test.chart,
+				"charts/app/values.yaml": lines("image:", "  repository: example.com/app", "  tag: 2.0.0"),
+			})
+			cfg := &config{
+				GithubOwner:  "o",
+				GithubRepo:   "r",
+				GithubBranch: "master",
+				Replacement:  "2.1.0",
+				HelmChart:    "charts/app",
+				ChartBump:    test.bump,
+			}
+			err := run(context.Background(), client, cfg, ioutil.Discard)
+			if test.wantErr {
+				if err == nil {
+					t.Error("expected error")
+				}
+				if after := gh.head("o/r", "master").SHA; after != before {
+					t.Errorf("branch moved from %s to %s", before, after)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("run: %v", err)
+			}
+			head := gh.head("o/r", "master")
+			if diff := cmp.Diff([]string{before}, head.Parents); diff != "" {
+				t.Errorf("parents of the new head (-want +got):\n%s", diff)
+			}
+			got, _ := gh.file("o/r", "master", "charts/app/Chart.yaml")
+			if diff := cmp.Diff(test.wantChart, got); diff != "" {
+				t.Errorf("Chart.yaml (-want +got):\n%s", diff)
+			}
+			got, _ = gh.file("o/r", "master", "charts/app/values.yaml")
+			if diff := cmp.Diff(lines("image:", "  repository: example.com/app", "  tag: 2.1.0"), got); diff != "" {
+				t.Errorf("values.yaml (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
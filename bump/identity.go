@@ -0,0 +1,95 @@
+package bump
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"time"
+
+	"github.com/google/go-github/v32/github"
+	"golang.org/x/crypto/openpgp"
+)
+
+// person is the name and email address a commit is attributed to.
+type person struct {
+	Name  string `json:"name"`
+	Email string `json:"email"`
+}
+
+func (p person) String() string {
+	return fmt.Sprintf("%s <%s>", p.Name, p.Email)
+}
+
+// commitAuthor returns p as the author or committer of a commit made at date.
+func (p person) commitAuthor(date time.Time) *github.CommitAuthor {
+	return &github.CommitAuthor{Name: github.String(p.Name), Email: github.String(p.Email), Date: &date}
+}
+
+// identity is who commits are attributed to: the author, who wrote the change, and the
+// committer, who made the commit.  If SigningKey is set, commits are signed with it.
+type identity struct {
+	Author     person          `json:"author"`
+	Committer  person          `json:"committer"`
+	SigningKey *openpgp.Entity `json:"-"`
+}
+
+// resolveIdentity returns the identity that commits made for cfg carry.  The author is
+// --author-username, with their noreply address, unless --author-name or --author-email say
+// otherwise.  The committer is the author, unless --committer-username, --committer-name, or
+// --committer-email say otherwise.
+func resolveIdentity(cfg *config) identity {
+	var author person
+	if cfg.AuthorUser != "" {
+		author = person{Name: cfg.AuthorUser, Email: noreplyEmail(cfg.AuthorUser)}
+	}
+	if cfg.AuthorName != "" {
+		author.Name = cfg.AuthorName
+	}
+	if cfg.AuthorEmail != "" {
+		author.Email = cfg.AuthorEmail
+	}
+	committer := author
+	if cfg.CommitterUser != "" {
+		committer = person{Name: cfg.CommitterUser, Email: noreplyEmail(cfg.CommitterUser)}
+	}
+	if cfg.CommitterName != "" {
+		committer.Name = cfg.CommitterName
+	}
+	if cfg.CommitterMail != "" {
+		committer.Email = cfg.CommitterMail
+	}
+	return identity{Author: author, Committer: committer}
+}
+
+// emailAddress matches what looks like an email address: something, an @, and a domain.
+var emailAddress = regexp.MustCompile(`^[^@\s<>]+@[^@\s<>.]+(\.[^@\s<>.]+)*$`)
+
+// checkIdentity returns an error if --author-email or --committer-email does not look like an
+// email address.
+func checkIdentity(cfg *config) error {
+	for _, f := range []struct{ flag, email string }{{"--author-email", cfg.AuthorEmail}, {"--committer-email", cfg.CommitterMail}} {
+		if f.email != "" && !emailAddress.MatchString(f.email) {
+			return fmt.Errorf("%s %q is not an email address", f.flag, f.email)
+		}
+	}
+	return nil
+}
+
+// noreplyEmail returns the email address Github keeps private for user, which still attributes
+// commits to them.
+func noreplyEmail(user string) string {
+	return user + "@users.noreply.github.com"
+}
+
+// writeIdentity prints id to w in format, "text" or "json".
+func writeIdentity(w io.Writer, format string, id identity) error {
+	if format == "json" {
+		e := json.NewEncoder(w)
+		e.SetIndent("", "  ")
+		return e.Encode(id)
+	}
+	fmt.Fprintf(w, "author: %s\n", id.Author)
+	fmt.Fprintf(w, "committer: %s\n", id.Committer)
+	return nil
+}
@@ -0,0 +1,152 @@
+package bump
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestPrintIdentity(t *testing.T) {
+	cfg := &config{
+		GithubOwner:  "o",
+		GithubRepo:   "r",
+		GithubBranch: "master",
+		File:         "config.yaml",
+		Locations:    []string{"image.tag"},
+		Replacement:  "v2",
+		AuthorName:   "Release Bot",
+		AuthorEmail:  "bot@example.com",
+	}
+
+	var text strings.Builder
+	if err := writeIdentity(&text, "text", resolveIdentity(cfg)); err != nil {
+		t.Fatalf("write text identity: %v", err)
+	}
+	wantText := lines("author: Release Bot <bot@example.com>", "committer: Release Bot <bot@example.com>")
+	if diff := cmp.Diff(wantText, text.String()); diff != "" {
+		t.Errorf("text identity (-want +got):\n%s", diff)
+	}
+
+	var js strings.Builder
+	if err := writeIdentity(&js, "json", resolveIdentity(cfg)); err != nil {
+		t.Fatalf("write json identity: %v", err)
+	}
+	var printed identity
+	if err := json.Unmarshal([]byte(js.String()), &printed); err != nil {
+		t.Fatalf("unmarshal identity: %v", err)
+	}
+
+	// The plan that --dry-run-apply prints agrees with the printed identity.
+	gh, client := newFakeGitHub(t)
+	gh.push("o/r", "master", map[string]string{"config.yaml": lines("image:", "  tag: v1")})
+	planCfg := *cfg
+	planCfg.DryRunApply = true
+	planCfg.Output = "json"
+	var out strings.Builder
+	if err := run(context.Background(), client, &planCfg, &out); err != nil {
+		t.Fatalf("dry-run-apply: %v", err)
+	}
+	var plan commitPlan
+	if err := json.Unmarshal([]byte(out.String()), &plan); err != nil {
+		t.Fatalf("unmarshal plan: %v", err)
+	}
+	if plan.Author != printed.Author.String() || plan.Committer != printed.Committer.String() {
+		t.Errorf("plan has author %q and committer %q; printed identity is %+v", plan.Author, plan.Committer, printed)
+	}
+
+	// And so does the commit itself.
+	if err := run(context.Background(), client, cfg, ioutil.Discard); err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	head := gh.head("o/r", "master")
+	got := identity{
+		Author:    person{Name: head.Author.GetName(), Email: head.Author.GetEmail()},
+		Committer: person{Name: head.Committer.GetName(), Email: head.Committer.GetEmail()},
+	}
+	if diff := cmp.Diff(printed, got); diff != "" {
+		t.Errorf("commit identity (-printed +committed):\n%s", diff)
+	}
+}
+
+func TestCommitter(t *testing.T) {
+	author := person{Name: "Jane Doe", Email: "jane@example.com"}
+	testData := []struct {
+		name          string
+		user          string
+		committerName string
+		email         string
+		want          person
+	}{
+		{name: "author", want: author},
+		{name: "username", user: "release-bot", want: person{Name: "release-bot", Email: "release-bot@users.noreply.github.com"}},
+		{name: "username and email", user: "release-bot", email: "bot@example.com", want: person{Name: "release-bot", Email: "bot@example.com"}},
+		{name: "name and email", committerName: "Release Bot", email: "bot@example.com", want: person{Name: "Release Bot", Email: "bot@example.com"}},
+	}
+	for _, test := range testData {
+		t.Run(test.name, func(t *testing.T) {
+			gh, client := newFakeGitHub(t)
+			gh.push("o/r", "master", map[string]string{"config.yaml": lines("image:", "  tag: v1")})
+			cfg := &config{
+				GithubOwner:   "o",
+				GithubRepo:    "r",
+				GithubBranch:  "master",
+				File:          "config.yaml",
+				Locations:     []string{"image.tag"},
+				Replacement:   "v2",
+				AuthorName:    author.Name,
+				AuthorEmail:   author.Email,
+				CommitterUser: test.user,
+				CommitterName: test.committerName,
+				CommitterMail: test.email,
+			}
+			if err := run(context.Background(), client, cfg, ioutil.Discard); err != nil {
+				t.Fatalf("run: %v", err)
+			}
+			head := gh.head("o/r", "master")
+			got := identity{
+				Author:    person{Name: head.Author.GetName(), Email: head.Author.GetEmail()},
+				Committer: person{Name: head.Committer.GetName(), Email: head.Committer.GetEmail()},
+			}
+			if diff := cmp.Diff(identity{Author: author, Committer: test.want}, got); diff != "" {
+				t.Errorf("commit identity (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestAuthor(t *testing.T) {
+	testData := []struct {
+		name    string
+		cfg     config
+		want    person
+		wantErr bool
+	}{
+		{name: "username", cfg: config{AuthorUser: "release-bot"}, want: person{Name: "release-bot", Email: "release-bot@users.noreply.github.com"}},
+		{name: "username and email", cfg: config{AuthorUser: "release-bot", AuthorEmail: "releases@example.com"}, want: person{Name: "release-bot", Email: "releases@example.com"}},
+		{name: "username and name", cfg: config{AuthorUser: "release-bot", AuthorName: "Release Bot"}, want: person{Name: "Release Bot", Email: "release-bot@users.noreply.github.com"}},
+		{name: "name and email", cfg: config{AuthorName: "Release Bot", AuthorEmail: "releases@example.com"}, want: person{Name: "Release Bot", Email: "releases@example.com"}},
+		{name: "bad email", cfg: config{AuthorEmail: "release bot"}, wantErr: true},
+		{name: "bad committer email", cfg: config{AuthorEmail: "releases@example.com", CommitterMail: "releases@"}, wantErr: true},
+	}
+	for _, test := range testData {
+		t.Run(test.name, func(t *testing.T) {
+			err := checkIdentity(&test.cfg)
+			if test.wantErr {
+				if err == nil {
+					t.Errorf("expected an error for %+v", test.cfg)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("check identity: %v", err)
+			}
+			if diff := cmp.Diff(test.want, resolveIdentity(&test.cfg).Author); diff != "" {
+				t.Errorf("author (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,96 @@
+package bump
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"sigs.k8s.io/kustomize/kyaml/yaml"
+)
+
+// lintIndent checks that content is indented consistently: only with spaces, and by the same
+// number of spaces at every level of nested mappings.  YAML parsers either reject or
+// misread files that break these rules in ways that are easy to miss, so it's better not to
+// build an edit on top of one.  Sequences may be indented by that number of spaces, or not at
+// all.
+func lintIndent(content string) error {
+	if err := checkTabs(content); err != nil {
+		return err
+	}
+	l := &indentLinter{}
+	d := yaml.NewDecoder(strings.NewReader(content))
+	for {
+		var doc yaml.Node
+		err := d.Decode(&doc)
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("parse: %w", err)
+		}
+		if err := l.check(&doc); err != nil {
+			return err
+		}
+	}
+}
+
+// checkTabs returns an error pointing at the first line of content indented with a tab.
+func checkTabs(content string) error {
+	for i, line := range strings.Split(content, "\n") {
+		indent := line[:len(line)-len(strings.TrimLeft(line, " \t"))]
+		if strings.Contains(indent, "\t") {
+			return fmt.Errorf("line %d: indented with a tab; YAML must be indented with spaces", i+1)
+		}
+	}
+	return nil
+}
+
+// indentLinter remembers the indentation of the first nested mapping it sees, and checks the
+// rest against it.
+type indentLinter struct {
+	step int
+	line int
+}
+
+func (l *indentLinter) check(node *yaml.Node) error {
+	if node.Kind == yaml.MappingNode && node.Style&yaml.FlowStyle == 0 {
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, value := node.Content[i], node.Content[i+1]
+			if value.Line == key.Line || value.Style&yaml.FlowStyle != 0 {
+				continue
+			}
+			switch value.Kind {
+			case yaml.MappingNode:
+				if err := l.nested(value.Column-key.Column, value.Line); err != nil {
+					return err
+				}
+			case yaml.SequenceNode:
+				if step := value.Column - key.Column; step != 0 {
+					if err := l.nested(step, value.Line); err != nil {
+						return err
+					}
+				}
+			}
+		}
+	}
+	for _, child := range node.Content {
+		if err := l.check(child); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// nested checks that a block nested step columns to the right of its parent, on the given
+// line, is indented like the others.
+func (l *indentLinter) nested(step, line int) error {
+	if l.step == 0 {
+		l.step, l.line = step, line
+		return nil
+	}
+	if step != l.step {
+		return fmt.Errorf("line %d: indented by %d spaces, but line %d is indented by %d", line, step, l.line, l.step)
+	}
+	return nil
+}
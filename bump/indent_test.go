@@ -0,0 +1,64 @@
+package bump
+
+import (
+	"context"
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+func TestLintIndent(t *testing.T) {
+	testData := []struct {
+		name    string
+		content string
+		wantErr string
+	}{
+		{name: "two spaces", content: lines("image:", "  tag: v1", "env:", "  - name: A", "    value:", "      x: y")},
+		{name: "unindented sequence", content: lines("env:", "- name: A", "  value: b", "image:", "  tag: v1")},
+		{name: "flow style", content: lines("image: {tag: v1}", "env: [a,", "    b]", "other:", "  x: y")},
+		{name: "multiple documents", content: lines("a:", "  b: c", "---", "d:", "  e: f")},
+		{name: "tab", content: lines("image:", "  repo: app", "\ttag: v1"), wantErr: "line 3: indented with a tab"},
+		{name: "tab after spaces", content: lines("image:", "  \ttag: v1"), wantErr: "line 2: indented with a tab"},
+		{name: "mixed widths", content: lines("image:", "  tag: v1", "resources:", "    limits:", "        cpu: 1"), wantErr: "line 4: indented by 4 spaces, but line 2 is indented by 2"},
+		{name: "mixed widths across documents", content: lines("a:", "  b: c", "---", "d:", "   e: f"), wantErr: "line 5: indented by 3 spaces, but line 2 is indented by 2"},
+		{name: "indented sequence of another width", content: lines("image:", "  tag: v1", "env:", "    - a"), wantErr: "line 4: indented by 4 spaces"},
+	}
+	for _, test := range testData {
+		t.Run(test.name, func(t *testing.T) {
+			err := lintIndent(test.content)
+			if test.wantErr == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+				t.Errorf("error: got %v, want %q", err, test.wantErr)
+			}
+		})
+	}
+}
+
+func TestLintIndentBeforeEdit(t *testing.T) {
+	gh, client := newFakeGitHub(t)
+	before := gh.push("o/r", "master", map[string]string{"config.yaml": lines("image:", "  repo: app", "\ttag: v1")})
+	cfg := &config{
+		GithubOwner:  "o",
+		GithubRepo:   "r",
+		GithubBranch: "master",
+		File:         "config.yaml",
+		Locations:    []string{"image.repo"},
+		Replacement:  "other",
+		LintIndent:   true,
+	}
+	err := run(context.Background(), client, cfg, ioutil.Discard)
+	if err == nil || !strings.Contains(err.Error(), "line 3") {
+		t.Errorf("error: got %v, want one pointing at line 3", err)
+	}
+	if after := gh.head("o/r", "master").SHA; after != before {
+		t.Errorf("branch moved from %s to %s", before, after)
+	}
+	if n := gh.called("POST", "/repos/o/r/git/blobs"); n != 0 {
+		t.Errorf("created %d blobs", n)
+	}
+}
@@ -0,0 +1,48 @@
+package bump
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+)
+
+// readLocal reads file from the git repository in dir.  If ref is empty, file is read from
+// the working tree; otherwise it is read from the commit that ref (a branch, tag, or commit
+// SHA) resolves to, and that commit's SHA is returned along with the content.
+func readLocal(dir, ref, file string) (string, string, error) {
+	repo, err := git.PlainOpen(dir)
+	if err != nil {
+		return "", "", fmt.Errorf("open repository: %w", err)
+	}
+	if ref == "" {
+		wt, err := repo.Worktree()
+		if err != nil {
+			return "", "", fmt.Errorf("open working tree: %w", err)
+		}
+		content, err := ioutil.ReadFile(filepath.Join(wt.Filesystem.Root(), filepath.FromSlash(file)))
+		if err != nil {
+			return "", "", err
+		}
+		return string(content), "", nil
+	}
+	hash, err := repo.ResolveRevision(plumbing.Revision(ref))
+	if err != nil {
+		return "", "", fmt.Errorf("resolve %s: %w", ref, err)
+	}
+	commit, err := repo.CommitObject(*hash)
+	if err != nil {
+		return "", "", fmt.Errorf("read commit %s: %w", hash, err)
+	}
+	f, err := commit.File(file)
+	if err != nil {
+		return "", "", fmt.Errorf("find file in commit %s: %w", hash, err)
+	}
+	content, err := f.Contents()
+	if err != nil {
+		return "", "", fmt.Errorf("read blob %s: %w", f.Hash, err)
+	}
+	return content, hash.String(), nil
+}
@@ -0,0 +1,150 @@
+package bump
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// initLocalRepo creates a git repository containing file with the given content, committed
+// to master and to a branch called release, and returns its directory and commit.
+func initLocalRepo(t *testing.T, file, content string) (string, string) {
+	t.Helper()
+	dir := t.TempDir()
+	repo, err := git.PlainInit(dir, false)
+	if err != nil {
+		t.Fatalf("init: %v", err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, file), []byte(content), 0644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	wt, err := repo.Worktree()
+	if err != nil {
+		t.Fatalf("worktree: %v", err)
+	}
+	if _, err := wt.Add(file); err != nil {
+		t.Fatalf("add: %v", err)
+	}
+	hash, err := wt.Commit("initial", &git.CommitOptions{
+		Author: &object.Signature{Name: "test", Email: "test@example.com", When: time.Unix(0, 0)},
+	})
+	if err != nil {
+		t.Fatalf("commit: %v", err)
+	}
+	if err := repo.Storer.SetReference(plumbing.NewHashReference("refs/heads/release", hash)); err != nil {
+		t.Fatalf("create branch: %v", err)
+	}
+	return dir, hash.String()
+}
+
+func TestRunLocal(t *testing.T) {
+	dir, sha := initLocalRepo(t, "config.yaml", lines("image:", "  tag: v1"))
+	// Uncommitted changes are only seen when reading the working tree.
+	if err := ioutil.WriteFile(filepath.Join(dir, "config.yaml"), []byte(lines("image:", "  tag: dirty", "  pull: Always")), 0644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+
+	testData := []struct {
+		name, ref    string
+		format       string
+		printSHAOnly bool
+		extraFile    bool
+		want         string
+		wantErr      bool
+	}{
+		{name: "branch", ref: "release", format: "full", want: lines("image:", "  tag: v2")},
+		{name: "commit", ref: sha, format: "full", want: lines("image:", "  tag: v2")},
+		{name: "working tree", format: "full", want: lines("image:", "  tag: v2", "  pull: Always")},
+		{name: "diff", ref: "release", format: "diff", want: unifiedDiff("config.yaml", lines("image:", "  tag: v1"), lines("image:", "  tag: v2"))},
+		{name: "print SHA only", ref: "release", printSHAOnly: true},
+		{name: "missing ref", ref: "nope", wantErr: true},
+		{name: "second file", ref: "release", extraFile: true, wantErr: true},
+	}
+	for _, test := range testData {
+		t.Run(test.name, func(t *testing.T) {
+			cfg := &config{
+				LocalRepo:    dir,
+				Ref:          test.ref,
+				Files:        []string{"config.yaml"},
+				Locations:    []string{"image.tag"},
+				Replacement:  "v2",
+				DryRunFormat: test.format,
+				PrintSHAOnly: test.printSHAOnly,
+			}
+			if test.extraFile {
+				cfg.Files = append(cfg.Files, "other.yaml")
+			}
+			var out bytes.Buffer
+			err := runLocal(cfg, &out)
+			if test.wantErr {
+				if err == nil {
+					t.Error("expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("run: %v", err)
+			}
+			if got := out.String(); got != test.want {
+				t.Errorf("output: got %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestRunLocalFile(t *testing.T) {
+	testData := []struct {
+		name         string
+		dryRun       bool
+		dryRunFormat string
+		wantFile     string
+		wantOut      string
+	}{
+		{name: "in place", wantFile: lines("image:", "  tag: v2")},
+		{name: "dry run", dryRun: true, dryRunFormat: "full", wantFile: lines("image:", "  tag: v1"), wantOut: lines("image:", "  tag: v2")},
+	}
+	for _, test := range testData {
+		t.Run(test.name, func(t *testing.T) {
+			file := filepath.Join(t.TempDir(), "config.yaml")
+			if err := ioutil.WriteFile(file, []byte(lines("image:", "  tag: v1")), 0600); err != nil {
+				t.Fatalf("write: %v", err)
+			}
+			cfg := &config{
+				Local:        true,
+				Files:        []string{file},
+				Locations:    []string{"image.tag"},
+				Replacement:  "v2",
+				DryRun:       test.dryRun,
+				DryRunFormat: test.dryRunFormat,
+			}
+			var out bytes.Buffer
+			if err := runLocal(cfg, &out); err != nil {
+				t.Fatalf("run: %v", err)
+			}
+			got, err := ioutil.ReadFile(file)
+			if err != nil {
+				t.Fatalf("read: %v", err)
+			}
+			if string(got) != test.wantFile {
+				t.Errorf("file: got %q, want %q", got, test.wantFile)
+			}
+			if out.String() != test.wantOut {
+				t.Errorf("output: got %q, want %q", out.String(), test.wantOut)
+			}
+			info, err := os.Stat(file)
+			if err != nil {
+				t.Fatalf("stat: %v", err)
+			}
+			if info.Mode().Perm() != 0600 {
+				t.Errorf("mode: got %v, want 0600", info.Mode().Perm())
+			}
+		})
+	}
+}
@@ -0,0 +1,315 @@
+package bump
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"sigs.k8s.io/kustomize/kyaml/yaml"
+)
+
+// parseLocation parses a location into the path of segments that editYAML follows through a
+// document.  Three syntaxes are accepted, the first two of which may be mixed:
+//
+//	spec.template.spec.containers.[name=app].image  dotted, as accepted by yaml.Lookup
+//	spec.template.spec.containers[0].image          brackets, for indices and matches
+//	/spec/template/spec/containers/0/image          a JSON pointer (RFC 6901)
+//
+// A key containing dots is written with each dot escaped by a backslash, like
+// data.application\.yaml, or quoted in brackets, like data["application.yaml"]; a backslash
+// also escapes a [ or another backslash.
+//
+// Keys are returned as they are; sequence elements are returned in brackets, either as an
+// index like "[0]" or as a field match like "[name=app]".  In JSON pointers, segments that are
+// entirely digits are taken to be sequence indices; in dotted locations, like
+// "spec.containers.0.image", they are returned as keys, and indexKeys decides which they are.
+// A "*" or "[*]" segment is a wildcard, which expandWildcards matches against a document.
+func parseLocation(location string) ([]string, error) {
+	if strings.HasPrefix(location, "/") {
+		return parsePointer(location)
+	}
+	var path []string
+	for i := 0; i < len(location); {
+		switch location[i] {
+		case '.':
+			return nil, fmt.Errorf("location %q has an empty key at offset %d", location, i)
+		case '[':
+			if i+1 < len(location) && (location[i+1] == '"' || location[i+1] == '\'') {
+				key, n, err := quotedKey(location[i:])
+				if err != nil {
+					return nil, fmt.Errorf("location %q: %w", location, err)
+				}
+				path = append(path, key)
+				i += n
+				break
+			}
+			end := strings.IndexByte(location[i:], ']')
+			if end < 0 {
+				return nil, fmt.Errorf("location %q has an unclosed [", location)
+			}
+			elem := location[i : i+end+1]
+			switch {
+			case elem == "["+wildcard+"]":
+				elem = wildcard
+			case !isIndex(elem) && !strings.Contains(elem, "="):
+				return nil, fmt.Errorf("location %q: %s is neither an index like [0], a match like [name=value], nor [*]", location, elem)
+			}
+			path = append(path, elem)
+			i += end + 1
+		default:
+			var key strings.Builder
+			for ; i < len(location) && location[i] != '.' && location[i] != '['; i++ {
+				if location[i] == '\\' && i+1 < len(location) {
+					i++
+				}
+				key.WriteByte(location[i])
+			}
+			path = append(path, key.String())
+		}
+		if i < len(location) && location[i] == '.' {
+			i++
+			if i == len(location) {
+				return nil, fmt.Errorf("location %q ends with a .", location)
+			}
+		}
+	}
+	if len(path) == 0 {
+		return nil, errors.New("empty location")
+	}
+	return path, nil
+}
+
+// quotedKey parses the key quoted in brackets at the start of s, like ["application.yaml"] or
+// ['application.yaml'], and returns it with the length of the brackets.  Double-quoted keys
+// may contain escapes, as in Go.
+func quotedKey(s string) (string, int, error) {
+	quote := s[1]
+	for j := 2; j < len(s); j++ {
+		if s[j] == '\\' && quote == '"' {
+			j++
+			continue
+		}
+		if s[j] != quote {
+			continue
+		}
+		if j+1 == len(s) || s[j+1] != ']' {
+			return "", 0, fmt.Errorf("quoted key %s is not followed by ]", s[1:j+1])
+		}
+		key := s[2:j]
+		if quote == '"' {
+			var err error
+			key, err = strconv.Unquote(s[1 : j+1])
+			if err != nil {
+				return "", 0, fmt.Errorf("quoted key %s: %w", s[1:j+1], err)
+			}
+		}
+		if key == "" {
+			return "", 0, errors.New("quoted key is empty")
+		}
+		return key, j + 2, nil
+	}
+	return "", 0, fmt.Errorf("quoted key %s is not closed", s[1:])
+}
+
+// parsePointer parses a JSON pointer into a path, as for parseLocation.
+func parsePointer(pointer string) ([]string, error) {
+	var path []string
+	for _, segment := range strings.Split(pointer[1:], "/") {
+		segment = strings.NewReplacer("~1", "/", "~0", "~").Replace(segment)
+		switch {
+		case segment == "":
+			return nil, fmt.Errorf("pointer %q has an empty key", pointer)
+		case strings.ContainsAny(segment, ".[]"):
+			return nil, fmt.Errorf("pointer %q: key %q cannot be written as a location", pointer, segment)
+		case strings.Trim(segment, "0123456789") == "":
+			path = append(path, "["+segment+"]")
+		default:
+			path = append(path, segment)
+		}
+	}
+	return path, nil
+}
+
+// formatLocation returns the canonical form of a path returned by parseLocation: keys
+// separated by dots, with any dots in them escaped, and sequence elements in brackets directly
+// after their sequence.
+func formatLocation(path []string) string {
+	var b strings.Builder
+	for i, segment := range path {
+		if yaml.IsListIndex(segment) {
+			b.WriteString(segment)
+			continue
+		}
+		if i > 0 {
+			b.WriteByte('.')
+		}
+		b.WriteString(keyEscaper.Replace(segment))
+	}
+	return b.String()
+}
+
+// keyEscaper escapes the characters in a key that would otherwise end it in a location.
+var keyEscaper = strings.NewReplacer(`\`, `\\`, ".", `\.`, "[", `\[`)
+
+// isIndex returns true if segment is a sequence index like "[0]".
+func isIndex(segment string) bool {
+	if !yaml.IsListIndex(segment) || len(segment) < 3 {
+		return false
+	}
+	return strings.Trim(segment[1:len(segment)-1], "0123456789") == ""
+}
+
+// locate parses location, as for parseLocation, and makes it fit doc, which may be nil, with
+// indexKeys and checkRoot.
+func locate(doc *yaml.RNode, docNum int, location string) ([]string, error) {
+	path, err := parseLocation(location)
+	if err != nil {
+		return nil, err
+	}
+	path, err = indexKeys(doc, path)
+	if err != nil {
+		return nil, fmt.Errorf("look up %s in document %d: %w", location, docNum, err)
+	}
+	if err := checkRoot(doc, docNum, location, path); err != nil {
+		return nil, err
+	}
+	return path, nil
+}
+
+// indexKeys returns path with each key made only of digits, like the 0 in
+// "spec.containers.0.image", turned into an index like "[0]" if it is looked up in a sequence
+// in doc.  Looked up in a mapping, it stays a key.  doc may be nil, in which case path is
+// returned as it is.
+func indexKeys(doc *yaml.RNode, path []string) ([]string, error) {
+	if doc == nil {
+		return path, nil
+	}
+	resolved := append([]string(nil), path...)
+	for k, segment := range path {
+		if segment == wildcard {
+			// Keys after a wildcard are resolved as it is expanded.
+			break
+		}
+		if strings.Trim(segment, "0123456789") != "" {
+			continue
+		}
+		parent, err := doc.Pipe(lookup(resolved[:k])...)
+		if err != nil {
+			return nil, err
+		}
+		if parent == nil {
+			// Missing; the edit will skip it.
+			break
+		}
+		if parent.YNode().Kind == yaml.SequenceNode {
+			resolved[k] = "[" + segment + "]"
+		}
+	}
+	return resolved, nil
+}
+
+// wildcard is a path segment that matches every key of a mapping and every element of a
+// sequence, like the * in "spec.values.*.image.tag".
+const wildcard = "*"
+
+// hasWildcard returns true if path has a wildcard segment.
+func hasWildcard(path []string) bool {
+	for _, segment := range path {
+		if segment == wildcard {
+			return true
+		}
+	}
+	return false
+}
+
+// expandWildcards returns the paths in doc that path matches, with each wildcard replaced by a
+// key or index, in document order.  If the wildcards match nothing, there are none.
+// yaml.PathMatcher can match sequence elements by field, but not every key of a mapping, so
+// the document is walked here.
+func expandWildcards(doc *yaml.RNode, path []string) ([][]string, error) {
+	i := 0
+	for i < len(path) && path[i] != wildcard {
+		i++
+	}
+	if i == len(path) {
+		return [][]string{path}, nil
+	}
+	parent, err := doc.Pipe(lookup(path[:i])...)
+	if err != nil || parent == nil {
+		return nil, err
+	}
+	var segments []string
+	switch parent.YNode().Kind {
+	case yaml.MappingNode:
+		segments, err = parent.Fields()
+		if err != nil {
+			return nil, err
+		}
+	case yaml.SequenceNode:
+		for k := range parent.YNode().Content {
+			segments = append(segments, fmt.Sprintf("[%d]", k))
+		}
+	}
+	var paths [][]string
+	for _, segment := range segments {
+		matched := append(append(append([]string(nil), path[:i]...), segment), path[i+1:]...)
+		matched, err := indexKeys(doc, matched)
+		if err != nil {
+			return nil, err
+		}
+		more, err := expandWildcards(doc, matched)
+		if err != nil {
+			return nil, err
+		}
+		paths = append(paths, more...)
+	}
+	return paths, nil
+}
+
+// checkRoot returns an error if path can never match in doc because it starts with a key and
+// the document is a sequence.  Elements of a top-level list are addressed with an index like
+// "[0].tag" or a match like "[name=app].tag".  doc may be nil, in which case any path is
+// accepted.
+func checkRoot(doc *yaml.RNode, docNum int, location string, path []string) error {
+	if doc == nil || doc.YNode().Kind != yaml.SequenceNode || yaml.IsListIndex(path[0]) || path[0] == wildcard {
+		return nil
+	}
+	return fmt.Errorf("document %d is a sequence, so location %s must start with an index like [0] or a match like [name=value]", docNum, location)
+}
+
+// lookup returns filters that find the node at path, or stop the pipeline if there is none.
+// Runs of keys and field matches are handed to yaml.Lookup; indices select one element of a
+// sequence.
+func lookup(path []string) []yaml.Filter {
+	var filters []yaml.Filter
+	var run []string
+	for _, segment := range path {
+		if !isIndex(segment) {
+			run = append(run, segment)
+			continue
+		}
+		if len(run) > 0 {
+			filters = append(filters, yaml.Lookup(run...))
+			run = nil
+		}
+		i, _ := strconv.Atoi(segment[1 : len(segment)-1])
+		filters = append(filters, elementAt(i))
+	}
+	if len(run) > 0 {
+		filters = append(filters, yaml.Lookup(run...))
+	}
+	return filters
+}
+
+// elementAt is a yaml.Filter that returns the element of a sequence at an index, or nil if the
+// index is out of range or the node is not a sequence.
+type elementAt int
+
+func (i elementAt) Filter(rn *yaml.RNode) (*yaml.RNode, error) {
+	content := rn.YNode().Content
+	if rn.YNode().Kind != yaml.SequenceNode || int(i) >= len(content) {
+		return nil, nil
+	}
+	return yaml.NewRNode(content[i]), nil
+}
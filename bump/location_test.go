@@ -0,0 +1,390 @@
+package bump
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestParseLocation(t *testing.T) {
+	testData := []struct {
+		name     string
+		location string
+		want     []string
+		wantErr  bool
+	}{
+		{name: "dotted", location: "image.tag", want: []string{"image", "tag"}},
+		{name: "index", location: "spec.containers[0].image", want: []string{"spec", "containers", "[0]", "image"}},
+		{name: "dotted index", location: "spec.containers.0.image", want: []string{"spec", "containers", "0", "image"}},
+		{name: "wildcard", location: "spec.values.*.image.tag", want: []string{"spec", "values", "*", "image", "tag"}},
+		{name: "bracketed wildcard", location: "spec.containers[*].image", want: []string{"spec", "containers", "*", "image"}},
+		{name: "dotted match", location: "spec.containers.[name=app].image", want: []string{"spec", "containers", "[name=app]", "image"}},
+		{name: "bracketed match", location: "spec.containers[name=app].image", want: []string{"spec", "containers", "[name=app]", "image"}},
+		{name: "match containing dots", location: "hosts[name=a.example.com].port", want: []string{"hosts", "[name=a.example.com]", "port"}},
+		{name: "nested indices", location: "matrix[1][2]", want: []string{"matrix", "[1]", "[2]"}},
+		{name: "root index", location: "[0].tag", want: []string{"[0]", "tag"}},
+		{name: "pointer", location: "/spec/containers/0/image", want: []string{"spec", "containers", "[0]", "image"}},
+		{name: "pointer escapes", location: "/metadata/annotations/a~1b~0c", want: []string{"metadata", "annotations", "a/b~c"}},
+		{name: "escaped dot", location: `data.application\.yaml`, want: []string{"data", "application.yaml"}},
+		{name: "escaped bracket and backslash", location: `data.a\[0\]\\b.c`, want: []string{"data", `a[0]\b`, "c"}},
+		{name: "double-quoted key", location: `data["application.yaml"].x`, want: []string{"data", "application.yaml", "x"}},
+		{name: "single-quoted key", location: `data['application.yaml']`, want: []string{"data", "application.yaml"}},
+		{name: "quoted key with escapes", location: `data["a\"]b"]`, want: []string{"data", `a"]b`}},
+		{name: "quoted key then index", location: `data["a.b"][0]`, want: []string{"data", "a.b", "[0]"}},
+		{name: "empty", location: "", wantErr: true},
+		{name: "unclosed quote", location: `data["a.b]`, wantErr: true},
+		{name: "quote not followed by bracket", location: `data["a"b]`, wantErr: true},
+		{name: "empty quoted key", location: `data[""]`, wantErr: true},
+		{name: "empty key", location: "a..b", wantErr: true},
+		{name: "leading dot", location: ".a", wantErr: true},
+		{name: "trailing dot", location: "a.", wantErr: true},
+		{name: "unclosed bracket", location: "a[0", wantErr: true},
+		{name: "bad bracket", location: "a[x]", wantErr: true},
+		{name: "empty bracket", location: "a[]", wantErr: true},
+		{name: "pointer with dots", location: "/data/app.yaml", wantErr: true},
+		{name: "pointer with empty key", location: "/a//b", wantErr: true},
+	}
+	for _, test := range testData {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := parseLocation(test.location)
+			if test.wantErr {
+				if err == nil {
+					t.Errorf("expected error, got %#v", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parse: %v", err)
+			}
+			if diff := cmp.Diff(got, test.want); diff != "" {
+				t.Errorf("path:\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestPrintNormalizedLocations(t *testing.T) {
+	locations := []string{
+		"spec.containers.[name=app].image",
+		"spec.containers[name=app].image",
+		"spec.containers[0].image",
+		"/spec/containers/0/image",
+		"image.tag",
+		`data["application.yaml"]`,
+		`data.application\.yaml`,
+	}
+	var out bytes.Buffer
+	if err := printLocations(locations, &out); err != nil {
+		t.Fatalf("print locations: %v", err)
+	}
+	want := lines(
+		"spec.containers[name=app].image",
+		"spec.containers[name=app].image",
+		"spec.containers[0].image",
+		"spec.containers[0].image",
+		"image.tag",
+		`data.application\.yaml`,
+		`data.application\.yaml`,
+	)
+	if diff := cmp.Diff(out.String(), want); diff != "" {
+		t.Errorf("output:\n%s", diff)
+	}
+
+	if err := printLocations([]string{"a..b"}, &out); err == nil {
+		t.Error("expected an error printing an invalid location")
+	}
+}
+
+func TestEditIndex(t *testing.T) {
+	input := lines(
+		"spec:",
+		"  containers:",
+		"  - name: app",
+		"    image: app:v1",
+		"  - name: sidecar",
+		"    image: sidecar:v1",
+	)
+	testData := []struct {
+		name     string
+		location string
+		want     string
+	}{
+		{
+			name:     "brackets",
+			location: "spec.containers[1].image",
+			want:     lines("spec:", "  containers:", "  - name: app", "    image: app:v1", "  - name: sidecar", "    image: new"),
+		},
+		{
+			name:     "pointer",
+			location: "/spec/containers/0/image",
+			want:     lines("spec:", "  containers:", "  - name: app", "    image: new", "  - name: sidecar", "    image: sidecar:v1"),
+		},
+		{
+			name:     "dotted index",
+			location: "spec.containers.1.image",
+			want:     lines("spec:", "  containers:", "  - name: app", "    image: app:v1", "  - name: sidecar", "    image: new"),
+		},
+		{
+			name:     "out of range",
+			location: "spec.containers[2].image",
+			want:     input,
+		},
+		{
+			name:     "dotted out of range",
+			location: "spec.containers.2.image",
+			want:     input,
+		},
+		{
+			name:     "dotted index past a missing key",
+			location: "spec.initContainers.0.image",
+			want:     input,
+		},
+		{
+			name:     "not a sequence",
+			location: "spec[0].image",
+			want:     input,
+		},
+	}
+	for _, test := range testData {
+		t.Run(test.name, func(t *testing.T) {
+			got, _, err := editYAML(input, editsFor([]string{test.location}, "new"), editOptions{})
+			if err != nil {
+				t.Fatalf("edit: %v", err)
+			}
+			if diff := cmp.Diff(got, test.want); diff != "" {
+				t.Errorf("output:\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestEditRootSequence(t *testing.T) {
+	input := lines(
+		"- name: app",
+		"  tag: v1",
+		"- name: sidecar",
+		"  tag: v1",
+	)
+	testData := []struct {
+		name     string
+		input    string
+		location string
+		labels   map[string]string
+		want     string
+		wantErr  bool
+	}{
+		{
+			name:     "index",
+			location: "[1].tag",
+			want:     lines("- name: app", "  tag: v1", "- name: sidecar", "  tag: v2"),
+		},
+		{
+			name:     "match",
+			location: "[name=app].tag",
+			want:     lines("- name: app", "  tag: v2", "- name: sidecar", "  tag: v1"),
+		},
+		{
+			name:     "pointer",
+			location: "/0/tag",
+			want:     lines("- name: app", "  tag: v2", "- name: sidecar", "  tag: v1"),
+		},
+		{
+			name:     "out of range",
+			location: "[2].tag",
+			want:     input,
+		},
+		{
+			name:     "empty sequence",
+			input:    lines("[]"),
+			location: "[0].tag",
+			want:     lines("[]"),
+		},
+		{
+			name:     "no labels on a sequence",
+			location: "[0].tag",
+			labels:   map[string]string{"app": "app"},
+			want:     input,
+		},
+		{
+			name:     "key",
+			location: "tag",
+			wantErr:  true,
+		},
+	}
+	for _, test := range testData {
+		t.Run(test.name, func(t *testing.T) {
+			in := input
+			if test.input != "" {
+				in = test.input
+			}
+			got, _, err := editYAML(in, editsFor([]string{test.location}, "v2"), editOptions{MatchLabels: test.labels})
+			if test.wantErr {
+				if err == nil {
+					t.Errorf("expected error, got %q", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("edit: %v", err)
+			}
+			if diff := cmp.Diff(got, test.want); diff != "" {
+				t.Errorf("output:\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestEditNumericKey(t *testing.T) {
+	input := lines(
+		"ports:",
+		"  80:",
+		"    name: http",
+		"  443:",
+		"    name: https",
+		"hosts:",
+		"- ports:",
+		"    80: web",
+	)
+	testData := []struct {
+		name     string
+		location string
+		want     string
+	}{
+		{
+			name:     "key in a mapping",
+			location: "ports.443.name",
+			want:     lines("ports:", "  80:", "    name: http", "  443:", "    name: new", "hosts:", "- ports:", "    80: web"),
+		},
+		{
+			name:     "index then key",
+			location: "hosts.0.ports.80",
+			want:     lines("ports:", "  80:", "    name: http", "  443:", "    name: https", "hosts:", "- ports:", "    80: new"),
+		},
+		{
+			name:     "missing key",
+			location: "ports.0.name",
+			want:     input,
+		},
+	}
+	for _, test := range testData {
+		t.Run(test.name, func(t *testing.T) {
+			got, _, err := editYAML(input, editsFor([]string{test.location}, "new"), editOptions{})
+			if err != nil {
+				t.Fatalf("edit: %v", err)
+			}
+			if diff := cmp.Diff(got, test.want); diff != "" {
+				t.Errorf("output:\n%s", diff)
+			}
+			value, found, err := valueAt(test.want, test.location)
+			if err != nil {
+				t.Fatalf("valueAt: %v", err)
+			}
+			if wantFound := test.want != input; found != wantFound || (found && value != "new") {
+				t.Errorf("valueAt: got %q, %v", value, found)
+			}
+		})
+	}
+}
+
+func TestEditWildcard(t *testing.T) {
+	input := lines(
+		"spec:",
+		"  values:",
+		"    api:",
+		"      image:",
+		"        tag: v1",
+		"    worker:",
+		"      image:",
+		"        repo: worker",
+		"    docs:",
+		"      enabled: false",
+		"  containers:",
+		"  - name: app",
+		"    image: app:v1",
+		"  - name: sidecar",
+		"    image: sidecar:v1",
+		"  matrix:",
+		"  - [a, b]",
+		"  - [c]",
+	)
+	testData := []struct {
+		name     string
+		location string
+		want     string
+	}{
+		{
+			name:     "mapping keys",
+			location: "spec.values.*.image.tag",
+			want:     strings.Replace(input, "        tag: v1", "        tag: new", 1),
+		},
+		{
+			name:     "sequence elements",
+			location: "spec.containers.*.image",
+			want:     strings.NewReplacer("image: app:v1", "image: new", "image: sidecar:v1", "image: new").Replace(input),
+		},
+		{
+			name:     "bracketed sequence elements",
+			location: "spec.containers[*].image",
+			want:     strings.NewReplacer("image: app:v1", "image: new", "image: sidecar:v1", "image: new").Replace(input),
+		},
+		{
+			name:     "two wildcards and an index",
+			location: "spec.matrix.*.0",
+			want:     strings.NewReplacer("[a, b]", "[new, b]", "[c]", "[new]").Replace(input),
+		},
+		{
+			name:     "matches nothing",
+			location: "spec.values.*.image.digest",
+			want:     input,
+		},
+		{
+			name:     "under a missing key",
+			location: "spec.missing.*.image",
+			want:     input,
+		},
+		{
+			name:     "under a scalar",
+			location: "spec.values.docs.enabled.*",
+			want:     input,
+		},
+	}
+	for _, test := range testData {
+		t.Run(test.name, func(t *testing.T) {
+			got, _, err := editYAML(input, editsFor([]string{test.location}, "new"), editOptions{})
+			if err != nil {
+				t.Fatalf("edit: %v", err)
+			}
+			if diff := cmp.Diff(test.want, got); diff != "" {
+				t.Errorf("output (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestEditKeyWithDots(t *testing.T) {
+	input := lines(
+		"data:",
+		"  application.yaml: old",
+		"  application:",
+		"    yaml: other",
+	)
+	want := lines(
+		"data:",
+		"  application.yaml: new",
+		"  application:",
+		"    yaml: other",
+	)
+	for _, location := range []string{`data.application\.yaml`, `data["application.yaml"]`, `data['application.yaml']`} {
+		t.Run(location, func(t *testing.T) {
+			got, _, err := editYAML(input, editsFor([]string{location}, "new"), editOptions{})
+			if err != nil {
+				t.Fatalf("edit: %v", err)
+			}
+			if diff := cmp.Diff(want, got); diff != "" {
+				t.Errorf("output (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
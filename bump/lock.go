@@ -0,0 +1,124 @@
+package bump
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/google/go-github/v32/github"
+)
+
+// lockPollInterval is how often a run waiting for a lock checks whether it has been released.
+var lockPollInterval = 2 * time.Second
+
+// lockRef returns the ref, without the leading "refs/", that runs editing branch hold while
+// they work.
+func lockRef(branch string) string {
+	return "version-bump/lock/" + branch
+}
+
+// acquireLock takes the advisory lock on branch, waiting up to wait for another run to release
+// it.  The lock is a ref pointing at a commit made when it was taken, whose tree is the tree of
+// the head of base; a lock older than ttl is assumed to be left over from a run that died, and
+// is broken.  If ttl is zero, locks never go stale.  The returned function releases the lock.
+func acquireLock(ctx context.Context, client *github.Client, owner, repo, branch, base string, ttl, wait time.Duration) (func(), error) {
+	br, _, err := client.Repositories.GetBranch(ctx, owner, repo, base)
+	if err != nil {
+		return nil, fmt.Errorf("get branch %s: %w", base, err)
+	}
+	now := time.Now()
+	who := &github.CommitAuthor{Name: github.String("version-bump"), Email: github.String("version-bump@localhost"), Date: &now}
+	c, _, err := client.Git.CreateCommit(ctx, owner, repo, &github.Commit{
+		Message:   github.String(fmt.Sprintf("version-bump lock for %s", branch)),
+		Tree:      &github.Tree{SHA: br.GetCommit().GetCommit().GetTree().SHA},
+		Author:    who,
+		Committer: who,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("create lock commit: %w", err)
+	}
+	ref := lockRef(branch)
+	deadline := now.Add(wait)
+	for {
+		_, resp, err := client.Git.CreateRef(ctx, owner, repo, &github.Reference{Ref: github.String("refs/" + ref), Object: &github.GitObject{SHA: c.SHA}})
+		if err == nil {
+			log.Printf("locked %s", branch)
+			return func() { releaseLock(ctx, client, owner, repo, branch, c.GetSHA()) }, nil
+		}
+		if resp == nil || resp.StatusCode != http.StatusUnprocessableEntity {
+			return nil, fmt.Errorf("create lock ref %s: %w", ref, err)
+		}
+
+		// Someone else holds the lock.
+		held, err := lockTime(ctx, client, owner, repo, ref)
+		if errors.Is(err, errNoLock) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if ttl > 0 && time.Since(held) > ttl {
+			log.Printf("breaking stale lock on %s, taken at %s", branch, held.Format(time.RFC3339))
+			if _, err := client.Git.DeleteRef(ctx, owner, repo, ref); err != nil {
+				log.Printf("break lock on %s: %v", branch, err)
+			}
+			continue
+		}
+		remaining := time.Until(deadline)
+		if remaining <= 0 {
+			return nil, fmt.Errorf("branch %s is locked by another run since %s", branch, held.Format(time.RFC3339))
+		}
+		log.Printf("waiting for the lock on %s, held since %s", branch, held.Format(time.RFC3339))
+		sleep := lockPollInterval
+		if sleep > remaining {
+			sleep = remaining
+		}
+		select {
+		case <-time.After(sleep):
+		case <-ctx.Done():
+			return nil, fmt.Errorf("wait for lock on %s: %w", branch, ctx.Err())
+		}
+	}
+}
+
+// errNoLock is returned by lockTime if the lock has been released.
+var errNoLock = errors.New("lock released")
+
+// lockTime returns the time the lock at ref was taken.
+func lockTime(ctx context.Context, client *github.Client, owner, repo, ref string) (time.Time, error) {
+	r, resp, err := client.Git.GetRef(ctx, owner, repo, ref)
+	if resp != nil && resp.StatusCode == http.StatusNotFound {
+		return time.Time{}, errNoLock
+	}
+	if err != nil {
+		return time.Time{}, fmt.Errorf("read lock ref %s: %w", ref, err)
+	}
+	c, _, err := client.Git.GetCommit(ctx, owner, repo, r.GetObject().GetSHA())
+	if err != nil {
+		return time.Time{}, fmt.Errorf("read lock commit %s: %w", r.GetObject().GetSHA(), err)
+	}
+	return c.GetCommitter().GetDate(), nil
+}
+
+// releaseLock deletes the lock on branch, if it is still the lock at sha.  Errors are logged;
+// a lock that can't be released goes stale eventually.
+func releaseLock(ctx context.Context, client *github.Client, owner, repo, branch, sha string) {
+	ref := lockRef(branch)
+	r, _, err := client.Git.GetRef(ctx, owner, repo, ref)
+	if err != nil {
+		log.Printf("release lock on %s: %v", branch, err)
+		return
+	}
+	if r.GetObject().GetSHA() != sha {
+		log.Printf("release lock on %s: lock was broken by another run", branch)
+		return
+	}
+	if _, err := client.Git.DeleteRef(ctx, owner, repo, ref); err != nil {
+		log.Printf("release lock on %s: %v", branch, err)
+		return
+	}
+	log.Printf("unlocked %s", branch)
+}
@@ -0,0 +1,82 @@
+package bump
+
+import (
+	"context"
+	"io/ioutil"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestConcurrencySafeLock(t *testing.T) {
+	defer func(d time.Duration) { lockPollInterval = d }(lockPollInterval)
+	lockPollInterval = 10 * time.Millisecond
+
+	testData := []struct {
+		name      string
+		held      bool
+		releaseIn time.Duration
+		ttl       time.Duration
+		wait      time.Duration
+		wantErr   bool
+	}{
+		{name: "unlocked"},
+		{name: "fresh lock", held: true, ttl: time.Hour, wantErr: true},
+		{name: "fresh lock, never stale", held: true, wantErr: true},
+		{name: "stale lock", held: true, ttl: time.Nanosecond},
+		{name: "released while waiting", held: true, ttl: time.Hour, releaseIn: 50 * time.Millisecond, wait: 10 * time.Second},
+		{name: "not released in time", held: true, ttl: time.Hour, wait: 50 * time.Millisecond, wantErr: true},
+	}
+	for _, test := range testData {
+		t.Run(test.name, func(t *testing.T) {
+			ctx := context.Background()
+			gh, client := newFakeGitHub(t)
+			before := gh.push("o/r", "master", map[string]string{"config.yaml": lines("image:", "  tag: v1")})
+			if test.held {
+				release, err := acquireLock(ctx, client, "o", "r", "master", "master", 0, 0)
+				if err != nil {
+					t.Fatalf("take lock: %v", err)
+				}
+				if test.releaseIn > 0 {
+					go func() {
+						time.Sleep(test.releaseIn)
+						release()
+					}()
+				}
+			}
+			cfg := &config{
+				GithubOwner:  "o",
+				GithubRepo:   "r",
+				GithubBranch: "master",
+				File:         "config.yaml",
+				Locations:    []string{"image.tag"},
+				Replacement:  "v2",
+				Lock:         true,
+				LockTTL:      test.ttl,
+				LockWait:     test.wait,
+			}
+			err := run(ctx, client, cfg, ioutil.Discard)
+			if test.wantErr {
+				if err == nil || !strings.Contains(err.Error(), "locked by another run") {
+					t.Errorf("want an error about the lock, got %v", err)
+				}
+				if after := gh.head("o/r", "master").SHA; after != before {
+					t.Errorf("branch moved from %s to %s", before, after)
+				}
+				if _, _, err := client.Git.GetRef(ctx, "o", "r", lockRef("master")); err != nil {
+					t.Errorf("the other run's lock was released: %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("run: %v", err)
+			}
+			if got, _ := gh.file("o/r", "master", "config.yaml"); got != lines("image:", "  tag: v2") {
+				t.Errorf("content: got %q", got)
+			}
+			if _, _, err := client.Git.GetRef(ctx, "o", "r", lockRef("master")); err == nil {
+				t.Error("lock was not released")
+			}
+		})
+	}
+}
@@ -0,0 +1,100 @@
+package bump
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"strings"
+	"sync"
+	"time"
+)
+
+// logEntry is a line of --log-format json output.
+type logEntry struct {
+	Time      string   `json:"time"`
+	Level     string   `json:"level"`
+	Message   string   `json:"message"`
+	CommitSHA string   `json:"commit_sha,omitempty"`
+	File      string   `json:"file,omitempty"`
+	Locations []string `json:"locations,omitempty"`
+}
+
+// logFields are the fields of a log entry that go beyond its message.  In text format they are
+// not printed; the message should say the same.
+type logFields struct {
+	CommitSHA string
+}
+
+// jsonLog is where the standard logger writes with --log-format json.  Each line logged
+// becomes an entry at level info, or warn if it starts with "warning: ", carrying the file and
+// locations being edited.
+type jsonLog struct {
+	mu        sync.Mutex
+	w         io.Writer
+	file      string
+	locations []string
+	now       func() time.Time
+}
+
+// newJSONLog returns a jsonLog writing entries for a run with cfg to w.
+func newJSONLog(w io.Writer, cfg *config) *jsonLog {
+	file := cfg.File
+	if file == "" && len(cfg.Files) > 0 {
+		file = cfg.Files[0]
+	}
+	return &jsonLog{w: w, file: file, locations: cfg.Locations, now: time.Now}
+}
+
+// Write writes an entry for each line in p.
+func (j *jsonLog) Write(p []byte) (int, error) {
+	for _, line := range strings.Split(strings.TrimSuffix(string(p), "\n"), "\n") {
+		level := "info"
+		if strings.HasPrefix(line, "warning: ") {
+			level, line = "warn", strings.TrimPrefix(line, "warning: ")
+		}
+		if err := j.entry(level, line, logFields{}); err != nil {
+			return 0, err
+		}
+	}
+	return len(p), nil
+}
+
+// entry writes an entry with the given level, message, and fields.
+func (j *jsonLog) entry(level, message string, f logFields) error {
+	out, err := json.Marshal(logEntry{
+		Time:      j.now().UTC().Format(time.RFC3339),
+		Level:     level,
+		Message:   message,
+		CommitSHA: f.CommitSHA,
+		File:      j.file,
+		Locations: j.locations,
+	})
+	if err != nil {
+		return err
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	_, err = j.w.Write(append(out, '\n'))
+	return err
+}
+
+// logWith logs a message like log.Printf, with fields added to it if the standard logger is
+// writing JSON.
+func logWith(f logFields, format string, args ...interface{}) {
+	if j, ok := log.Writer().(*jsonLog); ok {
+		j.entry("info", fmt.Sprintf(format, args...), f)
+		return
+	}
+	log.Printf(format, args...)
+}
+
+// logError reports err to w as an error entry with --log-format json, and as a log line
+// otherwise.
+func logError(w io.Writer, cfg *config, err error) {
+	if cfg.LogFormat == "json" {
+		newJSONLog(w, cfg).entry("error", err.Error(), logFields{})
+		return
+	}
+	log.New(w, "", log.LstdFlags).Print(err)
+}
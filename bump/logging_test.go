@@ -0,0 +1,88 @@
+package bump
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"log"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// readLog parses the entries of a --log-format json log.
+func readLog(t *testing.T, out string) []logEntry {
+	t.Helper()
+	var entries []logEntry
+	for _, line := range strings.Split(strings.TrimSuffix(out, "\n"), "\n") {
+		var e logEntry
+		if err := json.Unmarshal([]byte(line), &e); err != nil {
+			t.Fatalf("unmarshal log line %q: %v", line, err)
+		}
+		entries = append(entries, e)
+	}
+	return entries
+}
+
+func TestJSONLog(t *testing.T) {
+	gh, client := newFakeGitHub(t)
+	gh.push("o/r", "master", map[string]string{"config.yaml": lines("image:", "  tag: v1")})
+	cfg := &config{
+		GithubOwner:  "o",
+		GithubRepo:   "r",
+		GithubBranch: "master",
+		File:         "config.yaml",
+		Locations:    []string{"image.tag", "image.missing"},
+		Replacement:  "v2",
+		LogFormat:    "json",
+	}
+	var buf bytes.Buffer
+	j := newJSONLog(&buf, cfg)
+	j.now = func() time.Time { return time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC) }
+	log.SetFlags(0)
+	log.SetOutput(j)
+	defer func() {
+		log.SetFlags(log.LstdFlags)
+		log.SetOutput(os.Stderr)
+	}()
+	if err := run(context.Background(), client, cfg, ioutil.Discard); err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	sha := gh.head("o/r", "master").SHA
+
+	// Leave out the URLs, which have the port of the fake in them.
+	var got []logEntry
+	for _, e := range readLog(t, buf.String()) {
+		if !strings.HasPrefix(e.Message, "commit: ") && !strings.HasPrefix(e.Message, "compare: ") {
+			got = append(got, e)
+		}
+	}
+	entry := func(level, message, commit string) logEntry {
+		return logEntry{Time: "2020-01-02T03:04:05Z", Level: level, Message: message, CommitSHA: commit, File: "config.yaml", Locations: cfg.Locations}
+	}
+	want := []logEntry{
+		entry("info", "config.yaml: image.tag: v1 -> v2", ""),
+		entry("warn", "no match in config.yaml for image.missing", ""),
+		entry("info", "created commit "+sha, sha),
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("log entries (-want +got):\n%s", diff)
+	}
+}
+
+func TestJSONLogError(t *testing.T) {
+	cfg := &config{File: "config.yaml", Locations: []string{"image.tag"}, LogFormat: "json"}
+	var stderr bytes.Buffer
+	if code := fail(&stderr, cfg, errors.New("parse yaml")); code != 1 {
+		t.Errorf("exit code: got %d, want 1", code)
+	}
+	entries := readLog(t, stderr.String())
+	if len(entries) != 1 || entries[0].Level != "error" || entries[0].Message != "parse yaml" || entries[0].File != "config.yaml" {
+		t.Errorf("want one error entry for config.yaml, got %+v", entries)
+	}
+}
@@ -0,0 +1,72 @@
+package bump
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/google/go-github/v32/github"
+)
+
+// hasMergeQueue returns true if the rules that apply to branch require changes to go through a
+// merge queue.  Servers that do not know about branch rules have no merge queues.
+func hasMergeQueue(ctx context.Context, client *github.Client, owner, repo, branch string) (bool, error) {
+	req, err := client.NewRequest("GET", fmt.Sprintf("repos/%v/%v/rules/branches/%v", owner, repo, branch), nil)
+	if err != nil {
+		return false, err
+	}
+	var rules []struct {
+		Type string `json:"type"`
+	}
+	resp, err := client.Do(ctx, req, &rules)
+	if resp != nil && resp.StatusCode == http.StatusNotFound {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("get rules for branch %s: %w", branch, err)
+	}
+	for _, r := range rules {
+		if r.Type == "merge_queue" {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// enqueuePullRequest adds the pull request with the given GraphQL node ID to the merge queue of
+// its base branch.  There is no REST API for merge queues.
+func enqueuePullRequest(ctx context.Context, client *github.Client, nodeID string) error {
+	req, err := client.NewRequest("POST", graphQLPath(client), map[string]interface{}{
+		"query":     `mutation($id: ID!) { enqueuePullRequest(input: {pullRequestId: $id}) { mergeQueueEntry { position } } }`,
+		"variables": map[string]string{"id": nodeID},
+	})
+	if err != nil {
+		return err
+	}
+	var result struct {
+		Errors []struct {
+			Message string `json:"message"`
+		} `json:"errors"`
+	}
+	if _, err := client.Do(ctx, req, &result); err != nil {
+		return fmt.Errorf("enqueue pull request: %w", err)
+	}
+	if len(result.Errors) > 0 {
+		var msgs []string
+		for _, e := range result.Errors {
+			msgs = append(msgs, e.Message)
+		}
+		return fmt.Errorf("enqueue pull request: %s", strings.Join(msgs, "; "))
+	}
+	return nil
+}
+
+// graphQLPath returns the path of the GraphQL endpoint relative to client's REST API base URL.
+// On Github Enterprise, the REST API is at /api/v3/ and GraphQL is at /api/graphql.
+func graphQLPath(client *github.Client) string {
+	if strings.HasSuffix(client.BaseURL.Path, "/api/v3/") {
+		return "../graphql"
+	}
+	return "graphql"
+}
@@ -0,0 +1,103 @@
+package bump
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"testing"
+
+	"github.com/google/go-github/v32/github"
+)
+
+func TestMergeQueue(t *testing.T) {
+	testData := []struct {
+		name         string
+		queue        bool
+		mergeQueue   bool
+		wantPR       bool
+		wantEnqueued bool
+		wantErr      bool
+	}{
+		{name: "no queue", wantPR: false},
+		{name: "queue detected", queue: true, wantPR: true},
+		{name: "queue detected and enqueued", queue: true, mergeQueue: true, wantPR: true, wantEnqueued: true},
+		{name: "enqueue without a queue", mergeQueue: true, wantErr: true},
+	}
+	for _, test := range testData {
+		t.Run(test.name, func(t *testing.T) {
+			gh, client := newFakeGitHub(t)
+			before := gh.push("o/r", "main", map[string]string{"config.yaml": lines("image:", "  tag: v1")})
+			if test.queue {
+				gh.handle("GET", "/repos/o/r/rules/branches/main", func(w http.ResponseWriter, r *http.Request) {
+					gh.reply(w, http.StatusOK, []map[string]string{{"type": "pull_request"}, {"type": "merge_queue"}})
+				})
+			}
+			var enqueued []string
+			gh.handle("POST", "/graphql", func(w http.ResponseWriter, r *http.Request) {
+				var req struct {
+					Variables map[string]string `json:"variables"`
+				}
+				if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+					t.Errorf("decode graphql request: %v", err)
+				}
+				enqueued = append(enqueued, req.Variables["id"])
+				gh.reply(w, http.StatusOK, map[string]interface{}{"data": map[string]interface{}{}})
+			})
+
+			cfg := &config{
+				GithubOwner:  "o",
+				GithubRepo:   "r",
+				GithubBranch: "main",
+				File:         "config.yaml",
+				Locations:    []string{"image.tag"},
+				Replacement:  "v2",
+				MergeQueue:   test.mergeQueue,
+			}
+			err := run(context.Background(), client, cfg, ioutil.Discard)
+			if test.wantErr {
+				if err == nil {
+					t.Error("expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("run: %v", err)
+			}
+			pulls := gh.pulls("o/r")
+			if got := len(pulls) == 1; got != test.wantPR {
+				t.Fatalf("opened %d pull requests, want PR: %v", len(pulls), test.wantPR)
+			}
+			if moved := gh.head("o/r", "main").SHA != before; moved == test.wantPR {
+				t.Errorf("main moved: %v, want %v", moved, !test.wantPR)
+			}
+			var want []string
+			if test.wantEnqueued {
+				want = []string{pulls[0].GetNodeID()}
+			}
+			if len(enqueued) != len(want) || len(want) > 0 && enqueued[0] != want[0] {
+				t.Errorf("enqueued %v, want %v", enqueued, want)
+			}
+		})
+	}
+}
+
+func TestGraphQLPath(t *testing.T) {
+	enterprise, err := github.NewEnterpriseClient("https://github.example.com/api/v3/", "https://github.example.com/api/uploads/", nil)
+	if err != nil {
+		t.Fatalf("new enterprise client: %v", err)
+	}
+	for _, client := range []*github.Client{github.NewClient(nil), enterprise} {
+		u, err := client.BaseURL.Parse(graphQLPath(client))
+		if err != nil {
+			t.Fatalf("parse: %v", err)
+		}
+		want := "https://api.github.com/graphql"
+		if client == enterprise {
+			want = "https://github.example.com/api/graphql"
+		}
+		if u.String() != want {
+			t.Errorf("graphql url: got %s, want %s", u, want)
+		}
+	}
+}
@@ -0,0 +1,114 @@
+package bump
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"sigs.k8s.io/kustomize/kyaml/yaml"
+)
+
+// parseObject parses the value of --replacement-object, which must be a mapping, in YAML or
+// JSON.
+func parseObject(object string) (*yaml.RNode, error) {
+	rn, err := yaml.Parse(object)
+	if errors.Is(err, io.EOF) {
+		return nil, errors.New("--replacement-object is empty")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("parse --replacement-object: %w", err)
+	}
+	if err := yaml.ErrorIfInvalid(rn, yaml.MappingNode); err != nil {
+		return nil, fmt.Errorf("--replacement-object: %w", err)
+	}
+	restyle(rn.YNode())
+	return rn, nil
+}
+
+// restyle clears the styles that JSON or flow-style input gives node and its children, so that
+// they are written in the block style of the surrounding document.  Strings that would not read
+// back as strings without quotes are quoted.
+func restyle(node *yaml.Node) {
+	node.Style = 0
+	if node.Kind == yaml.ScalarNode && node.Tag == yaml.NodeTagString && (node.Value == "" || yaml.IsValueNonString(node.Value)) {
+		node.Style = yaml.SingleQuotedStyle
+	}
+	for _, c := range node.Content {
+		restyle(c)
+	}
+}
+
+// mergeObject is a yaml.Filter that merges object into the mapping node it's given, like a JSON
+// merge patch (RFC 7396): keys in object are added or overwritten, and keys whose value in
+// object is null are removed.  If shallow is set, values are replaced outright; otherwise,
+// mappings in object are merged into the mappings they overwrite.
+type mergeObject struct {
+	object  *yaml.RNode
+	shallow bool
+	change  *change
+}
+
+func (m mergeObject) Filter(rn *yaml.RNode) (*yaml.RNode, error) {
+	if err := yaml.ErrorIfInvalid(rn, yaml.MappingNode); err != nil {
+		return nil, fmt.Errorf("merge object: %w", err)
+	}
+	m.change.Found = true
+	m.change.Line = rn.YNode().Line
+	m.change.Old = flowString(rn)
+	// The object is merged into every location in every document; each gets its own copy.
+	if err := mergeMapping(rn, m.object.Copy(), m.shallow); err != nil {
+		return nil, err
+	}
+	m.change.New = flowString(rn)
+	return rn, nil
+}
+
+// mergeMapping merges the fields of src into dst, as described for mergeObject.
+func mergeMapping(dst, src *yaml.RNode, shallow bool) error {
+	return src.VisitFields(func(f *yaml.MapNode) error {
+		key := f.Key.YNode().Value
+		if f.Value.YNode().Tag == yaml.NodeTagNull {
+			_, err := dst.Pipe(yaml.Clear(key))
+			return err
+		}
+		existing := dst.Field(key)
+		if !shallow && existing != nil && existing.Value.YNode().Kind == yaml.MappingNode && f.Value.YNode().Kind == yaml.MappingNode {
+			return mergeMapping(existing.Value, f.Value, shallow)
+		}
+		if existing == nil {
+			_, err := dst.Pipe(yaml.SetField(key, f.Value))
+			return err
+		}
+		// Keep the style and comments of the value being overwritten, unless the new value
+		// needs quotes of its own.
+		old, v := existing.Value.YNode(), f.Value.YNode()
+		if v.Style == 0 && v.Kind == old.Kind {
+			v.Style = old.Style
+		}
+		v.HeadComment, v.LineComment, v.FootComment = old.HeadComment, old.LineComment, old.FootComment
+		existing.Value.SetYNode(v)
+		return nil
+	})
+}
+
+// flowString returns rn on a single line, for traces.
+func flowString(rn *yaml.RNode) string {
+	c := rn.Copy()
+	var flow func(*yaml.Node)
+	flow = func(n *yaml.Node) {
+		if n.Kind == yaml.MappingNode || n.Kind == yaml.SequenceNode {
+			n.Style = yaml.FlowStyle
+		}
+		n.HeadComment, n.LineComment, n.FootComment = "", "", ""
+		for _, child := range n.Content {
+			flow(child)
+		}
+	}
+	flow(c.YNode())
+	s, err := c.String()
+	if err != nil {
+		return "?"
+	}
+	return strings.TrimSpace(s)
+}
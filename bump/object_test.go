@@ -0,0 +1,138 @@
+package bump
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestEditReplacementObject(t *testing.T) {
+	input := lines(
+		"image:",
+		"  repository: example.com/app # where the image lives",
+		"  tag: v1",
+		"  resources:",
+		"    cpu: 100m",
+		"    memory: 128Mi",
+		"replicas: 2",
+	)
+	testData := []struct {
+		name     string
+		location string
+		object   string
+		merge    string
+		want     string
+		wantErr  bool
+	}{
+		{
+			name:     "yaml",
+			location: "image",
+			object:   "{tag: v2, pullPolicy: Always}",
+			want: lines(
+				"image:",
+				"  repository: example.com/app # where the image lives",
+				"  tag: v2",
+				"  resources:",
+				"    cpu: 100m",
+				"    memory: 128Mi",
+				"  pullPolicy: Always",
+				"replicas: 2",
+			),
+		},
+		{
+			name:     "json",
+			location: "image",
+			object:   `{"tag": "1.20", "pullPolicy": "IfNotPresent"}`,
+			want: lines(
+				"image:",
+				"  repository: example.com/app # where the image lives",
+				"  tag: '1.20'",
+				"  resources:",
+				"    cpu: 100m",
+				"    memory: 128Mi",
+				"  pullPolicy: IfNotPresent",
+				"replicas: 2",
+			),
+		},
+		{
+			name:     "deep",
+			location: "image",
+			object:   "{resources: {cpu: 200m}}",
+			want: lines(
+				"image:",
+				"  repository: example.com/app # where the image lives",
+				"  tag: v1",
+				"  resources:",
+				"    cpu: 200m",
+				"    memory: 128Mi",
+				"replicas: 2",
+			),
+		},
+		{
+			name:     "shallow",
+			location: "image",
+			object:   "{resources: {cpu: 200m}}",
+			merge:    "shallow",
+			want: lines(
+				"image:",
+				"  repository: example.com/app # where the image lives",
+				"  tag: v1",
+				"  resources:",
+				"    cpu: 200m",
+				"replicas: 2",
+			),
+		},
+		{
+			name:     "null removes",
+			location: "image",
+			object:   "{resources: null, tag: v2}",
+			want: lines(
+				"image:",
+				"  repository: example.com/app # where the image lives",
+				"  tag: v2",
+				"replicas: 2",
+			),
+		},
+		{
+			name:     "missing location",
+			location: "sidecar",
+			object:   "{tag: v2}",
+			want:     input,
+		},
+		{
+			name:     "not a mapping",
+			location: "replicas",
+			object:   "{tag: v2}",
+			wantErr:  true,
+		},
+		{
+			name:     "object is not a mapping",
+			location: "image",
+			object:   "[v2]",
+			wantErr:  true,
+		},
+	}
+	for _, test := range testData {
+		t.Run(test.name, func(t *testing.T) {
+			cfg := &config{
+				File:        "values.yaml",
+				Locations:   []string{test.location},
+				Object:      test.object,
+				ObjectMerge: test.merge,
+			}
+			got, _, err := edit(cfg, input, nil)
+			if test.wantErr {
+				if err == nil {
+					t.Errorf("expected error, got %q", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("edit: %v", err)
+			}
+			if diff := cmp.Diff(got, test.want); diff != "" {
+				t.Errorf("output:\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,89 @@
+package bump
+
+import (
+	"crypto/sha1"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// commitPlan describes a commit that is about to be made, and what will be done with it.  It is
+// what createCommit builds, and what --dry-run-apply prints instead of building it.
+type commitPlan struct {
+	// Action is "update-branch", "create-branch", or "pull-request".
+	Action string `json:"action"`
+	// Branch is the branch that will point at the commit.  For pull requests, it is the head
+	// branch, and Base is the branch the pull request is against.
+	Branch    string      `json:"branch"`
+	Base      string      `json:"base,omitempty"`
+	Force     bool        `json:"force,omitempty"`
+	Parent    string      `json:"parent"`
+	BaseTree  string      `json:"base_tree"`
+	Message   string      `json:"message"`
+	Author    string      `json:"author"`
+	Committer string      `json:"committer"`
+	Entries   []planEntry `json:"entries"`
+}
+
+// planEntry is an entry written into the base tree.  BlobSHA is the SHA that git will give the
+// blob; it is computed locally, and the blob does not exist until the commit is made.
+type planEntry struct {
+	Path    string `json:"path"`
+	Mode    string `json:"mode"`
+	Type    string `json:"type"`
+	BlobSHA string `json:"blob_sha"`
+	Size    int    `json:"size"`
+}
+
+// newCommitPlan plans a commit writing files on top of baseTreeSHA, with baseCommit as its
+// parent.  The caller fills in Action and Branch.
+func newCommitPlan(baseTreeSHA, baseCommit string, files map[string]string, commitMsg string, id identity) *commitPlan {
+	p := &commitPlan{
+		Parent:    baseCommit,
+		BaseTree:  baseTreeSHA,
+		Message:   commitMsg,
+		Author:    id.Author.String(),
+		Committer: id.Committer.String(),
+	}
+	for _, path := range sortedPaths(files) {
+		p.Entries = append(p.Entries, planEntry{
+			Path:    path,
+			Mode:    "100644",
+			Type:    "blob",
+			BlobSHA: blobSHA(files[path]),
+			Size:    len(files[path]),
+		})
+	}
+	return p
+}
+
+// blobSHA returns the SHA that git gives a blob with the given content.
+func blobSHA(content string) string {
+	return fmt.Sprintf("%x", sha1.Sum([]byte(fmt.Sprintf("blob %d\x00%s", len(content), content))))
+}
+
+// writePlan prints p to w in format, "text" or "json".
+func writePlan(w io.Writer, format string, p *commitPlan) error {
+	if format == "json" {
+		e := json.NewEncoder(w)
+		e.SetIndent("", "  ")
+		return e.Encode(p)
+	}
+	target := p.Branch
+	if p.Base != "" {
+		target = fmt.Sprintf("%s into %s", p.Branch, p.Base)
+	}
+	if p.Force {
+		target += " (forced)"
+	}
+	fmt.Fprintf(w, "%s: %s\n", p.Action, target)
+	fmt.Fprintf(w, "parent: %s\n", p.Parent)
+	fmt.Fprintf(w, "base tree: %s\n", p.BaseTree)
+	fmt.Fprintf(w, "author: %s\n", p.Author)
+	fmt.Fprintf(w, "committer: %s\n", p.Committer)
+	fmt.Fprintf(w, "message: %q\n", p.Message)
+	for _, e := range p.Entries {
+		fmt.Fprintf(w, "%s %s %s\t%s\n", e.Mode, e.Type, e.BlobSHA, e.Path)
+	}
+	return nil
+}
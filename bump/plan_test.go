@@ -0,0 +1,105 @@
+package bump
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestDryRunApply(t *testing.T) {
+	graph := filepath.Join(t.TempDir(), "graph.yaml")
+	if err := ioutil.WriteFile(graph, []byte(testGraph), 0644); err != nil {
+		t.Fatalf("write graph: %v", err)
+	}
+	gh, client := newFakeGitHub(t)
+	before := gh.push("o/r", "master", map[string]string{
+		"api/version.yaml":   lines("version: v1.0.0"),
+		"web/version.yaml":   lines("version: v3.1.0"),
+		"web/deps.yaml":      lines("dependencies:", "  api: v1.1.0"),
+		"worker/values.yaml": lines("image:", "  tag: v2.0.0", "api:", "  tag: v1.0.0"),
+	})
+	cfg := &config{
+		GithubOwner:   "o",
+		GithubRepo:    "r",
+		GithubBranch:  "master",
+		Graph:         graph,
+		Component:     "api",
+		Replacement:   "v1.1.0",
+		CommitMessage: "Bump api to v1.1.0",
+		AuthorName:    "Bot",
+		AuthorEmail:   "bot@example.com",
+		DryRunApply:   true,
+		Output:        "json",
+	}
+	out := new(bytes.Buffer)
+	if err := run(context.Background(), client, cfg, out); err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	var got commitPlan
+	if err := json.Unmarshal(out.Bytes(), &got); err != nil {
+		t.Fatalf("parse plan %q: %v", out.String(), err)
+	}
+	apiVersion := lines("version: v1.1.0")
+	workerValues := lines("image:", "  tag: v2.0.0", "api:", "  tag: v1.1.0")
+	want := commitPlan{
+		Action:    "update-branch",
+		Branch:    "master",
+		Parent:    before,
+		BaseTree:  gh.head("o/r", "master").Tree,
+		Message:   "Bump api to v1.1.0",
+		Author:    "Bot <bot@example.com>",
+		Committer: "Bot <bot@example.com>",
+		Entries: []planEntry{
+			{Path: "api/version.yaml", Mode: "100644", Type: "blob", BlobSHA: hashObject("blob", apiVersion), Size: len(apiVersion)},
+			{Path: "worker/values.yaml", Mode: "100644", Type: "blob", BlobSHA: hashObject("blob", workerValues), Size: len(workerValues)},
+		},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("plan:\n%s", diff)
+	}
+	if n := gh.called("POST", "/repos/o/r/git/"); n != 0 {
+		t.Errorf("made %d POST requests to the git API", n)
+	}
+	if after := gh.head("o/r", "master").SHA; after != before {
+		t.Errorf("branch moved from %s to %s", before, after)
+	}
+
+	// Making the commit for real writes exactly the planned blobs.
+	cfg.DryRunApply = false
+	if err := run(context.Background(), client, cfg, ioutil.Discard); err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	for _, e := range want.Entries {
+		content, _ := gh.file("o/r", "master", e.Path)
+		if got := hashObject("blob", content); got != e.BlobSHA {
+			t.Errorf("%s: committed blob %s, planned %s", e.Path, got, e.BlobSHA)
+		}
+	}
+}
+
+func TestWritePlanText(t *testing.T) {
+	p := newCommitPlan("tree", "parent", map[string]string{"b.yaml": "b\n", "a.yaml": "a\n"}, "Bump", identity{Author: person{Name: "Bot", Email: "bot@example.com"}, Committer: person{Name: "Bot", Email: "bot@example.com"}})
+	p.Action, p.Branch, p.Base = "pull-request", "version-bump/123", "master"
+	out := new(bytes.Buffer)
+	if err := writePlan(out, "text", p); err != nil {
+		t.Fatalf("write plan: %v", err)
+	}
+	want := lines(
+		"pull-request: version-bump/123 into master",
+		"parent: parent",
+		"base tree: tree",
+		"author: Bot <bot@example.com>",
+		"committer: Bot <bot@example.com>",
+		`message: "Bump"`,
+		"100644 blob "+hashObject("blob", "a\n")+"\ta.yaml",
+		"100644 blob "+hashObject("blob", "b\n")+"\tb.yaml",
+	)
+	if diff := cmp.Diff(want, out.String()); diff != "" {
+		t.Errorf("plan:\n%s", diff)
+	}
+}
@@ -0,0 +1,37 @@
+package bump
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+)
+
+// quantityRE matches a Kubernetes resource quantity in its usual notation, like "512Mi", "250m",
+// or "1.5".  Exponent notation ("1e3") is not supported.
+var quantityRE = regexp.MustCompile(`^([0-9]*\.?[0-9]+)(Ki|Mi|Gi|Ti|Pi|Ei|n|u|m|k|M|G|T|P|E)?$`)
+
+// scaleQuantity multiplies a Kubernetes resource quantity by factor, keeping its unit.  The result
+// is rounded to at most three decimal places, or to a whole number for the sub-unit suffixes (n,
+// u, and m), which Kubernetes doesn't allow fractions of.
+func scaleQuantity(quantity string, factor float64) (string, error) {
+	m := quantityRE.FindStringSubmatch(quantity)
+	if m == nil {
+		return "", fmt.Errorf("%q is not a resource quantity", quantity)
+	}
+	if factor <= 0 {
+		return "", fmt.Errorf("scale factor %v must be positive", factor)
+	}
+	n, err := strconv.ParseFloat(m[1], 64)
+	if err != nil {
+		return "", fmt.Errorf("parse quantity %q: %w", quantity, err)
+	}
+	n *= factor
+	switch m[2] {
+	case "n", "u", "m":
+		n = math.Round(n)
+	default:
+		n = math.Round(n*1000) / 1000
+	}
+	return strconv.FormatFloat(n, 'f', -1, 64) + m[2], nil
+}
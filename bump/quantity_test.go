@@ -0,0 +1,66 @@
+package bump
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestScaleQuantity(t *testing.T) {
+	testData := []struct {
+		quantity string
+		factor   float64
+		want     string
+		wantErr  string
+	}{
+		{quantity: "512Mi", factor: 1.5, want: "768Mi"},
+		{quantity: "1Gi", factor: 0.5, want: "0.5Gi"},
+		{quantity: "100Mi", factor: 1.1, want: "110Mi"},
+		{quantity: "100Mi", factor: 1.3333, want: "133.33Mi"},
+		{quantity: "250m", factor: 1.5, want: "375m"},
+		{quantity: "333m", factor: 1.5, want: "500m"},
+		{quantity: "2", factor: 1.25, want: "2.5"},
+		{quantity: "1.5G", factor: 2, want: "3G"},
+		{quantity: "big", factor: 2, wantErr: "not a resource quantity"},
+		{quantity: "1e3", factor: 2, wantErr: "not a resource quantity"},
+		{quantity: "1Gi", factor: 0, wantErr: "must be positive"},
+	}
+	for _, test := range testData {
+		t.Run(test.quantity, func(t *testing.T) {
+			got, err := scaleQuantity(test.quantity, test.factor)
+			if test.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+					t.Fatalf("want error containing %q, got %v", test.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != test.want {
+				t.Errorf("%s * %v: got %q, want %q", test.quantity, test.factor, got, test.want)
+			}
+		})
+	}
+}
+
+func TestEditScale(t *testing.T) {
+	input := lines(
+		"resources:",
+		"  limits:",
+		"    memory: 512Mi",
+		"    cpu: 250m",
+	)
+	want := lines(
+		"resources:",
+		"  limits:",
+		"    memory: 768Mi",
+		"    cpu: 375m",
+	)
+	got, _, err := editYAML(input, editsFor([]string{"resources.limits.memory", "resources.limits.cpu"}, ""), editOptions{Scale: 1.5})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
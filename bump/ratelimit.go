@@ -0,0 +1,97 @@
+package bump
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// rateLimitLow is how many requests may be left before the rate limit resets for writes to wait
+// for the reset, with --rate-limit-wait.  A bump makes a handful of writes, so this leaves room
+// to finish one that is under way.
+const rateLimitLow = 10
+
+// rateLimitTransport is an http.RoundTripper that keeps track of Github's primary rate limit,
+// from the X-RateLimit-Remaining and X-RateLimit-Reset headers of every response, like
+// github.Response.Rate.  When no more than low requests are left, it waits for the limit to
+// reset before sending a write, so that a run is not cut off halfway through by a 403.  Reads
+// are sent at once; they fail harmlessly.  If the request's context is done before the reset,
+// the write fails with the context's error.
+type rateLimitTransport struct {
+	base http.RoundTripper
+	low  int
+	now  func() time.Time
+	// sleep waits for d, or until ctx is done.
+	sleep func(ctx context.Context, d time.Duration) error
+
+	mu        sync.Mutex
+	known     bool
+	remaining int
+	reset     time.Time
+}
+
+func (t *rateLimitTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Method != "GET" && req.Method != "HEAD" {
+		if wait, remaining := t.wait(); wait > 0 {
+			log.Printf("%d Github requests left until the rate limit resets; waiting %v before %s %s", remaining, wait.Round(time.Second), req.Method, req.URL.Path)
+			if err := t.sleep(req.Context(), wait); err != nil {
+				return nil, fmt.Errorf("wait for the Github rate limit to reset: %w", err)
+			}
+		}
+	}
+	resp, err := t.base.RoundTrip(req)
+	if err == nil {
+		t.observe(resp.Header)
+	}
+	return resp, err
+}
+
+// wait returns how long to wait before a write, and how many requests are left.
+func (t *rateLimitTransport) wait() (time.Duration, int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if !t.known || t.remaining > t.low {
+		return 0, t.remaining
+	}
+	return t.reset.Sub(t.now()), t.remaining
+}
+
+// observe records the rate limit that header reports, if any.
+func (t *rateLimitTransport) observe(header http.Header) {
+	remaining, err := strconv.Atoi(header.Get("X-RateLimit-Remaining"))
+	if err != nil {
+		return
+	}
+	reset, err := strconv.ParseInt(header.Get("X-RateLimit-Reset"), 10, 64)
+	if err != nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.known, t.remaining, t.reset = true, remaining, time.Unix(reset, 0)
+}
+
+// sleepContext waits for d, or until ctx is done, returning its error.
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// withRateLimit returns base wrapped to wait for the rate limit to reset before writes when it
+// is nearly used up, or base itself if wait is false.
+func withRateLimit(base http.RoundTripper, wait bool) http.RoundTripper {
+	if !wait {
+		return base
+	}
+	return &rateLimitTransport{base: base, low: rateLimitLow, now: time.Now, sleep: sleepContext}
+}
@@ -0,0 +1,79 @@
+package bump
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestRateLimitTransport(t *testing.T) {
+	now := time.Unix(1600000000, 0)
+	testData := []struct {
+		name      string
+		remaining string
+		method    string
+		sleepErr  error
+		wantWait  time.Duration
+		wantErr   bool
+	}{
+		{name: "plenty left", remaining: "4000", method: "POST"},
+		{name: "low, write", remaining: "3", method: "POST", wantWait: time.Minute},
+		{name: "low, read", remaining: "3", method: "GET"},
+		{name: "no headers", method: "POST"},
+		{name: "deadline first", remaining: "0", method: "PATCH", sleepErr: context.DeadlineExceeded, wantWait: time.Minute, wantErr: true},
+	}
+	for _, test := range testData {
+		t.Run(test.name, func(t *testing.T) {
+			calls := 0
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				calls++
+				if test.remaining != "" {
+					w.Header().Set("X-RateLimit-Remaining", test.remaining)
+					w.Header().Set("X-RateLimit-Reset", strconv.FormatInt(now.Add(time.Minute).Unix(), 10))
+				}
+			}))
+			defer srv.Close()
+			var waited time.Duration
+			client := &http.Client{Transport: &rateLimitTransport{
+				base: http.DefaultTransport,
+				low:  rateLimitLow,
+				now:  func() time.Time { return now },
+				sleep: func(ctx context.Context, d time.Duration) error {
+					waited += d
+					return test.sleepErr
+				},
+			}}
+			// The first request learns the rate limit, and the second may wait for it.
+			for _, method := range []string{"GET", test.method} {
+				req, err := http.NewRequest(method, srv.URL, nil)
+				if err != nil {
+					t.Fatal(err)
+				}
+				resp, err := client.Do(req)
+				if method == test.method && test.wantErr {
+					if err == nil {
+						t.Errorf("%s: expected an error", method)
+					}
+					continue
+				}
+				if err != nil {
+					t.Fatalf("%s: %v", method, err)
+				}
+				resp.Body.Close()
+			}
+			if waited != test.wantWait {
+				t.Errorf("waited %v, want %v", waited, test.wantWait)
+			}
+			wantCalls := 2
+			if test.wantErr {
+				wantCalls = 1
+			}
+			if calls != wantCalls {
+				t.Errorf("%d requests reached the server, want %d", calls, wantCalls)
+			}
+		})
+	}
+}
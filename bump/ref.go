@@ -0,0 +1,95 @@
+package bump
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/google/go-github/v32/github"
+)
+
+// commitSHA matches a full commit SHA.
+var commitSHA = regexp.MustCompile(`^[0-9a-fA-F]{40}$`)
+
+// refBranch returns the branch that ref, a --ref like heads/main, names, if it names one.
+func refBranch(ref string) (string, bool) {
+	ref = strings.TrimPrefix(ref, "refs/")
+	if !strings.HasPrefix(ref, "heads/") {
+		return "", false
+	}
+	return strings.TrimPrefix(ref, "heads/"), true
+}
+
+// isTag returns true if ref, a --ref or --branch, names a tag.
+func isTag(ref string) bool {
+	return strings.HasPrefix(strings.TrimPrefix(ref, "refs/"), "tags/")
+}
+
+// checkRef checks --ref against the rest of cfg, for a Github repository.  A --ref naming a
+// branch is the same as --branch, and is returned as one.  A tag or commit is only read from,
+// so in commit mode the edit is committed to --branch, a new branch created from it.
+func checkRef(cfg *config) (*config, error) {
+	if cfg.Ref == "" || cfg.LocalRepo != "" {
+		return cfg, nil
+	}
+	if branch, ok := refBranch(cfg.Ref); ok {
+		if branch == "" {
+			return nil, fmt.Errorf("--ref %s names no branch", cfg.Ref)
+		}
+		if cfg.GithubBranch != "" && cfg.GithubBranch != branch {
+			return nil, fmt.Errorf("--ref %s and --branch %s name different branches", cfg.Ref, cfg.GithubBranch)
+		}
+		withBranch := *cfg
+		withBranch.GithubBranch, withBranch.Ref = branch, ""
+		return &withBranch, nil
+	}
+	if !isTag(cfg.Ref) && !commitSHA.MatchString(cfg.Ref) {
+		return nil, fmt.Errorf("--ref %s is not heads/<branch>, tags/<tag>, or a full commit SHA", cfg.Ref)
+	}
+	if isTag(cfg.GithubBranch) {
+		return nil, fmt.Errorf("cannot commit to tag %s; --branch must name a branch", cfg.GithubBranch)
+	}
+	if !cfg.DryRun && cfg.GithubBranch == "" {
+		return nil, fmt.Errorf("--ref %s is not a branch, so the edit can only be committed to a new branch named with --branch", cfg.Ref)
+	}
+	var conflicting []string
+	for flag, set := range map[string]bool{
+		"--pull-request":          cfg.PullRequest,
+		"--fork":                  cfg.Fork != "",
+		"--create-from":           cfg.CreateFrom != "",
+		"--create-branch":         cfg.CreateBranch,
+		"--concurrency-safe-lock": cfg.Lock,
+		"--state-file":            cfg.StateFile != "",
+	} {
+		if set {
+			conflicting = append(conflicting, flag)
+		}
+	}
+	if len(conflicting) > 0 {
+		sort.Strings(conflicting)
+		return nil, fmt.Errorf("--ref naming a tag or commit cannot be combined with %s", strings.Join(conflicting, ", "))
+	}
+	return cfg, nil
+}
+
+// resolveRef returns the commit that ref, a tag or commit SHA, points to in owner/repo.
+func resolveRef(ctx context.Context, client *github.Client, owner, repo, ref string) (*github.Commit, error) {
+	sha := ref
+	if isTag(ref) {
+		var err error
+		sha, err = tagCommit(ctx, client, owner, repo, strings.TrimPrefix(strings.TrimPrefix(ref, "refs/"), "tags/"))
+		if err != nil {
+			return nil, err
+		}
+	}
+	commit, _, err := client.Git.GetCommit(ctx, owner, repo, sha)
+	if err != nil {
+		return nil, fmt.Errorf("get commit %s in %s/%s: %w", sha, owner, repo, err)
+	}
+	if commit.GetTree().GetSHA() == "" {
+		return nil, fmt.Errorf("no tree in commit %s", sha)
+	}
+	return commit, nil
+}
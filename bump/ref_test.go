@@ -0,0 +1,108 @@
+package bump
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/google/go-github/v32/github"
+)
+
+func TestRef(t *testing.T) {
+	testData := []struct {
+		name       string
+		ref        string // "<v1>" is replaced with the SHA of the tagged commit
+		branch     string
+		dryRun     bool
+		existing   bool
+		lock       bool
+		create     bool
+		wantBranch string // the branch that gets the commit
+		wantParent string // "v1" or "main"
+		wantErr    string
+	}{
+		{name: "branch", ref: "heads/main", wantBranch: "main", wantParent: "main"},
+		{name: "branch and same --branch", ref: "refs/heads/main", branch: "main", wantBranch: "main", wantParent: "main"},
+		{name: "branch and other --branch", ref: "heads/main", branch: "release", wantErr: "name different branches"},
+		{name: "tag", ref: "tags/v1", branch: "release", wantBranch: "release", wantParent: "v1"},
+		{name: "annotated tag", ref: "tags/v1-annotated", branch: "release", wantBranch: "release", wantParent: "v1"},
+		{name: "commit", ref: "<v1>", branch: "release", wantBranch: "release", wantParent: "v1"},
+		{name: "tag in dry run", ref: "tags/v1", dryRun: true},
+		{name: "tag without branch", ref: "tags/v1", wantErr: "named with --branch"},
+		{name: "tag to existing branch", ref: "tags/v1", branch: "release", existing: true, wantErr: "branch release already exists"},
+		{name: "commit to tag", ref: "tags/v1", branch: "tags/v2", wantErr: "cannot commit to tag tags/v2"},
+		{name: "missing tag", ref: "tags/nope", branch: "release", wantErr: "get tag nope"},
+		{name: "tag with lock and --create-branch", ref: "tags/v1", branch: "release", lock: true, create: true, wantErr: "cannot be combined with --concurrency-safe-lock, --create-branch"},
+		{name: "short SHA", ref: "abc123", branch: "release", wantErr: "not heads/<branch>, tags/<tag>, or a full commit SHA"},
+	}
+	for _, test := range testData {
+		t.Run(test.name, func(t *testing.T) {
+			gh, client := newFakeGitHub(t)
+			tagged := gh.push("o/r", "main", map[string]string{"config.yaml": lines("image:", "  tag: v1")})
+			heads := map[string]string{
+				"v1":   tagged,
+				"main": gh.push("o/r", "main", map[string]string{"config.yaml": lines("image:", "  tag: v1.5")}),
+			}
+			annotated := hashObject("tag", "v1-annotated "+tagged)
+			gh.tags[annotated] = &github.Tag{
+				Tag:    github.String("v1-annotated"),
+				SHA:    github.String(annotated),
+				Object: &github.GitObject{Type: github.String("commit"), SHA: github.String(tagged)},
+			}
+			gh.repo("o/r").refs["tags/v1"] = tagged
+			gh.repo("o/r").refs["tags/v1-annotated"] = annotated
+			if test.existing {
+				gh.push("o/r", test.branch, map[string]string{"config.yaml": lines("image:", "  tag: v0")})
+			}
+			cfg := &config{
+				GithubOwner:  "o",
+				GithubRepo:   "r",
+				GithubBranch: test.branch,
+				Ref:          strings.Replace(test.ref, "<v1>", tagged, 1),
+				DryRun:       test.dryRun,
+				Lock:         test.lock,
+				CreateBranch: test.create,
+				File:         "config.yaml",
+				Locations:    []string{"image.tag"},
+				Replacement:  "v2",
+			}
+			out := new(bytes.Buffer)
+			err := run(context.Background(), client, cfg, out)
+			if test.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+					t.Fatalf("want error containing %q, got %v", test.wantErr, err)
+				}
+				if !test.existing && gh.head("o/r", "release") != nil {
+					t.Error("branch release was created despite the error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("run: %v", err)
+			}
+			if test.dryRun {
+				if got, want := out.String(), "+  tag: v2"; !strings.Contains(got, want) {
+					t.Errorf("dry run output %q does not contain %q", got, want)
+				}
+				if !strings.Contains(out.String(), "-  tag: v1\n") {
+					t.Errorf("dry run output %q is not a diff of the tagged commit", out.String())
+				}
+				return
+			}
+			head := gh.head("o/r", test.wantBranch)
+			if head == nil {
+				t.Fatalf("branch %s does not exist", test.wantBranch)
+			}
+			if want := heads[test.wantParent]; len(head.Parents) != 1 || head.Parents[0] != want {
+				t.Errorf("parents: got %v, want [%s]", head.Parents, want)
+			}
+			if got, _ := gh.file("o/r", test.wantBranch, "config.yaml"); got != lines("image:", "  tag: v2") {
+				t.Errorf("content: got %q", got)
+			}
+			if got := gh.repo("o/r").refs["tags/v1"]; got != tagged {
+				t.Errorf("tag v1 moved to %s", got)
+			}
+		})
+	}
+}
@@ -0,0 +1,88 @@
+package bump
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// regexEdit is a locationEdit made with --regex: Value replaces what the first capture group of
+// Pattern matches.
+type regexEdit struct {
+	Pattern *regexp.Regexp
+	Value   string
+}
+
+// compileRegexEdits compiles the location of each edit as a regular expression with at least
+// one capture group.
+func compileRegexEdits(edits []locationEdit) ([]regexEdit, error) {
+	result := make([]regexEdit, 0, len(edits))
+	for _, e := range edits {
+		re, err := regexp.Compile(e.Path)
+		if err != nil {
+			return nil, fmt.Errorf("--regex location %q is not a valid regular expression: %w", e.Path, err)
+		}
+		if re.NumSubexp() == 0 {
+			return nil, fmt.Errorf("--regex location %q has no capture group to replace", e.Path)
+		}
+		result = append(result, regexEdit{Pattern: re, Value: e.Value})
+	}
+	return result, nil
+}
+
+// checkRegex validates the --regex flags of cfg, so that a bad pattern is reported before
+// anything is read.
+func checkRegex(cfg *config) error {
+	if !cfg.Regex {
+		if cfg.RegexAll {
+			return errors.New("--regex-all requires --regex")
+		}
+		return nil
+	}
+	if cfg.Object != "" || cfg.Scale != 0 || cfg.RangeBound != "" || cfg.Base64 || cfg.Comment != "" || len(cfg.RenameKeys) > 0 || len(cfg.Mirrors) > 0 || len(cfg.MatchLabels) > 0 {
+		return errors.New("--regex only replaces values, and cannot be combined with options that edit structure")
+	}
+	edits, err := locationEdits(cfg)
+	if err != nil {
+		return err
+	}
+	_, err = compileRegexEdits(edits)
+	return err
+}
+
+// editRegex replaces what the first capture group of each pattern matches in input with its
+// value: in the first match only, or in every match if all is set.  Each edit applies to the
+// result of the one before it.  It returns the changes like editYAML, with the pattern as the
+// location, for a file with only one document.
+func editRegex(input string, edits []regexEdit, all bool) (string, []change, error) {
+	var changes []change
+	for _, e := range edits {
+		n := 1
+		if all {
+			n = -1
+		}
+		var b strings.Builder
+		last := 0
+		found := false
+		for _, m := range e.Pattern.FindAllStringSubmatchIndex(input, n) {
+			start, end := m[2], m[3]
+			if start < 0 {
+				continue // The group did not take part in this match.
+			}
+			found = true
+			b.WriteString(input[last:start])
+			b.WriteString(e.Value)
+			last = end
+			line := strings.Count(input[:start], "\n") + 1
+			changes = append(changes, change{Location: e.Pattern.String(), Found: true, Document: 1, Line: line, Old: input[start:end], New: e.Value})
+		}
+		if !found {
+			changes = append(changes, change{Location: e.Pattern.String()})
+			continue
+		}
+		b.WriteString(input[last:])
+		input = b.String()
+	}
+	return input, changes, nil
+}
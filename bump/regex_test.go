@@ -0,0 +1,120 @@
+package bump
+
+import (
+	"context"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestEditRegex(t *testing.T) {
+	dockerfile := lines("FROM golang:1.15", "ARG VERSION=1.0.0", "ARG OTHER_VERSION=1.0.0")
+	testData := []struct {
+		name        string
+		input       string
+		location    string
+		all         bool
+		want        string
+		wantChanges []change
+	}{
+		{
+			name:        "first match",
+			input:       dockerfile,
+			location:    `VERSION=(\S+)`,
+			want:        lines("FROM golang:1.15", "ARG VERSION=2.0.0", "ARG OTHER_VERSION=1.0.0"),
+			wantChanges: []change{{Location: `VERSION=(\S+)`, Found: true, Document: 1, Line: 2, Old: "1.0.0", New: "2.0.0"}},
+		},
+		{
+			name:     "every match",
+			input:    dockerfile,
+			location: `VERSION=(\S+)`,
+			all:      true,
+			want:     lines("FROM golang:1.15", "ARG VERSION=2.0.0", "ARG OTHER_VERSION=2.0.0"),
+		},
+		{
+			name:     "only the first group is replaced",
+			input:    lines("image: app:v1"),
+			location: `(app):(v\d+)`,
+			want:     lines("image: 2.0.0:v1"),
+		},
+		{
+			name:        "no match leaves the file alone",
+			input:       dockerfile,
+			location:    `RELEASE=(\S+)`,
+			want:        dockerfile,
+			wantChanges: []change{{Location: `RELEASE=(\S+)`}},
+		},
+	}
+	for _, test := range testData {
+		t.Run(test.name, func(t *testing.T) {
+			edits, err := compileRegexEdits(editsFor([]string{test.location}, "2.0.0"))
+			if err != nil {
+				t.Fatalf("compile: %v", err)
+			}
+			got, changes, err := editRegex(test.input, edits, test.all)
+			if err != nil {
+				t.Fatalf("edit: %v", err)
+			}
+			if diff := cmp.Diff(test.want, got); diff != "" {
+				t.Errorf("output (-want +got):\n%s", diff)
+			}
+			if test.wantChanges != nil {
+				if diff := cmp.Diff(test.wantChanges, changes); diff != "" {
+					t.Errorf("changes (-want +got):\n%s", diff)
+				}
+			}
+		})
+	}
+}
+
+func TestRegex(t *testing.T) {
+	testData := []struct {
+		name     string
+		location string
+		want     string
+		wantErr  string
+	}{
+		{name: "edit", location: `ARG VERSION=(.*)`, want: lines("FROM scratch", "ARG VERSION=v2")},
+		{name: "does not compile", location: `ARG VERSION=(.*`, wantErr: "is not a valid regular expression"},
+		{name: "no capture group", location: `ARG VERSION=.*`, wantErr: "has no capture group"},
+	}
+	for _, test := range testData {
+		t.Run(test.name, func(t *testing.T) {
+			gh, client := newFakeGitHub(t)
+			gh.push("o/r", "master", map[string]string{"Dockerfile": lines("FROM scratch", "ARG VERSION=v1")})
+			cfg := &config{
+				GithubOwner:   "o",
+				GithubRepo:    "r",
+				GithubBranch:  "master",
+				File:          "Dockerfile",
+				Locations:     []string{test.location},
+				Replacement:   "v2",
+				CommitMessage: "Bump VERSION",
+				Regex:         true,
+			}
+			err := run(context.Background(), client, cfg, ioutil.Discard)
+			if test.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+					t.Fatalf("want error containing %q, got %v", test.wantErr, err)
+				}
+				if n := gh.called("GET", "/repos/"); n != 0 {
+					t.Errorf("made %d requests before rejecting the pattern", n)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("run: %v", err)
+			}
+			got, _ := gh.file("o/r", "master", "Dockerfile")
+			if diff := cmp.Diff(test.want, got); diff != "" {
+				t.Errorf("Dockerfile (-want +got):\n%s", diff)
+			}
+		})
+	}
+
+	if err := checkRegex(&config{RegexAll: true}); err == nil {
+		t.Error("expected an error for --regex-all without --regex")
+	}
+}
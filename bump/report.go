@@ -0,0 +1,159 @@
+package bump
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/google/go-github/v32/github"
+	"sigs.k8s.io/kustomize/kyaml/yaml"
+)
+
+// target is a branch of a repository to read or edit.
+type target struct {
+	Owner, Repo, Branch string
+}
+
+func (t target) String() string {
+	return fmt.Sprintf("%s/%s@%s", t.Owner, t.Repo, t.Branch)
+}
+
+// parseTarget parses a target of the form owner/repo or owner/repo@branch.  Without a branch,
+// branch is used; if that is empty, the target's default branch is looked up.
+func parseTarget(ctx context.Context, client *github.Client, spec, branch string) (target, error) {
+	repo := spec
+	if i := strings.LastIndex(spec, "@"); i >= 0 {
+		repo, branch = spec[:i], spec[i+1:]
+	}
+	parts := strings.SplitN(repo, "/", 2)
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" || strings.HasSuffix(spec, "@") {
+		return target{}, fmt.Errorf("invalid target %q: want owner/repo or owner/repo@branch", spec)
+	}
+	t := target{Owner: parts[0], Repo: parts[1], Branch: branch}
+	if t.Branch == "" {
+		r, _, err := client.Repositories.Get(ctx, t.Owner, t.Repo)
+		if err != nil {
+			return target{}, fmt.Errorf("look up default branch of %s/%s: %w", t.Owner, t.Repo, err)
+		}
+		t.Branch = r.GetDefaultBranch()
+	}
+	return t, nil
+}
+
+// valueAt returns the scalar at location in the first document of content that has one.
+func valueAt(content, location string) (string, bool, error) {
+	path, err := parseLocation(location)
+	if err != nil {
+		return "", false, err
+	}
+	docs, _ := splitDocuments(content)
+	for i, doc := range docs {
+		nodes, err := yaml.Parse(doc)
+		if errors.Is(err, io.EOF) {
+			continue
+		}
+		if err != nil {
+			return "", false, fmt.Errorf("parse yaml document %d: %w", i+1, err)
+		}
+		docPath, err := indexKeys(nodes, path)
+		if err != nil {
+			return "", false, fmt.Errorf("look up %s in document %d: %w", location, i+1, err)
+		}
+		if hasWildcard(docPath) {
+			// The value at the first match.
+			paths, err := expandWildcards(nodes, docPath)
+			if err != nil {
+				return "", false, fmt.Errorf("expand %s in document %d: %w", location, i+1, err)
+			}
+			if len(paths) == 0 {
+				continue
+			}
+			docPath = paths[0]
+		}
+		rn, err := nodes.Pipe(lookup(docPath)...)
+		if err != nil {
+			return "", false, fmt.Errorf("look up %s in document %d: %w", location, i+1, err)
+		}
+		if rn == nil {
+			continue
+		}
+		if err := yaml.ErrorIfInvalid(rn, yaml.ScalarNode); err != nil {
+			return "", false, fmt.Errorf("%s in document %d: %w", location, i+1, err)
+		}
+		return rn.YNode().Value, true, nil
+	}
+	return "", false, nil
+}
+
+// drift is one line of a report: the value at one location in one target.
+type drift struct {
+	Target   string `json:"target"`
+	File     string `json:"file"`
+	Location string `json:"location"`
+	Found    bool   `json:"found"`
+	Current  string `json:"current"`
+	Desired  string `json:"desired"`
+	Drift    bool   `json:"drift"`
+}
+
+// report reads cfg.File from each target, and compares the value at each location with
+// cfg.Replacement.  Nothing is edited.
+func report(ctx context.Context, client *github.Client, cfg *config) ([]drift, error) {
+	specs := cfg.Targets
+	if len(specs) == 0 {
+		specs = []string{cfg.GithubOwner + "/" + cfg.GithubRepo}
+	}
+	var result []drift
+	for _, spec := range specs {
+		t, err := parseTarget(ctx, client, spec, cfg.GithubBranch)
+		if err != nil {
+			return nil, err
+		}
+		f, err := fetch(ctx, client, t.Owner, t.Repo, t.Branch, cfg.File, cfg.PathPrefix, false)
+		if err != nil {
+			return nil, fmt.Errorf("fetch %s from %s: %w", cfg.File, t, err)
+		}
+		for _, location := range cfg.Locations {
+			current, found, err := valueAt(f.Content, location)
+			if err != nil {
+				return nil, fmt.Errorf("read %s from %s: %w", cfg.File, t, err)
+			}
+			result = append(result, drift{
+				Target:   t.String(),
+				File:     cfg.File,
+				Location: location,
+				Found:    found,
+				Current:  current,
+				Desired:  cfg.Replacement,
+				Drift:    !found || current != cfg.Replacement,
+			})
+		}
+	}
+	return result, nil
+}
+
+// writeReport writes a report to w as a table, or as a JSON array if format is "json".
+func writeReport(w io.Writer, format string, rows []drift) error {
+	if format == "json" {
+		if rows == nil {
+			rows = []drift{}
+		}
+		e := json.NewEncoder(w)
+		e.SetIndent("", "  ")
+		return e.Encode(rows)
+	}
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, "TARGET\tFILE\tLOCATION\tCURRENT\tDESIRED\tDRIFT")
+	for _, r := range rows {
+		current := r.Current
+		if !r.Found {
+			current = "(missing)"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%v\n", r.Target, r.File, r.Location, current, r.Desired, r.Drift)
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,77 @@
+package bump
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestReport(t *testing.T) {
+	gh, client := newFakeGitHub(t)
+	gh.push("o/current", "main", map[string]string{"config.yaml": lines("image:", "  tag: v2")})
+	gh.push("o/stale", "main", map[string]string{"config.yaml": lines("image:", "  tag: v1")})
+	gh.push("o/stale", "release", map[string]string{"config.yaml": lines("image:", "  tag: v2")})
+	gh.push("o/unpinned", "master", map[string]string{"config.yaml": lines("image:", "  name: app")})
+
+	cfg := &config{
+		Report:      true,
+		Targets:     []string{"o/current", "o/stale", "o/stale@release", "o/unpinned"},
+		File:        "config.yaml",
+		Locations:   []string{"image.tag"},
+		Replacement: "v2",
+		Output:      "json",
+	}
+	var out bytes.Buffer
+	if err := run(context.Background(), client, cfg, &out); err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	var got []drift
+	if err := json.Unmarshal(out.Bytes(), &got); err != nil {
+		t.Fatalf("parse report %q: %v", out.String(), err)
+	}
+	row := func(target, current string, found, isDrift bool) drift {
+		return drift{Target: target, File: "config.yaml", Location: "image.tag", Found: found, Current: current, Desired: "v2", Drift: isDrift}
+	}
+	want := []drift{
+		row("o/current@main", "v2", true, false),
+		row("o/stale@main", "v1", true, true),
+		row("o/stale@release", "v2", true, false),
+		row("o/unpinned@master", "", false, true),
+	}
+	if diff := cmp.Diff(got, want); diff != "" {
+		t.Errorf("report:\n%s", diff)
+	}
+	for _, method := range []string{"POST", "PATCH", "DELETE"} {
+		if n := gh.called(method, "/"); n != 0 {
+			t.Errorf("report made %d %s requests", n, method)
+		}
+	}
+
+	out.Reset()
+	cfg.Output = "text"
+	if err := run(context.Background(), client, cfg, &out); err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	wantText := lines(
+		"TARGET             FILE         LOCATION   CURRENT    DESIRED  DRIFT",
+		"o/current@main     config.yaml  image.tag  v2         v2       false",
+		"o/stale@main       config.yaml  image.tag  v1         v2       true",
+		"o/stale@release    config.yaml  image.tag  v2         v2       false",
+		"o/unpinned@master  config.yaml  image.tag  (missing)  v2       true",
+	)
+	if diff := cmp.Diff(out.String(), wantText); diff != "" {
+		t.Errorf("text report:\n%s", diff)
+	}
+}
+
+func TestParseTargetErrors(t *testing.T) {
+	_, client := newFakeGitHub(t)
+	for _, spec := range []string{"o", "/r", "o/", "o/r@"} {
+		if _, err := parseTarget(context.Background(), client, spec, "main"); err == nil {
+			t.Errorf("expected error parsing target %q", spec)
+		}
+	}
+}
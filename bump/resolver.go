@@ -0,0 +1,55 @@
+package bump
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// resolveReplacement runs --resolver-cmd to find the replacement.  The command is split into
+// words and run directly, not by a shell, with two more arguments: the current value at the
+// first location in content, and --resolver-pattern.  Whatever it prints to stdout, less
+// surrounding whitespace, is the replacement.  It is killed if ctx expires.
+func resolveReplacement(ctx context.Context, cfg *config, content string) (string, error) {
+	if cfg.Replacement != "" || cfg.FromLocation || cfg.Object != "" || cfg.Upstream != "" {
+		return "", errors.New("--resolver-cmd provides the replacement; do not pass --replacement, --replacement-is-location, --replacement-object, or --upstream")
+	}
+	if len(cfg.Locations) == 0 {
+		return "", errors.New("--resolver-cmd needs a --location to resolve")
+	}
+	args := strings.Fields(cfg.Resolver)
+	if len(args) == 0 {
+		return "", errors.New("--resolver-cmd is empty")
+	}
+	current, found, err := valueAt(content, cfg.Locations[0])
+	if err != nil {
+		return "", fmt.Errorf("read current value of %s: %w", cfg.Locations[0], err)
+	}
+	if !found {
+		return "", fmt.Errorf("location %s not found in %s; nothing to resolve", cfg.Locations[0], cfg.File)
+	}
+	var stdout bytes.Buffer
+	cmd := exec.CommandContext(ctx, args[0], append(args[1:], current, cfg.ResolverPat)...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() != nil {
+			err = ctx.Err()
+		}
+		return "", fmt.Errorf("run resolver %s: %w", args[0], err)
+	}
+	value := strings.TrimSpace(stdout.String())
+	if value == "" {
+		return "", fmt.Errorf("resolver %s printed nothing", args[0])
+	}
+	if strings.Contains(value, "\n") {
+		return "", fmt.Errorf("resolver %s printed more than one line: %q", args[0], value)
+	}
+	log.Printf("resolver %s resolved %q to %q", args[0], current, value)
+	return value, nil
+}
@@ -0,0 +1,60 @@
+package bump
+
+import (
+	"context"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+func TestResolverCmd(t *testing.T) {
+	testData := []struct {
+		name    string
+		script  string
+		pattern string
+		want    string
+		wantErr bool
+	}{
+		{name: "prints the value", script: `echo "$2.1"`, pattern: "v1.5", want: lines("image:", "  tag: v1.5.1")},
+		{name: "sees the current value", script: `echo "$1-patched"`, want: lines("image:", "  tag: v1.4.0-patched")},
+		{name: "surrounding whitespace", script: `printf '\n  v2  \n\n'`, want: lines("image:", "  tag: v2")},
+		{name: "fails", script: `echo v2; exit 1`, wantErr: true},
+		{name: "prints nothing", script: `exit 0`, wantErr: true},
+		{name: "prints two lines", script: `echo v2; echo v3`, wantErr: true},
+	}
+	for _, test := range testData {
+		t.Run(test.name, func(t *testing.T) {
+			script := filepath.Join(t.TempDir(), "resolve.sh")
+			if err := ioutil.WriteFile(script, []byte(test.script+"\n"), 0644); err != nil {
+				t.Fatalf("write script: %v", err)
+			}
+			gh, client := newFakeGitHub(t)
+			before := gh.push("o/r", "master", map[string]string{"config.yaml": lines("image:", "  tag: v1.4.0")})
+			cfg := &config{
+				GithubOwner:  "o",
+				GithubRepo:   "r",
+				GithubBranch: "master",
+				File:         "config.yaml",
+				Locations:    []string{"image.tag"},
+				Resolver:     "sh " + script,
+				ResolverPat:  test.pattern,
+			}
+			err := run(context.Background(), client, cfg, ioutil.Discard)
+			if test.wantErr {
+				if err == nil {
+					t.Error("expected error")
+				}
+				if after := gh.head("o/r", "master").SHA; after != before {
+					t.Errorf("branch moved from %s to %s", before, after)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("run: %v", err)
+			}
+			if got, _ := gh.file("o/r", "master", "config.yaml"); got != test.want {
+				t.Errorf("content: got %q, want %q", got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,42 @@
+package bump
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// result is what a run did, printed with --output json.
+type result struct {
+	Changed        bool   `json:"changed"`
+	SHA            string `json:"sha,omitempty"`
+	URL            string `json:"url,omitempty"`
+	CompareURL     string `json:"compare_url,omitempty"`
+	PullRequestURL string `json:"pull_request_url,omitempty"`
+}
+
+// commitURL returns the URL of the Github page showing a commit.
+func commitURL(web, owner, repo, sha string) string {
+	return fmt.Sprintf("%s/%s/%s/commit/%s", web, owner, repo, sha)
+}
+
+// writeResult prints res to w: the SHA of the new commit, if any, with --print-sha-only, and
+// all of it as JSON with --output json.  Otherwise, the log says it all, and nothing is
+// printed.
+func writeResult(w io.Writer, cfg *config, res result) error {
+	if cfg.PrintSHAOnly {
+		if res.SHA != "" {
+			fmt.Fprintln(w, res.SHA)
+		}
+		return nil
+	}
+	if cfg.Output != "json" {
+		return nil
+	}
+	out, err := json.Marshal(res)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "%s\n", out)
+	return err
+}
@@ -0,0 +1,158 @@
+package bump
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-github/v32/github"
+)
+
+func TestResult(t *testing.T) {
+	testData := []struct {
+		name        string
+		replacement string
+		pullRequest bool
+		wantChanged bool
+	}{
+		{name: "commit", replacement: "v2", wantChanged: true},
+		{name: "pull request", replacement: "v2", pullRequest: true, wantChanged: true},
+		{name: "unchanged", replacement: "v1"},
+	}
+	for _, test := range testData {
+		t.Run(test.name, func(t *testing.T) {
+			gh, client := newFakeGitHub(t)
+			before := gh.push("o/r", "master", map[string]string{"config.yaml": lines("image:", "  tag: v1")})
+			cfg := &config{
+				GithubOwner:   "o",
+				GithubRepo:    "r",
+				GithubBranch:  "master",
+				File:          "config.yaml",
+				Locations:     []string{"image.tag"},
+				Replacement:   test.replacement,
+				CommitMessage: "Bump image.tag",
+				PullRequest:   test.pullRequest,
+				Output:        "json",
+			}
+			var stdout bytes.Buffer
+			if err := run(context.Background(), client, cfg, &stdout); err != nil {
+				t.Fatalf("run: %v", err)
+			}
+			var got result
+			if err := json.Unmarshal(stdout.Bytes(), &got); err != nil {
+				t.Fatalf("unmarshal %q: %v", stdout.String(), err)
+			}
+			want := result{Changed: test.wantChanged}
+			if test.wantChanged {
+				want.SHA = gh.head("o/r", "master").SHA
+				if test.pullRequest {
+					want.SHA = got.SHA // On the pull request's branch, checked below.
+					want.PullRequestURL = gh.url + "/o/r/pull/1"
+				}
+				want.URL = gh.url + "/o/r/commit/" + want.SHA
+				want.CompareURL = gh.url + "/o/r/compare/" + before + "..." + want.SHA
+			}
+			if diff := cmp.Diff(want, got); diff != "" {
+				t.Errorf("result (-want +got):\n%s", diff)
+			}
+			if test.pullRequest && got.SHA == gh.head("o/r", "master").SHA {
+				t.Errorf("pull request commit %s is on master", got.SHA)
+			}
+		})
+	}
+}
+
+func TestCommitURL(t *testing.T) {
+	enterprise, err := github.NewEnterpriseClient("https://github.example.com/api/v3/", "https://github.example.com/api/uploads/", nil)
+	if err != nil {
+		t.Fatalf("new enterprise client: %v", err)
+	}
+	testData := []struct {
+		name   string
+		client *github.Client
+		want   string
+	}{
+		{name: "github.com", client: github.NewClient(nil), want: "https://github.com/o/r/commit/abc"},
+		{name: "enterprise", client: enterprise, want: "https://github.example.com/o/r/commit/abc"},
+	}
+	for _, test := range testData {
+		t.Run(test.name, func(t *testing.T) {
+			if got := commitURL(webURL(test.client), "o", "r", "abc"); got != test.want {
+				t.Errorf("got %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+// rehost is an http.RoundTripper that sends every request to target instead, without the
+// /api/v3 prefix of Github Enterprise, so that a client for any host can talk to a fake.
+type rehost struct {
+	base   http.RoundTripper
+	target *url.URL
+}
+
+func (t rehost) RoundTrip(req *http.Request) (*http.Response, error) {
+	r := req.Clone(req.Context())
+	r.URL.Scheme, r.URL.Host, r.Host = t.target.Scheme, t.target.Host, ""
+	r.URL.Path = strings.TrimPrefix(r.URL.Path, "/api/v3")
+	return t.base.RoundTrip(r)
+}
+
+func TestResultURLs(t *testing.T) {
+	testData := []struct {
+		name    string
+		baseURL string
+		web     string
+	}{
+		{name: "github.com", baseURL: "https://api.github.com/", web: "https://github.com"},
+		{name: "enterprise", baseURL: "https://github.example.com/api/v3/", web: "https://github.example.com"},
+	}
+	for _, test := range testData {
+		t.Run(test.name, func(t *testing.T) {
+			gh, _ := newFakeGitHub(t)
+			before := gh.push("o/r", "master", map[string]string{"config.yaml": lines("image:", "  tag: v1")})
+			target, err := url.Parse(gh.url)
+			if err != nil {
+				t.Fatalf("parse fake server url: %v", err)
+			}
+			client := github.NewClient(&http.Client{Transport: rehost{base: http.DefaultTransport, target: target}})
+			client.BaseURL, err = url.Parse(test.baseURL)
+			if err != nil {
+				t.Fatalf("parse base url: %v", err)
+			}
+			cfg := &config{
+				GithubOwner:   "o",
+				GithubRepo:    "r",
+				GithubBranch:  "master",
+				File:          "config.yaml",
+				Locations:     []string{"image.tag"},
+				Replacement:   "v2",
+				CommitMessage: "Bump image.tag",
+				Output:        "json",
+			}
+			var stdout bytes.Buffer
+			if err := run(context.Background(), client, cfg, &stdout); err != nil {
+				t.Fatalf("run: %v", err)
+			}
+			var got result
+			if err := json.Unmarshal(stdout.Bytes(), &got); err != nil {
+				t.Fatalf("unmarshal %q: %v", stdout.String(), err)
+			}
+			sha := gh.head("o/r", "master").SHA
+			want := result{
+				Changed:    true,
+				SHA:        sha,
+				URL:        test.web + "/o/r/commit/" + sha,
+				CompareURL: test.web + "/o/r/compare/" + before + "..." + sha,
+			}
+			if diff := cmp.Diff(want, got); diff != "" {
+				t.Errorf("result (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
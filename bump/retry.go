@@ -0,0 +1,263 @@
+package bump
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/v32/github"
+)
+
+// isNotFastForward returns true if err is Github refusing to move a branch because it has moved
+// on from the new commit's parent.
+func isNotFastForward(err error) bool {
+	var resp *github.ErrorResponse
+	if !errors.As(err, &resp) || resp.Response == nil {
+		return false
+	}
+	return resp.Response.StatusCode == http.StatusUnprocessableEntity && strings.Contains(resp.Message, "fast forward")
+}
+
+// errBranchMoved is returned when a branch is found to have moved on from the commit it was
+// read at, just before it would have been moved to a new commit.
+var errBranchMoved = errors.New("branch moved, please retry")
+
+// defaultConflictRetries is how many times --retry-on-conflict retries, unless
+// --conflict-retries says otherwise.
+const defaultConflictRetries = 3
+
+// isConflict returns true if err is a commit refused because its branch moved on from the
+// commit's parent, whether noticed by commit or by Github.
+func isConflict(err error) bool {
+	return errors.Is(err, errBranchMoved) || isNotFastForward(err)
+}
+
+// explainConflict adds to err, if it is a commit refused because branch has moved on from
+// parent, what to do about it.  Other errors are returned as they are.
+func explainConflict(err error, branch, parent string) error {
+	if !isConflict(err) {
+		return err
+	}
+	return fmt.Errorf("%s has moved on from commit %s since it was read; run again to edit its new head, pass --retry-on-conflict to do so automatically, or pass --force to discard the changes made since: %w", branch, parent, err)
+}
+
+// reapply reads --file again from the head of --branch and edits it afresh, for a retry after
+// the branch moved on.  The edit is made to the new content from scratch, looking up each
+// location again, rather than replaying the old change, so it still lands in the right place if
+// the file changed in other ways.  The new edit is checked just like the first.  It returns the new read, and the files to commit on top of
+// it.
+func reapply(ctx context.Context, client *github.Client, cfg *config, source string) (*fileInTree, map[string]string, error) {
+	orig, err := fetch(ctx, client, cfg.GithubOwner, cfg.GithubRepo, cfg.GithubBranch, cfg.File, cfg.PathPrefix, readsTree(cfg))
+	if err != nil {
+		return nil, nil, inPhase(phaseFetch, fmt.Errorf("fetch %s again from github.com/%s/%s@%s: %w", cfg.File, cfg.GithubOwner, cfg.GithubRepo, cfg.GithubBranch, err))
+	}
+	new, _, err := editChecked(ctx, cfg, orig.Content, nil)
+	if err != nil {
+		return nil, nil, inPhase(phaseEdit, fmt.Errorf("re-apply edit to commit %s: %w", orig.CommitSHA, err))
+	}
+	text, known := isText(orig.Attributes, cfg.File)
+	new = fixLineEndings(orig.Content, new, text, known)
+	files, err := commitFiles(ctx, client, cfg, source, orig, new)
+	if err != nil {
+		return nil, nil, err
+	}
+	return orig, files, nil
+}
+
+// readsTree returns true if a run with cfg reads other files from the same commit as --file,
+// and so is better off reading the whole tree once than reading --file alone.
+func readsTree(cfg *config) bool {
+	return len(cfg.ExtraFiles) > 0 || cfg.HelmChart != "" || cfg.Attestation != ""
+}
+
+// commitFiles returns the files to commit for an edit of orig into new: the file itself, any
+// --file after the first, read from the same commit and edited and checked the same way, and
+// the chart and attestation files that go along with it.
+func commitFiles(ctx context.Context, client *github.Client, cfg *config, source string, orig *fileInTree, new string) (map[string]string, error) {
+	files := map[string]string{cfg.File: new}
+	for _, name := range cfg.ExtraFiles {
+		content, err := readFile(ctx, client, cfg.GithubOwner, cfg.GithubRepo, orig.Tree, name)
+		if err != nil {
+			return nil, inPhase(phaseFetch, fmt.Errorf("fetch %s from commit %s: %w", name, orig.CommitSHA, err))
+		}
+		other := *cfg
+		other.File = name
+		edited, _, err := editChecked(ctx, &other, content, nil)
+		if err != nil {
+			return nil, inPhase(phaseEdit, err)
+		}
+		files[name] = fixLineEndings(content, edited, false, false)
+	}
+	if cfg.HelmChart != "" {
+		name, content, err := editChart(ctx, client, cfg, orig)
+		if err != nil {
+			return nil, err
+		}
+		files[name] = content
+	}
+	if cfg.Attestation != "" {
+		content, err := attest(ctx, client, cfg, source, orig, new)
+		if err != nil {
+			return nil, err
+		}
+		files[cfg.Attestation] = content
+	}
+	return files, nil
+}
+
+// retryTransport is an http.RoundTripper that retries requests that fail transiently: with a
+// server error, unless they are POSTs, or with Github's secondary rate limit.  It waits between
+// attempts as long as Retry-After says, or else backs off exponentially from delay, with jitter.
+// It gives up early rather than wait past the request's deadline.  Other failures, like 404 and
+// 422, are returned at once.
+type retryTransport struct {
+	base  http.RoundTripper
+	max   int
+	delay time.Duration
+}
+
+func (t *retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	for attempt := 0; ; attempt++ {
+		if attempt > 0 && req.Body != nil {
+			if req.GetBody == nil {
+				return nil, fmt.Errorf("retry %s %s: request body cannot be re-read", req.Method, req.URL)
+			}
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			req = req.Clone(req.Context())
+			req.Body = body
+		}
+		resp, err := t.base.RoundTrip(req)
+		if err != nil || attempt >= t.max || !isTransient(req, resp) {
+			return resp, err
+		}
+		wait, ok := retryAfter(resp)
+		if !ok {
+			backoff := t.delay << attempt
+			wait = backoff/2 + time.Duration(rand.Int63n(int64(backoff/2)+1))
+		}
+		if deadline, ok := req.Context().Deadline(); ok && time.Now().Add(wait).After(deadline) {
+			return resp, nil
+		}
+		log.Printf("%s %s: %s; retrying in %v (retry %d of %d)", req.Method, req.URL.Path, resp.Status, wait, attempt+1, t.max)
+		io.Copy(ioutil.Discard, resp.Body)
+		resp.Body.Close()
+		timer := time.NewTimer(wait)
+		select {
+		case <-req.Context().Done():
+			timer.Stop()
+			return nil, req.Context().Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// repeatable holds the methods whose requests may be sent again after a server error.  A POST,
+// like creating a commit or a pull request, may have taken effect before the server failed.
+var repeatable = map[string]bool{
+	http.MethodGet:    true,
+	http.MethodHead:   true,
+	http.MethodPut:    true,
+	http.MethodPatch:  true,
+	http.MethodDelete: true,
+}
+
+// isTransient returns true if resp, the reply to req, is a failure that may succeed if tried
+// again: a server error, if req is repeatable, or Github's secondary rate limit, which comes as
+// a 403 or 429 with Retry-After, or a 403 saying so.  Requests refused by the rate limit were
+// not carried out, so are retried whatever their method.  The body of resp is left readable.
+func isTransient(req *http.Request, resp *http.Response) bool {
+	switch {
+	case resp.StatusCode >= 500:
+		return repeatable[req.Method]
+	case resp.StatusCode != http.StatusForbidden && resp.StatusCode != http.StatusTooManyRequests:
+		return false
+	case resp.Header.Get("Retry-After") != "":
+		return true
+	}
+	body, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	resp.Body = ioutil.NopCloser(bytes.NewReader(body))
+	return err == nil && strings.Contains(strings.ToLower(string(body)), "secondary rate limit")
+}
+
+// retryAfter returns how long resp's Retry-After header says to wait, and false if it says
+// nothing usable.
+func retryAfter(resp *http.Response) (time.Duration, bool) {
+	seconds, err := strconv.Atoi(resp.Header.Get("Retry-After"))
+	if err != nil || seconds < 0 {
+		return 0, false
+	}
+	return time.Duration(seconds) * time.Second, true
+}
+
+// withRetries returns base wrapped to retry transient failures up to max times, or base itself
+// if max is 0.
+func withRetries(base http.RoundTripper, max int) http.RoundTripper {
+	if max <= 0 {
+		return base
+	}
+	return &retryTransport{base: base, max: max, delay: time.Second}
+}
+
+// timeoutTransport is an http.RoundTripper that gives each request at most timeout, on top of
+// any deadline the request already has.  Wrapped around a retryTransport, every attempt at a
+// request shares the one budget, and retries stop when it runs out.  The timeout covers reading
+// the response body, which is why it is only cancelled when the body is closed.
+type timeoutTransport struct {
+	base    http.RoundTripper
+	timeout time.Duration
+}
+
+func (t *timeoutTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx, cancel := context.WithTimeout(req.Context(), t.timeout)
+	resp, err := t.base.RoundTrip(req.WithContext(ctx))
+	if err != nil {
+		cancel()
+		if ctx.Err() == context.DeadlineExceeded && req.Context().Err() == nil {
+			return nil, fmt.Errorf("%s %s: no response within --request-timeout of %v: %w", req.Method, req.URL.Path, t.timeout, err)
+		}
+		return nil, err
+	}
+	resp.Body = &cancelOnClose{ReadCloser: resp.Body, cancel: cancel}
+	return resp, nil
+}
+
+// cancelOnClose is a response body that cancels its request's context once it is closed.
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelOnClose) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
+
+// withRequestTimeout returns base wrapped to time each request out after timeout, or base
+// itself if timeout is 0.
+func withRequestTimeout(base http.RoundTripper, timeout time.Duration) http.RoundTripper {
+	if timeout <= 0 {
+		return base
+	}
+	return &timeoutTransport{base: base, timeout: timeout}
+}
+
+// apiTransport returns base wrapped for talking to an API with the options in a: each request
+// is retried as --max-retries says, within --request-timeout, and writes wait for the rate
+// limit with --rate-limit-wait.
+func apiTransport(base http.RoundTripper, a *auth) http.RoundTripper {
+	return withRateLimit(withRequestTimeout(withRetries(base, a.MaxRetries), a.RequestTimeout), a.RateLimitWait)
+}
@@ -0,0 +1,169 @@
+package bump
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestRetryTransport(t *testing.T) {
+	testData := []struct {
+		name      string
+		method    string // PUT if unset
+		responses []int  // the status of each response, in turn
+		header    string // the Retry-After header of failures
+		body      string // the body of failures
+		deadline  time.Duration
+		wantCalls int
+		want      int
+	}{
+		{name: "bad gateway", responses: []int{502, 502, 200}, wantCalls: 3, want: 200},
+		{name: "gives up", responses: []int{503, 503, 503, 503, 200}, wantCalls: 4, want: 503},
+		{name: "bad gateway on POST", method: "POST", responses: []int{502, 200}, wantCalls: 1, want: 502},
+		{name: "secondary rate limit on POST", method: "POST", responses: []int{429, 200}, header: "0", wantCalls: 2, want: 200},
+		{name: "not found", responses: []int{404, 200}, wantCalls: 1, want: 404},
+		{name: "unprocessable", responses: []int{422, 200}, wantCalls: 1, want: 422},
+		{name: "forbidden", responses: []int{403, 200}, wantCalls: 1, want: 403},
+		{name: "secondary rate limit", responses: []int{403, 200}, body: `{"message": "You have exceeded a secondary rate limit."}`, wantCalls: 2, want: 200},
+		{name: "retry after", responses: []int{429, 200}, header: "0", wantCalls: 2, want: 200},
+		{name: "retry after the deadline", responses: []int{403, 200}, header: "60", deadline: time.Minute / 2, wantCalls: 1, want: 403},
+	}
+	for _, test := range testData {
+		t.Run(test.name, func(t *testing.T) {
+			var bodies []string
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				body, _ := ioutil.ReadAll(r.Body)
+				bodies = append(bodies, string(body))
+				status := test.responses[len(bodies)-1]
+				if status != 200 && test.header != "" {
+					w.Header().Set("Retry-After", test.header)
+				}
+				w.WriteHeader(status)
+				if status != 200 {
+					fmt.Fprint(w, test.body)
+				}
+			}))
+			defer srv.Close()
+
+			ctx := context.Background()
+			if test.deadline != 0 {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithTimeout(ctx, test.deadline)
+				defer cancel()
+			}
+			method := test.method
+			if method == "" {
+				method = "PUT"
+			}
+			req, err := http.NewRequestWithContext(ctx, method, srv.URL, strings.NewReader("payload"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			client := &http.Client{Transport: &retryTransport{base: http.DefaultTransport, max: 3, delay: time.Millisecond}}
+			resp, err := client.Do(req)
+			if err != nil {
+				t.Fatalf("do: %v", err)
+			}
+			defer resp.Body.Close()
+			if resp.StatusCode != test.want {
+				t.Errorf("status: got %d, want %d", resp.StatusCode, test.want)
+			}
+			if got, _ := ioutil.ReadAll(resp.Body); resp.StatusCode != 200 && string(got) != test.body {
+				t.Errorf("body of the last response: got %q, want %q", got, test.body)
+			}
+			want := strings.Split(strings.Repeat("payload ", test.wantCalls), " ")[:test.wantCalls]
+			if diff := cmp.Diff(want, bodies); diff != "" {
+				t.Errorf("request bodies (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestConflictAfterCheck(t *testing.T) {
+	gh, client := newFakeGitHub(t)
+	gh.push("o/r", "master", map[string]string{"config.yaml": lines("image:", "  tag: v1")})
+	// Someone else pushes after the head was checked, but before it is moved.
+	gh.before("PATCH", "/repos/o/r/git/refs/heads/master", func() {
+		gh.push("o/r", "master", map[string]string{"other.yaml": lines("a: b")})
+	})
+	cfg := &config{
+		GithubOwner:  "o",
+		GithubRepo:   "r",
+		GithubBranch: "master",
+		File:         "config.yaml",
+		Locations:    []string{"image.tag"},
+		Replacement:  "v2",
+	}
+	err := run(context.Background(), client, cfg, ioutil.Discard)
+	if err == nil || !strings.Contains(err.Error(), "not a fast forward") || !strings.Contains(err.Error(), "master has moved on from commit") {
+		t.Fatalf("want Github's refusal, explained, got %v", err)
+	}
+	if !isConflict(err) {
+		t.Errorf("isConflict(%v) = false, want true", err)
+	}
+}
+
+func TestTimeoutTransport(t *testing.T) {
+	testData := []struct {
+		name      string
+		delay     time.Duration // how long the server takes to answer
+		status    int
+		wantErr   string
+		wantBody  string
+		maxCalls  int
+		wantCalls int
+	}{
+		{name: "in time", status: 200, wantBody: "ok", wantCalls: 1},
+		{name: "too slow", delay: time.Second, status: 200, wantErr: "no response within --request-timeout", wantCalls: 1},
+		// Each retry waits longer, so the retries run out of budget before max.
+		{name: "retries share the budget", status: 502, maxCalls: 9},
+	}
+	for _, test := range testData {
+		t.Run(test.name, func(t *testing.T) {
+			calls := 0
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				calls++
+				select {
+				case <-time.After(test.delay):
+				case <-r.Context().Done():
+				}
+				w.WriteHeader(test.status)
+				fmt.Fprint(w, "ok")
+			}))
+			defer srv.Close()
+			retries := &retryTransport{base: http.DefaultTransport, max: 10, delay: 20 * time.Millisecond}
+			client := &http.Client{Transport: withRequestTimeout(retries, 200*time.Millisecond)}
+			resp, err := client.Get(srv.URL)
+			if test.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+					t.Fatalf("want error containing %q, got %v", test.wantErr, err)
+				}
+			} else {
+				if err != nil {
+					t.Fatalf("get: %v", err)
+				}
+				body, err := ioutil.ReadAll(resp.Body)
+				resp.Body.Close()
+				if err != nil {
+					t.Fatalf("read body: %v", err)
+				}
+				if test.wantBody != "" && string(body) != test.wantBody {
+					t.Errorf("body: got %q, want %q", body, test.wantBody)
+				}
+			}
+			if test.wantCalls != 0 && calls != test.wantCalls {
+				t.Errorf("calls: got %d, want %d", calls, test.wantCalls)
+			}
+			if test.maxCalls != 0 && calls > test.maxCalls {
+				t.Errorf("calls: got %d, want at most %d", calls, test.maxCalls)
+			}
+		})
+	}
+}
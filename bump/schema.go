@@ -0,0 +1,105 @@
+package bump
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"path/filepath"
+	"strings"
+
+	"github.com/xeipuuv/gojsonschema"
+	"sigs.k8s.io/kustomize/kyaml/yaml"
+)
+
+// loadSchema reads and compiles the JSON schema in file.  References in it are resolved
+// relative to file.
+func loadSchema(file string) (*gojsonschema.Schema, error) {
+	abs, err := filepath.Abs(file)
+	if err != nil {
+		return nil, fmt.Errorf("load schema %s: %w", file, err)
+	}
+	schema, err := gojsonschema.NewSchema(gojsonschema.NewReferenceLoader("file://" + filepath.ToSlash(abs)))
+	if err != nil {
+		return nil, fmt.Errorf("load schema %s: %w", file, err)
+	}
+	return schema, nil
+}
+
+// schemaDocuments returns each document of content, in format, converted to JSON.
+func schemaDocuments(format, content string) ([][]byte, error) {
+	var values []interface{}
+	switch format {
+	case formatYAML, formatJSON:
+		docs, _ := splitDocuments(content)
+		for _, doc := range docs {
+			var v interface{}
+			if err := yaml.Unmarshal([]byte(doc), &v); err != nil {
+				return nil, fmt.Errorf("parse document %d: %w", len(values)+1, err)
+			}
+			if v != nil {
+				values = append(values, v)
+			}
+		}
+	case formatTOML:
+		root, _, err := parseTOML(content)
+		if err != nil {
+			return nil, err
+		}
+		var v interface{}
+		if err := root.Decode(&v); err != nil {
+			return nil, err
+		}
+		values = append(values, v)
+	default:
+		return nil, fmt.Errorf("--schema only validates YAML, JSON, and TOML files, not %s", format)
+	}
+	var result [][]byte
+	for i, v := range values {
+		js, err := json.Marshal(v)
+		if err != nil {
+			return nil, fmt.Errorf("convert document %d to JSON: %w", i+1, err)
+		}
+		result = append(result, js)
+	}
+	return result, nil
+}
+
+// checkSchema validates content, the edited content of cfg.File, against --schema, and logs
+// that it matches.  Each document of a YAML file is validated on its own.  It returns an error
+// listing what does not match the schema, if anything.
+func checkSchema(cfg *config, content string) error {
+	if cfg.Schema == "" {
+		return nil
+	}
+	schema, err := loadSchema(cfg.Schema)
+	if err != nil {
+		return err
+	}
+	format, err := fileFormat(cfg, content)
+	if err != nil {
+		return err
+	}
+	docs, err := schemaDocuments(format, content)
+	if err != nil {
+		return fmt.Errorf("validate %s against %s: %w", cfg.File, cfg.Schema, err)
+	}
+	var problems []string
+	for i, doc := range docs {
+		res, err := schema.Validate(gojsonschema.NewBytesLoader(doc))
+		if err != nil {
+			return fmt.Errorf("validate %s against %s: %w", cfg.File, cfg.Schema, err)
+		}
+		for _, e := range res.Errors() {
+			p := e.String()
+			if len(docs) > 1 {
+				p = fmt.Sprintf("document %d: %s", i+1, p)
+			}
+			problems = append(problems, p)
+		}
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("edited %s does not match schema %s: %s", cfg.File, cfg.Schema, strings.Join(problems, "; "))
+	}
+	log.Printf("edited %s matches schema %s", cfg.File, cfg.Schema)
+	return nil
+}
@@ -0,0 +1,87 @@
+package bump
+
+import (
+	"context"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSchema(t *testing.T) {
+	schema := filepath.Join(t.TempDir(), "schema.json")
+	if err := ioutil.WriteFile(schema, []byte(`{
+		"type": "object",
+		"properties": {"replicas": {"type": "integer", "minimum": 1}}
+	}`), 0644); err != nil {
+		t.Fatalf("write schema: %v", err)
+	}
+	testData := []struct {
+		name        string
+		file        string
+		content     string
+		replacement string
+		dryRun      bool
+		wantErr     string
+	}{
+		{name: "valid", file: "config.yaml", content: lines("replicas: 1"), replacement: "3"},
+		{name: "invalid", file: "config.yaml", content: lines("replicas: 1"), replacement: "0", wantErr: "replicas: Must be greater than or equal to 1"},
+		{name: "invalid dry run", file: "config.yaml", content: lines("replicas: 1"), replacement: "0", dryRun: true, wantErr: "does not match schema"},
+		{name: "second document", file: "config.yaml", content: lines("replicas: 1", "---", "replicas: 2"), replacement: "-1", wantErr: "document 2: replicas"},
+		{name: "toml", file: "config.toml", content: lines("replicas = 1"), replacement: "0", wantErr: "replicas: Must be greater"},
+		{name: "dotenv", file: "config.env", content: lines("replicas=1"), replacement: "3", wantErr: "only validates YAML, JSON, and TOML"},
+	}
+	for _, test := range testData {
+		t.Run(test.name, func(t *testing.T) {
+			gh, client := newFakeGitHub(t)
+			gh.push("o/r", "master", map[string]string{test.file: test.content})
+			head := gh.head("o/r", "master").SHA
+			cfg := &config{
+				GithubOwner:   "o",
+				GithubRepo:    "r",
+				GithubBranch:  "master",
+				File:          test.file,
+				Locations:     []string{"replicas"},
+				Replacement:   test.replacement,
+				CommitMessage: "Scale",
+				Schema:        schema,
+				DryRun:        test.dryRun,
+			}
+			err := run(context.Background(), client, cfg, ioutil.Discard)
+			if test.wantErr == "" {
+				if err != nil {
+					t.Fatalf("run: %v", err)
+				}
+				if gh.head("o/r", "master").SHA == head {
+					t.Error("no commit was made")
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+				t.Fatalf("want error containing %q, got %v", test.wantErr, err)
+			}
+			if gh.head("o/r", "master").SHA != head {
+				t.Error("committed content that does not match the schema")
+			}
+		})
+	}
+}
+
+func TestSchemaBeforeFetch(t *testing.T) {
+	gh, client := newFakeGitHub(t)
+	cfg := &config{
+		GithubOwner:  "o",
+		GithubRepo:   "r",
+		GithubBranch: "master",
+		File:         "config.yaml",
+		Locations:    []string{"replicas"},
+		Replacement:  "3",
+		Schema:       filepath.Join(t.TempDir(), "missing.json"),
+	}
+	if err := run(context.Background(), client, cfg, ioutil.Discard); err == nil || !strings.Contains(err.Error(), "load schema") {
+		t.Fatalf("want an error loading the schema, got %v", err)
+	}
+	if n := gh.called("GET", "/repos/"); n != 0 {
+		t.Errorf("made %d requests before loading the schema", n)
+	}
+}
@@ -0,0 +1,157 @@
+package bump
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// errInvalidReplacement is returned when --semver is set and a replacement is not a semantic
+// version.
+var errInvalidReplacement = errors.New("invalid replacement")
+
+// A version is a semantic version, as described at https://semver.org.  A leading "v" is
+// accepted and ignored.
+type version struct {
+	Major, Minor, Patch int64
+	Prerelease          []string
+	Build               string
+}
+
+var semverRE = regexp.MustCompile(`^v?(0|[1-9]\d*)\.(0|[1-9]\d*)\.(0|[1-9]\d*)` +
+	`(?:-((?:0|[1-9]\d*|\d*[a-zA-Z-][0-9a-zA-Z-]*)(?:\.(?:0|[1-9]\d*|\d*[a-zA-Z-][0-9a-zA-Z-]*))*))?` +
+	`(?:\+([0-9a-zA-Z-]+(?:\.[0-9a-zA-Z-]+)*))?$`)
+
+func parseVersion(s string) (version, error) {
+	m := semverRE.FindStringSubmatch(s)
+	if m == nil {
+		return version{}, fmt.Errorf("%q is not a semantic version", s)
+	}
+	var v version
+	for i, dst := range []*int64{&v.Major, &v.Minor, &v.Patch} {
+		n, err := strconv.ParseInt(m[i+1], 10, 64)
+		if err != nil {
+			return version{}, fmt.Errorf("parse %q: %w", s, err)
+		}
+		*dst = n
+	}
+	if m[4] != "" {
+		v.Prerelease = strings.Split(m[4], ".")
+	}
+	v.Build = m[5]
+	return v, nil
+}
+
+// compare returns -1, 0, or 1 if v has lower, equal, or higher precedence than o.  Build
+// metadata does not affect precedence.
+func (v version) compare(o version) int {
+	for _, p := range [][2]int64{{v.Major, o.Major}, {v.Minor, o.Minor}, {v.Patch, o.Patch}} {
+		if p[0] != p[1] {
+			return sign(p[0] - p[1])
+		}
+	}
+	// A version without a prerelease has higher precedence than one with a prerelease.
+	switch {
+	case len(v.Prerelease) == 0 && len(o.Prerelease) == 0:
+		return 0
+	case len(v.Prerelease) == 0:
+		return 1
+	case len(o.Prerelease) == 0:
+		return -1
+	}
+	for i := 0; i < len(v.Prerelease) && i < len(o.Prerelease); i++ {
+		a, b := v.Prerelease[i], o.Prerelease[i]
+		if a == b {
+			continue
+		}
+		an, aerr := strconv.ParseInt(a, 10, 64)
+		bn, berr := strconv.ParseInt(b, 10, 64)
+		switch {
+		case aerr == nil && berr == nil:
+			return sign(an - bn)
+		case aerr == nil:
+			// Numeric identifiers sort before alphanumeric ones.
+			return -1
+		case berr == nil:
+			return 1
+		case a < b:
+			return -1
+		default:
+			return 1
+		}
+	}
+	return sign(int64(len(v.Prerelease) - len(o.Prerelease)))
+}
+
+func sign(n int64) int {
+	switch {
+	case n < 0:
+		return -1
+	case n > 0:
+		return 1
+	}
+	return 0
+}
+
+// bump returns v with part, "major", "minor", or "patch", incremented, and the parts after it
+// reset.  Prerelease and build metadata are dropped.
+func (v version) bump(part string) (version, error) {
+	switch part {
+	case "major":
+		return version{Major: v.Major + 1}, nil
+	case "minor":
+		return version{Major: v.Major, Minor: v.Minor + 1}, nil
+	case "patch":
+		return version{Major: v.Major, Minor: v.Minor, Patch: v.Patch + 1}, nil
+	}
+	return version{}, fmt.Errorf("unknown version part %q; want major, minor, or patch", part)
+}
+
+// String returns v as a semantic version, without a leading "v".
+func (v version) String() string {
+	s := fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+	if len(v.Prerelease) > 0 {
+		s += "-" + strings.Join(v.Prerelease, ".")
+	}
+	if v.Build != "" {
+		s += "+" + v.Build
+	}
+	return s
+}
+
+// checkDowngrades looks for edits cfg would make to content that replace a semantic version
+// with a lower one.  With --no-downgrade, it returns an error for the first, and warns of
+// locations it can't check because the value there or its replacement is not a semantic
+// version; otherwise, it only warns of each downgrade.  Locations that are not found are
+// skipped.
+func checkDowngrades(cfg *config, content string) error {
+	edits, err := locationEdits(cfg)
+	if err != nil {
+		return err
+	}
+	for _, e := range edits {
+		old, found, err := valueAt(content, e.Path)
+		if err != nil || !found {
+			continue
+		}
+		oldV, oldErr := parseVersion(old)
+		newV, newErr := parseVersion(e.Value)
+		if oldErr != nil || newErr != nil {
+			if cfg.NoDowngrade {
+				log.Printf("warning: not checking %s in %s for a downgrade from %q to %q: both must be semantic versions", e.Path, cfg.File, old, e.Value)
+			}
+			continue
+		}
+		if newV.compare(oldV) >= 0 {
+			continue
+		}
+		if cfg.NoDowngrade {
+			return fmt.Errorf("%s in %s is %s; refusing to downgrade it to %s, since --no-downgrade is set", e.Path, cfg.File, old, e.Value)
+		}
+		log.Printf("warning: %s in %s is %s; replacing it with %s is a downgrade", e.Path, cfg.File, old, e.Value)
+	}
+	return nil
+}
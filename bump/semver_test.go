@@ -0,0 +1,119 @@
+package bump
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io/ioutil"
+	"log"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestSemver(t *testing.T) {
+	testData := []struct {
+		name        string
+		replacement string
+		wantErr     bool
+		wantWarning bool
+	}{
+		{name: "upgrade", replacement: "v1.3.0"},
+		{name: "prerelease", replacement: "1.3.0-rc.1+build.5"},
+		{name: "downgrade", replacement: "v1.1.9", wantWarning: true},
+		{name: "prerelease of the same version", replacement: "v1.2.0-rc.1", wantWarning: true},
+		{name: "not a version", replacement: "latest", wantErr: true},
+		{name: "missing patch", replacement: "v1.3", wantErr: true},
+	}
+	for _, test := range testData {
+		t.Run(test.name, func(t *testing.T) {
+			gh, client := newFakeGitHub(t)
+			before := gh.push("o/r", "master", map[string]string{"config.yaml": lines("image:", "  tag: v1.2.0")})
+			cfg := &config{
+				GithubOwner:  "o",
+				GithubRepo:   "r",
+				GithubBranch: "master",
+				File:         "config.yaml",
+				Locations:    []string{"image.tag"},
+				Replacement:  test.replacement,
+				Semver:       true,
+			}
+			var logs bytes.Buffer
+			log.SetOutput(&logs)
+			defer log.SetOutput(os.Stderr)
+			err := run(context.Background(), client, cfg, ioutil.Discard)
+			if gotWarning := strings.Contains(logs.String(), "downgrade"); gotWarning != test.wantWarning {
+				t.Errorf("warned of a downgrade: got %v, want %v; logs:\n%s", gotWarning, test.wantWarning, logs.String())
+			}
+			if !test.wantErr {
+				if err != nil {
+					t.Fatalf("run: %v", err)
+				}
+				return
+			}
+			if !errors.Is(err, errInvalidReplacement) {
+				t.Fatalf("want an invalid replacement error, got %v", err)
+			}
+			if code := fail(ioutil.Discard, cfg, err); code != 3 {
+				t.Errorf("exit code: got %d, want 3", code)
+			}
+			if n := gh.called("GET", "/repos/o/r/"); n != 0 {
+				t.Errorf("read the repository %d times before refusing the replacement", n)
+			}
+			if after := gh.head("o/r", "master").SHA; after != before {
+				t.Errorf("branch moved from %s to %s", before, after)
+			}
+		})
+	}
+}
+
+func TestNoDowngrade(t *testing.T) {
+	testData := []struct {
+		name        string
+		replacement string
+		current     string
+		wantErr     string
+		wantWarning string
+	}{
+		{name: "upgrade", current: "v1.2.0", replacement: "v1.3.0"},
+		{name: "same version", current: "v1.2.0", replacement: "1.2.0"},
+		{name: "downgrade", current: "v1.2.0", replacement: "v1.1.9", wantErr: "refusing to downgrade"},
+		{name: "release to prerelease", current: "v1.2.0", replacement: "v1.2.0-rc.1", wantErr: "refusing to downgrade"},
+		{name: "current is not a version", current: "latest", replacement: "v1.0.0", wantWarning: "not checking image.tag"},
+		{name: "replacement is not a version", current: "v1.2.0", replacement: "latest", wantWarning: "not checking image.tag"},
+	}
+	for _, test := range testData {
+		t.Run(test.name, func(t *testing.T) {
+			gh, client := newFakeGitHub(t)
+			before := gh.push("o/r", "master", map[string]string{"config.yaml": lines("image:", "  tag: "+test.current)})
+			cfg := &config{
+				GithubOwner:  "o",
+				GithubRepo:   "r",
+				GithubBranch: "master",
+				File:         "config.yaml",
+				Locations:    []string{"image.tag"},
+				Replacement:  test.replacement,
+				NoDowngrade:  true,
+			}
+			var logs bytes.Buffer
+			log.SetOutput(&logs)
+			defer log.SetOutput(os.Stderr)
+			err := run(context.Background(), client, cfg, ioutil.Discard)
+			if test.wantWarning != "" && !strings.Contains(logs.String(), test.wantWarning) {
+				t.Errorf("want a warning containing %q, got logs:\n%s", test.wantWarning, logs.String())
+			}
+			if test.wantErr == "" {
+				if err != nil {
+					t.Fatalf("run: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+				t.Fatalf("want an error containing %q, got %v", test.wantErr, err)
+			}
+			if after := gh.head("o/r", "master").SHA; after != before {
+				t.Errorf("branch moved from %s to %s", before, after)
+			}
+		})
+	}
+}
@@ -0,0 +1,58 @@
+package bump
+
+import (
+	"fmt"
+	"os"
+
+	"golang.org/x/crypto/openpgp"
+)
+
+// readSigningKey reads the armored OpenPGP private key in file, decrypting it, and any
+// subkeys, with passphrase if it is encrypted.  If the file holds more than one key, the first
+// is used.
+func readSigningKey(file, passphrase string) (*openpgp.Entity, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, fmt.Errorf("open signing key: %w", err)
+	}
+	defer f.Close()
+	keys, err := openpgp.ReadArmoredKeyRing(f)
+	if err != nil {
+		return nil, fmt.Errorf("read signing key %s: %w", file, err)
+	}
+	key := keys[0]
+	if key.PrivateKey == nil {
+		return nil, fmt.Errorf("%s does not contain a private key", file)
+	}
+	if key.PrivateKey.Encrypted {
+		if passphrase == "" {
+			return nil, fmt.Errorf("signing key %s is encrypted; pass --signing-passphrase", file)
+		}
+		if err := key.PrivateKey.Decrypt([]byte(passphrase)); err != nil {
+			return nil, fmt.Errorf("decrypt signing key %s: %w", file, err)
+		}
+	}
+	for _, sub := range key.Subkeys {
+		if sub.PrivateKey != nil && sub.PrivateKey.Encrypted {
+			if err := sub.PrivateKey.Decrypt([]byte(passphrase)); err != nil {
+				return nil, fmt.Errorf("decrypt signing subkey %s: %w", sub.PublicKey.KeyIdString(), err)
+			}
+		}
+	}
+	return key, nil
+}
+
+// commitIdentity is resolveIdentity, with the key from --signing-key-file, if any, to sign
+// commits with.
+func commitIdentity(cfg *config) (identity, error) {
+	id := resolveIdentity(cfg)
+	if cfg.SigningKey == "" {
+		return id, nil
+	}
+	key, err := readSigningKey(cfg.SigningKey, cfg.SigningPass)
+	if err != nil {
+		return id, err
+	}
+	id.SigningKey = key
+	return id, nil
+}
@@ -0,0 +1,126 @@
+package bump
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-github/v32/github"
+	"golang.org/x/crypto/openpgp"
+	"golang.org/x/crypto/openpgp/armor"
+)
+
+// writeKey writes key, armored, to a file in dir and returns its path.  The private key is
+// included if private is set.
+func writeKey(t *testing.T, dir string, key *openpgp.Entity, private bool) string {
+	t.Helper()
+	var b strings.Builder
+	blockType := openpgp.PublicKeyType
+	if private {
+		blockType = openpgp.PrivateKeyType
+	}
+	w, err := armor.Encode(&b, blockType, nil)
+	if err != nil {
+		t.Fatalf("armor: %v", err)
+	}
+	if private {
+		err = key.SerializePrivate(w, nil)
+	} else {
+		err = key.Serialize(w)
+	}
+	if err != nil {
+		t.Fatalf("serialize key: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("armor: %v", err)
+	}
+	name := filepath.Join(dir, "public.asc")
+	if private {
+		name = filepath.Join(dir, "private.asc")
+	}
+	if err := ioutil.WriteFile(name, []byte(b.String()), 0600); err != nil {
+		t.Fatalf("write key: %v", err)
+	}
+	return name
+}
+
+// signedPayload returns the commit object that git, and Github, verify c's signature over.
+func signedPayload(c *fakeCommit) string {
+	who := func(a *github.CommitAuthor) string {
+		return fmt.Sprintf("%s <%s> %d %s", a.GetName(), a.GetEmail(), a.GetDate().Unix(), a.GetDate().Format("-0700"))
+	}
+	lines := []string{"tree " + c.Tree}
+	for _, p := range c.Parents {
+		lines = append(lines, "parent "+p)
+	}
+	lines = append(lines, "author "+who(c.Author), "committer "+who(c.Committer), "", c.Message)
+	return strings.Join(lines, "\n")
+}
+
+func TestSigning(t *testing.T) {
+	key, err := openpgp.NewEntity("Release Bot", "", "bot@example.com", nil)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	dir := t.TempDir()
+	private := writeKey(t, dir, key, true)
+	public := writeKey(t, dir, key, false)
+
+	testData := []struct {
+		name, keyFile string
+		wantErr       string
+	}{
+		{name: "unsigned"},
+		{name: "signed", keyFile: private},
+		{name: "public key only", keyFile: public, wantErr: "does not contain a private key"},
+		{name: "missing key", keyFile: filepath.Join(dir, "missing.asc"), wantErr: "open signing key"},
+	}
+	for _, test := range testData {
+		t.Run(test.name, func(t *testing.T) {
+			gh, client := newFakeGitHub(t)
+			gh.push("o/r", "master", map[string]string{"config.yaml": lines("image:", "  tag: v1")})
+			cfg := &config{
+				GithubOwner:   "o",
+				GithubRepo:    "r",
+				GithubBranch:  "master",
+				File:          "config.yaml",
+				Locations:     []string{"image.tag"},
+				Replacement:   "v2",
+				AuthorName:    "Release Bot",
+				AuthorEmail:   "bot@example.com",
+				CommitMessage: "Bump image.tag to v2",
+				SigningKey:    test.keyFile,
+			}
+			err := run(context.Background(), client, cfg, ioutil.Discard)
+			if test.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+					t.Fatalf("run: got error %v, want one containing %q", err, test.wantErr)
+				}
+				if n := gh.called("POST", "/repos/o/r/git/commits"); n != 0 {
+					t.Errorf("created %d commits despite the error", n)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("run: %v", err)
+			}
+			head := gh.head("o/r", "master")
+			if test.keyFile == "" {
+				if head.Signature != "" {
+					t.Errorf("commit is signed, but no key was given:\n%s", head.Signature)
+				}
+				return
+			}
+			signer, err := openpgp.CheckArmoredDetachedSignature(openpgp.EntityList{key}, strings.NewReader(signedPayload(head)), strings.NewReader(head.Signature))
+			if err != nil {
+				t.Fatalf("verify signature: %v\nsignature:\n%s", err, head.Signature)
+			}
+			if signer.PrimaryKey.KeyId != key.PrimaryKey.KeyId {
+				t.Errorf("signed by %s, want %s", signer.PrimaryKey.KeyIdString(), key.PrimaryKey.KeyIdString())
+			}
+		})
+	}
+}
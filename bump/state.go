@@ -0,0 +1,82 @@
+package bump
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// state is what version-bump remembers between runs, in the file named by --state-file.  It
+// describes the last file that was read from Github, and the ETag of the branch it was read
+// from, so that an unchanged branch need not be read again, and what was edited in it.
+type state struct {
+	Owner     string `json:"owner"`
+	Repo      string `json:"repo"`
+	Branch    string `json:"branch"`
+	File      string `json:"file"`
+	ETag      string `json:"etag"`
+	CommitSHA string `json:"commit_sha"`
+	TreeSHA   string `json:"tree_sha"`
+	Content   string `json:"content"`
+
+	// Attributes are the .gitattributes files that apply to the file, keyed by path.
+	Attributes map[string]string `json:"attributes,omitempty"`
+
+	// Anchors record the elements that indices in locations pointed at when the file was
+	// last edited.
+	Anchors anchors `json:"anchors,omitempty"`
+}
+
+// sameFile returns true if s was recorded from file on the given branch.
+func (s *state) sameFile(owner, repo, branch, file string) bool {
+	return s != nil && s.Owner == owner && s.Repo == repo && s.Branch == branch && s.File == file
+}
+
+// describes returns true if s was recorded from file on the given branch, and has an ETag to
+// check whether the branch has changed since.
+func (s *state) describes(owner, repo, branch, file string) bool {
+	return s.sameFile(owner, repo, branch, file) && s.ETag != ""
+}
+
+// loadState reads the state file at path.  A missing file is an empty state.
+func loadState(path string) (*state, error) {
+	js, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return &state{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read state file: %w", err)
+	}
+	s := new(state)
+	if err := json.Unmarshal(js, s); err != nil {
+		return nil, fmt.Errorf("parse state file %s: %w", path, err)
+	}
+	return s, nil
+}
+
+// saveState writes s to path, replacing it atomically so that an interrupted run never leaves
+// a truncated state file behind.
+func saveState(path string, s *state) error {
+	js, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshal state: %w", err)
+	}
+	tmp, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return fmt.Errorf("create state file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(append(js, '\n')); err != nil {
+		tmp.Close()
+		return fmt.Errorf("write state file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("write state file: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("replace state file: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,58 @@
+package bump
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"sigs.k8s.io/kustomize/kyaml/yaml"
+)
+
+// checkStrict returns an error if content is not strictly valid YAML, even though parsers may
+// accept it: if it is indented with tabs, or a mapping has the same key twice.  An edit to a
+// duplicated key would change whichever one the parser happened to pick, while readers of
+// the file may use the other.
+func checkStrict(content string) error {
+	if err := checkTabs(content); err != nil {
+		return err
+	}
+	d := yaml.NewDecoder(strings.NewReader(content))
+	for {
+		var doc yaml.Node
+		err := d.Decode(&doc)
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("parse: %w", err)
+		}
+		if err := checkDuplicateKeys(&doc); err != nil {
+			return err
+		}
+	}
+}
+
+// checkDuplicateKeys returns an error if any mapping in node has two keys with the same value.
+func checkDuplicateKeys(node *yaml.Node) error {
+	if node.Kind == yaml.MappingNode {
+		seen := map[string]int{}
+		for i := 0; i < len(node.Content); i += 2 {
+			key := node.Content[i]
+			// Merge keys ("<<") may be repeated, and complex keys can't be compared by value.
+			if key.Kind != yaml.ScalarNode || key.Tag == "!!merge" {
+				continue
+			}
+			if line, ok := seen[key.Value]; ok {
+				return fmt.Errorf("line %d: duplicate key %q; it is already set on line %d", key.Line, key.Value, line)
+			}
+			seen[key.Value] = key.Line
+		}
+	}
+	for _, child := range node.Content {
+		if err := checkDuplicateKeys(child); err != nil {
+			return err
+		}
+	}
+	return nil
+}
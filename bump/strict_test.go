@@ -0,0 +1,64 @@
+package bump
+
+import (
+	"context"
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+func TestCheckStrict(t *testing.T) {
+	testData := []struct {
+		name    string
+		content string
+		wantErr string
+	}{
+		{name: "valid", content: lines("image:", "  repo: app", "  tag: v1", "other:", "  tag: v1")},
+		{name: "same key in different mappings", content: lines("a:", "  tag: v1", "b:", "  tag: v1", "c: [{tag: 1}, {tag: 2}]")},
+		{name: "merge keys", content: lines("base: &base", "  a: 1", "more: &more", "  b: 2", "c:", "  <<: *base", "  <<: *more")},
+		{name: "same key in different documents", content: lines("tag: v1", "---", "tag: v2")},
+		{name: "duplicate key", content: lines("image:", "  tag: v1", "  repo: app", "  tag: v2"), wantErr: `line 4: duplicate key "tag"; it is already set on line 2`},
+		{name: "duplicate top-level key", content: lines("a: 1", "---", "b: 1", "b: 2"), wantErr: `line 4: duplicate key "b"`},
+		{name: "duplicate key in flow mapping", content: lines("image: {tag: v1, tag: v2}"), wantErr: `duplicate key "tag"`},
+		{name: "tab", content: lines("image:", "\ttag: v1"), wantErr: "line 2: indented with a tab"},
+	}
+	for _, test := range testData {
+		t.Run(test.name, func(t *testing.T) {
+			err := checkStrict(test.content)
+			if test.wantErr == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+				t.Errorf("error: got %v, want %q", err, test.wantErr)
+			}
+		})
+	}
+}
+
+func TestStrictYAML(t *testing.T) {
+	content := lines("image:", "  tag: v1", "  repo: app", "  tag: v1")
+	for _, strict := range []bool{false, true} {
+		gh, client := newFakeGitHub(t)
+		before := gh.push("o/r", "master", map[string]string{"config.yaml": content})
+		cfg := &config{
+			GithubOwner:  "o",
+			GithubRepo:   "r",
+			GithubBranch: "master",
+			File:         "config.yaml",
+			Locations:    []string{"image.repo"},
+			Replacement:  "other",
+			Strict:       strict,
+		}
+		err := run(context.Background(), client, cfg, ioutil.Discard)
+		moved := gh.head("o/r", "master").SHA != before
+		if strict && (err == nil || moved) {
+			t.Errorf("strict: error %v, branch moved %v; want an error and no commit", err, moved)
+		}
+		if !strict && (err != nil || !moved) {
+			t.Errorf("not strict: error %v, branch moved %v; want a commit", err, moved)
+		}
+	}
+}
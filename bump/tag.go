@@ -0,0 +1,67 @@
+package bump
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/v32/github"
+)
+
+// taggedCommit is the commit a tag points at.
+type taggedCommit struct {
+	SHA  string
+	Date time.Time
+}
+
+// resolveTag finds the commit that tag points to in source, given as owner/repo.  Annotated
+// tags are followed to the commit they tag.
+func resolveTag(ctx context.Context, client *github.Client, source, tag string) (*taggedCommit, error) {
+	parts := strings.SplitN(source, "/", 2)
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return nil, fmt.Errorf("tag source %q is not of the form owner/repo", source)
+	}
+	owner, repo := parts[0], parts[1]
+
+	sha, err := tagCommit(ctx, client, owner, repo, tag)
+	if err != nil {
+		return nil, err
+	}
+	commit, _, err := client.Git.GetCommit(ctx, owner, repo, sha)
+	if err != nil {
+		return nil, fmt.Errorf("get commit %s in %s: %w", sha, source, err)
+	}
+	return &taggedCommit{SHA: commit.GetSHA(), Date: commit.GetCommitter().GetDate()}, nil
+}
+
+// tagCommit returns the SHA of the commit that tag points to in owner/repo, following annotated
+// tags.
+func tagCommit(ctx context.Context, client *github.Client, owner, repo, tag string) (string, error) {
+	source := owner + "/" + repo
+	ref, _, err := client.Git.GetRef(ctx, owner, repo, "tags/"+tag)
+	if err != nil {
+		return "", fmt.Errorf("get tag %s in %s: %w", tag, source, err)
+	}
+	obj := ref.GetObject()
+	// Annotated tags can tag other tags, so follow them until we reach something else.
+	for obj.GetType() == "tag" {
+		t, _, err := client.Git.GetTag(ctx, owner, repo, obj.GetSHA())
+		if err != nil {
+			return "", fmt.Errorf("get annotated tag %s in %s: %w", obj.GetSHA(), source, err)
+		}
+		obj = t.GetObject()
+	}
+	if obj.GetType() != "commit" {
+		return "", fmt.Errorf("tag %s in %s points to a %s, not a commit", tag, source, obj.GetType())
+	}
+	return obj.GetSHA(), nil
+}
+
+// checkTagAge returns an error if the tagged commit was made more than maxAge before now.
+func checkTagAge(tag string, c *taggedCommit, maxAge time.Duration, now time.Time) error {
+	if age := now.Sub(c.Date); age > maxAge {
+		return fmt.Errorf("tag %s points to commit %s from %s, which is older than --max-tag-age=%s", tag, c.SHA, c.Date.Format(time.RFC3339), maxAge)
+	}
+	return nil
+}
@@ -0,0 +1,68 @@
+package bump
+
+import (
+	"context"
+	"io/ioutil"
+	"testing"
+	"time"
+)
+
+func TestMaxTagAge(t *testing.T) {
+	gh, client := newFakeGitHub(t)
+	gh.push("o/r", "master", map[string]string{"config.yaml": lines("image:", "  tag: v1.0.0")})
+	now := time.Now()
+	gh.tag("o/app", "v1.1.0", now.Add(-400*24*time.Hour), false)
+	gh.tag("o/app", "v1.2.0", now.Add(-time.Hour), false)
+	gh.tag("o/app", "v1.3.0", now.Add(-2*time.Hour), true)
+	gh.tag("o/app", "v0.9.0", now.Add(-1000*24*time.Hour), true)
+
+	testData := []struct {
+		name, tag string
+		wantErr   bool
+	}{
+		{name: "old lightweight tag", tag: "v1.1.0", wantErr: true},
+		{name: "recent lightweight tag", tag: "v1.2.0"},
+		{name: "recent annotated tag", tag: "v1.3.0"},
+		{name: "old annotated tag", tag: "v0.9.0", wantErr: true},
+		{name: "missing tag", tag: "v9.9.9", wantErr: true},
+	}
+	for _, test := range testData {
+		t.Run(test.name, func(t *testing.T) {
+			before := gh.head("o/r", "master").SHA
+			cfg := &config{
+				GithubOwner:  "o",
+				GithubRepo:   "r",
+				GithubBranch: "master",
+				File:         "config.yaml",
+				Locations:    []string{"image.tag"},
+				Replacement:  test.tag,
+				TagSource:    "o/app",
+				MaxTagAge:    30 * 24 * time.Hour,
+			}
+			err := run(context.Background(), client, cfg, ioutil.Discard)
+			if test.wantErr {
+				if err == nil {
+					t.Error("expected error")
+				}
+				if after := gh.head("o/r", "master").SHA; after != before {
+					t.Errorf("branch moved from %s to %s despite the error", before, after)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("run: %v", err)
+			}
+			if got, _ := gh.file("o/r", "master", "config.yaml"); got != lines("image:", "  tag: "+test.tag) {
+				t.Errorf("content: got %q", got)
+			}
+		})
+	}
+}
+
+func TestMaxTagAgeRequiresTagSource(t *testing.T) {
+	_, client := newFakeGitHub(t)
+	cfg := &config{GithubOwner: "o", GithubRepo: "r", GithubBranch: "master", File: "config.yaml", MaxTagAge: time.Hour}
+	if err := run(context.Background(), client, cfg, ioutil.Discard); err == nil {
+		t.Error("expected an error using --max-tag-age without --tag-source")
+	}
+}
@@ -0,0 +1,618 @@
+package bump
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"sigs.k8s.io/kustomize/kyaml/yaml"
+)
+
+// editTOML is like editYAML, for a TOML document.  Like editJSON, the document is converted to
+// YAML, edited with editYAML, and each value that changed is written back into input where it
+// was, leaving comments, tables, and the rest of the formatting as they were.  Edits that do
+// more than change values, like adding or removing keys, can't be written back, and fail.
+func editTOML(input string, edits []locationEdit, opts editOptions) (string, []change, error) {
+	node, spans, err := parseTOML(input)
+	if err != nil {
+		return "", nil, fmt.Errorf("parse toml: %w", err)
+	}
+	before, err := yaml.NewRNode(node).String()
+	if err != nil {
+		return "", nil, fmt.Errorf("convert toml to yaml: %w", err)
+	}
+	after, changes, err := editYAML(before, edits, opts)
+	if err != nil {
+		return "", nil, err
+	}
+	if after == before {
+		return input, changes, nil
+	}
+	edited, err := yaml.Parse(after)
+	if err != nil {
+		return "", nil, fmt.Errorf("parse edited yaml: %w", err)
+	}
+	out, ok, err := spliceScalars(input, node, edited.YNode(), spans, writeTOMLScalar)
+	if err != nil {
+		return "", nil, err
+	}
+	if !ok {
+		return "", nil, errors.New("the edit changes more than values, which cannot be written back into a TOML file")
+	}
+	return out, changes, nil
+}
+
+// tomlDate matches the dates, times, and date-times of TOML.
+var tomlDate = regexp.MustCompile(`^(\d{4}-\d{2}-\d{2}([Tt ]\d{2}:\d{2}:\d{2}(\.\d+)?([Zz]|[+-]\d{2}:\d{2})?)?|\d{2}:\d{2}:\d{2}(\.\d+)?)$`)
+
+// tomlInt and tomlFloat match decimal integers and floats, as TOML writes them, after any
+// underscores are removed.
+var (
+	tomlInt   = regexp.MustCompile(`^[+-]?(0|[1-9][0-9]*)$`)
+	tomlFloat = regexp.MustCompile(`^[+-]?(0|[1-9][0-9]*)(\.[0-9]+)?([eE][+-]?[0-9]+)?$`)
+)
+
+// writeTOMLScalar returns edited, the new value of orig, written as a TOML value.  Strings are
+// written with the quotes orig had, if they can be, and dates are left unquoted if they still
+// are dates.
+func writeTOMLScalar(orig, edited *yaml.Node) (string, error) {
+	v := edited.Value
+	switch edited.ShortTag() {
+	case yaml.NodeTagInt:
+		if tomlInt.MatchString(v) {
+			return v, nil
+		}
+	case yaml.NodeTagFloat:
+		switch strings.TrimPrefix(strings.TrimPrefix(strings.ToLower(v), "+"), "-") {
+		case ".inf":
+			return strings.Replace(strings.ToLower(v), ".inf", "inf", 1), nil
+		case ".nan":
+			return "nan", nil
+		}
+		if tomlFloat.MatchString(v) {
+			return v, nil
+		}
+	case yaml.NodeTagBool:
+		if v == "true" || v == "false" {
+			return v, nil
+		}
+	case yaml.NodeTagNull:
+		return "", fmt.Errorf("cannot set a null value in a TOML file")
+	case yaml.NodeTagString:
+		if orig.ShortTag() == yaml.NodeTagString && orig.Style == 0 && tomlDate.MatchString(v) {
+			return v, nil
+		}
+		if orig.Style == yaml.SingleQuotedStyle && !strings.ContainsAny(v, "'\r\n") {
+			return "'" + v + "'", nil
+		}
+	}
+	return quoteTOML(v), nil
+}
+
+// quoteTOML returns s as a TOML basic string.
+func quoteTOML(s string) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	for _, r := range s {
+		switch r {
+		case '"':
+			b.WriteString(`\"`)
+		case '\\':
+			b.WriteString(`\\`)
+		case '\b':
+			b.WriteString(`\b`)
+		case '\t':
+			b.WriteString(`\t`)
+		case '\n':
+			b.WriteString(`\n`)
+		case '\f':
+			b.WriteString(`\f`)
+		case '\r':
+			b.WriteString(`\r`)
+		default:
+			if r < 0x20 || r == 0x7f {
+				fmt.Fprintf(&b, `\u%04X`, r)
+			} else {
+				b.WriteRune(r)
+			}
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
+
+// parseTOML parses the TOML document in input into YAML nodes: tables become mappings, with
+// keys in the order they first appear; arrays, and arrays of tables, become sequences; and
+// strings are quoted so that they stay strings.  Integers are written in decimal, and dates as
+// unquoted strings.  It also returns where in input each scalar value is.
+func parseTOML(input string) (*yaml.Node, map[*yaml.Node]span, error) {
+	p := &tomlParser{input: input, spans: map[*yaml.Node]span{}}
+	root := &yaml.Node{Kind: yaml.MappingNode, Tag: yaml.NodeTagMap}
+	if err := p.document(root); err != nil {
+		return nil, nil, fmt.Errorf("line %d: %w", strings.Count(input[:p.pos], "\n")+1, err)
+	}
+	return root, p.spans, nil
+}
+
+type tomlParser struct {
+	input string
+	pos   int
+	spans map[*yaml.Node]span
+}
+
+// document parses the whole document into root.
+func (p *tomlParser) document(root *yaml.Node) error {
+	table := root
+	for {
+		p.skipSpace(true)
+		if p.pos >= len(p.input) {
+			return nil
+		}
+		var err error
+		if p.input[p.pos] == '[' {
+			table, err = p.header(root)
+		} else {
+			err = p.keyValue(table)
+		}
+		if err != nil {
+			return err
+		}
+		if err := p.endOfLine(); err != nil {
+			return err
+		}
+	}
+}
+
+// header parses a [table] or [[array of tables]] header, and returns the table that the
+// key/value pairs after it belong to.
+func (p *tomlParser) header(root *yaml.Node) (*yaml.Node, error) {
+	array := strings.HasPrefix(p.input[p.pos:], "[[")
+	if array {
+		p.pos += 2
+	} else {
+		p.pos++
+	}
+	keys, err := p.key()
+	if err != nil {
+		return nil, err
+	}
+	closing := "]"
+	if array {
+		closing = "]]"
+	}
+	if !strings.HasPrefix(p.input[p.pos:], closing) {
+		return nil, fmt.Errorf("expected %s after table name", closing)
+	}
+	p.pos += len(closing)
+	table := root
+	for _, k := range keys[:len(keys)-1] {
+		if table, err = tomlTable(table, k); err != nil {
+			return nil, err
+		}
+	}
+	last := keys[len(keys)-1]
+	if !array {
+		return tomlTable(table, last)
+	}
+	seq := mappingValue(table, last)
+	if seq == nil {
+		seq = &yaml.Node{Kind: yaml.SequenceNode, Tag: yaml.NodeTagSeq}
+		table.Content = append(table.Content, tomlKey(last), seq)
+	} else if seq.Kind != yaml.SequenceNode {
+		return nil, fmt.Errorf("%s is not an array of tables", last)
+	}
+	t := &yaml.Node{Kind: yaml.MappingNode, Tag: yaml.NodeTagMap}
+	seq.Content = append(seq.Content, t)
+	return t, nil
+}
+
+// keyValue parses a key = value pair into table.
+func (p *tomlParser) keyValue(table *yaml.Node) error {
+	keys, err := p.key()
+	if err != nil {
+		return err
+	}
+	if p.pos >= len(p.input) || p.input[p.pos] != '=' {
+		return errors.New("expected = after key")
+	}
+	p.pos++
+	p.skipSpace(false)
+	value, err := p.value()
+	if err != nil {
+		return err
+	}
+	for _, k := range keys[:len(keys)-1] {
+		if table, err = tomlTable(table, k); err != nil {
+			return err
+		}
+	}
+	last := keys[len(keys)-1]
+	if mappingValue(table, last) != nil {
+		return fmt.Errorf("key %s is defined twice", last)
+	}
+	table.Content = append(table.Content, tomlKey(last), value)
+	return nil
+}
+
+// key parses a dotted key, and any whitespace after it.
+func (p *tomlParser) key() ([]string, error) {
+	var keys []string
+	for {
+		p.skipSpace(false)
+		if p.pos >= len(p.input) {
+			return nil, errors.New("expected a key")
+		}
+		var k string
+		switch c := p.input[p.pos]; {
+		case c == '"':
+			s, err := p.basicString()
+			if err != nil {
+				return nil, err
+			}
+			k = s
+		case c == '\'':
+			s, err := p.literalString()
+			if err != nil {
+				return nil, err
+			}
+			k = s
+		default:
+			start := p.pos
+			for p.pos < len(p.input) && isBareKeyChar(p.input[p.pos]) {
+				p.pos++
+			}
+			if p.pos == start {
+				return nil, fmt.Errorf("unexpected %q in key", p.input[p.pos])
+			}
+			k = p.input[start:p.pos]
+		}
+		keys = append(keys, k)
+		p.skipSpace(false)
+		if p.pos >= len(p.input) || p.input[p.pos] != '.' {
+			return keys, nil
+		}
+		p.pos++
+	}
+}
+
+func isBareKeyChar(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '_' || c == '-'
+}
+
+// value parses a value, recording where it is if it is a scalar.
+func (p *tomlParser) value() (*yaml.Node, error) {
+	if p.pos >= len(p.input) {
+		return nil, errors.New("expected a value")
+	}
+	start := p.pos
+	var node *yaml.Node
+	switch p.input[p.pos] {
+	case '[':
+		return p.array()
+	case '{':
+		return p.inlineTable()
+	case '"':
+		s, err := p.basicString()
+		if err != nil {
+			return nil, err
+		}
+		node = &yaml.Node{Kind: yaml.ScalarNode, Tag: yaml.NodeTagString, Value: s, Style: yaml.DoubleQuotedStyle}
+	case '\'':
+		s, err := p.literalString()
+		if err != nil {
+			return nil, err
+		}
+		node = &yaml.Node{Kind: yaml.ScalarNode, Tag: yaml.NodeTagString, Value: s, Style: yaml.SingleQuotedStyle}
+	default:
+		var err error
+		if node, err = p.bareValue(); err != nil {
+			return nil, err
+		}
+	}
+	p.spans[node] = span{start: start, end: p.pos}
+	return node, nil
+}
+
+// bareValue parses an unquoted value: a boolean, number, or date.
+func (p *tomlParser) bareValue() (*yaml.Node, error) {
+	start := p.pos
+	for p.pos < len(p.input) && strings.IndexByte(" \t\r\n,]}#", p.input[p.pos]) < 0 {
+		p.pos++
+	}
+	// A date and a time may be separated by a space.
+	if tomlDate.MatchString(p.input[start:p.pos]) && p.pos+1 < len(p.input) && p.input[p.pos] == ' ' && p.input[p.pos+1] >= '0' && p.input[p.pos+1] <= '9' {
+		end := p.pos + 1
+		for end < len(p.input) && strings.IndexByte(" \t\r\n,]}#", p.input[end]) < 0 {
+			end++
+		}
+		if tomlDate.MatchString(p.input[start:end]) {
+			p.pos = end
+		}
+	}
+	token := p.input[start:p.pos]
+	scalar := func(tag, value string) *yaml.Node {
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: tag, Value: value}
+	}
+	switch {
+	case token == "true" || token == "false":
+		return scalar(yaml.NodeTagBool, token), nil
+	case tomlDate.MatchString(token):
+		return scalar(yaml.NodeTagString, token), nil
+	}
+	sign, digits := "", strings.ReplaceAll(token, "_", "")
+	if strings.HasPrefix(digits, "+") || strings.HasPrefix(digits, "-") {
+		sign, digits = digits[:1], digits[1:]
+	}
+	switch {
+	case digits == "inf" || digits == "nan":
+		return scalar(yaml.NodeTagFloat, sign+"."+digits), nil
+	case len(digits) > 2 && digits[0] == '0' && strings.ContainsRune("xob", rune(digits[1])):
+		n, ok := new(big.Int).SetString(digits[2:], map[byte]int{'x': 16, 'o': 8, 'b': 2}[digits[1]])
+		if !ok || sign != "" {
+			break
+		}
+		return scalar(yaml.NodeTagInt, n.String()), nil
+	case tomlInt.MatchString(sign + digits):
+		return scalar(yaml.NodeTagInt, strings.TrimPrefix(sign, "+")+digits), nil
+	case tomlFloat.MatchString(sign + digits):
+		return scalar(yaml.NodeTagFloat, strings.TrimPrefix(sign, "+")+digits), nil
+	}
+	if token == "" {
+		return nil, errors.New("expected a value")
+	}
+	return nil, fmt.Errorf("invalid value %q", token)
+}
+
+// array parses an array, which may span lines.
+func (p *tomlParser) array() (*yaml.Node, error) {
+	node := &yaml.Node{Kind: yaml.SequenceNode, Tag: yaml.NodeTagSeq}
+	p.pos++
+	for {
+		p.skipSpace(true)
+		if p.pos >= len(p.input) {
+			return nil, errors.New("unterminated array")
+		}
+		if p.input[p.pos] == ']' {
+			p.pos++
+			return node, nil
+		}
+		item, err := p.value()
+		if err != nil {
+			return nil, err
+		}
+		node.Content = append(node.Content, item)
+		p.skipSpace(true)
+		if p.pos < len(p.input) && p.input[p.pos] == ',' {
+			p.pos++
+		} else if p.pos >= len(p.input) || p.input[p.pos] != ']' {
+			return nil, errors.New("expected , or ] in array")
+		}
+	}
+}
+
+// inlineTable parses an inline table, which is all on one line.
+func (p *tomlParser) inlineTable() (*yaml.Node, error) {
+	node := &yaml.Node{Kind: yaml.MappingNode, Tag: yaml.NodeTagMap, Style: yaml.FlowStyle}
+	p.pos++
+	p.skipSpace(false)
+	if p.pos < len(p.input) && p.input[p.pos] == '}' {
+		p.pos++
+		return node, nil
+	}
+	for {
+		if err := p.keyValue(node); err != nil {
+			return nil, err
+		}
+		p.skipSpace(false)
+		if p.pos >= len(p.input) {
+			return nil, errors.New("unterminated inline table")
+		}
+		switch p.input[p.pos] {
+		case ',':
+			p.pos++
+		case '}':
+			p.pos++
+			return node, nil
+		default:
+			return nil, errors.New("expected , or } in inline table")
+		}
+	}
+}
+
+// basicString parses a string in double quotes, or in triple double quotes across lines, and
+// returns its value.
+func (p *tomlParser) basicString() (string, error) {
+	multiline := strings.HasPrefix(p.input[p.pos:], `"""`)
+	if multiline {
+		p.pos += 3
+		p.skipNewline()
+	} else {
+		p.pos++
+	}
+	var b strings.Builder
+	for {
+		if p.pos >= len(p.input) {
+			return "", errors.New("unterminated string")
+		}
+		c := p.input[p.pos]
+		switch {
+		case multiline && strings.HasPrefix(p.input[p.pos:], `"""`):
+			// Up to two more quotes may end the content.
+			end := p.pos + 3
+			for i := 0; i < 2 && end < len(p.input) && p.input[end] == '"'; i++ {
+				b.WriteByte('"')
+				end++
+			}
+			p.pos = end
+			return b.String(), nil
+		case !multiline && c == '"':
+			p.pos++
+			return b.String(), nil
+		case !multiline && c == '\n':
+			return "", errors.New("unterminated string")
+		case c == '\\':
+			if err := p.escape(&b, multiline); err != nil {
+				return "", err
+			}
+		default:
+			b.WriteByte(c)
+			p.pos++
+		}
+	}
+}
+
+// escape parses an escape sequence in a basic string into b.
+func (p *tomlParser) escape(b *strings.Builder, multiline bool) error {
+	p.pos++
+	if p.pos >= len(p.input) {
+		return errors.New("unterminated string")
+	}
+	c := p.input[p.pos]
+	p.pos++
+	switch c {
+	case 'b':
+		b.WriteByte('\b')
+	case 't':
+		b.WriteByte('\t')
+	case 'n':
+		b.WriteByte('\n')
+	case 'f':
+		b.WriteByte('\f')
+	case 'r':
+		b.WriteByte('\r')
+	case 'e':
+		b.WriteByte(0x1b)
+	case '"', '\\':
+		b.WriteByte(c)
+	case 'u', 'U':
+		n := 4
+		if c == 'U' {
+			n = 8
+		}
+		if p.pos+n > len(p.input) {
+			return errors.New("short unicode escape")
+		}
+		r, err := strconv.ParseUint(p.input[p.pos:p.pos+n], 16, 32)
+		if err != nil || !utf8.ValidRune(rune(r)) {
+			return fmt.Errorf("invalid unicode escape \\%c%s", c, p.input[p.pos:p.pos+n])
+		}
+		b.WriteRune(rune(r))
+		p.pos += n
+	default:
+		// In a multi-line string, a backslash at the end of a line trims the line break and
+		// the whitespace after it.
+		rest := strings.TrimLeft(p.input[p.pos-1:], " \t")
+		if multiline && (strings.HasPrefix(rest, "\n") || strings.HasPrefix(rest, "\r\n")) {
+			p.pos = len(p.input) - len(strings.TrimLeft(rest, " \t\r\n"))
+			return nil
+		}
+		return fmt.Errorf("invalid escape \\%c", c)
+	}
+	return nil
+}
+
+// literalString parses a string in single quotes, or in triple single quotes across lines,
+// and returns its value.
+func (p *tomlParser) literalString() (string, error) {
+	if strings.HasPrefix(p.input[p.pos:], "'''") {
+		p.pos += 3
+		p.skipNewline()
+		end := strings.Index(p.input[p.pos:], "'''")
+		if end < 0 {
+			return "", errors.New("unterminated string")
+		}
+		end += p.pos
+		// Up to two more quotes may end the content.
+		for i := 0; i < 2 && end+3 < len(p.input) && p.input[end+3] == '\''; i++ {
+			end++
+		}
+		s := p.input[p.pos:end]
+		p.pos = end + 3
+		return s, nil
+	}
+	p.pos++
+	end := strings.IndexAny(p.input[p.pos:], "'\n")
+	if end < 0 || p.input[p.pos+end] != '\'' {
+		return "", errors.New("unterminated string")
+	}
+	s := p.input[p.pos : p.pos+end]
+	p.pos += end + 1
+	return s, nil
+}
+
+// skipSpace skips spaces and tabs, and, if lines is set, line breaks and comments too.
+func (p *tomlParser) skipSpace(lines bool) {
+	for p.pos < len(p.input) {
+		switch c := p.input[p.pos]; {
+		case c == ' ' || c == '\t':
+			p.pos++
+		case lines && (c == '\n' || c == '\r'):
+			p.pos++
+		case lines && c == '#':
+			for p.pos < len(p.input) && p.input[p.pos] != '\n' {
+				p.pos++
+			}
+		default:
+			return
+		}
+	}
+}
+
+// skipNewline skips a line break right after the opening quotes of a multi-line string.
+func (p *tomlParser) skipNewline() {
+	if strings.HasPrefix(p.input[p.pos:], "\r\n") {
+		p.pos += 2
+	} else if strings.HasPrefix(p.input[p.pos:], "\n") {
+		p.pos++
+	}
+}
+
+// endOfLine checks that nothing but whitespace and a comment follows on the line.
+func (p *tomlParser) endOfLine() error {
+	p.skipSpace(false)
+	if p.pos < len(p.input) && p.input[p.pos] == '#' {
+		for p.pos < len(p.input) && p.input[p.pos] != '\n' {
+			p.pos++
+		}
+	}
+	if p.pos < len(p.input) && p.input[p.pos] != '\n' && p.input[p.pos] != '\r' {
+		return fmt.Errorf("unexpected %q at the end of a line", p.input[p.pos])
+	}
+	return nil
+}
+
+// tomlTable returns the table at key in table, creating it if it does not exist.  If key holds
+// an array of tables, the last one is returned, as TOML has headers after [[key]] refer to it.
+func tomlTable(table *yaml.Node, key string) (*yaml.Node, error) {
+	v := mappingValue(table, key)
+	switch {
+	case v == nil:
+		t := &yaml.Node{Kind: yaml.MappingNode, Tag: yaml.NodeTagMap}
+		table.Content = append(table.Content, tomlKey(key), t)
+		return t, nil
+	case v.Kind == yaml.MappingNode:
+		return v, nil
+	case v.Kind == yaml.SequenceNode && len(v.Content) > 0 && v.Content[len(v.Content)-1].Kind == yaml.MappingNode:
+		return v.Content[len(v.Content)-1], nil
+	}
+	return nil, fmt.Errorf("%s is not a table", key)
+}
+
+// mappingValue returns the value of key in mapping, or nil if it has none.
+func mappingValue(mapping *yaml.Node, key string) *yaml.Node {
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			return mapping.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// tomlKey returns a mapping key for key.
+func tomlKey(key string) *yaml.Node {
+	return &yaml.Node{Kind: yaml.ScalarNode, Tag: yaml.NodeTagString, Value: key}
+}
@@ -0,0 +1,160 @@
+package bump
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"sigs.k8s.io/kustomize/kyaml/yaml"
+)
+
+func TestEditTOML(t *testing.T) {
+	testData := []struct {
+		name        string
+		input       string
+		location    string
+		replacement string
+		want        string
+	}{
+		{
+			name:        "top level, keeping comments",
+			input:       lines("# Pins", `version = "1.0.0" # bumped by CI`, "", `name = "app"`),
+			location:    "version",
+			replacement: "1.1.0",
+			want:        lines("# Pins", `version = "1.1.0" # bumped by CI`, "", `name = "app"`),
+		},
+		{
+			name:        "nested tables",
+			input:       lines("[package]", `name = "app"`, `version = "0.1.0"`, "", "[dependencies.serde]", `version = "1.0"`, "features = [", `  "derive",`, "]"),
+			location:    "dependencies.serde.version",
+			replacement: "1.0.188",
+			want:        lines("[package]", `name = "app"`, `version = "0.1.0"`, "", "[dependencies.serde]", `version = "1.0.188"`, "features = [", `  "derive",`, "]"),
+		},
+		{
+			name:        "array of tables",
+			input:       lines("[[bin]]", `name = "a"`, "", "[[bin]]", `name = "b"`, "path = 'src/b.rs'"),
+			location:    "bin[1].path",
+			replacement: "cmd/b.rs",
+			want:        lines("[[bin]]", `name = "a"`, "", "[[bin]]", `name = "b"`, "path = 'cmd/b.rs'"),
+		},
+		{
+			name:        "subtable of an array of tables",
+			input:       lines("[[target]]", `name = "a"`, "[target.image]", `tag = "v1"`, "[[target]]", `name = "b"`, "[target.image]", `tag = "v1"`),
+			location:    "target[1].image.tag",
+			replacement: "v2",
+			want:        lines("[[target]]", `name = "a"`, "[target.image]", `tag = "v1"`, "[[target]]", `name = "b"`, "[target.image]", `tag = "v2"`),
+		},
+		{
+			name:        "dotted keys and inline tables",
+			input:       lines(`image.tag = "v1"`, `serde = { version = "1.0", features = ["derive"] }`),
+			location:    "serde.version",
+			replacement: "1.1",
+			want:        lines(`image.tag = "v1"`, `serde = { version = "1.1", features = ["derive"] }`),
+		},
+		{
+			name:        "quoted keys",
+			input:       lines(`"app.kubernetes.io/version" = "v1"`),
+			location:    `["app.kubernetes.io/version"]`,
+			replacement: "v2",
+			want:        lines(`"app.kubernetes.io/version" = "v2"`),
+		},
+		{
+			name:        "number stays a number",
+			input:       lines("replicas = 1_000", "ratio = 0.5", "enabled = true"),
+			location:    "replicas",
+			replacement: "3",
+			want:        lines("replicas = 3", "ratio = 0.5", "enabled = true"),
+		},
+		{
+			name:        "date stays a date",
+			input:       lines("released = 1979-05-27T07:32:00Z"),
+			location:    "released",
+			replacement: "2020-01-02T03:04:05Z",
+			want:        lines("released = 2020-01-02T03:04:05Z"),
+		},
+		{
+			name:        "escapes",
+			input:       lines(`motd = """`, `Hello,\`, `  world"""`, `tag = "v1"`),
+			location:    "tag",
+			replacement: `say "hi"`,
+			want:        lines(`motd = """`, `Hello,\`, `  world"""`, `tag = "say \"hi\""`),
+		},
+		{
+			name:        "missing location leaves the file alone",
+			input:       lines(`version = "1.0"`),
+			location:    "missing",
+			replacement: "1.1",
+			want:        lines(`version = "1.0"`),
+		},
+	}
+	for _, test := range testData {
+		t.Run(test.name, func(t *testing.T) {
+			got, _, err := editTOML(test.input, editsFor([]string{test.location}, test.replacement), editOptions{})
+			if err != nil {
+				t.Fatalf("edit: %v", err)
+			}
+			if diff := cmp.Diff(test.want, got); diff != "" {
+				t.Errorf("output (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestParseTOML(t *testing.T) {
+	testData := []struct {
+		name    string
+		input   string
+		want    string
+		wantErr string
+	}{
+		{
+			name:  "values",
+			input: lines(`s = "a\tb"`, "l = 'C:\\dir'", "h = 0xff", "f = -inf", "d = 1979-05-27 07:32:00", "a = [1, [2, 3]]", "m = '''", "x'''"),
+			want:  lines(`s: "a\tb"`, `l: 'C:\dir'`, "h: 255", "f: -.inf", `d: "1979-05-27 07:32:00"`, "a:", "- 1", "- - 2", "  - 3", "m: 'x'"),
+		},
+		{name: "duplicate key", input: lines("a = 1", "a = 2"), wantErr: "line 2: key a is defined twice"},
+		{name: "unterminated string", input: lines(`a = "b`), wantErr: "line 1: unterminated string"},
+		{name: "not a table", input: lines("a = 1", "[a.b]"), wantErr: "line 2: a is not a table"},
+		{name: "trailing garbage", input: lines("a = 1 2"), wantErr: "line 1: unexpected '2'"},
+	}
+	for _, test := range testData {
+		t.Run(test.name, func(t *testing.T) {
+			node, _, err := parseTOML(test.input)
+			if test.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+					t.Fatalf("want error containing %q, got %v", test.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parse: %v", err)
+			}
+			text, err := yaml.NewRNode(node).String()
+			if err != nil {
+				t.Fatalf("convert to yaml: %v", err)
+			}
+			got, err := normalize(text)
+			if err != nil {
+				t.Fatalf("normalize: %v", err)
+			}
+			want, err := normalize(test.want)
+			if err != nil {
+				t.Fatalf("normalize: %v", err)
+			}
+			if diff := cmp.Diff(want, got); diff != "" {
+				t.Errorf("yaml (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestEditTOMLObject(t *testing.T) {
+	object, err := parseObject(`{"pull": "always"}`)
+	if err != nil {
+		t.Fatalf("parse object: %v", err)
+	}
+	input := lines("[image]", `tag = "v1"`)
+	if _, _, err := editTOML(input, editsFor([]string{"image"}, ""), editOptions{Object: object}); err == nil || !strings.Contains(err.Error(), "changes more than values") {
+		t.Errorf("want an error adding a key, got %v", err)
+	}
+}
@@ -0,0 +1,47 @@
+package bump
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/google/go-github/v32/github"
+)
+
+// readUpstream reads the value that --upstream points to: the scalar at --upstream-location
+// (by default, the first --location) in --upstream-file (by default, --file) at the head of the
+// upstream repository's branch.
+func readUpstream(ctx context.Context, client *github.Client, cfg *config) (string, error) {
+	if cfg.Replacement != "" || cfg.FromLocation || cfg.Object != "" {
+		return "", errors.New("--upstream provides the replacement; do not pass --replacement, --replacement-is-location, or --replacement-object")
+	}
+	t, err := parseTarget(ctx, client, cfg.Upstream, "")
+	if err != nil {
+		return "", fmt.Errorf("upstream: %w", err)
+	}
+	file := cfg.UpstreamFile
+	if file == "" {
+		file = cfg.File
+	}
+	location := cfg.UpstreamLoc
+	if location == "" {
+		if len(cfg.Locations) == 0 {
+			return "", errors.New("--upstream needs --upstream-location or --location")
+		}
+		location = cfg.Locations[0]
+	}
+	f, err := fetch(ctx, client, t.Owner, t.Repo, t.Branch, file, "", false)
+	if err != nil {
+		return "", fmt.Errorf("fetch upstream %s from github.com/%s/%s@%s: %w", file, t.Owner, t.Repo, t.Branch, err)
+	}
+	value, found, err := valueAt(f.Content, location)
+	if err != nil {
+		return "", fmt.Errorf("read %s from upstream %s: %w", location, file, err)
+	}
+	if !found {
+		return "", fmt.Errorf("location %s not found in upstream %s at commit %s", location, file, f.CommitSHA)
+	}
+	log.Printf("using %q from %s in %s/%s@%s (commit %s) as the replacement", value, location, t.Owner, t.Repo, t.Branch, f.CommitSHA)
+	return value, nil
+}
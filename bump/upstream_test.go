@@ -0,0 +1,62 @@
+package bump
+
+import (
+	"context"
+	"io/ioutil"
+	"testing"
+)
+
+func TestUpstream(t *testing.T) {
+	testData := []struct {
+		name     string
+		upstream string
+		file     string
+		location string
+		want     string
+		wantErr  bool
+	}{
+		{name: "same file and location", upstream: "upstream/charts", want: lines("image:", "  tag: v1.4.0")},
+		{name: "branch", upstream: "upstream/charts@release", want: lines("image:", "  tag: v1.3.2")},
+		{name: "other file", upstream: "upstream/charts", file: "VERSIONS.yaml", location: "app", want: lines("image:", "  tag: v1.5.0-rc.1")},
+		{name: "missing location", upstream: "upstream/charts", location: "image.digest", wantErr: true},
+		{name: "missing file", upstream: "upstream/charts", file: "missing.yaml", wantErr: true},
+		{name: "bad repository", upstream: "upstream", wantErr: true},
+	}
+	for _, test := range testData {
+		t.Run(test.name, func(t *testing.T) {
+			gh, client := newFakeGitHub(t)
+			gh.push("upstream/charts", "main", map[string]string{
+				"config.yaml":   lines("image:", "  tag: v1.4.0"),
+				"VERSIONS.yaml": lines("app: v1.5.0-rc.1"),
+			})
+			gh.push("upstream/charts", "release", map[string]string{"config.yaml": lines("image:", "  tag: v1.3.2")})
+			before := gh.push("o/r", "master", map[string]string{"config.yaml": lines("image:", "  tag: v1.0.0")})
+			cfg := &config{
+				GithubOwner:  "o",
+				GithubRepo:   "r",
+				GithubBranch: "master",
+				File:         "config.yaml",
+				Locations:    []string{"image.tag"},
+				Upstream:     test.upstream,
+				UpstreamFile: test.file,
+				UpstreamLoc:  test.location,
+			}
+			err := run(context.Background(), client, cfg, ioutil.Discard)
+			if test.wantErr {
+				if err == nil {
+					t.Error("expected error")
+				}
+				if after := gh.head("o/r", "master").SHA; after != before {
+					t.Errorf("branch moved from %s to %s", before, after)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("run: %v", err)
+			}
+			if got, _ := gh.file("o/r", "master", "config.yaml"); got != test.want {
+				t.Errorf("content: got %q, want %q", got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,49 @@
+package bump
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// runValidator runs --validate-cmd with content, the edited content of cfg.File, on its stdin.
+// The command is split into words and run directly, not by a shell, with VERSION_BUMP_FILE set
+// to the name of the file in its environment.  Its stdout is copied to stderr.  If it exits
+// nonzero, the error includes what it printed to stderr.  It is killed if ctx expires.
+func runValidator(ctx context.Context, cfg *config, content string) error {
+	args := strings.Fields(cfg.ValidateCmd)
+	if len(args) == 0 {
+		return errors.New("--validate-cmd is empty")
+	}
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+	cmd.Env = append(os.Environ(), "VERSION_BUMP_FILE="+cfg.File)
+	cmd.Stdin = strings.NewReader(content)
+	cmd.Stdout = os.Stderr
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() != nil {
+			err = ctx.Err()
+		}
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return fmt.Errorf("validator %s rejected edited %s: %w: %s", args[0], cfg.File, err, msg)
+		}
+		return fmt.Errorf("validator %s rejected edited %s: %w", args[0], cfg.File, err)
+	}
+	log.Printf("validator %s accepted edited %s", args[0], cfg.File)
+	return nil
+}
+
+// validates returns true if --validate-cmd should be run: always when committing, and in dry
+// runs only with --validate-in-dry-run.
+func validates(cfg *config) bool {
+	if cfg.ValidateCmd == "" {
+		return false
+	}
+	return !(cfg.DryRun || cfg.DryRunApply || cfg.LocalRepo != "") || cfg.ValidateDry
+}
@@ -0,0 +1,59 @@
+package bump
+
+import (
+	"context"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestValidateCmd(t *testing.T) {
+	testData := []struct {
+		name        string
+		script      string
+		dryRun      bool
+		validateDry bool
+		wantCommit  bool
+		wantErr     string
+	}{
+		{name: "accepts", script: `grep -q "tag: v2"`, wantCommit: true},
+		{name: "sees the file name", script: `test "$VERSION_BUMP_FILE" = config.yaml`, wantCommit: true},
+		{name: "rejects", script: `echo "bad tag" >&2; exit 1`, wantErr: "exit status 1: bad tag"},
+		{name: "skipped in dry run", script: `exit 1`, dryRun: true},
+		{name: "run in dry run", script: `exit 1`, dryRun: true, validateDry: true, wantErr: "rejected edited config.yaml"},
+	}
+	for _, test := range testData {
+		t.Run(test.name, func(t *testing.T) {
+			script := filepath.Join(t.TempDir(), "validate.sh")
+			if err := ioutil.WriteFile(script, []byte(test.script+"\n"), 0644); err != nil {
+				t.Fatalf("write script: %v", err)
+			}
+			gh, client := newFakeGitHub(t)
+			before := gh.push("o/r", "master", map[string]string{"config.yaml": lines("image:", "  tag: v1")})
+			cfg := &config{
+				GithubOwner:   "o",
+				GithubRepo:    "r",
+				GithubBranch:  "master",
+				File:          "config.yaml",
+				Locations:     []string{"image.tag"},
+				Replacement:   "v2",
+				CommitMessage: "Bump image.tag",
+				ValidateCmd:   "sh " + script,
+				ValidateDry:   test.validateDry,
+				DryRun:        test.dryRun,
+			}
+			err := run(context.Background(), client, cfg, ioutil.Discard)
+			if test.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+					t.Errorf("want error containing %q, got %v", test.wantErr, err)
+				}
+			} else if err != nil {
+				t.Fatalf("run: %v", err)
+			}
+			if moved := gh.head("o/r", "master").SHA != before; moved != test.wantCommit {
+				t.Errorf("committed: got %v, want %v", moved, test.wantCommit)
+			}
+		})
+	}
+}
@@ -0,0 +1,166 @@
+package bump
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// errOutsideWindow is returned when a commit is refused because of --allow-window.
+var errOutsideWindow = errors.New("outside change window")
+
+// changeWindow is a set of times of the week during which commits are allowed, parsed from a
+// spec like "Mon-Fri 09:00-17:00 America/New_York".
+type changeWindow struct {
+	days   [7]bool // Indexed by time.Weekday.
+	ranges []clockRange
+	loc    *time.Location
+}
+
+// clockRange is a range of times of day, in minutes since midnight.  If end is before start,
+// the range runs past midnight into the next day.
+type clockRange struct {
+	start, end int
+}
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday, "mon": time.Monday, "tue": time.Tuesday, "wed": time.Wednesday,
+	"thu": time.Thursday, "fri": time.Friday, "sat": time.Saturday,
+}
+
+// parseWindow parses a change window.  A spec has up to three fields: the days, as a
+// comma-separated list of days and ranges of days like "Mon-Fri,Sun", defaulting to every day;
+// the times, as a comma-separated list of ranges like "09:00-12:00,13:00-17:00"; and the time
+// zone, as an IANA name, defaulting to UTC.  A range of times like "22:00-02:00" belongs to the
+// day it starts on.
+func parseWindow(spec string) (*changeWindow, error) {
+	w := &changeWindow{loc: time.UTC}
+	fields := strings.Fields(spec)
+	if len(fields) == 0 || len(fields) > 3 {
+		return nil, fmt.Errorf("change window %q: want days, times, and a time zone, like \"Mon-Fri 09:00-17:00 America/New_York\"", spec)
+	}
+	if !strings.Contains(fields[0], ":") {
+		if err := w.parseDays(fields[0]); err != nil {
+			return nil, fmt.Errorf("change window %q: %w", spec, err)
+		}
+		fields = fields[1:]
+	} else {
+		for d := range w.days {
+			w.days[d] = true
+		}
+	}
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("change window %q has no times", spec)
+	}
+	for _, r := range strings.Split(fields[0], ",") {
+		parts := strings.Split(r, "-")
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("change window %q: times %q are not a range like 09:00-17:00", spec, r)
+		}
+		start, err := parseClock(parts[0])
+		if err != nil {
+			return nil, fmt.Errorf("change window %q: %w", spec, err)
+		}
+		end, err := parseClock(parts[1])
+		if err != nil {
+			return nil, fmt.Errorf("change window %q: %w", spec, err)
+		}
+		if start == end {
+			return nil, fmt.Errorf("change window %q: times %q are empty", spec, r)
+		}
+		w.ranges = append(w.ranges, clockRange{start: start, end: end})
+	}
+	if len(fields) > 2 {
+		return nil, fmt.Errorf("change window %q: unexpected %q after the time zone", spec, fields[2])
+	}
+	if len(fields) == 2 {
+		loc, err := time.LoadLocation(fields[1])
+		if err != nil {
+			return nil, fmt.Errorf("change window %q: %w", spec, err)
+		}
+		w.loc = loc
+	}
+	return w, nil
+}
+
+// parseDays parses a list of days like "Mon-Fri,Sun" into w.days.  Ranges may wrap around the
+// end of the week, like "Fri-Mon".
+func (w *changeWindow) parseDays(spec string) error {
+	for _, r := range strings.Split(spec, ",") {
+		parts := strings.Split(r, "-")
+		if len(parts) > 2 {
+			return fmt.Errorf("days %q are not a day or a range like Mon-Fri", r)
+		}
+		var days []time.Weekday
+		for _, p := range parts {
+			d, ok := weekdays[strings.ToLower(p)]
+			if !ok {
+				return fmt.Errorf("unknown day %q; want Mon, Tue, Wed, Thu, Fri, Sat, or Sun", p)
+			}
+			days = append(days, d)
+		}
+		d, last := days[0], days[len(days)-1]
+		for {
+			w.days[d] = true
+			if d == last {
+				break
+			}
+			d = (d + 1) % 7
+		}
+	}
+	return nil
+}
+
+// parseClock parses a time of day like "09:00" into minutes since midnight.  "24:00" is the end
+// of the day.
+func parseClock(s string) (int, error) {
+	if len(s) != 5 || s[2] != ':' || strings.Trim(s[:2]+s[3:], "0123456789") != "" {
+		return 0, fmt.Errorf("time %q is not like 09:00", s)
+	}
+	h, _ := strconv.Atoi(s[:2])
+	m, _ := strconv.Atoi(s[3:])
+	if m > 59 || h > 24 || h == 24 && m != 0 {
+		return 0, fmt.Errorf("time %q is out of range", s)
+	}
+	return h*60 + m, nil
+}
+
+// contains returns true if t is inside the window.
+func (w *changeWindow) contains(t time.Time) bool {
+	t = t.In(w.loc)
+	minute := t.Hour()*60 + t.Minute()
+	yesterday := (t.Weekday() + 6) % 7
+	for _, r := range w.ranges {
+		if r.start < r.end {
+			if w.days[t.Weekday()] && minute >= r.start && minute < r.end {
+				return true
+			}
+			continue
+		}
+		// The range runs past midnight.
+		if w.days[t.Weekday()] && minute >= r.start || w.days[yesterday] && minute < r.end {
+			return true
+		}
+	}
+	return false
+}
+
+// checkWindows returns an error wrapping errOutsideWindow if windows are given and now is in
+// none of them.
+func checkWindows(specs []string, now time.Time) error {
+	if len(specs) == 0 {
+		return nil
+	}
+	for _, spec := range specs {
+		w, err := parseWindow(spec)
+		if err != nil {
+			return err
+		}
+		if w.contains(now) {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: %s is not in %s", errOutsideWindow, now.Format(time.RFC3339), strings.Join(specs, " or "))
+}
@@ -0,0 +1,115 @@
+package bump
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"testing"
+	"time"
+)
+
+func TestChangeWindow(t *testing.T) {
+	ny, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Fatalf("load time zone: %v", err)
+	}
+	// 2020-06-03 was a Wednesday.
+	wednesday := func(hour, minute int, loc *time.Location) time.Time {
+		return time.Date(2020, 6, 3, hour, minute, 0, 0, loc)
+	}
+	testData := []struct {
+		name  string
+		specs []string
+		now   time.Time
+		want  bool
+	}{
+		{name: "inside", specs: []string{"Mon-Fri 09:00-17:00 America/New_York"}, now: wednesday(12, 0, ny), want: true},
+		{name: "start is inside", specs: []string{"Mon-Fri 09:00-17:00 America/New_York"}, now: wednesday(9, 0, ny), want: true},
+		{name: "end is outside", specs: []string{"Mon-Fri 09:00-17:00 America/New_York"}, now: wednesday(17, 0, ny)},
+		{name: "inside in another zone", specs: []string{"Mon-Fri 09:00-17:00 America/New_York"}, now: wednesday(20, 0, time.UTC), want: true},
+		{name: "outside in another zone", specs: []string{"Mon-Fri 09:00-17:00 America/New_York"}, now: wednesday(12, 0, time.UTC)},
+		{name: "weekend", specs: []string{"Mon-Fri 09:00-17:00 America/New_York"}, now: wednesday(12, 0, ny).AddDate(0, 0, 3)},
+		{name: "default zone is UTC", specs: []string{"Wed 09:00-17:00"}, now: wednesday(9, 30, time.UTC), want: true},
+		{name: "every day", specs: []string{"00:00-24:00"}, now: wednesday(23, 59, time.UTC), want: true},
+		{name: "lunch break", specs: []string{"Mon-Fri 09:00-12:00,13:00-17:00"}, now: wednesday(12, 30, time.UTC)},
+		{name: "second range", specs: []string{"Mon-Fri 09:00-12:00,13:00-17:00"}, now: wednesday(13, 30, time.UTC), want: true},
+		{name: "second window", specs: []string{"Mon 09:00-17:00", "Wed,Fri 09:00-17:00"}, now: wednesday(10, 0, time.UTC), want: true},
+		{name: "past midnight", specs: []string{"Tue 22:00-02:00"}, now: wednesday(1, 0, time.UTC), want: true},
+		{name: "past midnight, wrong day", specs: []string{"Wed 22:00-02:00"}, now: wednesday(1, 0, time.UTC)},
+		{name: "days wrap around the week", specs: []string{"Fri-Mon 00:00-24:00"}, now: wednesday(1, 0, time.UTC).AddDate(0, 0, 4), want: true},
+	}
+	for _, test := range testData {
+		t.Run(test.name, func(t *testing.T) {
+			err := checkWindows(test.specs, test.now)
+			if test.want && err != nil {
+				t.Errorf("want %s inside %v, got %v", test.now, test.specs, err)
+			}
+			if !test.want && !errors.Is(err, errOutsideWindow) {
+				t.Errorf("want %s outside %v, got %v", test.now, test.specs, err)
+			}
+		})
+	}
+}
+
+func TestParseWindowErrors(t *testing.T) {
+	for _, spec := range []string{
+		"",
+		"Mon-Fri",
+		"Mon-Fri 9:00-17:00",
+		"Mon-Fri 09:00-25:00",
+		"Mon-Fri 09:00",
+		"Mon-Fri 09:00-09:00",
+		"Someday 09:00-17:00",
+		"Mon-Fri 09:00-17:00 Nowhere/Special",
+		"Mon-Fri 09:00-17:00 UTC extra",
+		"09:00-17:00 UTC extra",
+	} {
+		if _, err := parseWindow(spec); err == nil {
+			t.Errorf("parse %q: expected error", spec)
+		}
+	}
+}
+
+func TestAllowWindowRefusesCommit(t *testing.T) {
+	// A window on the day after tomorrow is certainly not now.
+	later := time.Now().UTC().AddDate(0, 0, 2).Format("Mon")
+	testData := []struct {
+		name    string
+		window  string
+		dryRun  bool
+		wantErr bool
+	}{
+		{name: "inside", window: "00:00-24:00"},
+		{name: "outside", window: later + " 00:00-24:00", wantErr: true},
+		{name: "dry run outside", window: later + " 00:00-24:00", dryRun: true},
+	}
+	for _, test := range testData {
+		t.Run(test.name, func(t *testing.T) {
+			gh, client := newFakeGitHub(t)
+			before := gh.push("o/r", "master", map[string]string{"config.yaml": lines("image:", "  tag: v1")})
+			cfg := &config{
+				GithubOwner:  "o",
+				GithubRepo:   "r",
+				GithubBranch: "master",
+				File:         "config.yaml",
+				Locations:    []string{"image.tag"},
+				Replacement:  "v2",
+				AllowWindows: []string{test.window},
+				DryRun:       test.dryRun,
+			}
+			err := run(context.Background(), client, cfg, ioutil.Discard)
+			if test.wantErr {
+				if !errors.Is(err, errOutsideWindow) {
+					t.Errorf("want an error about the change window, got %v", err)
+				}
+				if after := gh.head("o/r", "master").SHA; after != before {
+					t.Errorf("branch moved from %s to %s", before, after)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("run: %v", err)
+			}
+		})
+	}
+}
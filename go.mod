@@ -4,9 +4,13 @@ go 1.15
 
 require (
 	github.com/bradleyfalzon/ghinstallation v1.1.1
+	github.com/go-git/go-billy/v5 v5.0.0
+	github.com/go-git/go-git/v5 v5.1.0
 	github.com/google/go-cmp v0.3.1
 	github.com/google/go-github/v32 v32.1.0
 	github.com/jessevdk/go-flags v1.4.0
+	github.com/xeipuuv/gojsonschema v1.2.0
+	golang.org/x/crypto v0.0.0-20200302210943-78000ba7a073
 	golang.org/x/oauth2 v0.0.0-20200107190931-bf48bf16ab8d
 	sigs.k8s.io/kustomize/kyaml v0.7.0
 )